@@ -1,18 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	qrcode "github.com/skip2/go-qrcode"
 	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restful/jwtauth"
 	"github.com/tangblue/goapi/restfulspec"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -32,22 +33,15 @@ type JWTToken struct {
 type Auth struct {
 	secret string
 
-	paramAuth   *restful.Parameter
 	paramOAuth2 *restful.Parameter
 	paramCode   *restful.Parameter
 	paramData   *restful.Parameter
 
-	errorAuth *restful.ResponseError
+	jwt       *jwtauth.Authenticator
+	tenantKey restful.FilterFunction
 }
 
 func NewAuth(secret string) *Auth {
-	paramAuth := restful.HeaderParameter("authorization", "JWT in authorization header").
-		Regex(`[Bb]earer \w+\.\w+\.\w+`).
-		DataType("Bearer ")
-	paramAuth.CommonValidations.
-		WithMinLength(8).
-		WithMaxLength(128)
-
 	paramOAuth2 := restful.QueryParameter("oauth2", "OAuth2")
 	paramOAuth2.CommonValidations.WithEnum("google")
 
@@ -60,47 +54,47 @@ func NewAuth(secret string) *Auth {
 	paramData := restful.QueryParameter("data", "qr data")
 	paramData.AsRequired()
 
-	return &Auth{
+	a := &Auth{
 		secret:      secret,
-		paramAuth:   paramAuth,
 		paramOAuth2: paramOAuth2,
 		paramCode:   paramCode,
 		paramData:   paramData,
-		errorAuth:   restful.NewResponseError(http.StatusUnauthorized, "Not Authorized", nil).SetRefName("Unauthorized"),
 	}
+	// the secret is checked by CheckSecrets, such that the service starts without it
+	a.jwt, _ = jwtauth.New(jwtauth.Config{KeyFunc: a.hmacSecret, Algorithms: []string{"HS256"}})
+	// the location of the tenant key is valid
+	a.tenantKey, _ = restful.APIKeyFilter(tenantKey)
+	return a
 }
 
 func (a *Auth) WebService(path string, tags []string) *restful.WebService {
 	ws := new(restful.WebService)
 	ws.Path(path).
 		Consumes(restful.MIME_JSON).
-		Produces(restful.MIME_JSON)
+		Produces(restful.MIME_JSON).
+		Metadata(restfulspec.KeyOpenAPITags, tags)
 
 	ws.Route(ws.GET("").Doc("login with OAuth2").
 		Handler(a.loginOAuth2).
 		Params(a.paramOAuth2).
 		Return(http.StatusOK, "OK", JWTToken{}).
-		Return(http.StatusInternalServerError, "Internal Server Error", nil).
-		Metadata(restfulspec.KeyOpenAPITags, tags))
+		Return(http.StatusInternalServerError, "Internal Server Error", nil))
 
 	ws.Route(ws.POST("").Doc("login user").
 		Handler(a.loginUser).
 		Read(LoginInfo{}).
 		Return(http.StatusOK, "OK", JWTToken{}).
 		Return(http.StatusInternalServerError, "Internal Server Error", nil).
-		Return(http.StatusUnprocessableEntity, "Bad user name or password", nil).
-		Metadata(restfulspec.KeyOpenAPITags, tags))
+		Return(http.StatusUnprocessableEntity, "Bad user name or password", nil))
 
 	ws.Route(ws.GET("/auth").Doc("oauth2 google").
 		Handler(a.oauth2).
-		Params(a.paramCode).
-		Metadata(restfulspec.KeyOpenAPITags, tags))
+		Params(a.paramCode))
 
 	ws.Route(ws.GET("/qr").Doc("qr code").
 		Handler(a.qr).
 		Params(a.paramData).
-		Produces("image/png").
-		Metadata(restfulspec.KeyOpenAPITags, tags))
+		WriteBinary("image/png"))
 
 	return ws
 }
@@ -108,7 +102,7 @@ func (a *Auth) WebService(path string, tags []string) *restful.WebService {
 var conf = &oauth2.Config{
 	ClientID:     secret.ClientID,
 	ClientSecret: secret.ClientSecret,
-	RedirectURL:  "http://127.0.0.1:8080/login/auth",
+	RedirectURL:  "http://127.0.0.1:8080/api/v1/login/auth",
 	Scopes: []string{
 		"https://www.googleapis.com/auth/userinfo.email",
 	},
@@ -156,17 +150,6 @@ func (a *Auth) oauth2(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(a.createJWTToken(u.Email))
 }
 
-func (a *Auth) basicAuthenticate(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
-	// usr/pwd = admin/admin
-	u, p, ok := req.Request.BasicAuth()
-	if !ok || u != "admin" || p != "admin" {
-		resp.AddHeader("WWW-Authenticate", "Basic realm=Protected Area")
-		resp.WriteErrorString(http.StatusUnauthorized, "401: Not Authorized")
-		return
-	}
-	next(req, resp)
-}
-
 func (a *Auth) loginOAuth2(req *restful.Request, resp *restful.Response) {
 	var vendor string
 	if err := req.GetParameter(a.paramOAuth2, &vendor); err != nil {
@@ -206,9 +189,20 @@ func (a *Auth) qr(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	resp.AddHeader("Content-Type", "image/png")
-	resp.AddHeader("Content-Length", strconv.Itoa(len(png)))
-	resp.Write(png)
+	// WriteFile sets Content-Type and Content-Length and handles Range requests
+	resp.WriteFile("qr.png", time.Time{}, bytes.NewReader(png))
+}
+
+// CheckSecrets reports whether the secrets to sign tokens and to log in with OAuth2 are available ;
+// it is a readiness check of the service.
+func (a *Auth) CheckSecrets(ctx context.Context) error {
+	if len(a.secret) == 0 {
+		return errors.New("no secret to sign tokens")
+	}
+	if len(conf.ClientID) == 0 || len(conf.ClientSecret) == 0 {
+		return errors.New("no OAuth2 client credentials")
+	}
+	return ctx.Err()
 }
 
 func (a *Auth) createJWTToken(sub string) JWTToken {
@@ -220,49 +214,32 @@ func (a *Auth) createJWTToken(sub string) JWTToken {
 	return JWTToken{Token: tokenString}
 }
 
-func (a *Auth) validateJWTToken(req *restful.Request) *jwt.Token {
-	var ah string
-	if err := req.GetParameter(a.paramAuth, &ah); err != nil {
-		log.Printf("Error in parameter {%s}: %s", a.paramAuth, err)
-		return nil
+// hmacSecret is the key of the tokens
+func (a *Auth) hmacSecret(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(a.secret) == 0 {
+		return nil, errors.New("no key for the token")
 	}
-	bt := strings.Fields(ah)
-	if len(bt) != 2 || !strings.EqualFold(bt[0], "bearer") {
-		return nil
-	}
-
-	token, err := jwt.Parse(bt[1], func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("There was an error")
-		}
-		return []byte(a.secret), nil
-	})
-	if err != nil || !token.Valid {
-		return nil
-	}
-
-	return token
+	return []byte(a.secret), nil
 }
 
-func (a *Auth) JWTAuthenticate(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
-	token := a.validateJWTToken(req)
-	if token == nil {
-		resp.WriteErrorString(http.StatusUnauthorized, "401: Not Authorized")
-		return
-	}
-
-	log.Printf("Claims: %v", token.Claims)
-	next(req, resp)
-}
+// usr/pwd = admin/admin
+var basicAuth = restful.BasicAuth("Protected Area", restful.BasicAuthCredentials("admin", "admin"))
 
 func (a *Auth) BasicAuth(b *restful.RouteBuilder) {
-	b.Filter(a.basicAuthenticate).
-		Security("Basic", []string{}).
-		ReturnResponses(a.errorAuth)
+	b.Do(basicAuth)
 }
 
 func (a *Auth) JWTAuth(b *restful.RouteBuilder) {
-	b.Filter(a.JWTAuthenticate).
-		Security("Bearer", []string{}).
-		ReturnResponses(a.errorAuth)
+	b.Do(a.jwt.Require)
+}
+
+// tenantKey is the API key of the tenant, required with a JWT by the admin routes
+var tenantKey = restful.APIKeyOptions{Name: "X-Tenant-Key", In: "header", Lookup: restful.APIKeyValue("tenant-key", "tenant")}
+
+// AdminAuth requires a JWT and the tenant key ; the principal is the subject of the JWT
+func (a *Auth) AdminAuth(b *restful.RouteBuilder) {
+	b.Filter(a.tenantKey).
+		Filter(a.jwt.Filter).
+		SecurityAll(restful.SecurityPair{Name: jwtauth.SecurityBearer}, restful.SecurityPair{Name: "TenantKey"}).
+		ReturnResponses(restful.ErrorUnauthorized, restful.ErrorForbidden)
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 
 	"github.com/tangblue/goapi/restful"
 	"github.com/tangblue/goapi/restfulspec"
@@ -22,29 +23,49 @@ func main() {
 	}
 	baseURL = baseURL + port
 
+	// all resources are served under /api/v1
+	api := new(restful.WebService).Path("/api/v1")
+
 	auth := NewAuth(secret.AuthKey)
-	restful.DefaultContainer.Add(auth.WebService("/login", []string{"authentication"}))
+	api.Mount("", auth.WebService("/login", []string{"authentication"}))
 
 	u := NewUserResource(auth)
-	restful.DefaultContainer.Add(u.WebService("/users", []string{"users"}))
+	api.Mount("", u.WebService("/users", []string{"users"}))
+	u.api = api // build links from the mounted routes
+	if err := restful.DefaultContainer.Add(api); err != nil {
+		log.Fatal(err)
+	}
+
+	// GET /healthz and /readyz, the latter checks the secrets of the authentication
+	health := restful.NewHealthService("/").AddCheck("auth", auth.CheckSecrets).Tags("health")
+	if err := restful.DefaultContainer.Add(health.WebService); err != nil {
+		log.Fatal(err)
+	}
+
+	// the swagger-ui is served by the container, such that its filters apply ; it is not documented
+	swaggerPath := "/apidocs/"
+	if err := restful.DefaultContainer.Add(restful.StaticService("/apidocs", os.DirFS("./swagger-ui/dist"))); err != nil {
+		log.Fatal(err)
+	}
 
 	swaggerJson := "/apidocs.json"
 	config := restfulspec.Config{
-		WebServices: restful.RegisteredWebServices(),
-		APIPath:     swaggerJson,
+		WebServices:                   restful.RegisteredWebServices(),
+		APIPath:                       swaggerJson,
+		BasePath:                      "/api/v1",
+		SecurityDefinitions:           securityDefinitions(),
 		PostBuildSwaggerObjectHandler: enrichSwaggerObject}
-	restful.DefaultContainer.Add(restfulspec.NewOpenAPIService(config))
+	if err := restful.DefaultContainer.Add(restfulspec.NewOpenAPIService(config)); err != nil {
+		log.Fatal(err)
+	}
 
-	swaggerPath := "/apidocs/"
-	http.Handle(swaggerPath, http.StripPrefix(swaggerPath, http.FileServer(http.Dir("./swagger-ui/dist"))))
+	// log each request with the path of its route, e.g. /api/v1/users/{userID} ; not the health checks
+	restful.DefaultContainer.Filter(restful.AccessLogFilter(restful.AccessLogOptions{Skip: []string{"/healthz", "/readyz"}}))
 
 	// Optionally, you may need to enable CORS for the UI to work.
-	cors := restful.CrossOriginResourceSharing{
+	restful.DefaultContainer.Filter(restful.CORS(restful.CORSOptions{
 		AllowedHeaders: []string{"Content-Type", "Accept"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
-		CookiesAllowed: false,
-		Container:      restful.DefaultContainer}
-	restful.DefaultContainer.Filter(cors.Filter)
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"}}))
 
 	swaggerJson = baseURL + swaggerJson
 	log.Printf("Get the API: " + swaggerJson)
@@ -83,12 +104,16 @@ func enrichSwaggerObject(swo *spec.Swagger) {
 			},
 		},
 	}
+}
+
+func securityDefinitions() spec.SecurityDefinitions {
 	gOAuth2 := spec.OAuth2AccessToken("https://accounts.google.com/o/oauth2/auth", "https://accounts.google.com/o/oauth2/token")
 	gOAuth2.AddScope("userinfo.email", "https://www.googleapis.com/auth/userinfo.email")
-	swo.SecurityDefinitions = spec.SecurityDefinitions{
+	return spec.SecurityDefinitions{
 		"Basic":         spec.BasicAuth(),
 		"Bearer":        spec.APIKeyAuth("Authorization", "head"),
 		"google_oauth2": gOAuth2,
+		"TenantKey":     tenantKey.SecurityScheme(),
 	}
 }
 
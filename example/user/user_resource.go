@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/tangblue/goapi/restful"
 	"github.com/tangblue/goapi/restfulspec"
@@ -13,10 +14,23 @@ type User struct {
 	ID   UID    `json:"id" description:"identifier of the user" default:"1"`
 	Name string `json:"name" description:"name of the user" default:"john"`
 	Age  int    `json:"age" description:"age of the user" default:"21"`
+	// HAL-style links, only set in responses
+	Links *UserLinks `json:"_links,omitempty" xml:"-" description:"links to related resources"`
+}
+
+type Link struct {
+	Href string `json:"href" description:"URL of the resource"`
+}
+
+type UserLinks struct {
+	Self       Link `json:"self" description:"the user"`
+	Collection Link `json:"collection" description:"all users"`
 }
 
 type UserResource struct {
 	auth *Auth
+	// the WebService serving the user routes, used to build links
+	api *restful.WebService
 
 	paramUID          *restful.Parameter
 	errorBadUserID    *restful.ResponseError
@@ -47,46 +61,43 @@ func (u *UserResource) WebService(path string, tags []string) *restful.WebServic
 		log.Printf("Path: %v", req.Request.URL.Path)
 		next(req, resp)
 	}
-	tagUsers := func(b *restful.RouteBuilder) {
-		b.Metadata(restfulspec.KeyOpenAPITags, tags)
-	}
 
 	ws := new(restful.WebService)
 	ws.Path(path).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON, restful.MIME_XML).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Filter(printPath)
 
 	resp := restful.NewResponseError(200, "OK", []User{}).Header("x-google-x", "desc", UID(0))
 	ws.Route(ws.GET("/").Doc("get all users").
 		Handler(u.findAllUsers).
 		ReturnResponses(resp).
-		Do(tagUsers, u.auth.BasicAuth))
+		Do(u.auth.BasicAuth))
 
 	ws.Route(ws.PUT("").Doc("create a user").
 		Handler(u.createUser).
 		Read(User{}).
 		Return(http.StatusCreated, "Created", User{}).
-		Do(tagUsers, u.auth.JWTAuth))
+		Do(u.auth.JWTAuth))
 
 	ws.Route(ws.GET("/{%s}", u.paramUID).Doc("get a user").
 		Handler(u.findUser).
 		ReturnResponses(u.errorBadUserID, u.errorUserNotFound).
-		Return(http.StatusOK, "OK", User{}).
-		Do(tagUsers))
+		Return(http.StatusOK, "OK", User{}))
 
 	ws.Route(ws.PUT("/{%s}", u.paramUID).Doc("update a user").
 		Handler(u.updateUser).
 		Read(User{}).
 		ReturnResponses(u.errorBadUserID, u.errorUserNotFound).
 		Return(http.StatusOK, "OK", User{}).
-		Do(tagUsers, u.auth.JWTAuth))
+		Do(u.auth.JWTAuth))
 
 	ws.Route(ws.DELETE("/{%s}", u.paramUID).Doc("delete a user").
 		Handler(u.removeUser).
 		ReturnResponses(u.errorBadUserID, u.errorUserNotFound).
 		Return(http.StatusNoContent, "No Content", nil).
-		Do(tagUsers, u.auth.JWTAuth))
+		Do(u.auth.AdminAuth))
 
 	return ws
 }
@@ -110,10 +121,31 @@ func (u *UserResource) findUser(req *restful.Request, resp *restful.Response) {
 	if usr, ok := u.users[id]; !ok {
 		resp.WriteErrorResponse(u.errorUserNotFound)
 	} else {
+		u.addLinks(&usr)
 		resp.WriteEntity(usr)
 	}
 }
 
+// addLinks sets the links of the user using the routes of the operations, so they follow changes of their paths.
+func (u *UserResource) addLinks(usr *User) {
+	if u.api == nil {
+		return
+	}
+	links := &UserLinks{}
+	if route, ok := u.api.RouteByOperation("findUser"); ok {
+		href, err := route.URL(map[string]string{u.paramUID.Name: strconv.Itoa(int(usr.ID))}, nil)
+		if err != nil {
+			log.Printf("no link to user %d: %v", usr.ID, err)
+			return
+		}
+		links.Self.Href = href
+	}
+	if route, ok := u.api.RouteByOperation("findAllUsers"); ok {
+		links.Collection.Href, _ = route.URL(nil, nil)
+	}
+	usr.Links = links
+}
+
 func (u *UserResource) updateUser(req *restful.Request, resp *restful.Response) {
 	var id UID
 	err := req.GetParameter(u.paramUID, &id)
@@ -134,7 +166,9 @@ func (u *UserResource) updateUser(req *restful.Request, resp *restful.Response)
 	}
 
 	usr.ID = id
+	usr.Links = nil
 	u.users[id] = usr
+	u.addLinks(&usr)
 	resp.WriteEntity(usr)
 }
 
@@ -144,7 +178,9 @@ func (u *UserResource) createUser(req *restful.Request, resp *restful.Response)
 		resp.WriteError(http.StatusInternalServerError, err)
 		return
 	}
+	usr.Links = nil
 	u.users[usr.ID] = usr
+	u.addLinks(&usr)
 	resp.WriteHeaderAndEntity(http.StatusCreated, usr)
 }
 
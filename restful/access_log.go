@@ -0,0 +1,212 @@
+package restful
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tangblue/goapi/restful/log"
+)
+
+// LogEntry describes a request and its response, see AccessLogFilter.
+type LogEntry struct {
+	Time       time.Time              `json:"time"`                 // when the request arrived
+	Method     string                 `json:"method"`               // e.g. GET
+	Path       string                 `json:"path"`                 // e.g. /users/42 ; the values of Sensitive path parameters are REDACTED
+	RoutePath  string                 `json:"route"`                // the path of the selected Route, e.g. /users/{userID} ; empty if none
+	Status     int                    `json:"status"`               // e.g. 200
+	Bytes      int                    `json:"bytes"`                // the length of the response body
+	Latency    time.Duration          `json:"latency"`              // the time it took to respond
+	ClientIP   string                 `json:"client_ip"`            // e.g. 192.0.2.1
+	UserAgent  string                 `json:"user_agent"`           // the User-Agent header of the request
+	RequestID  string                 `json:"request_id,omitempty"` // the ID set by the RequestIDFilter, if any
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// JSON returns the entry as a JSON object ; use it as the AccessLogOptions.Render function.
+func (e LogEntry) JSON() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(data)
+}
+
+// AccessLogFormatCommon is the default format of AccessLogOptions, e.g. 192.0.2.1 "GET /users/42" /users/{userID} 200 512 1.2ms "curl/7.68.0"
+const AccessLogFormatCommon = `{ip} "{method} {path}" {route} {status} {bytes} {latency} "{agent}"`
+
+// AccessLogOptions configures the FilterFunction created by AccessLogFilter.
+type AccessLogOptions struct {
+	// Format of an entry ; default is AccessLogFormatCommon. The placeholders {time}, {method}, {path}, {route},
+	// {status}, {bytes}, {latency}, {ip}, {agent} and {request_id} are replaced by the fields of the LogEntry
+	// and {attr:name} by the request attribute with the name, see Attributes. A missing value is written as "-".
+	// If the Format has no {request_id} then the ID of a request, if any, is written at the end as request_id=ID.
+	Format string
+	// Render, if set, renders an entry instead of the Format, e.g. LogEntry.JSON.
+	Render func(entry LogEntry) string
+	// Attributes lists the names of the request attributes in the entry, e.g. the subject set by an authentication filter.
+	Attributes []string
+	// Skip lists the request paths that are not logged, e.g. /healthz.
+	Skip []string
+	// ForwardedFor uses the first address of the X-Forwarded-For header, if any, as the client IP.
+	// Only set it if the requests come through a trusted proxy.
+	ForwardedFor bool
+	// Logger writes the entries ; default is the package logger, see SetLogger.
+	Logger log.StdLogger
+}
+
+// AccessLogFilter returns a FilterFunction that logs an entry for each request once it has been responded to,
+// with the path of the selected Route such that entries can be aggregated. Install it as a Container filter.
+func AccessLogFilter(opts AccessLogOptions) FilterFunction {
+	if len(opts.Format) == 0 {
+		opts.Format = AccessLogFormatCommon
+	}
+	if opts.Render == nil {
+		opts.Render = opts.format
+	}
+	return opts.filter
+}
+
+func (o AccessLogOptions) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	if containsString(o.Skip, req.Request.URL.Path) {
+		next(req, resp)
+		return
+	}
+	start := req.StartTime()
+	if start.IsZero() {
+		start = time.Now()
+	}
+	resp.OnWrite(func(stats ResponseStats) {
+		entry := LogEntry{
+			Time:      start,
+			Method:    req.Request.Method,
+			Path:      redactedPath(req),
+			RoutePath: req.SelectedRoutePath(),
+			Status:    stats.StatusCode,
+			Bytes:     stats.ContentLength,
+			Latency:   time.Since(start),
+			ClientIP:  clientIP(req, o.ForwardedFor),
+			UserAgent: req.Request.UserAgent(),
+			RequestID: requestID(req),
+		}
+		for _, name := range o.Attributes {
+			if value := req.Attribute(name); value != nil {
+				if entry.Attributes == nil {
+					entry.Attributes = map[string]interface{}{}
+				}
+				entry.Attributes[name] = value
+			}
+		}
+		logger := o.Logger
+		if logger == nil {
+			logger = log.Logger
+		}
+		logger.Print(o.Render(entry))
+	})
+	next(req, resp)
+}
+
+// redactedPath returns the path of the request with the values of its Sensitive path parameters replaced by REDACTED.
+func redactedPath(req *Request) string {
+	path := req.Request.URL.Path
+	sensitive := map[string]bool{}
+	for _, each := range req.routeParams {
+		if each.In == "path" && each.IsSensitive() {
+			sensitive[each.Name] = true
+		}
+	}
+	if len(sensitive) == 0 {
+		return path
+	}
+	// the first segment is empty, before the leading slash
+	segments := strings.Split(path, "/")
+	for i, each := range tokenizePath(req.selectedRoutePath) {
+		if i+1 >= len(segments) || !strings.HasPrefix(each, "{") {
+			continue
+		}
+		if name, _ := parameterToRegularExpression(each); !sensitive[name] {
+			continue
+		}
+		if isCatchAllToken(each) {
+			segments = append(segments[:i+1], "REDACTED")
+			break
+		}
+		segments[i+1] = "REDACTED"
+	}
+	return strings.Join(segments, "/")
+}
+
+// clientIP returns the address of the client without the port ; the first address of the X-Forwarded-For header,
+// if any, if the requests come through a trusted proxy.
+func clientIP(req *Request, forwardedFor bool) string {
+	if forwarded := req.Request.Header.Get("X-Forwarded-For"); forwardedFor && len(forwarded) > 0 {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	if host, _, err := net.SplitHostPort(req.Request.RemoteAddr); err == nil {
+		return host
+	}
+	return req.Request.RemoteAddr
+}
+
+// format renders the entry using the Format.
+func (o AccessLogOptions) format(entry LogEntry) string {
+	var line strings.Builder
+	rest := o.Format
+	for {
+		begin := strings.Index(rest, "{")
+		end := strings.Index(rest[begin+1:], "}")
+		if begin == -1 || end == -1 {
+			line.WriteString(rest)
+			if len(entry.RequestID) > 0 && !strings.Contains(o.Format, "{request_id}") {
+				line.WriteString(" request_id=" + entry.RequestID)
+			}
+			return line.String()
+		}
+		line.WriteString(rest[:begin])
+		line.WriteString(orDash(entry.field(rest[begin+1 : begin+1+end])))
+		rest = rest[begin+end+2:]
+	}
+}
+
+// field returns the value of the placeholder, e.g. status or attr:subject.
+func (e LogEntry) field(placeholder string) string {
+	switch placeholder {
+	case "time":
+		return e.Time.Format(time.RFC3339)
+	case "method":
+		return e.Method
+	case "path":
+		return e.Path
+	case "route":
+		return e.RoutePath
+	case "status":
+		return strconv.Itoa(e.Status)
+	case "bytes":
+		return strconv.Itoa(e.Bytes)
+	case "latency":
+		return e.Latency.String()
+	case "ip":
+		return e.ClientIP
+	case "agent":
+		return e.UserAgent
+	case "request_id":
+		return e.RequestID
+	}
+	if name := strings.TrimPrefix(placeholder, "attr:"); name != placeholder {
+		if value, ok := e.Attributes[name]; ok {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+	return "{" + placeholder + "}"
+}
+
+func orDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,134 @@
+package restful
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// entryRecorder is a StdLogger that records the access log lines
+type entryRecorder struct {
+	lines []string
+}
+
+func (e *entryRecorder) Print(v ...interface{}) {
+	e.lines = append(e.lines, fmt.Sprint(v...))
+}
+
+func (e *entryRecorder) Printf(format string, v ...interface{}) {
+	e.lines = append(e.lines, fmt.Sprintf(format, v...))
+}
+
+func setSubject(req *Request, resp *Response, next func(*Request, *Response)) {
+	req.SetAttribute("subject", "alice")
+	next(req, resp)
+}
+
+func writeNotFoundResponse(req *Request, resp *Response) {
+	resp.WriteErrorResponse(NewResponseError(http.StatusNotFound, "user not found", nil))
+}
+
+func newAccessLogContainer(opts AccessLogOptions) *Container {
+	wc := NewContainer()
+	wc.Filter(AccessLogFilter(opts))
+	wc.Filter(setSubject)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{id}").Handler(writeUserId))
+	ws.Route(ws.DELETE("/{id}").Handler(writeNotFoundResponse))
+	wc.Add(ws)
+	wc.Add(NewHealthService("/").WebService)
+	return wc
+}
+
+func TestAccessLogFilterFormat(t *testing.T) {
+	recorder := &entryRecorder{}
+	wc := newAccessLogContainer(AccessLogOptions{
+		Format:     `{ip} {method} {path} {route} {status} {bytes} {attr:subject} {attr:missing} "{agent}"`,
+		Attributes: []string{"subject"},
+		Skip:       []string{"/healthz"},
+		Logger:     recorder,
+	})
+	for _, each := range []struct{ method, path string }{
+		{"GET", "/users/42"},
+		{"DELETE", "/users/7"},
+		{"GET", "/healthz"},
+		{"GET", "/unknown"},
+	} {
+		httpRequest := httptest.NewRequest(each.method, each.path, nil)
+		httpRequest.RemoteAddr = "192.0.2.1:1234"
+		httpRequest.Header.Set("User-Agent", "test/1.0")
+		wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	}
+	want := []string{
+		`192.0.2.1 GET /users/42 /users/{id} 200 2 alice - "test/1.0"`,
+		`192.0.2.1 DELETE /users/7 /users/{id} 404 14 alice - "test/1.0"`,
+		`192.0.2.1 GET /unknown - 404 19 alice - "test/1.0"`,
+	}
+	if got := strings.Join(recorder.lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestAccessLogFilterJSON(t *testing.T) {
+	recorder := &entryRecorder{}
+	wc := newAccessLogContainer(AccessLogOptions{Render: LogEntry.JSON, Attributes: []string{"subject"}, ForwardedFor: true, Logger: recorder})
+	httpRequest := httptest.NewRequest("GET", "/users/42", nil)
+	httpRequest.Header.Set("X-Forwarded-For", "198.51.100.7, 10.0.0.1")
+	wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+
+	if len(recorder.lines) != 1 {
+		t.Fatalf("got %d entries want 1", len(recorder.lines))
+	}
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(recorder.lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Method != "GET" || entry.Path != "/users/42" || entry.RoutePath != "/users/{id}" || entry.Status != 200 || entry.Bytes != 2 {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.ClientIP != "198.51.100.7" || entry.Attributes["subject"] != "alice" || entry.Time.IsZero() {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func writeSensitiveFields(req *Request, resp *Response) {
+	io.WriteString(resp, strings.Join(SensitiveFields(req), ","))
+}
+
+func TestAccessLogFilterSensitive(t *testing.T) {
+	recorder := &entryRecorder{}
+	wc := NewContainer()
+	wc.Filter(AccessLogFilter(AccessLogOptions{Format: "{method} {path} {route}", Logger: recorder}))
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.POST("/{id}/reset/{token}").Handler(writeSensitiveFields).
+		Params(ws.PathParameter("id", "the user"),
+			ws.PathParameter("token", "the reset token").Sensitive(),
+			ws.QueryParameter("password", "the new password").Sensitive()))
+	ws.Route(ws.GET("/{id}").Handler(writeSensitiveFields))
+	wc.Add(ws)
+
+	httpRequest := httptest.NewRequest("POST", "/users/42/reset/s3cr3t?password=hunter2", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "token,password"; got != want {
+		t.Errorf("got sensitive fields %q want %q", got, want)
+	}
+	httpRequest = httptest.NewRequest("GET", "/users/42", nil)
+	httpWriter = httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got := httpWriter.Body.String(); got != "" {
+		t.Errorf("got sensitive fields %q want none", got)
+	}
+
+	want := []string{
+		`POST /users/42/reset/REDACTED /users/{id}/reset/{token}`,
+		`GET /users/42 /users/{id}`,
+	}
+	if got := strings.Join(recorder.lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,173 @@
+package restful
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+// ErrorUnauthorized is the 401 response of the Routes with authentication, see BasicAuth and APIKeyAuth.
+// It is documented once in the OpenAPI definitions as Unauthorized.
+var ErrorUnauthorized = NewResponseError(http.StatusUnauthorized, "Not Authorized", nil).SetRefName("Unauthorized")
+
+// ErrorForbidden is the 403 response of the Routes with authentication, see APIKeyAuth.
+// It is documented once in the OpenAPI definitions as Forbidden.
+var ErrorForbidden = NewResponseError(http.StatusForbidden, "Forbidden", nil).SetRefName("Forbidden")
+
+// BasicAuthVerifier returns the principal, e.g. a user, of the credentials of a request and whether they are valid.
+// Passwords must be compared in constant time, e.g. using crypto/subtle.ConstantTimeCompare or a password hash function.
+type BasicAuthVerifier func(ctx context.Context, user, password string) (principal interface{}, ok bool)
+
+// BasicAuthCredentials returns a BasicAuthVerifier that accepts the user and password only, compared in constant time.
+// The principal is the user.
+func BasicAuthCredentials(user, password string) BasicAuthVerifier {
+	return func(ctx context.Context, u, p string) (interface{}, bool) {
+		userOK := subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		return u, userOK && passwordOK
+	}
+}
+
+type basicAuthFilter struct {
+	challenge string
+	verify    BasicAuthVerifier
+}
+
+// NewBasicAuthFilter returns a FilterFunction that authenticates requests with the Basic scheme.
+// If the Authorization header has credentials the verifier accepts, their principal is set as the KeyPrincipal
+// attribute of the Request ; otherwise the chain is aborted with 401 Unauthorized and a WWW-Authenticate header
+// for the realm, see AbortWith.
+func NewBasicAuthFilter(realm string, verify BasicAuthVerifier) FilterFunction {
+	return basicAuthFilter{
+		challenge: `Basic realm=` + strconv.Quote(realm) + `, charset="UTF-8"`,
+		verify:    verify,
+	}.filter
+}
+
+func (f basicAuthFilter) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	user, password, ok := req.Request.BasicAuth()
+	if ok {
+		var principal interface{}
+		if principal, ok = f.verify(req.Request.Context(), user, password); ok {
+			req.SetAttribute(KeyPrincipal, principal)
+			next(req, resp)
+			return
+		}
+	}
+	resp.Header().Set(HEADER_WWWAuthenticate, f.challenge)
+	AbortWith(resp, NewError(http.StatusUnauthorized, "401: Unauthorized"))
+}
+
+// BasicAuth returns a function for RouteBuilder.Do that adds the NewBasicAuthFilter filter to the Route,
+// the SecurityBasic security requirement and the ErrorUnauthorized response.
+//
+//	basic := restful.BasicAuth("users", verifyUser)
+//	ws.Route(ws.GET("/").Handler(findAllUsers).Do(basic))
+func BasicAuth(realm string, verify BasicAuthVerifier) func(*RouteBuilder) {
+	filter := NewBasicAuthFilter(realm, verify)
+	return func(b *RouteBuilder) {
+		b.Filter(filter).
+			Security(SecurityBasic, []string{}).
+			ReturnResponses(ErrorUnauthorized)
+	}
+}
+
+// APIKeyLookup returns the principal, e.g. a client, of the API key of a request and whether the key is valid.
+// Keys must be compared in constant time, e.g. using crypto/subtle.ConstantTimeCompare or by looking up a hash of the key.
+type APIKeyLookup func(ctx context.Context, key string) (principal interface{}, ok bool)
+
+// APIKeyValue returns an APIKeyLookup that accepts the key only, compared in constant time, with the principal.
+func APIKeyValue(key string, principal interface{}) APIKeyLookup {
+	return func(ctx context.Context, k string) (interface{}, bool) {
+		return principal, subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1
+	}
+}
+
+// APIKeyOptions configures the FilterFunction created by APIKeyFilter.
+type APIKeyOptions struct {
+	// Name is the name of the header or query parameter with the key, e.g. X-API-Key.
+	Name string
+	// In is the location of the key, "header" or "query".
+	In string
+	// Lookup returns the principal of a key.
+	Lookup APIKeyLookup
+	// OnFailure, if set, is called with each request without a valid key and the status code of its response,
+	// 401 if it has no key and 403 if its key is not valid, e.g. to count the failures of a client and limit them.
+	OnFailure func(req *Request, code int)
+}
+
+// SecurityScheme returns the definition of the security scheme of the key in the OpenAPI documentation.
+func (o APIKeyOptions) SecurityScheme() *spec.SecurityScheme {
+	return spec.APIKeyAuth(o.Name, o.In)
+}
+
+// APIKeyFilter returns a FilterFunction that authenticates requests with an API key. If the key is valid,
+// its principal is set as the KeyPrincipal attribute of the Request ; otherwise the chain is aborted with
+// 401 Unauthorized if the request has no key or 403 Forbidden if the key is not valid, see AbortWith.
+// It returns an error if the location of the key is neither "header" nor "query".
+func APIKeyFilter(opts APIKeyOptions) (FilterFunction, error) {
+	if opts.In != "header" && opts.In != "query" {
+		return nil, fmt.Errorf("API key location must be header or query: %q", opts.In)
+	}
+	return apiKeyFilter(opts).filter, nil
+}
+
+// NewAPIKeyFilter is like APIKeyFilter with the name, location and lookup of the key but panics if the location
+// is neither "header" nor "query".
+func NewAPIKeyFilter(name, in string, lookup APIKeyLookup) FilterFunction {
+	filter, err := APIKeyFilter(APIKeyOptions{Name: name, In: in, Lookup: lookup})
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+type apiKeyFilter APIKeyOptions
+
+func (f apiKeyFilter) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	var key string
+	if f.In == "header" {
+		key = req.Request.Header.Get(f.Name)
+	} else {
+		key = req.Request.URL.Query().Get(f.Name)
+	}
+	code := http.StatusUnauthorized
+	if len(key) > 0 {
+		principal, ok := f.Lookup(req.Request.Context(), key)
+		if ok {
+			req.SetAttribute(KeyPrincipal, principal)
+			next(req, resp)
+			return
+		}
+		code = http.StatusForbidden
+	}
+	if f.OnFailure != nil {
+		f.OnFailure(req, code)
+	}
+	AbortWith(resp, NewError(code, fmt.Sprintf("%d: %s", code, http.StatusText(code))))
+}
+
+// APIKeyAuth returns a function for RouteBuilder.Do that adds the APIKeyFilter filter to the Route,
+// the security requirement of the scheme name and the ErrorUnauthorized and ErrorForbidden responses.
+// The scheme name is the one of the SecurityScheme of the options in the OpenAPI documentation.
+// If the options are not valid, the Route is not built and the error is reported by WebService.Err.
+//
+//	apiKey := restful.APIKeyOptions{Name: "X-API-Key", In: "header", Lookup: lookupClient}
+//	config.SecurityDefinitions = spec.SecurityDefinitions{"ApiKey": apiKey.SecurityScheme()}
+//	ws.Route(ws.GET("/").Handler(findAllUsers).Do(restful.APIKeyAuth("ApiKey", apiKey)))
+func APIKeyAuth(scheme string, opts APIKeyOptions) func(*RouteBuilder) {
+	filter, err := APIKeyFilter(opts)
+	return func(b *RouteBuilder) {
+		if err != nil {
+			b.addError(err)
+			return
+		}
+		b.Filter(filter).
+			Security(scheme, []string{}).
+			ReturnResponses(ErrorUnauthorized, ErrorForbidden)
+	}
+}
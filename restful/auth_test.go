@@ -0,0 +1,184 @@
+package restful
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func writePrincipal(req *Request, resp *Response) {
+	principal, _ := req.Attribute(KeyPrincipal).(string)
+	io.WriteString(resp, principal)
+}
+
+func verifyAdmin(ctx context.Context, user, password string) (interface{}, bool) {
+	return BasicAuthCredentials("admin", "secret")(ctx, user, password)
+}
+
+func TestBasicAuth(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/private")
+	rb := ws.GET("").Handler(writePrincipal).Do(BasicAuth("Private Area", verifyAdmin))
+	ws.Route(rb)
+	wc.Add(ws)
+
+	for _, each := range []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"valid", "Basic YWRtaW46c2VjcmV0", http.StatusOK},                    // admin:secret
+		{"wrong password", "Basic YWRtaW46YWRtaW4=", http.StatusUnauthorized}, // admin:admin
+		{"no header", "", http.StatusUnauthorized},
+		{"not base64", "Basic !!!", http.StatusUnauthorized},
+		{"no colon", "Basic YWRtaW4=", http.StatusUnauthorized}, // admin
+		{"other scheme", "Bearer YWRtaW46c2VjcmV0", http.StatusUnauthorized},
+	} {
+		httpRequest := httptest.NewRequest("GET", "/private", nil)
+		if len(each.authorization) > 0 {
+			httpRequest.Header.Set("Authorization", each.authorization)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if httpWriter.Code != each.want {
+			t.Errorf("%s: got status %d want %d", each.name, httpWriter.Code, each.want)
+		}
+		challenge := httpWriter.Header().Get(HEADER_WWWAuthenticate)
+		if each.want == http.StatusOK {
+			if got := httpWriter.Body.String(); got != "admin" || len(challenge) != 0 {
+				t.Errorf("%s: got principal %q and challenge %q", each.name, got, challenge)
+			}
+		} else if want := `Basic realm="Private Area", charset="UTF-8"`; challenge != want {
+			t.Errorf("%s: got challenge %q want %q", each.name, challenge, want)
+		}
+	}
+
+	route, _ := rb.Build()
+	if want := []map[string][]string{{SecurityBasic: {}}}; !reflect.DeepEqual(route.Security, want) {
+		t.Errorf("got security %v want %v", route.Security, want)
+	}
+	if got := route.ResponseErrors[http.StatusUnauthorized]; got != ErrorUnauthorized {
+		t.Errorf("got 401 response %v want ErrorUnauthorized", got)
+	}
+}
+
+// failureCounter counts the requests without a valid API key by status code
+type failureCounter struct {
+	counts map[int]int
+}
+
+func (f *failureCounter) count(req *Request, code int) {
+	f.counts[code]++
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	for _, in := range []string{"header", "query"} {
+		failures := &failureCounter{counts: map[int]int{}}
+		apiKey := APIKeyOptions{Name: "X-API-Key", In: in, Lookup: APIKeyValue("s3cr3t", "client-1"), OnFailure: failures.count}
+		wc := NewContainer()
+		ws := new(WebService).Path("/private")
+		rb := ws.GET("").Handler(writePrincipal).Do(APIKeyAuth("ApiKey", apiKey))
+		ws.Route(rb)
+		wc.Add(ws)
+
+		for _, each := range []struct {
+			key  string
+			want int
+		}{
+			{"s3cr3t", http.StatusOK},
+			{"other", http.StatusForbidden},
+			{"", http.StatusUnauthorized},
+		} {
+			httpRequest := httptest.NewRequest("GET", "/private", nil)
+			if len(each.key) > 0 && in == "header" {
+				httpRequest.Header.Set("X-API-Key", each.key)
+			}
+			if len(each.key) > 0 && in == "query" {
+				httpRequest = httptest.NewRequest("GET", "/private?X-API-Key="+each.key, nil)
+			}
+			httpWriter := httptest.NewRecorder()
+			wc.ServeHTTP(httpWriter, httpRequest)
+			if httpWriter.Code != each.want {
+				t.Errorf("%s %q: got status %d want %d", in, each.key, httpWriter.Code, each.want)
+			}
+			if each.want == http.StatusOK && httpWriter.Body.String() != "client-1" {
+				t.Errorf("%s %q: got principal %q want client-1", in, each.key, httpWriter.Body.String())
+			}
+		}
+		if failures.counts[http.StatusUnauthorized] != 1 || failures.counts[http.StatusForbidden] != 1 {
+			t.Errorf("%s: got failures %v want one 401 and one 403", in, failures.counts)
+		}
+
+		route, _ := rb.Build()
+		if want := []map[string][]string{{"ApiKey": {}}}; !reflect.DeepEqual(route.Security, want) {
+			t.Errorf("%s: got security %v want %v", in, route.Security, want)
+		}
+		if route.ResponseErrors[http.StatusForbidden] != ErrorForbidden {
+			t.Errorf("%s: got 403 response %v want ErrorForbidden", in, route.ResponseErrors[http.StatusForbidden])
+		}
+		if scheme := apiKey.SecurityScheme(); scheme.Type != "apiKey" || scheme.Name != "X-API-Key" || scheme.In != in {
+			t.Errorf("%s: got scheme %+v", in, scheme.SecuritySchemeProps)
+		}
+	}
+}
+
+func TestAPIKeyAuthInvalidLocation(t *testing.T) {
+	apiKey := APIKeyOptions{Name: "X-API-Key", In: "cookie", Lookup: APIKeyValue("s3cr3t", "client-1")}
+	if _, err := APIKeyFilter(apiKey); err == nil {
+		t.Error("got no error of the filter want one")
+	}
+	ws := new(WebService).Path("/private")
+	ws.Route(ws.GET("").Handler(writePrincipal).Do(APIKeyAuth("ApiKey", apiKey)))
+	if ws.Err() == nil {
+		t.Error("got no error of the web service want one")
+	}
+	if len(ws.Routes()) != 0 {
+		t.Errorf("got %d routes want 0", len(ws.Routes()))
+	}
+}
+
+func TestNewAPIKeyFilter(t *testing.T) {
+	for _, in := range []string{"header", "query"} {
+		wc := NewContainer()
+		ws := new(WebService).Path("/private")
+		ws.Route(ws.GET("").Handler(writePrincipal).Filter(NewAPIKeyFilter("X-API-Key", in, APIKeyValue("s3cr3t", "client-1"))))
+		wc.Add(ws)
+
+		for _, each := range []struct {
+			key  string
+			want int
+		}{
+			{"s3cr3t", http.StatusOK},
+			{"other", http.StatusForbidden},
+			{"", http.StatusUnauthorized},
+		} {
+			httpRequest := httptest.NewRequest("GET", "/private", nil)
+			if len(each.key) > 0 && in == "header" {
+				httpRequest.Header.Set("X-API-Key", each.key)
+			}
+			if len(each.key) > 0 && in == "query" {
+				httpRequest = httptest.NewRequest("GET", "/private?X-API-Key="+each.key, nil)
+			}
+			httpWriter := httptest.NewRecorder()
+			wc.ServeHTTP(httpWriter, httpRequest)
+			if httpWriter.Code != each.want {
+				t.Errorf("%s %q: got status %d want %d", in, each.key, httpWriter.Code, each.want)
+			}
+			if each.want == http.StatusOK && httpWriter.Body.String() != "client-1" {
+				t.Errorf("%s %q: got principal %q want client-1", in, each.key, httpWriter.Body.String())
+			}
+		}
+	}
+}
+
+func TestNewAPIKeyFilterInvalidLocation(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("got no panic want one")
+		}
+	}()
+	NewAPIKeyFilter("X-API-Key", "cookie", APIKeyValue("s3cr3t", "client-1"))
+}
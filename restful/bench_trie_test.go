@@ -0,0 +1,47 @@
+package restful
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// setupRoutes returns WebServices with 20 Routes each, up to count Routes, and a request for each Route.
+func setupRoutes(count int) ([]*WebService, []*http.Request) {
+	services := []*WebService{}
+	requests := []*http.Request{}
+	var ws *WebService
+	for i := 0; i < count; i++ {
+		if i%20 == 0 {
+			ws = new(WebService).Path(fmt.Sprintf("/service%d", i/20))
+			services = append(services, ws)
+		}
+		resource := fmt.Sprintf("/resource%d", i/2)
+		if i%2 == 0 {
+			ws.Route(ws.GET(resource).Handler(echo))
+			request, _ := http.NewRequest("GET", ws.RootPath()+resource, nil)
+			requests = append(requests, request)
+		} else {
+			ws.Route(ws.GET(resource + "/{id}/items/{item}").Handler(echo))
+			request, _ := http.NewRequest("GET", ws.RootPath()+resource+"/42/items/7", nil)
+			requests = append(requests, request)
+		}
+	}
+	return services, requests
+}
+
+// go test -run=^$ -bench=BenchmarkSelectRoute ...restful
+func BenchmarkSelectRoute(b *testing.B) {
+	for _, count := range []int{10, 100, 1000} {
+		services, requests := setupRoutes(count)
+		for _, router := range []RouteSelector{CurlyRouter{}, TrieRouter{}} {
+			b.Run(fmt.Sprintf("%T/%d", router, count), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, _, err := router.SelectRoute(services, requests[i%len(requests)]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package restful
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// bufferedWriter is an http.ResponseWriter that buffers the header, status and body of a response
+// until they are copied to a Response, e.g. to write them differently or not at all. It is not safe for concurrent use.
+type bufferedWriter struct {
+	header      http.Header
+	buffer      bytes.Buffer
+	code        int
+	wroteHeader bool
+}
+
+// newBufferedWriter returns a bufferedWriter with a copy of the header of the response.
+func newBufferedWriter(resp *Response) *bufferedWriter {
+	return &bufferedWriter{header: cloneHeader(resp.Header())}
+}
+
+// Header is part of http.ResponseWriter interface
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.header
+}
+
+// WriteHeader is part of http.ResponseWriter interface
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if bw.wroteHeader {
+		return
+	}
+	bw.code, bw.wroteHeader = code, true
+}
+
+// Write is part of http.ResponseWriter interface
+func (bw *bufferedWriter) Write(data []byte) (int, error) {
+	if !bw.wroteHeader {
+		bw.code, bw.wroteHeader = http.StatusOK, true
+	}
+	return bw.buffer.Write(data)
+}
+
+// copyTo writes the buffered header, status and body to the response.
+func (bw *bufferedWriter) copyTo(resp *Response) {
+	header := resp.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range bw.header {
+		header[key] = values
+	}
+	if !bw.wroteHeader {
+		return
+	}
+	resp.WriteHeader(bw.code)
+	resp.Write(bw.buffer.Bytes())
+}
+
+// cloneHeader returns a copy of the header.
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
@@ -23,6 +23,8 @@ type CompressingResponseWriter struct {
 	writer     http.ResponseWriter
 	compressor io.WriteCloser
 	encoding   string
+	started    bool // set when the header or body has been written
+	disabled   bool // set when writes pass through uncompressed, see disable
 }
 
 // Header is part of http.ResponseWriter interface
@@ -32,12 +34,17 @@ func (c *CompressingResponseWriter) Header() http.Header {
 
 // WriteHeader is part of http.ResponseWriter interface
 func (c *CompressingResponseWriter) WriteHeader(status int) {
+	c.started = true
 	c.writer.WriteHeader(status)
 }
 
 // Write is part of http.ResponseWriter interface
 // It is passed through the compressor
 func (c *CompressingResponseWriter) Write(bytes []byte) (int, error) {
+	c.started = true
+	if c.disabled {
+		return c.writer.Write(bytes)
+	}
 	if c.isCompressorClosed() {
 		return -1, errors.New("Compressing error: tried to write data using closed compressor")
 	}
@@ -49,22 +56,63 @@ func (c *CompressingResponseWriter) CloseNotify() <-chan bool {
 	return c.writer.(http.CloseNotifier).CloseNotify()
 }
 
+// Flush is part of http.Flusher interface
+// It flushes the compressor and then the underlying ResponseWriter, if it supports flushing.
+func (c *CompressingResponseWriter) Flush() {
+	if c.isCompressorClosed() && !c.disabled {
+		return
+	}
+	if f, ok := c.compressor.(interface {
+		Flush() error
+	}); ok {
+		f.Flush()
+	}
+	if f, ok := c.writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // Close the underlying compressor
 func (c *CompressingResponseWriter) Close() error {
+	if c.disabled {
+		return nil
+	}
 	if c.isCompressorClosed() {
 		return errors.New("Compressing error: tried to close already closed compressor")
 	}
 
 	c.compressor.Close()
+	c.release()
+	// gc hint needed?
+	c.compressor = nil
+	return nil
+}
+
+// release returns the compressor to the provider
+func (c *CompressingResponseWriter) release() {
 	if ENCODING_GZIP == c.encoding {
 		currentCompressorProvider.ReleaseGzipWriter(c.compressor.(*gzip.Writer))
 	}
 	if ENCODING_DEFLATE == c.encoding {
 		currentCompressorProvider.ReleaseZlibWriter(c.compressor.(*zlib.Writer))
 	}
-	// gc hint needed?
+}
+
+// disable makes all writes pass through uncompressed, provided nothing has been written yet.
+// This is needed for content that sets its own Content-Length or Content-Range, see Response.WriteFile.
+func (c *CompressingResponseWriter) disable() bool {
+	if c.disabled {
+		return true
+	}
+	if c.started || c.isCompressorClosed() {
+		return false
+	}
+	c.writer.Header().Del(HEADER_ContentEncoding)
+	// the compressor has not written anything so it can be released without closing
+	c.release()
 	c.compressor = nil
-	return nil
+	c.disabled = true
+	return true
 }
 
 func (c *CompressingResponseWriter) isCompressorClosed() bool {
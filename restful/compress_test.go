@@ -123,3 +123,36 @@ func TestZlibDecompressRequestBody(t *testing.T) {
 		t.Errorf("got %v want %v", got, want)
 	}
 }
+
+func TestRawBodyThenReadEntity(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := newGzipWriter()
+	w.Reset(b)
+	io.WriteString(w, `{"msg":"hi"}`)
+	w.Flush()
+	w.Close()
+
+	httpRequest, _ := http.NewRequest("POST", "/", bytes.NewReader(b.Bytes()))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Content-Encoding", "gzip")
+	req := NewRequest(httpRequest)
+
+	raw, err := req.RawBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(raw), `{"msg":"hi"}`; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	doc := make(map[string]interface{})
+	if err := req.ReadEntity(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc["msg"], "hi"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	// the body is still available
+	if again, err := req.RawBody(); err != nil || string(again) != string(raw) {
+		t.Errorf("got %q, %v want %q", again, err, raw)
+	}
+}
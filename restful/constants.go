@@ -5,14 +5,17 @@ package restful
 // that can be found in the LICENSE file.
 
 const (
-	MIME_XML   = "application/xml"          // Accept or Content-Type used in Consumes() and/or Produces()
-	MIME_JSON  = "application/json"         // Accept or Content-Type used in Consumes() and/or Produces()
-	MIME_OCTET = "application/octet-stream" // If Content-Type is not present in request, use the default
+	MIME_XML    = "application/xml"          // Accept or Content-Type used in Consumes() and/or Produces()
+	MIME_JSON   = "application/json"         // Accept or Content-Type used in Consumes() and/or Produces()
+	MIME_OCTET  = "application/octet-stream" // If Content-Type is not present in request, use the default
+	MIME_NDJSON = "application/x-ndjson"     // Newline delimited JSON, used by NDJSONWriter
+	MIME_TEXT   = "text/plain"               // Plain text, used by HealthChecks
 
 	HEADER_Allow                         = "Allow"
 	HEADER_Accept                        = "Accept"
 	HEADER_Origin                        = "Origin"
 	HEADER_ContentType                   = "Content-Type"
+	HEADER_ContentLength                 = "Content-Length"
 	HEADER_LastModified                  = "Last-Modified"
 	HEADER_AcceptEncoding                = "Accept-Encoding"
 	HEADER_ContentEncoding               = "Content-Encoding"
@@ -24,6 +27,49 @@ const (
 	HEADER_AccessControlAllowCredentials = "Access-Control-Allow-Credentials"
 	HEADER_AccessControlAllowHeaders     = "Access-Control-Allow-Headers"
 	HEADER_AccessControlMaxAge           = "Access-Control-Max-Age"
+	HEADER_Vary                          = "Vary"
+	HEADER_Link                          = "Link"
+	HEADER_Deprecation                   = "Deprecation"
+	HEADER_Sunset                        = "Sunset"
+	HEADER_ContentDisposition            = "Content-Disposition"
+	HEADER_XAcceptable                   = "X-Acceptable"
+	HEADER_Location                      = "Location"
+	HEADER_XHTTPMethodOverride           = "X-HTTP-Method-Override"
+	HEADER_XRouteTrace                   = "X-Route-Trace"
+	HEADER_XRequestID                    = "X-Request-Id"
+	HEADER_RetryAfter                    = "Retry-After"
+	HEADER_RateLimitLimit                = "RateLimit-Limit"
+	HEADER_RateLimitRemaining            = "RateLimit-Remaining"
+	HEADER_RateLimitReset                = "RateLimit-Reset"
+	HEADER_ETag                          = "ETag"
+	HEADER_IfNoneMatch                   = "If-None-Match"
+	HEADER_WWWAuthenticate               = "WWW-Authenticate"
+
+	// KeyXMLRootName is a Route Metadata key ; its string value is the name of the root element written by the XML entity writer
+	KeyXMLRootName = "xml.root"
+	// KeyXMLHeader is a Route Metadata key ; its bool value controls whether the XML entity writer writes the <?xml ...?> header,
+	// true also without pretty printing
+	KeyXMLHeader = "xml.header"
+	// KeyOpenAPITags is a Route Metadata key ; its []string value lists the tags of the Route in the OpenAPI documentation
+	KeyOpenAPITags = "openapi.tags"
+	// KeyOpenAPIHidden is a Route Metadata key ; its bool value true leaves the Route out of the OpenAPI documentation
+	KeyOpenAPIHidden = "openapi.hidden"
+	// KeyCORS is a Route Metadata key ; its *CrossOriginResourceSharing value is the CORS policy of the Route,
+	// used by the CORS filter of the Container instead of its own, see RouteBuilder.CORS
+	KeyCORS = "cors"
+	// KeyRateLimit is a Route Metadata key ; its RateLimit value is the rate of requests of a client to the Route
+	// allowed by the RateLimitFilter instead of its own, see RouteBuilder.RateLimit
+	KeyRateLimit = "ratelimit"
+	// KeyOriginalMethod is a Request attribute key ; its string value is the method of a request whose method was overridden,
+	// see Container.EnableMethodOverride
+	KeyOriginalMethod = "http.method.original"
+	// KeyRequestID is a Request attribute key ; its string value is the ID of the request, see RequestIDFilter
+	KeyRequestID = "http.request.id"
+	// KeyPrincipal is a Request attribute key ; its value is the principal, e.g. the user, of an authenticated request,
+	// see NewBasicAuthFilter, NewAPIKeyFilter and APIKeyFilter
+	KeyPrincipal = "auth.principal"
+	// SecurityBasic is the name of the security scheme of the Routes authenticated with NewBasicAuthFilter, see BasicAuth
+	SecurityBasic = "Basic"
 
 	ENCODING_GZIP    = "gzip"
 	ENCODING_DEFLATE = "deflate"
@@ -6,13 +6,15 @@ package restful
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/tangblue/goapi/restful/log"
 )
@@ -20,6 +22,7 @@ import (
 // Container holds a collection of WebServices and a http.ServeMux to dispatch http requests.
 // The requests are further dispatched to routes of WebServices using a RouteSelector
 type Container struct {
+	inFlight               int64 // number of requests being dispatched, see InFlight ; first for 64-bit alignment
 	webServicesLock        sync.RWMutex
 	webServices            []*WebService
 	ServeMux               *http.ServeMux
@@ -30,6 +33,23 @@ type Container struct {
 	serviceErrorHandleFunc ServiceErrorHandleFunction
 	router                 RouteSelector // default is a CurlyRouter (RouterJSR311 is a slower alternative)
 	contentEncodingEnabled bool          // default is false
+	declaredSuccessStatus  bool          // default is false
+	omitXMLHeader          bool          // default is false
+	caseInsensitivePaths   bool          // default is false
+	trailingSlash          TrailingSlashPolicy
+	autoOPTIONS            bool   // default is false
+	autoHEAD               bool   // default is false
+	methodOverrideHeader   string // default is empty, method override disabled
+	panicOnAddError        bool   // default is false
+	routeTracing           bool   // default is false
+	versionResolver        VersionResolverFunction
+	defaultProduces        []string // default is empty
+	defaultConsumes        []string // default is empty
+	encodedSlashes         EncodedSlashPolicy
+	serverLock             sync.Mutex
+	server                 *http.Server // set by Serve until it returns
+	shuttingDown           bool         // set by Shutdown
+	shutdownHooks          []func(ctx context.Context) error
 }
 
 // NewContainer creates a new Container using a new ServeMux and default router (CurlyRouter)
@@ -41,7 +61,6 @@ func NewContainer() *Container {
 		containerFilters:       []FilterFunction{},
 		doNotRecover:           true,
 		recoverHandleFunc:      logStackOnRecover,
-		serviceErrorHandleFunc: writeServiceError,
 		router:                 CurlyRouter{},
 		contentEncodingEnabled: false}
 }
@@ -62,11 +81,26 @@ func (c *Container) RecoverHandler(handler RecoverHandleFunction) {
 type ServiceErrorHandleFunction func(ServiceError, *Request, *Response)
 
 // ServiceErrorHandler changes the default function (writeServiceError) to be called
-// when a ServiceError is detected.
+// when a ServiceError is detected. Once set, it is also called for every error written by the framework
+// (e.g. 404, 405, 406, 415) and for errors written by Route functions using WriteError or WriteErrorString,
+// so that all error responses can be rendered uniformly.
 func (c *Container) ServiceErrorHandler(handler ServiceErrorHandleFunction) {
 	c.serviceErrorHandleFunc = handler
 }
 
+// serviceErrorHandler returns the function to call for a ServiceError ; writeServiceError unless changed.
+// The Header of the error, e.g. Allow or Location, is set on the response before a changed function is called.
+func (c *Container) serviceErrorHandler() ServiceErrorHandleFunction {
+	handler := c.serviceErrorHandleFunc
+	if handler == nil {
+		return writeServiceError
+	}
+	return func(err ServiceError, req *Request, resp *Response) {
+		setErrorHeader(err, resp)
+		handler(err, req, resp)
+	}
+}
+
 // DoNotRecover controls whether panics will be caught to return HTTP 500.
 // If set to true, Route functions are responsible for handling any error situation.
 // Default value is true.
@@ -84,21 +118,150 @@ func (c *Container) EnableContentEncoding(enabled bool) {
 	c.contentEncodingEnabled = enabled
 }
 
-// Add a WebService to the Container. It will detect duplicate root paths and exit in that case.
-func (c *Container) Add(service *WebService) *Container {
+// EnableDeclaredSuccessStatus (default=false) makes WriteEntity use the success status code of the Route
+// instead of 200 OK, if the Route documents exactly one 2xx code using Return, e.g. Return(201, "Created", nil).
+// WriteHeaderAndEntity still writes the status code it is given.
+func (c *Container) EnableDeclaredSuccessStatus(enabled bool) {
+	c.declaredSuccessStatus = enabled
+}
+
+// OmitXMLHeader (default=false) controls whether XML responses are written without the <?xml ...?> header,
+// which is otherwise written with pretty printing only. A Route can override this using the KeyXMLHeader Metadata.
+func (c *Container) OmitXMLHeader(omit bool) {
+	c.omitXMLHeader = omit
+}
+
+// EnableCaseInsensitivePaths (default=false) sets whether the static parts of paths match regardless of case
+// for the WebServices that are added afterwards and that did not set WebService.CaseInsensitivePaths themselves.
+func (c *Container) EnableCaseInsensitivePaths(enabled bool) {
+	c.caseInsensitivePaths = enabled
+}
+
+// DefaultProduces sets the MIME types produced by the Routes of a WebService, added after calling it,
+// if neither the WebService nor the Route specifies them, see WebService.Produces.
+func (c *Container) DefaultProduces(contentTypes ...string) {
+	c.defaultProduces = contentTypes
+}
+
+// DefaultConsumes sets the MIME types consumed by the Routes of a WebService, added after calling it,
+// if neither the WebService nor the Route specifies them, see WebService.Consumes.
+func (c *Container) DefaultConsumes(accepts ...string) {
+	c.defaultConsumes = accepts
+}
+
+// EnableAutoOPTIONS (default=false) sets whether an OPTIONS request for a path without an OPTIONS Route is answered
+// with 204 No Content and an Allow header listing the methods of the Routes for that path, and OPTIONS.
+// Container filters, such as CORS, are called before so that preflight requests get their headers.
+// A registered OPTIONS Route always takes precedence.
+func (c *Container) EnableAutoOPTIONS(enabled bool) {
+	c.autoOPTIONS = enabled
+}
+
+// EnableAutoHEAD (default=false) sets whether a HEAD request for a path without a HEAD Route is served by its GET Route.
+// The body written by the Route function is discarded ; the response has the header of the GET response,
+// including its Content-Type and Content-Length. Content encoding (compression) is not applied.
+// A registered HEAD Route always takes precedence.
+func (c *Container) EnableAutoHEAD(enabled bool) {
+	c.autoHEAD = enabled
+}
+
+// VersionResolverFunction returns the version of the API a request asks for, e.g. v2 from a header ;
+// empty if the request does not ask for a version.
+type VersionResolverFunction func(httpRequest *http.Request) string
+
+// VersionResolver sets the function that returns the version of the API a request asks for.
+// Routes of that version are preferred ; if none matches then the Routes of the default version are selected,
+// see RouteBuilder.Version and WebService.ApiVersion.
+func (c *Container) VersionResolver(resolver VersionResolverFunction) {
+	c.versionResolver = resolver
+}
+
+// requestedVersionKey is the context key of the version a request asks for, see VersionResolver.
+type requestedVersionKey struct{}
+
+// selectRoute selects the Route of the WebServices for the version the request asks for, if any, or else of the default version.
+func (c *Container) selectRoute(webServices []*WebService, httpRequest *http.Request) (*WebService, *Route, error) {
+	if c.versionResolver != nil {
+		if version := c.versionResolver(httpRequest); len(version) > 0 {
+			versioned := httpRequest.WithContext(context.WithValue(httpRequest.Context(), requestedVersionKey{}, version))
+			if webService, route, err := c.router.SelectRoute(webServices, versioned); err == nil {
+				return webService, route, nil
+			}
+		}
+	}
+	return c.router.SelectRoute(webServices, httpRequest)
+}
+
+// EnableMethodOverride (default=false) sets whether a POST request can select a PUT, PATCH or DELETE Route
+// by naming that method in the X-HTTP-Method-Override header or, for a form, in the _method field.
+// This is for clients behind proxies that only allow GET and POST. The original method is kept
+// in the request attribute KeyOriginalMethod ; filters and route functions see the overridden method.
+// Overriding to any other method is refused with 400 Bad Request.
+// Security note: proxies, firewalls and CSRF protections that rely on the method of a request no longer
+// see the method that is served ; only enable this if clients need it.
+func (c *Container) EnableMethodOverride(enabled bool) {
+	c.methodOverrideHeader = ""
+	if enabled {
+		c.methodOverrideHeader = HEADER_XHTTPMethodOverride
+	}
+}
+
+// MethodOverrideHeader enables method override, see EnableMethodOverride, using the header with the name
+// instead of X-HTTP-Method-Override.
+func (c *Container) MethodOverrideHeader(name string) {
+	c.methodOverrideHeader = name
+}
+
+// TrailingSlashPolicy controls how a request path is matched whose trailing slash differs from
+// the path of the Route that matches it otherwise, e.g. GET /users/ for a Route with path /users.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashTolerant matches the request path with or without a trailing slash. This is the default.
+	TrailingSlashTolerant TrailingSlashPolicy = iota
+	// TrailingSlashStrict responds with 404 unless the trailing slash of the request path is as declared by the Route.
+	TrailingSlashStrict
+	// TrailingSlashRedirect responds with a redirect to the path as declared by the Route, preserving the query.
+	// GET and HEAD requests are redirected with 301 ; other methods with 308 such that the method and body are kept.
+	TrailingSlashRedirect
+)
+
+// TrailingSlash (default=TrailingSlashTolerant) sets how a trailing slash of the request path is matched.
+// The policy does not apply to Routes that end with a catch-all parameter, e.g. /files/{path:*},
+// because a trailing slash is part of the parameter value.
+func (c *Container) TrailingSlash(policy TrailingSlashPolicy) {
+	c.trailingSlash = policy
+}
+
+// PanicOnAddError (default=false) sets whether Add panics instead of returning an error,
+// e.g. for programs that register their WebServices at startup and do not check the error.
+func (c *Container) PanicOnAddError(enabled bool) {
+	c.panicOnAddError = enabled
+}
+
+// Add a WebService to the Container. The WebService is not added if it has an error, see WebService.Err,
+// or if another WebService has the same root path ; the error is returned or, see PanicOnAddError, panics.
+func (c *Container) Add(service *WebService) error {
+	err := c.add(service)
+	if err != nil && c.panicOnAddError {
+		panic(err)
+	}
+	return err
+}
+
+func (c *Container) add(service *WebService) error {
 	c.webServicesLock.Lock()
 	defer c.webServicesLock.Unlock()
 
-	// if rootPath was not set then lazy initialize it
-	if len(service.rootPath) == 0 {
-		service.Path("/")
+	c.initializeWebService(service)
+	if err := service.Err(); err != nil {
+		return fmt.Errorf("invalid WebService ['%v']: %v", service.RootPath(), err)
 	}
 
 	// cannot have duplicate root paths
 	for _, each := range c.webServices {
-		if each.RootPath() == service.RootPath() {
-			log.Printf("WebService with duplicate root path detected:['%v']", each)
-			os.Exit(1)
+		if each.matchesRootPath(service) {
+			return fmt.Errorf("WebService with duplicate root path detected:['%v']", each)
 		}
 	}
 
@@ -106,23 +269,41 @@ func (c *Container) Add(service *WebService) *Container {
 	if !c.isRegisteredOnRoot {
 		c.isRegisteredOnRoot = c.addHandler(service, c.ServeMux)
 	}
-	c.webServices = append(c.webServices, service)
-	return c
+	// copy on write ; the slice may be used by requests in flight, see registered
+	c.webServices = append(c.webServices[:len(c.webServices):len(c.webServices)], service)
+	return nil
+}
+
+// initializeWebService sets the root path and the Container defaults that the WebService did not set.
+func (c *Container) initializeWebService(service *WebService) {
+	// if rootPath was not set then lazy initialize it
+	if len(service.rootPath) == 0 {
+		service.Path("/")
+	}
+	if !service.caseInsensitivePathsSet {
+		service.caseInsensitivePaths = c.caseInsensitivePaths
+	}
+	service.inheritDefaults(c.defaultProduces, c.defaultConsumes)
 }
 
 // addHandler may set a new HandleFunc for the serveMux
 // this function must run inside the critical region protected by the webServicesLock.
 // returns true if the function was registered on root ("/")
 func (c *Container) addHandler(service *WebService, serveMux *http.ServeMux) bool {
+	return c.addHandlerFor(c.webServices, service, serveMux)
+}
+
+// addHandlerFor is addHandler for a WebService added to the registered ones.
+func (c *Container) addHandlerFor(registered []*WebService, service *WebService, serveMux *http.ServeMux) bool {
 	pattern := fixedPrefixPath(service.RootPath())
-	// check if root path registration is needed
-	if "/" == pattern || "" == pattern {
+	// check if root path registration is needed ; the ServeMux matches case-sensitively
+	if "/" == pattern || "" == pattern || service.caseInsensitivePaths {
 		serveMux.HandleFunc("/", c.dispatch)
 		return true
 	}
 	// detect if registration already exists
 	alreadyMapped := false
-	for _, each := range c.webServices {
+	for _, each := range registered {
 		if each.RootPath() == service.RootPath() {
 			alreadyMapped = true
 			break
@@ -137,28 +318,62 @@ func (c *Container) addHandler(service *WebService, serveMux *http.ServeMux) boo
 	return false
 }
 
+// Remove unregisters the WebService with the root path of ws. It is safe to call while requests are served ;
+// requests that have selected a Route of the WebService before complete as usual.
+// Returns an error if no such WebService is registered or if the Container uses the DefaultServeMux.
 func (c *Container) Remove(ws *WebService) error {
+	return c.Replace(ws, nil)
+}
+
+// Replace unregisters the WebService with the root path of old and registers the other in its place, as one change.
+// The other WebService must not have the root path of any other registered WebService ; if it is nil then old is removed.
+// It is safe to call while requests are served ; requests that have selected a Route of old before complete as usual.
+// Returns an error if old is not registered, the other has an error or its root path conflicts
+// or if the Container uses the DefaultServeMux.
+func (c *Container) Replace(old, other *WebService) error {
 	if c.ServeMux == http.DefaultServeMux {
-		errMsg := fmt.Sprintf("cannot remove a WebService from a Container using the DefaultServeMux: ['%v']", ws)
+		errMsg := fmt.Sprintf("cannot remove or replace a WebService of a Container using the DefaultServeMux: ['%v']", old)
 		log.Print(errMsg)
 		return errors.New(errMsg)
 	}
 	c.webServicesLock.Lock()
 	defer c.webServicesLock.Unlock()
-	// build a new ServeMux and re-register all WebServices
-	newServeMux := http.NewServeMux()
+	if other != nil {
+		c.initializeWebService(other)
+		if err := other.Err(); err != nil {
+			return fmt.Errorf("invalid WebService ['%v']: %v", other.RootPath(), err)
+		}
+	}
 	newServices := []*WebService{}
-	newIsRegisteredOnRoot := false
+	found := false
 	for _, each := range c.webServices {
-		if each.rootPath != ws.rootPath {
-			// If not registered on root then add specific mapping
-			if !newIsRegisteredOnRoot {
-				newIsRegisteredOnRoot = c.addHandler(each, newServeMux)
+		if each.rootPath == old.rootPath {
+			found = true
+			if other != nil {
+				newServices = append(newServices, other)
 			}
-			newServices = append(newServices, each)
+			continue
+		}
+		if other != nil && each.matchesRootPath(other) {
+			return fmt.Errorf("WebService with duplicate root path detected:['%v']", each)
+		}
+		newServices = append(newServices, each)
+	}
+	if !found {
+		return fmt.Errorf("WebService is not registered:['%v']", old)
+	}
+	// build a new ServeMux and re-register all WebServices
+	newServeMux := http.NewServeMux()
+	registered, isRegisteredOnRoot := []*WebService{}, false
+	for _, each := range newServices {
+		// If not registered on root then add specific mapping
+		if !isRegisteredOnRoot {
+			isRegisteredOnRoot = c.addHandlerFor(registered, each, newServeMux)
 		}
+		registered = append(registered, each)
 	}
-	c.webServices, c.ServeMux, c.isRegisteredOnRoot = newServices, newServeMux, newIsRegisteredOnRoot
+	c.webServices, c.isRegisteredOnRoot = registered, isRegisteredOnRoot
+	c.ServeMux = newServeMux
 	return nil
 }
 
@@ -183,9 +398,87 @@ func logStackOnRecover(panicReason interface{}, httpWriter http.ResponseWriter)
 
 // writeServiceError is the default ServiceErrorHandleFunction and is called
 // when a ServiceError is returned during route selection. Default implementation
-// calls resp.WriteErrorString(err.Code, err.Message)
+// calls resp.WriteErrorString(err.Code, err.Message) ; the message ends with the request ID, if any, see RequestIDFilter
 func writeServiceError(err ServiceError, req *Request, resp *Response) {
-	resp.WriteErrorString(err.Code, err.Message)
+	setErrorHeader(err, resp)
+	if err.Code == http.StatusNotAcceptable {
+		writeNotAcceptable(err, resp)
+		return
+	}
+	message := err.Message
+	if len(err.RequestID) > 0 && !strings.Contains(message, err.RequestID) {
+		message += ", request id " + err.RequestID
+	}
+	resp.WriteErrorString(err.Code, message)
+}
+
+// setErrorHeader sets the Header of the error on the response, replacing the values of the same headers ;
+// each ServiceErrorHandleFunction gets the response with them.
+func setErrorHeader(err ServiceError, resp *Response) {
+	for header, values := range err.Header {
+		resp.Header().Del(header)
+		for _, value := range values {
+			resp.Header().Add(header, value)
+		}
+	}
+}
+
+// checkTrailingSlash returns a ServiceError if the trailing slash of the request path
+// differs from the path of the selected Route and the policy does not tolerate that.
+func (c *Container) checkTrailingSlash(route *Route, httpRequest *http.Request) error {
+	if c.trailingSlash == TrailingSlashTolerant || route.hasCatchAll() {
+		return nil
+	}
+	wantSlash := hasTrailingSlash(route.Path)
+	if hasTrailingSlash(httpRequest.URL.Path) == wantSlash {
+		return nil
+	}
+	if c.trailingSlash == TrailingSlashStrict {
+		return NewError(http.StatusNotFound, "404: Page Not Found")
+	}
+	location := strings.TrimRight(httpRequest.URL.EscapedPath(), "/")
+	if wantSlash {
+		location += "/"
+	}
+	if len(httpRequest.URL.RawQuery) > 0 {
+		location += "?" + httpRequest.URL.RawQuery
+	}
+	code := http.StatusPermanentRedirect
+	if httpRequest.Method == http.MethodGet || httpRequest.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	return NewErrorWithHeader(code, fmt.Sprintf("%d: %s", code, http.StatusText(code)), http.Header{HEADER_Location: []string{location}})
+}
+
+// allowMethod returns a copy of the 405 ServiceError with the method added to its Allow header.
+func allowMethod(err ServiceError, method string) ServiceError {
+	header := http.Header{}
+	for name, values := range err.Header {
+		header[name] = values
+	}
+	header.Set(HEADER_Allow, err.Header.Get(HEADER_Allow)+", "+method)
+	return NewErrorWithHeader(err.Code, err.Message, header)
+}
+
+// allowsGET returns whether the 405 ServiceError lists GET but not HEAD in its Allow header.
+func allowsGET(err ServiceError) bool {
+	methods := strings.Split(err.Header.Get(HEADER_Allow), ",")
+	for i := range methods {
+		methods[i] = strings.TrimSpace(methods[i])
+	}
+	return containsString(methods, "GET") && !containsString(methods, "HEAD")
+}
+
+// selectGETRoute selects the Route for the HEAD request as if it were a GET request, see EnableAutoHEAD.
+func (c *Container) selectGETRoute(webServices []*WebService, httpRequest *http.Request) (*WebService, *Route, error) {
+	getRequest := httpRequest.WithContext(httpRequest.Context())
+	getRequest.Method = "GET"
+	return c.selectRoute(webServices, getRequest)
+}
+
+// hasTrailingSlash returns whether path ends with a slash and is not the root path.
+func hasTrailingSlash(path string) bool {
+	return len(path) > 1 && strings.HasSuffix(path, "/")
 }
 
 // Dispatch the incoming Http Request to a matching WebService.
@@ -201,19 +494,44 @@ func (c *Container) Dispatch(httpWriter http.ResponseWriter, httpRequest *http.R
 
 // Dispatch the incoming Http Request to a matching WebService.
 func (c *Container) dispatch(httpWriter http.ResponseWriter, httpRequest *http.Request) {
+	atomic.AddInt64(&c.inFlight, 1)
+	defer atomic.AddInt64(&c.inFlight, -1)
 	writer := httpWriter
+	// set if a GET Route serves a HEAD request, see EnableAutoHEAD
+	var headWriter *headResponseWriter
 
 	// CompressingResponseWriter should be closed after all operations are done
 	defer func() {
+		if headWriter != nil {
+			headWriter.finish()
+		}
 		if compressWriter, ok := writer.(*CompressingResponseWriter); ok {
 			compressWriter.Close()
 		}
 	}()
 
+	// the response passed to the filters and route function ; its write hooks are called after all is done
+	var wrappedResponse *Response
+	start := time.Now()
+	defer func() {
+		if wrappedResponse != nil {
+			wrappedResponse.callWriteHooks()
+		}
+	}()
+
 	// Instal panic recovery unless told otherwise
 	if !c.doNotRecover { // catch all for 500 response
 		defer func() {
 			if r := recover(); r != nil {
+				if r == http.ErrAbortHandler {
+					// deliberate abort of the connection, e.g. by a NDJSONWriter
+					panic(r)
+				}
+				if wrappedResponse != nil {
+					// let the response record what is written
+					c.recoverHandleFunc(r, wrappedResponse)
+					return
+				}
 				c.recoverHandleFunc(r, writer)
 				return
 			}
@@ -237,34 +555,89 @@ func (c *Container) dispatch(httpWriter http.ResponseWriter, httpRequest *http.R
 	// Find best match Route ; err is non nil if no match was found
 	var webService *WebService
 	var route *Route
-	var err error
-	func() {
-		c.webServicesLock.RLock()
-		defer c.webServicesLock.RUnlock()
-		webService, route, err = c.router.SelectRoute(
-			c.webServices,
-			httpRequest)
-	}()
+	originalMethod := httpRequest.Method
+	httpRequest, err := c.overrideMethod(httpRequest)
+	if err == nil {
+		err = c.checkEscapedPath(httpRequest)
+	}
+	// all Routes are selected from the WebServices registered when the request arrived
+	webServices := c.registered()
+	if err == nil {
+		webService, route, err = c.selectRoute(webServices, httpRequest)
+	}
+	if ser, ok := err.(ServiceError); ok && c.autoHEAD && ser.Code == http.StatusMethodNotAllowed && httpRequest.Method == "HEAD" {
+		if getService, getRoute, getErr := c.selectGETRoute(webServices, httpRequest); getErr == nil {
+			webService, route, err = getService, getRoute, nil
+			if compressWriter, ok := writer.(*CompressingResponseWriter); ok && compressWriter.disable() {
+				writer = httpWriter
+			}
+			headWriter = &headResponseWriter{writer: writer}
+			writer = headWriter
+		}
+	}
+	// explains why no Route was selected, see EnableRouteTracing
+	var explanation string
+	if err != nil && c.routeTracing {
+		explanation = routeTrace(webServices, httpRequest)
+		traceLogger.Printf("no Route selected for %s %s: %s", httpRequest.Method, httpRequest.URL.Path, explanation)
+	}
+	if err == nil {
+		err = c.checkTrailingSlash(route, httpRequest)
+	}
 	if err != nil {
 		// a non-200 response has already been written
 		// run container filters anyway ; they should not touch the response...
 		chain := FilterChain{Filters: c.containerFilters, Target: func(req *Request, resp *Response) {
+			if len(explanation) > 0 {
+				resp.Header().Set(HEADER_XRouteTrace, explanation)
+			}
 			switch err.(type) {
 			case ServiceError:
 				ser := err.(ServiceError)
-				c.serviceErrorHandleFunc(ser, req, resp)
+				if c.autoHEAD && ser.Code == http.StatusMethodNotAllowed && allowsGET(ser) {
+					ser = allowMethod(ser, "HEAD")
+				}
+				if c.autoOPTIONS && ser.Code == http.StatusMethodNotAllowed {
+					ser = allowMethod(ser, "OPTIONS")
+					if req.Request.Method == "OPTIONS" {
+						resp.Header().Set(HEADER_Allow, ser.Header.Get(HEADER_Allow))
+						resp.WriteHeader(http.StatusNoContent)
+						return
+					}
+				}
+				c.serviceErrorHandler()(ser.withRequestID(req), req, resp)
 			}
 			// TODO
 		}}
-		chain.processFilter(NewRequest(httpRequest), NewResponse(writer))
+		wrappedRequest := NewRequest(httpRequest)
+		wrappedRequest.startTime = start
+		wrappedRequest.keepOriginalMethod(originalMethod)
+		wrappedResponse = NewResponse(writer)
+		wrappedResponse.request = wrappedRequest
+		chain.processFilter(wrappedRequest, wrappedResponse)
 		return
 	}
 	pathProcessor, routerProcessesPath := c.router.(PathProcessor)
 	if !routerProcessesPath {
 		pathProcessor = defaultPathProcessor{}
 	}
-	pathParams := pathProcessor.ExtractParameters(route, webService, httpRequest.URL.Path)
-	wrappedRequest, wrappedResponse := route.wrapRequestResponse(writer, httpRequest, pathParams)
+	pathParams, rawPathParams := c.pathParameters(pathProcessor, route, webService, httpRequest)
+	wrappedRequest, routeResponse := route.wrapRequestResponse(writer, httpRequest, pathParams)
+	wrappedRequest.startTime = start
+	wrappedRequest.keepOriginalMethod(originalMethod)
+	if route.hasCatchAll() {
+		wrappedRequest.rawPathParameters = rawPathParams
+	}
+	wrappedResponse = routeResponse
+	wrappedResponse.request = wrappedRequest
+	wrappedResponse.serviceErrorHandler = c.serviceErrorHandleFunc
+	if c.declaredSuccessStatus {
+		wrappedResponse.successStatus = route.successCode
+	}
+	wrappedResponse.omitXMLHeader = c.omitXMLHeader
+	if header, ok := route.Metadata[KeyXMLHeader].(bool); ok {
+		wrappedResponse.omitXMLHeader, wrappedResponse.xmlHeader = !header, header
+	}
 	// pass through filters (if any)
 	if len(c.containerFilters)+len(webService.filters)+len(route.Filters) > 0 {
 		// compose filter chain
@@ -294,12 +667,19 @@ func fixedPrefixPath(pathspec string) string {
 
 // ServeHTTP implements net/http.Handler therefore a Container can be a Handler in a http.Server
 func (c *Container) ServeHTTP(httpwriter http.ResponseWriter, httpRequest *http.Request) {
-	c.ServeMux.ServeHTTP(httpwriter, httpRequest)
+	// the ServeMux is replaced when a WebService is removed
+	c.webServicesLock.RLock()
+	serveMux := c.ServeMux
+	c.webServicesLock.RUnlock()
+	serveMux.ServeHTTP(httpwriter, httpRequest)
 }
 
 // Handle registers the handler for the given pattern. If a handler already exists for pattern, Handle panics.
 func (c *Container) Handle(pattern string, handler http.Handler) {
-	c.ServeMux.Handle(pattern, handler)
+	c.webServicesLock.RLock()
+	serveMux := c.ServeMux
+	c.webServicesLock.RUnlock()
+	serveMux.Handle(pattern, handler)
 }
 
 // HandleWithFilter registers the handler for the given pattern.
@@ -327,39 +707,52 @@ func (c *Container) Filter(filter FilterFunction) {
 	c.containerFilters = append(c.containerFilters, filter)
 }
 
-// RegisteredWebServices returns the collections of added WebServices
+// RegisteredWebServices returns a snapshot of the collection of added WebServices ;
+// it is not changed by adding, removing or replacing WebServices later.
 func (c *Container) RegisteredWebServices() []*WebService {
+	registered := c.registered()
+	result := make([]*WebService, len(registered))
+	copy(result, registered)
+	return result
+}
+
+// registered returns the added WebServices. The slice is never changed, the Container replaces it instead,
+// such that it can be used without holding the webServicesLock ; it must not be changed.
+func (c *Container) registered() []*WebService {
 	c.webServicesLock.RLock()
 	defer c.webServicesLock.RUnlock()
-	result := make([]*WebService, len(c.webServices))
-	for ix := range c.webServices {
-		result[ix] = c.webServices[ix]
-	}
-	return result
+	return c.webServices
 }
 
 // computeAllowedMethods returns a list of HTTP methods that are valid for a Request
 func (c *Container) computeAllowedMethods(req *Request) []string {
-	// Go through all RegisteredWebServices() and all its Routes to collect the options
 	methods := []string{}
-	requestPath := req.Request.URL.Path
-	for _, ws := range c.RegisteredWebServices() {
-		matches := ws.pathExpr.Matcher.FindStringSubmatch(requestPath)
+	for _, rt := range c.routesMatchingPath(req.Request.URL.Path) {
+		methods = append(methods, rt.Method)
+	}
+	// methods = append(methods, "OPTIONS")  not sure about this
+	return methods
+}
+
+// routesMatchingPath returns the Routes of all registered WebServices whose path matches the request path, whatever their method.
+func (c *Container) routesMatchingPath(requestPath string) []Route {
+	routes := []Route{}
+	for _, ws := range c.registered() {
+		matches := ws.pathExpr.matcher(ws.caseInsensitivePaths).FindStringSubmatch(requestPath)
 		if matches != nil {
 			finalMatch := matches[len(matches)-1]
 			for _, rt := range ws.Routes() {
-				matches := rt.pathExpr.Matcher.FindStringSubmatch(finalMatch)
+				matches := rt.pathExpr.matcher(ws.caseInsensitivePaths).FindStringSubmatch(finalMatch)
 				if matches != nil {
 					lastMatch := matches[len(matches)-1]
 					if lastMatch == "" || lastMatch == "/" { // do not include if value is neither empty nor ‘/’.
-						methods = append(methods, rt.Method)
+						routes = append(routes, rt)
 					}
 				}
 			}
 		}
 	}
-	// methods = append(methods, "OPTIONS")  not sure about this
-	return methods
+	return routes
 }
 
 // newBasicRequestResponse creates a pair of Request,Response from its http versions.
@@ -367,5 +760,8 @@ func (c *Container) computeAllowedMethods(req *Request) []string {
 func newBasicRequestResponse(httpWriter http.ResponseWriter, httpRequest *http.Request) (*Request, *Response) {
 	resp := NewResponse(httpWriter)
 	resp.requestAccept = httpRequest.Header.Get(HEADER_Accept)
-	return NewRequest(httpRequest), resp
+	resp.ctx = httpRequest.Context()
+	req := NewRequest(httpRequest)
+	resp.request = req
+	return req, resp
 }
@@ -1,8 +1,12 @@
 package restful
 
 import (
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -81,3 +85,740 @@ func TestContainerAddAndRemove(t *testing.T) {
 		t.Errorf("expected not on root registered")
 	}
 }
+
+func TestContainerRemoveNotRegistered(t *testing.T) {
+	wc := NewContainer()
+	wc.Add(new(WebService).Path("/users"))
+	if err := wc.Remove(new(WebService).Path("/orders")); err == nil {
+		t.Error("expected error for a WebService that is not registered")
+	}
+	if got, want := len(wc.RegisteredWebServices()), 1; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestContainerRemoveRoutes(t *testing.T) {
+	wc := NewContainer()
+	users := new(WebService).Path("/users")
+	users.Route(users.GET("").Handler(dummy))
+	orders := new(WebService).Path("/orders")
+	orders.Route(orders.GET("").Handler(curlyDummy))
+	wc.Add(users)
+	wc.Add(orders)
+	if err := wc.Remove(users); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int{"/users": 404, "/orders": 200} {
+		httpRequest, _ := http.NewRequest("GET", path, nil)
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Code; got != want {
+			t.Errorf("%s: got %v want %v", path, got, want)
+		}
+	}
+	if got := wc.RegisteredWebServices(); len(got) != 1 || got[0] != orders {
+		t.Errorf("unexpected registered WebServices %v", got)
+	}
+}
+
+func TestContainerReplace(t *testing.T) {
+	wc := NewContainer()
+	old := new(WebService).Path("/users")
+	old.Route(old.GET("").Handler(dummy))
+	orders := new(WebService).Path("/orders")
+	wc.Add(old)
+	wc.Add(orders)
+	other := new(WebService).Path("/users")
+	other.Route(other.GET("").Handler(curlyDummy))
+	if err := wc.Replace(old, other); err != nil {
+		t.Fatal(err)
+	}
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "curlyDummy"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if got := wc.RegisteredWebServices(); len(got) != 2 || got[0] != other || got[1] != orders {
+		t.Errorf("unexpected registered WebServices %v", got)
+	}
+	if err := wc.Replace(other, new(WebService).Path("/orders")); err == nil {
+		t.Error("expected error for a duplicate root path")
+	}
+	if err := wc.Replace(old, other); err != nil {
+		t.Errorf("replacing a WebService by itself failed: %v", err)
+	}
+}
+
+func TestContainerRemoveWhileServing(t *testing.T) {
+	wc := NewContainer()
+	started, release := make(chan bool), make(chan bool)
+	slow := new(WebService).Path("/slow")
+	slow.Route(slow.GET("").Handler(blockingHandler{started, release}.serve))
+	wc.Add(slow)
+	httpWriter := httptest.NewRecorder()
+	finished := make(chan bool)
+	go func() {
+		httpRequest, _ := http.NewRequest("GET", "/slow", nil)
+		wc.ServeHTTP(httpWriter, httpRequest)
+		close(finished)
+	}()
+	<-started
+	if err := wc.Remove(slow); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-finished
+	if got, want := httpWriter.Code, 200; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := httpWriter.Body.String(), "done"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	httpRequest, _ := http.NewRequest("GET", "/slow", nil)
+	httpWriter = httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Code, 404; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+// blockingHandler signals started and waits for release before it writes the response
+type blockingHandler struct {
+	started, release chan bool
+}
+
+func (b blockingHandler) serve(req *Request, resp *Response) {
+	b.started <- true
+	<-b.release
+	io.WriteString(resp, "done")
+}
+
+func TestContainerRemoveAndReplaceConcurrently(t *testing.T) {
+	wc := NewContainer()
+	stable := new(WebService).Path("/stable")
+	stable.Route(stable.GET("").Handler(dummy))
+	volatile := new(WebService).Path("/volatile")
+	volatile.Route(volatile.GET("").Handler(dummy))
+	wc.Add(stable)
+	wc.Add(volatile)
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+	failures := make(chan string, 100)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, path := range []string{"/stable", "/volatile"} {
+					httpRequest, _ := http.NewRequest("GET", path, nil)
+					httpWriter := httptest.NewRecorder()
+					wc.ServeHTTP(httpWriter, httpRequest)
+					code := httpWriter.Code
+					if code != 200 && (path == "/stable" || code != 404) {
+						select {
+						case failures <- fmt.Sprintf("%s: unexpected status %d", path, code):
+						default:
+						}
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		next := new(WebService).Path("/volatile")
+		next.Route(next.GET("").Handler(dummy))
+		if i%2 == 0 {
+			if err := wc.Replace(volatile, next); err != nil {
+				t.Fatal(err)
+			}
+			volatile = next
+			continue
+		}
+		if err := wc.Remove(volatile); err != nil {
+			t.Fatal(err)
+		}
+		wc.Add(next)
+		volatile = next
+	}
+	close(done)
+	wg.Wait()
+	close(failures)
+	for each := range failures {
+		t.Error(each)
+	}
+	if got, want := len(wc.RegisteredWebServices()), 2; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestContainer_ServiceErrorHandler(t *testing.T) {
+	wc := NewContainer()
+	wc.ServiceErrorHandler(func(err ServiceError, req *Request, resp *Response) {
+		resp.WriteHeader(err.Code)
+		resp.Write([]byte(fmt.Sprintf(`{"code":%d,"message":%q}`, err.Code, err.Message)))
+	})
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Produces(MIME_JSON).Handler(dummy))
+	ws.Route(ws.PUT("").Produces("text/unknown").Handler(writeUnencodable))
+	ws.Route(ws.POST("").Consumes(MIME_JSON, "text/unknown").Handler(readMap))
+	wc.Add(ws)
+
+	for _, each := range []struct {
+		method, path, header, value string
+		code                        int
+	}{
+		{"GET", "/users/missing", "", "", http.StatusNotFound},
+		{"DELETE", "/users", "", "", http.StatusMethodNotAllowed},
+		{"GET", "/users", HEADER_Accept, MIME_XML, http.StatusNotAcceptable},
+		{"PUT", "/users", "", "", http.StatusNotAcceptable},
+		{"POST", "/users", HEADER_ContentType, "text/unknown", http.StatusBadRequest},
+	} {
+		httpRequest, _ := http.NewRequest(each.method, each.path, strings.NewReader("{}"))
+		if each.header != "" {
+			httpRequest.Header.Set(each.header, each.value)
+		}
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httpRequest)
+		if recorder.Code != each.code {
+			t.Errorf("%s %s: got code %d want %d", each.method, each.path, recorder.Code, each.code)
+		}
+		if !strings.HasPrefix(recorder.Body.String(), fmt.Sprintf(`{"code":%d,`, each.code)) {
+			t.Errorf("%s %s: unexpected body %s", each.method, each.path, recorder.Body.String())
+		}
+	}
+}
+
+func TestContainer_MethodNotAllowed(t *testing.T) {
+	for _, router := range []RouteSelector{CurlyRouter{}, RouterJSR311{}} {
+		wc := NewContainer()
+		wc.Router(router)
+		// the Allow header of the error is set before the custom handler is called
+		wc.ServiceErrorHandler(func(err ServiceError, req *Request, resp *Response) {
+			resp.WriteHeaderAndJson(err.Code, map[string]string{"message": err.Message}, MIME_JSON)
+		})
+		ws := new(WebService).Path("/users")
+		ws.Route(ws.GET("/{id}").Handler(dummy))
+		ws.Route(ws.DELETE("/{id}").Handler(dummy))
+		ws.Route(ws.GET("/{id}/groups").Handler(dummy))
+		wc.Add(ws)
+
+		httpRequest, _ := http.NewRequest("PUT", "/users/1", nil)
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httpRequest)
+		if got, want := recorder.Code, http.StatusMethodNotAllowed; got != want {
+			t.Errorf("%T: got %v want %v", router, got, want)
+		}
+		if got, want := recorder.Header().Get(HEADER_Allow), "GET, DELETE"; got != want {
+			t.Errorf("%T: got %v want %v", router, got, want)
+		}
+		if !strings.Contains(recorder.Body.String(), `"message": "405: Method Not Allowed"`) {
+			t.Errorf("%T: unexpected body %s", router, recorder.Body.String())
+		}
+	}
+}
+
+func TestContainer_AutoOPTIONS(t *testing.T) {
+	wc := NewContainer()
+	wc.EnableAutoOPTIONS(true)
+	wc.Filter(CrossOriginResourceSharing{AllowedMethods: []string{"GET", "POST"}, Container: wc}.Filter)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.POST("").Handler(dummy))
+	ws.Route(ws.GET("/{id}").Handler(dummy))
+	ws.Route(ws.Method("OPTIONS").Path("/{id}").Handler(writeConflict))
+	wc.Add(ws)
+
+	for _, each := range []struct {
+		method, path, origin string
+		code                 int
+		allow                string
+	}{
+		{"OPTIONS", "/users", "", http.StatusNoContent, "GET, POST, OPTIONS"},
+		{"OPTIONS", "/unknown", "", http.StatusNotFound, ""},
+		{"OPTIONS", "/users/1", "", http.StatusConflict, ""},
+		{"DELETE", "/users", "", http.StatusMethodNotAllowed, "GET, POST, OPTIONS"},
+		{"OPTIONS", "/users", "http://example.com", http.StatusNoContent, ""},
+	} {
+		httpRequest, _ := http.NewRequest(each.method, each.path, nil)
+		if each.origin != "" {
+			httpRequest.Header.Set(HEADER_Origin, each.origin)
+			httpRequest.Header.Set(HEADER_AccessControlRequestMethod, "POST")
+		}
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httpRequest)
+		if got, want := recorder.Code, each.code; got != want {
+			t.Errorf("%s %s: got %v want %v", each.method, each.path, got, want)
+		}
+		if got, want := recorder.Header().Get(HEADER_Allow), each.allow; got != want {
+			t.Errorf("%s %s: got %v want %v", each.method, each.path, got, want)
+		}
+		if each.origin != "" && recorder.Header().Get(HEADER_AccessControlAllowMethods) != "GET,POST" {
+			t.Errorf("%s %s: expected preflight response, got %v", each.method, each.path, recorder.Header())
+		}
+	}
+}
+
+func TestContainer_MethodNotAllowedOPTIONSFilter(t *testing.T) {
+	wc := NewContainer()
+	wc.Filter(wc.OPTIONSFilter)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{id}").Handler(dummy))
+	ws.Route(ws.DELETE("/{id}").Handler(dummy))
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("OPTIONS", "/users/1", nil)
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if got, want := recorder.Code, http.StatusOK; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := recorder.Header().Get(HEADER_Allow), "GET,DELETE"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestContainer_DefaultServiceErrorHandler(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Handler(writeConflict))
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusConflict || recorder.Body.String() != "conflict" {
+		t.Errorf("unexpected response %d %s", recorder.Code, recorder.Body.String())
+	}
+	httpRequest, _ = http.NewRequest("GET", "/users/missing", nil)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusNotFound || recorder.Body.String() != "404: Page Not Found" {
+		t.Errorf("unexpected response %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestContainer_NotAcceptable(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Produces(MIME_JSON, MIME_XML).Handler(dummy))
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	httpRequest.Header.Set(HEADER_Accept, "text/csv")
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusNotAcceptable {
+		t.Errorf("got code %d want %d", recorder.Code, http.StatusNotAcceptable)
+	}
+	if got, want := recorder.Header().Get(HEADER_XAcceptable), "application/json,application/xml"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := recorder.Header().Get(HEADER_ContentType), MIME_JSON; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if !strings.Contains(recorder.Body.String(), `"acceptable": [`) {
+		t.Errorf("unexpected body %s", recorder.Body.String())
+	}
+
+	// also with a custom handler
+	handled := &serviceErrorRecorder{}
+	wc.ServiceErrorHandler(handled.write)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusNotAcceptable || handled.err.Code != http.StatusNotAcceptable {
+		t.Errorf("got code %d and error %v want %d", recorder.Code, handled.err, http.StatusNotAcceptable)
+	}
+	if got, want := recorder.Header().Get(HEADER_XAcceptable), "application/json,application/xml"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func writeUnencodable(req *Request, resp *Response) {
+	resp.WriteEntity("unencodable")
+}
+
+func readMap(req *Request, resp *Response) {
+	var value map[string]interface{}
+	if err := req.ReadEntity(&value); err != nil {
+		resp.WriteError(http.StatusBadRequest, err)
+	}
+}
+
+func writeConflict(req *Request, resp *Response) {
+	resp.WriteErrorString(http.StatusConflict, "conflict")
+}
+
+func TestContainer_ErrorInNegotiatedType(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/json").Produces(MIME_JSON, MIME_XML).Handler(writeConflict))
+	ws.Route(ws.GET("/text").Produces("text/plain").Handler(writeConflict))
+	wc.Add(ws)
+
+	for _, each := range []struct {
+		path, accept, contentType, body string
+	}{
+		{"/users/json", "", MIME_JSON, `"Message": "conflict"`},
+		{"/users/json", MIME_XML, MIME_XML, "<Message>conflict</Message>"},
+		{"/users/text", "", "", "conflict"},
+	} {
+		httpRequest, _ := http.NewRequest("GET", each.path, nil)
+		httpRequest.Header.Set(HEADER_Accept, each.accept)
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httpRequest)
+		if got, want := recorder.Code, http.StatusConflict; got != want {
+			t.Errorf("%s %s: got %v want %v", each.path, each.accept, got, want)
+		}
+		if got, want := recorder.Header().Get(HEADER_ContentType), each.contentType; !strings.HasPrefix(got, want) {
+			t.Errorf("%s %s: got %v want %v", each.path, each.accept, got, want)
+		}
+		if !strings.Contains(recorder.Body.String(), each.body) {
+			t.Errorf("%s %s: unexpected body %s", each.path, each.accept, recorder.Body.String())
+		}
+	}
+}
+
+func TestContainer_DeclaredSuccessStatus(t *testing.T) {
+	wc := NewContainer()
+	wc.EnableDeclaredSuccessStatus(true)
+	ws := new(WebService).Path("/users").Produces(MIME_JSON)
+	ws.Route(ws.POST("").Handler(writeUser).
+		Return(http.StatusCreated, "Created", food{}).
+		Return(http.StatusBadRequest, "Bad Request", nil))
+	ws.Route(ws.PUT("").Handler(writeUser).
+		Return(http.StatusOK, "OK", food{}).
+		Return(http.StatusCreated, "Created", food{}))
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("POST", "/users", nil)
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("got code %d want 201", recorder.Code)
+	}
+	// ambiguous success status
+	httpRequest, _ = http.NewRequest("PUT", "/users", nil)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("got code %d want 200", recorder.Code)
+	}
+	// disabled
+	wc.EnableDeclaredSuccessStatus(false)
+	httpRequest, _ = http.NewRequest("POST", "/users", nil)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("got code %d want 200", recorder.Code)
+	}
+}
+
+func writeUser(req *Request, resp *Response) {
+	resp.WriteEntity(food{"apple"})
+}
+
+func TestContainer_ResponseWriteHooks(t *testing.T) {
+	wc := NewContainer()
+	wc.DoNotRecover(false)
+	wc.RecoverHandler(func(reason interface{}, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	var collected []ResponseStats
+	wc.Filter(func(req *Request, resp *Response, next func(*Request, *Response)) {
+		resp.OnWrite(func(stats ResponseStats) {
+			collected = append(collected, stats)
+		})
+		next(req, resp)
+	})
+	ws := new(WebService).Path("/users").Produces(MIME_JSON)
+	ws.Route(ws.GET("").Handler(writeUser))
+	ws.Route(ws.PUT("").Handler(writeConflict))
+	ws.Route(ws.POST("").Handler(panicking))
+	wc.Add(ws)
+
+	for _, each := range []struct {
+		method      string
+		code        int
+		contentType string
+		err         bool
+	}{
+		{"GET", http.StatusOK, MIME_JSON, false},
+		{"PUT", http.StatusConflict, MIME_JSON, true},
+		{"POST", http.StatusInternalServerError, "", false},
+		{"DELETE", http.StatusMethodNotAllowed, "", true},
+	} {
+		collected = nil
+		httpRequest, _ := http.NewRequest(each.method, "/users", nil)
+		wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+		if len(collected) != 1 {
+			t.Errorf("%s: got %d calls want 1", each.method, len(collected))
+			continue
+		}
+		stats := collected[0]
+		if stats.StatusCode != each.code {
+			t.Errorf("%s: got code %d want %d", each.method, stats.StatusCode, each.code)
+		}
+		if stats.ContentType != each.contentType {
+			t.Errorf("%s: got content type %q want %q", each.method, stats.ContentType, each.contentType)
+		}
+		if (stats.Err != nil) != each.err {
+			t.Errorf("%s: unexpected error %v", each.method, stats.Err)
+		}
+		if stats.Elapsed <= 0 {
+			t.Errorf("%s: expected elapsed time", each.method)
+		}
+	}
+}
+
+func panicking(req *Request, resp *Response) {
+	panic("fire")
+}
+
+func TestContainer_TrailingSlash(t *testing.T) {
+	tests := []struct {
+		policy   TrailingSlashPolicy
+		method   string
+		url      string
+		code     int
+		location string
+		body     string
+	}{
+		{TrailingSlashTolerant, "GET", "/users/", 200, "", ""},
+		{TrailingSlashTolerant, "POST", "/users/", 200, "", ""},
+		{TrailingSlashTolerant, "GET", "/users/1/", 200, "", "1"},
+		{TrailingSlashTolerant, "GET", "/groups", 200, "", ""},
+		{TrailingSlashStrict, "GET", "/users", 200, "", ""},
+		{TrailingSlashStrict, "GET", "/users/", 404, "", ""},
+		{TrailingSlashStrict, "POST", "/users/", 404, "", ""},
+		{TrailingSlashStrict, "GET", "/users/1", 200, "", "1"},
+		{TrailingSlashStrict, "GET", "/users/1/", 404, "", ""},
+		{TrailingSlashStrict, "GET", "/groups/", 200, "", ""},
+		{TrailingSlashStrict, "GET", "/groups", 404, "", ""},
+		{TrailingSlashStrict, "GET", "/files/a/b/", 200, "", "a/b|a/b"},
+		{TrailingSlashRedirect, "GET", "/users", 200, "", ""},
+		{TrailingSlashRedirect, "GET", "/users/?q=1", 301, "/users?q=1", ""},
+		{TrailingSlashRedirect, "POST", "/users/", 308, "/users", ""},
+		{TrailingSlashRedirect, "GET", "/users/1/", 301, "/users/1", ""},
+		{TrailingSlashRedirect, "POST", "/users/1/", 308, "/users/1", ""},
+		{TrailingSlashRedirect, "GET", "/users/a%20b/", 301, "/users/a%20b", ""},
+		{TrailingSlashRedirect, "GET", "/groups?q=1", 301, "/groups/?q=1", ""},
+		{TrailingSlashRedirect, "GET", "/files/a/b/", 200, "", "a/b|a/b"},
+	}
+	for _, each := range tests {
+		c := NewContainer()
+		c.TrailingSlash(each.policy)
+		ws := new(WebService).Path("/users")
+		ws.Route(ws.GET("").Handler(writeUserId))
+		ws.Route(ws.POST("").Handler(writeUserId))
+		ws.Route(ws.GET("/{id}").Handler(writeUserId))
+		ws.Route(ws.POST("/{id}").Handler(writeUserId))
+		c.Add(ws)
+		groups := new(WebService).Path("/groups")
+		groups.Route(groups.GET("/").Handler(writeUserId))
+		c.Add(groups)
+		files := new(WebService).Path("/files")
+		files.Route(files.GET("/{path:*}").Handler(writeFilePath))
+		c.Add(files)
+
+		httpRequest, _ := http.NewRequest(each.method, each.url, nil)
+		httpWriter := httptest.NewRecorder()
+		c.ServeHTTP(httpWriter, httpRequest)
+		if got, want := httpWriter.Code, each.code; got != want {
+			t.Errorf("%v %s %s: got %v want %v", each.policy, each.method, each.url, got, want)
+		}
+		if got, want := httpWriter.Header().Get(HEADER_Location), each.location; got != want {
+			t.Errorf("%v %s %s: got location %q want %q", each.policy, each.method, each.url, got, want)
+		}
+		if each.code == 200 {
+			if got, want := httpWriter.Body.String(), each.body; got != want {
+				t.Errorf("%v %s %s: got body %q want %q", each.policy, each.method, each.url, got, want)
+			}
+		}
+	}
+}
+
+func TestContainer_TrailingSlashRedirectCustomHandler(t *testing.T) {
+	handled := &serviceErrorRecorder{}
+	c := NewContainer()
+	c.TrailingSlash(TrailingSlashRedirect)
+	c.ServiceErrorHandler(handled.write)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Handler(writeUserId))
+	c.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/users/", nil)
+	httpWriter := httptest.NewRecorder()
+	c.ServeHTTP(httpWriter, httpRequest)
+	if httpWriter.Code != http.StatusMovedPermanently || handled.err.Code != http.StatusMovedPermanently {
+		t.Errorf("got status %d and error %v want 301", httpWriter.Code, handled.err)
+	}
+	if got := httpWriter.Header().Get(HEADER_Location); got != "/users" {
+		t.Errorf("got location %q want /users", got)
+	}
+}
+
+func headerVersion(httpRequest *http.Request) string {
+	return httpRequest.Header.Get("X-API-Version")
+}
+
+func newVersionedContainer() *Container {
+	wc := NewContainer()
+	ws := new(WebService).Path("/users").ApiVersion("v1")
+	ws.Route(ws.GET("").Version("v1").Handler(foo))
+	ws.Route(ws.GET("").Version("v2").Handler(bar))
+	wc.Add(ws)
+	return wc
+}
+
+func TestVersionResolver(t *testing.T) {
+	wc := newVersionedContainer()
+	wc.VersionResolver(headerVersion)
+	for version, want := range map[string]string{"v1": "foo", "v2": "bar", "": "foo", "v9": "foo"} {
+		req := httptest.NewRequest("GET", "/users", nil)
+		req.Header.Set("X-API-Version", version)
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, req)
+		if got := recorder.Body.String(); got != want {
+			t.Errorf("version %q: got %q want %q", version, got, want)
+		}
+	}
+}
+
+func TestVersionWithoutResolver(t *testing.T) {
+	wc := newVersionedContainer()
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("X-API-Version", "v2")
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, req)
+	if got := recorder.Body.String(); got != "foo" {
+		t.Errorf("got %q want the default version", got)
+	}
+}
+
+func TestContainerDefaultProducesConsumes(t *testing.T) {
+	wc := NewContainer()
+	wc.DefaultProduces(MIME_JSON)
+	wc.DefaultConsumes(MIME_JSON)
+
+	inherited := new(WebService).Path("/inherited")
+	inherited.Route(inherited.POST("").Handler(dummy))
+	inherited.Route(inherited.POST("/xml").Produces(MIME_XML).Handler(dummy))
+	own := new(WebService).Path("/own").Produces(MIME_XML).Consumes(MIME_XML)
+	own.Route(own.POST("").Handler(dummy))
+	for _, each := range []*WebService{inherited, own} {
+		if err := wc.Add(each); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Routes added after the WebService was added inherit as well
+	inherited.Route(inherited.PUT("").Handler(dummy))
+
+	for i, each := range []struct {
+		route    Route
+		produces string
+		consumes string
+	}{
+		{inherited.Routes()[0], MIME_JSON, MIME_JSON}, // container
+		{inherited.Routes()[1], MIME_XML, MIME_JSON},  // route
+		{inherited.Routes()[2], MIME_JSON, MIME_JSON}, // container, added later
+		{own.Routes()[0], MIME_XML, MIME_XML},         // webservice
+	} {
+		if got := each.route.Produces; len(got) != 1 || got[0] != each.produces {
+			t.Errorf("%d: got produces %v want %s", i, got, each.produces)
+		}
+		if got := each.route.Consumes; len(got) != 1 || got[0] != each.consumes {
+			t.Errorf("%d: got consumes %v want %s", i, got, each.consumes)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/inherited", strings.NewReader("{}"))
+	req.Header.Set(HEADER_ContentType, MIME_XML)
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("got status %d want 415", recorder.Code)
+	}
+}
+
+func TestContainerAddWhileServing(t *testing.T) {
+	wc := NewContainer()
+	wc.EnableAutoHEAD(true)
+	wc.EnableRouteTracing(true)
+	stable := new(WebService).Path("/stable")
+	stable.Route(stable.GET("").Handler(dummy))
+	wc.Add(stable)
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+	failures := make(chan string, 100)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				method := []string{"GET", "HEAD"}[j%2]
+				for _, path := range []string{"/stable", fmt.Sprintf("/plugin%d", j%50)} {
+					httpRequest, _ := http.NewRequest(method, path, nil)
+					httpWriter := httptest.NewRecorder()
+					wc.ServeHTTP(httpWriter, httpRequest)
+					code := httpWriter.Code
+					if code != 200 && (path == "/stable" || code != 404) {
+						select {
+						case failures <- fmt.Sprintf("%s %s: unexpected status %d", method, path, code):
+						default:
+						}
+					}
+				}
+				wc.RegisteredWebServices()
+			}
+		}(i)
+	}
+	for i := 0; i < 50; i++ {
+		plugin := new(WebService).Path(fmt.Sprintf("/plugin%d", i))
+		plugin.Route(plugin.GET("").Handler(dummy))
+		if err := wc.Add(plugin); err != nil {
+			t.Fatal(err)
+		}
+		if i%5 == 0 {
+			if err := wc.Remove(plugin); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+	close(failures)
+	for each := range failures {
+		t.Error(each)
+	}
+	if got, want := len(wc.RegisteredWebServices()), 41; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestRegisteredWebServicesSnapshot(t *testing.T) {
+	wc := NewContainer()
+	ws1 := new(WebService).Path("/one")
+	ws1.Route(ws1.GET("").Handler(dummy))
+	wc.Add(ws1)
+	snapshot := wc.RegisteredWebServices()
+	ws2 := new(WebService).Path("/two")
+	ws2.Route(ws2.GET("").Handler(dummy))
+	wc.Add(ws2)
+	wc.Remove(ws1)
+	if len(snapshot) != 1 || snapshot[0] != ws1 {
+		t.Errorf("got %v want [%v]", snapshot, ws1)
+	}
+}
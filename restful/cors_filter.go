@@ -5,6 +5,7 @@ package restful
 // that can be found in the LICENSE file.
 
 import (
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,20 +19,123 @@ import (
 // http://enable-cors.org/server.html
 // http://www.html5rocks.com/en/tutorials/cors/#toc-handling-a-not-so-simple-request
 type CrossOriginResourceSharing struct {
-	ExposeHeaders  []string // list of Header names
-	AllowedHeaders []string // list of Header names
-	AllowedDomains []string // list of allowed values for Http Origin. An allowed value can be a regular expression to support subdomain matching. If empty all are allowed.
-	AllowedMethods []string
-	MaxAge         int // number of seconds before requiring new Options request
-	CookiesAllowed bool
+	ExposeHeaders  []string // list of Header names the script may read from an actual response, e.g. X-Total-Count ; the CORS-safelisted ones are readable anyway
+	AllowedHeaders []string // list of Header names a preflight request may ask for ; "*" allows all. If empty none are allowed.
+	// list of allowed values for Http Origin. An allowed value can be a "*" subdomain wildcard, e.g. https://*.example.com,
+	// or a regular expression to support subdomain matching. If empty or "*" all are allowed.
+	AllowedDomains []string
+	AllowedMethods []string // list of methods a preflight request may ask for. If empty those of the Routes of the path are allowed.
+	MaxAge         int      // number of seconds a preflight response can be cached before requiring new Options request ; 0 omits the header
+	CookiesAllowed bool     // the allowed origin is always echoed, never "*" that browsers reject with credentials
 	Container      *Container
+	// AllowedOriginFunc, if set, decides whether the Http Origin of a request, or a preflight request, is allowed
+	// instead of AllowedDomains, e.g. by looking it up in a database ; caching the decisions is up to the function.
+	// An allowed origin is echoed in the Access-Control-Allow-Origin header.
+	AllowedOriginFunc func(origin string, req *Request) bool
 
 	allowedOriginPatterns []*regexp.Regexp // internal field for origin regexp check.
 }
 
+// CORSOptions configures the FilterFunction created by CORS.
+type CORSOptions struct {
+	// AllowedOrigins lists the allowed values for the Http Origin header.
+	// "*" allows any origin ; a value can have a "*" subdomain wildcard, e.g. "https://*.example.com".
+	// If empty all origins are allowed.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods a preflight request may ask for.
+	// If empty then GET, HEAD, POST, PUT, PATCH and DELETE are allowed.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers a preflight request may ask for ; "*" allows all.
+	// If empty none are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers that the browser may expose to the script.
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and authorization headers to be sent.
+	AllowCredentials bool
+	// MaxAge is the number of seconds a preflight response can be cached. Zero means not specified.
+	MaxAge int
+	// Container has the Routes with a CORS policy of their own, see RouteBuilder.CORS ; default is the DefaultContainer.
+	Container *Container
+}
+
+var defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// CORS returns the Filter of a CrossOriginResourceSharing configured by the options.
+// A preflight request is answered with 204 No Content ; the chain is not continued.
+// Install it as a Container filter so that preflight requests for paths without an OPTIONS Route are handled too.
+func CORS(opts CORSOptions) FilterFunction {
+	cors := CrossOriginResourceSharing{
+		ExposeHeaders:  opts.ExposedHeaders,
+		AllowedHeaders: opts.AllowedHeaders,
+		AllowedMethods: opts.AllowedMethods,
+		MaxAge:         opts.MaxAge,
+		CookiesAllowed: opts.AllowCredentials,
+		Container:      opts.Container,
+	}
+	if len(cors.AllowedMethods) == 0 {
+		cors.AllowedMethods = defaultCORSMethods
+	}
+	for _, each := range opts.AllowedOrigins {
+		if each != "*" && !isSubdomainWildcard(each) {
+			// an origin, not a regular expression
+			each = "^" + regexp.QuoteMeta(each) + "$"
+		}
+		cors.AllowedDomains = append(cors.AllowedDomains, each)
+	}
+	return cors.Filter
+}
+
 // Filter is a filter function that implements the CORS flow as documented on http://enable-cors.org/server.html
 // and http://www.html5rocks.com/static/images/cors_server_flowchart.png
+// If the Route of the request, or of the method of a preflight request, has a CORS policy then that one is used instead, see RouteBuilder.CORS.
+// The responses to requests with an Origin vary by Origin.
 func (c CrossOriginResourceSharing) Filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	if len(req.Request.Header.Get(HEADER_Origin)) > 0 {
+		if policy, ok := c.routePolicy(req); ok {
+			policy.filter(req, resp, next)
+			return
+		}
+	}
+	c.filter(req, resp, next)
+}
+
+// routePolicy returns the CORS policy of the Route the request, or the preflight request, is for, if any.
+// The Route of an actual request is the selected one ; that of a preflight request is the first one
+// with a matching path and the requested method.
+func (c CrossOriginResourceSharing) routePolicy(req *Request) (CrossOriginResourceSharing, bool) {
+	acrm := req.Request.Header.Get(HEADER_AccessControlRequestMethod)
+	if req.Request.Method != "OPTIONS" || acrm == "" {
+		return c.policyOf(req.routeMetadata)
+	}
+	for _, each := range c.container().routesMatchingPath(req.Request.URL.Path) {
+		if each.Method == acrm {
+			return c.policyOf(each.Metadata)
+		}
+	}
+	return c, false
+}
+
+// policyOf returns the CORS policy of the Route Metadata, if any, using the Container of this one by default.
+func (c CrossOriginResourceSharing) policyOf(metadata map[string]interface{}) (CrossOriginResourceSharing, bool) {
+	policy, ok := metadata[KeyCORS].(*CrossOriginResourceSharing)
+	if !ok {
+		return c, false
+	}
+	routePolicy := *policy
+	if routePolicy.Container == nil {
+		routePolicy.Container = c.container()
+	}
+	return routePolicy, true
+}
+
+func (c CrossOriginResourceSharing) container() *Container {
+	if c.Container == nil {
+		return DefaultContainer
+	}
+	return c.Container
+}
+
+func (c CrossOriginResourceSharing) filter(req *Request, resp *Response, next func(*Request, *Response)) {
 	origin := req.Request.Header.Get(HEADER_Origin)
 	if len(origin) == 0 {
 		if trace {
@@ -40,7 +144,9 @@ func (c CrossOriginResourceSharing) Filter(req *Request, resp *Response, next fu
 		next(req, resp)
 		return
 	}
-	if !c.isOriginAllowed(origin) { // check whether this origin is allowed
+	// the response depends on the origin, which is echoed if allowed, such that caches must not share it between origins
+	resp.Header().Add(HEADER_Vary, HEADER_Origin)
+	if !c.allowsOrigin(origin, req) { // check whether this origin is allowed
 		if trace {
 			traceLogger.Printf("HTTP Origin:%s is not part of %v, neither matches any part of %v", origin, c.AllowedDomains, c.allowedOriginPatterns)
 		}
@@ -53,7 +159,9 @@ func (c CrossOriginResourceSharing) Filter(req *Request, resp *Response, next fu
 		return
 	}
 	if acrm := req.Request.Header.Get(HEADER_AccessControlRequestMethod); acrm != "" {
+		// the chain is not continued, also for a path without an OPTIONS Route
 		c.doPreflightRequest(req, resp)
+		resp.WriteHeader(http.StatusNoContent)
 	} else {
 		c.doActualRequest(req, resp)
 		next(req, resp)
@@ -63,16 +171,15 @@ func (c CrossOriginResourceSharing) Filter(req *Request, resp *Response, next fu
 
 func (c CrossOriginResourceSharing) doActualRequest(req *Request, resp *Response) {
 	c.setOptionsHeaders(req, resp)
+	c.checkAndSetExposeHeaders(resp)
 	// continue processing the response
 }
 
 func (c *CrossOriginResourceSharing) doPreflightRequest(req *Request, resp *Response) {
+	resp.Header().Add(HEADER_Vary, HEADER_AccessControlRequestMethod)
+	resp.Header().Add(HEADER_Vary, HEADER_AccessControlRequestHeaders)
 	if len(c.AllowedMethods) == 0 {
-		if c.Container == nil {
-			c.AllowedMethods = DefaultContainer.computeAllowedMethods(req)
-		} else {
-			c.AllowedMethods = c.Container.computeAllowedMethods(req)
-		}
+		c.AllowedMethods = c.container().computeAllowedMethods(req)
 	}
 
 	acrm := req.Request.Header.Get(HEADER_AccessControlRequestMethod)
@@ -100,19 +207,26 @@ func (c *CrossOriginResourceSharing) doPreflightRequest(req *Request, resp *Resp
 		}
 	}
 	resp.AddHeader(HEADER_AccessControlAllowMethods, strings.Join(c.AllowedMethods, ","))
-	resp.AddHeader(HEADER_AccessControlAllowHeaders, acrhs)
+	if len(acrhs) > 0 {
+		resp.AddHeader(HEADER_AccessControlAllowHeaders, acrhs)
+	}
 	c.setOptionsHeaders(req, resp)
-
-	// return http 200 response, no body
+	if c.MaxAge > 0 {
+		resp.AddHeader(HEADER_AccessControlMaxAge, strconv.Itoa(c.MaxAge))
+	}
 }
 
 func (c CrossOriginResourceSharing) setOptionsHeaders(req *Request, resp *Response) {
-	c.checkAndSetExposeHeaders(resp)
 	c.setAllowOriginHeader(req, resp)
 	c.checkAndSetAllowCredentials(resp)
-	if c.MaxAge > 0 {
-		resp.AddHeader(HEADER_AccessControlMaxAge, strconv.Itoa(c.MaxAge))
+}
+
+// allowsOrigin returns whether the origin of the request is allowed, by the AllowedOriginFunc if set.
+func (c CrossOriginResourceSharing) allowsOrigin(origin string, req *Request) bool {
+	if c.AllowedOriginFunc != nil {
+		return c.AllowedOriginFunc(origin, req)
 	}
+	return c.isOriginAllowed(origin)
 }
 
 func (c CrossOriginResourceSharing) isOriginAllowed(origin string) bool {
@@ -124,17 +238,19 @@ func (c CrossOriginResourceSharing) isOriginAllowed(origin string) bool {
 	}
 
 	allowed := false
+	domains := []string{}
 	for _, domain := range c.AllowedDomains {
-		if domain == origin {
+		if domain == origin || domain == "*" {
 			allowed = true
 			break
 		}
+		domains = append(domains, domain)
 	}
 
 	if !allowed {
 		if len(c.allowedOriginPatterns) == 0 {
 			// compile allowed domains to allowed origin patterns
-			allowedOriginRegexps, err := compileRegexps(c.AllowedDomains)
+			allowedOriginRegexps, err := compileRegexps(domains)
 			if err != nil {
 				return false
 			}
@@ -151,16 +267,15 @@ func (c CrossOriginResourceSharing) isOriginAllowed(origin string) bool {
 	return allowed
 }
 
+// setAllowOriginHeader echoes the origin of the request ; the filter has checked it is allowed.
 func (c CrossOriginResourceSharing) setAllowOriginHeader(req *Request, resp *Response) {
-	origin := req.Request.Header.Get(HEADER_Origin)
-	if c.isOriginAllowed(origin) {
-		resp.AddHeader(HEADER_AccessControlAllowOrigin, origin)
-	}
+	resp.AddHeader(HEADER_AccessControlAllowOrigin, req.Request.Header.Get(HEADER_Origin))
 }
 
+// checkAndSetExposeHeaders sets the headers of an actual response the script may read ; not of a preflight response.
 func (c CrossOriginResourceSharing) checkAndSetExposeHeaders(resp *Response) {
-	if len(c.ExposeHeaders) > 0 {
-		resp.AddHeader(HEADER_AccessControlExposeHeaders, strings.Join(c.ExposeHeaders, ","))
+	if exposed := exposedHeaders(c.ExposeHeaders); len(exposed) > 0 {
+		resp.AddHeader(HEADER_AccessControlExposeHeaders, exposed)
 	}
 }
 
@@ -180,18 +295,18 @@ func (c CrossOriginResourceSharing) isValidAccessControlRequestMethod(method str
 }
 
 func (c CrossOriginResourceSharing) isValidAccessControlRequestHeader(header string) bool {
-	for _, each := range c.AllowedHeaders {
-		if strings.ToLower(each) == strings.ToLower(header) {
-			return true
-		}
-	}
-	return false
+	return containsString(c.AllowedHeaders, "*") || containsStringFold(c.AllowedHeaders, header)
 }
 
-// Take a list of strings and compile them into a list of regular expressions.
+// Take a list of strings and compile them into a list of regular expressions ; a "*" subdomain wildcard matches
+// one label of a host, as in host patterns, e.g. https://*.example.com matches https://api.example.com
+// but not https://a.b.example.com.
 func compileRegexps(regexpStrings []string) ([]*regexp.Regexp, error) {
 	regexps := []*regexp.Regexp{}
 	for _, regexpStr := range regexpStrings {
+		if isSubdomainWildcard(regexpStr) {
+			regexpStr = "^" + strings.Replace(regexp.QuoteMeta(regexpStr), `\*`, `[^./]+`, -1) + "$"
+		}
 		r, err := regexp.Compile(regexpStr)
 		if err != nil {
 			return regexps, err
@@ -200,3 +315,72 @@ func compileRegexps(regexpStrings []string) ([]*regexp.Regexp, error) {
 	}
 	return regexps, nil
 }
+
+// isSubdomainWildcard returns whether the allowed domain has a "*" subdomain wildcard, e.g. https://*.example.com,
+// rather than being a regular expression.
+func isSubdomainWildcard(domain string) bool {
+	return strings.Contains(domain, "://*.")
+}
+
+// IsPreflight returns whether the request is a CORS preflight request, i.e. OPTIONS with the Origin and
+// Access-Control-Request-Method headers.
+func IsPreflight(req *Request) bool {
+	return req.Request.Method == "OPTIONS" &&
+		len(req.Request.Header.Get(HEADER_Origin)) > 0 &&
+		len(req.Request.Header.Get(HEADER_AccessControlRequestMethod)) > 0
+}
+
+// SkipOnPreflight returns a FilterFunction that calls the filter, except for CORS preflight requests that continue
+// the chain without it, see IsPreflight. Browsers send no credentials with a preflight request, so wrap the filters
+// that would reject it, e.g. authentication and rate limiting, if they run before the CORS filter answers it.
+// OPTIONS requests that are not preflight requests are passed to the filter.
+//
+//	restful.Filter(restful.SkipOnPreflight(auth.Filter))
+//	restful.Filter(restful.CrossOriginResourceSharing{AllowedDomains: []string{"https://app.example.com"}}.Filter)
+func SkipOnPreflight(filter FilterFunction) FilterFunction {
+	return preflightSkipper(filter).filter
+}
+
+type preflightSkipper FilterFunction
+
+func (f preflightSkipper) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	if IsPreflight(req) {
+		next(req, resp)
+		return
+	}
+	f(req, resp, next)
+}
+
+// safelistedResponseHeaders are the response headers a script can always read, see
+// https://fetch.spec.whatwg.org/#cors-safelisted-response-header-name
+var safelistedResponseHeaders = []string{"Cache-Control", "Content-Language", "Content-Length", "Content-Type", "Expires", "Last-Modified", "Pragma"}
+
+// exposedHeaders returns the value of the Access-Control-Expose-Headers header for the headers, without
+// the safelisted ones and duplicates ; empty if none.
+func exposedHeaders(headers []string) string {
+	exposed := []string{}
+	for _, each := range headers {
+		if !containsStringFold(safelistedResponseHeaders, each) && !containsStringFold(exposed, each) {
+			exposed = append(exposed, each)
+		}
+	}
+	return strings.Join(exposed, ",")
+}
+
+func containsString(list []string, value string) bool {
+	for _, each := range list {
+		if each == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStringFold(list []string, value string) bool {
+	for _, each := range list {
+		if strings.EqualFold(each, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,8 @@ package restful
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -125,5 +127,348 @@ func TestCORSFilter_AllowedDomains(t *testing.T) {
 		if actual == each.origin && !each.allowed {
 			t.Fatal("did not expect to be accepted")
 		}
+		if got := httpWriter.Header()[HEADER_Vary]; len(got) != 1 || got[0] != HEADER_Origin {
+			t.Errorf("%s: got Vary %v want Origin", each.origin, got)
+		}
+	}
+}
+
+func TestCORSFilter_RoutePolicy(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/api")
+	ws.Route(ws.GET("/widget").Handler(dummy).CORS(CrossOriginResourceSharing{AllowedHeaders: []string{"X-Widget"}}))
+	ws.Route(ws.PUT("/users").Handler(dummy))
+	// an actual request uses the policy of the selected Route, not of the first one matching the path
+	ws.Route(ws.GET("/widget/{id}").Handler(dummy))
+	ws.Route(ws.GET("/widget/special").Handler(dummy).CORS(CrossOriginResourceSharing{}))
+	wc.Add(ws)
+	cors := CrossOriginResourceSharing{AllowedDomains: []string{"http://app.example.com"}, AllowedHeaders: []string{"X-Custom-Header"}, Container: wc}
+	wc.Filter(cors.Filter)
+
+	for _, each := range []struct {
+		method, path, requestMethod, requestHeaders, origin string
+		allowOrigin, allowMethods                           string
+	}{
+		// preflight
+		{"OPTIONS", "/api/widget", "GET", "X-Widget", "http://anywhere.com", "http://anywhere.com", "GET"},
+		{"OPTIONS", "/api/users", "PUT", "X-Custom-Header", "http://anywhere.com", "", ""},
+		{"OPTIONS", "/api/users", "PUT", "X-Custom-Header", "http://app.example.com", "http://app.example.com", "PUT"},
+		{"OPTIONS", "/api/users", "PUT", "X-Widget", "http://app.example.com", "", ""},
+		// simple
+		{"GET", "/api/widget", "", "", "http://anywhere.com", "http://anywhere.com", ""},
+		{"PUT", "/api/users", "", "", "http://anywhere.com", "", ""},
+		{"PUT", "/api/users", "", "", "http://app.example.com", "http://app.example.com", ""},
+		{"GET", "/api/widget/special", "", "", "http://anywhere.com", "http://anywhere.com", ""},
+		{"GET", "/api/widget/7", "", "", "http://anywhere.com", "", ""},
+	} {
+		httpRequest := httptest.NewRequest(each.method, each.path, nil)
+		httpRequest.Header.Set(HEADER_Origin, each.origin)
+		if len(each.requestMethod) > 0 {
+			httpRequest.Header.Set(HEADER_AccessControlRequestMethod, each.requestMethod)
+			httpRequest.Header.Set(HEADER_AccessControlRequestHeaders, each.requestHeaders)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowOrigin); got != each.allowOrigin {
+			t.Errorf("%s %s from %s: got allowed origin %q want %q", each.method, each.path, each.origin, got, each.allowOrigin)
+		}
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowMethods); got != each.allowMethods {
+			t.Errorf("%s %s from %s: got allowed methods %q want %q", each.method, each.path, each.origin, got, each.allowMethods)
+		}
+	}
+}
+
+// allowCustomerOrigin allows the origins of the customer domains, as if looked up in a database
+func allowCustomerOrigin(origin string, req *Request) bool {
+	return origin == "https://shop.customer.com" && req.Request.URL.Path == "/api/users"
+}
+
+func TestCORSFilter_AllowedOriginFunc(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/api")
+	ws.Route(ws.PUT("/users").Handler(dummy))
+	wc.Add(ws)
+	// the function takes precedence over the domains
+	cors := CrossOriginResourceSharing{AllowedDomains: []string{"http://app.example.com"}, AllowedOriginFunc: allowCustomerOrigin, Container: wc}
+	wc.Filter(cors.Filter)
+
+	for _, each := range []struct {
+		method, requestMethod, origin string
+		allowOrigin, allowMethods     string
+	}{
+		// preflight
+		{"OPTIONS", "PUT", "https://shop.customer.com", "https://shop.customer.com", "PUT"},
+		{"OPTIONS", "PUT", "https://evil.com", "", ""},
+		{"OPTIONS", "PUT", "http://app.example.com", "", ""},
+		// actual
+		{"PUT", "", "https://shop.customer.com", "https://shop.customer.com", ""},
+		{"PUT", "", "https://evil.com", "", ""},
+		{"PUT", "", "http://app.example.com", "", ""},
+	} {
+		httpRequest := httptest.NewRequest(each.method, "/api/users", nil)
+		httpRequest.Header.Set(HEADER_Origin, each.origin)
+		if len(each.requestMethod) > 0 {
+			httpRequest.Header.Set(HEADER_AccessControlRequestMethod, each.requestMethod)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowOrigin); got != each.allowOrigin {
+			t.Errorf("%s from %s: got allowed origin %q want %q", each.method, each.origin, got, each.allowOrigin)
+		}
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowMethods); got != each.allowMethods {
+			t.Errorf("%s from %s: got allowed methods %q want %q", each.method, each.origin, got, each.allowMethods)
+		}
+		// once, also on a preflight response that varies by the requested method and headers too
+		if got := httpWriter.Header()[HEADER_Vary]; len(got) == 0 || got[0] != HEADER_Origin || containsString(got[1:], HEADER_Origin) {
+			t.Errorf("%s from %s: got Vary %v want Origin", each.method, each.origin, got)
+		}
+	}
+}
+
+// corsHeaders returns the Access-Control-* headers of the response
+func corsHeaders(header http.Header) map[string]string {
+	headers := map[string]string{}
+	for name, values := range header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			headers[name] = strings.Join(values, ";")
+		}
+	}
+	return headers
+}
+
+func TestCORSFilter_MaxAgeAndCredentials(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService)
+	ws.Route(ws.PUT("/cors").Handler(dummy))
+	wc.Add(ws)
+	wc.Filter(CrossOriginResourceSharing{AllowedDomains: []string{"*"}, CookiesAllowed: true, MaxAge: 600, Container: wc}.Filter)
+
+	// the origin is echoed instead of "*" that browsers reject with credentials
+	httpRequest, _ := http.NewRequest("OPTIONS", "http://api.alice.com/cors", nil)
+	httpRequest.Header.Set(HEADER_Origin, "http://api.bob.com")
+	httpRequest.Header.Set(HEADER_AccessControlRequestMethod, "PUT")
+	httpWriter := httptest.NewRecorder()
+	wc.Dispatch(httpWriter, httpRequest)
+	want := map[string]string{
+		HEADER_AccessControlAllowOrigin:      "http://api.bob.com",
+		HEADER_AccessControlAllowMethods:     "PUT",
+		HEADER_AccessControlAllowCredentials: "true",
+		HEADER_AccessControlMaxAge:           "600",
+	}
+	if got := corsHeaders(httpWriter.Header()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got preflight headers %v want %v", got, want)
+	}
+	if httpWriter.Code != http.StatusNoContent {
+		t.Errorf("got preflight status %d want 204", httpWriter.Code)
+	}
+
+	// the max age has no meaning for an actual request
+	httpRequest, _ = http.NewRequest("PUT", "http://api.alice.com/cors", nil)
+	httpRequest.Header.Set(HEADER_Origin, "http://api.bob.com")
+	httpWriter = httptest.NewRecorder()
+	wc.Dispatch(httpWriter, httpRequest)
+	want = map[string]string{
+		HEADER_AccessControlAllowOrigin:      "http://api.bob.com",
+		HEADER_AccessControlAllowCredentials: "true",
+	}
+	if got := corsHeaders(httpWriter.Header()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got actual headers %v want %v", got, want)
+	}
+}
+
+func TestCORSFilter_SubdomainWildcard(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService)
+	ws.Route(ws.PUT("/cors").Handler(dummy))
+	wc.Add(ws)
+	wc.Filter(CrossOriginResourceSharing{AllowedDomains: []string{"https://*.bob.com"}, AllowedMethods: []string{"GET", "PUT"}, AllowedHeaders: []string{"*"}, Container: wc}.Filter)
+
+	for _, each := range []struct {
+		origin, allowOrigin string
+	}{
+		{"https://api.bob.com", "https://api.bob.com"},
+		{"https://bob.com", ""},
+		{"https://.bob.com", ""},
+		{"https://a.b.bob.com", ""},
+		{"https://api.bob.com.evil.com", ""},
+		{"http://api.bob.com", ""},
+	} {
+		httpRequest, _ := http.NewRequest("OPTIONS", "http://api.alice.com/cors", nil)
+		httpRequest.Header.Set(HEADER_Origin, each.origin)
+		httpRequest.Header.Set(HEADER_AccessControlRequestMethod, "PUT")
+		httpRequest.Header.Set(HEADER_AccessControlRequestHeaders, "x-custom-header")
+		httpWriter := httptest.NewRecorder()
+		wc.Dispatch(httpWriter, httpRequest)
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowOrigin); got != each.allowOrigin {
+			t.Errorf("%s: got allowed origin %q want %q", each.origin, got, each.allowOrigin)
+		}
+		if len(each.allowOrigin) == 0 {
+			continue
+		}
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowHeaders); got != "x-custom-header" {
+			t.Errorf("%s: got allowed headers %q want x-custom-header", each.origin, got)
+		}
+		if got := httpWriter.Header()[HEADER_Vary]; !reflect.DeepEqual(got, []string{HEADER_Origin, HEADER_AccessControlRequestMethod, HEADER_AccessControlRequestHeaders}) {
+			t.Errorf("%s: got Vary %v", each.origin, got)
+		}
+	}
+}
+
+func TestSkipOnPreflight(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService)
+	ws.Route(ws.PUT("/cors").Handler(dummy))
+	wc.Add(ws)
+	wc.Filter(SkipOnPreflight(NewBasicAuthFilter("cors", BasicAuthCredentials("admin", "admin"))))
+	wc.Filter(CrossOriginResourceSharing{AllowedDomains: []string{"https://api.bob.com"}, Container: wc}.Filter)
+
+	for _, each := range []struct {
+		method, acrm string
+		code         int
+	}{
+		{"OPTIONS", "PUT", http.StatusNoContent},
+		// not a preflight request
+		{"OPTIONS", "", http.StatusUnauthorized},
+		{"PUT", "", http.StatusUnauthorized},
+	} {
+		httpRequest, _ := http.NewRequest(each.method, "http://api.alice.com/cors", nil)
+		httpRequest.Header.Set(HEADER_Origin, "https://api.bob.com")
+		if len(each.acrm) > 0 {
+			httpRequest.Header.Set(HEADER_AccessControlRequestMethod, each.acrm)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.Dispatch(httpWriter, httpRequest)
+		if httpWriter.Code != each.code {
+			t.Errorf("%s %s: got code %d want %d", each.method, each.acrm, httpWriter.Code, each.code)
+		}
+	}
+}
+
+func TestCORSFilter_ExposeHeaders(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Handler(dummy))
+	wc.Add(ws)
+	cors := CrossOriginResourceSharing{ExposeHeaders: []string{"X-Total-Count", "Content-Type", "x-total-count", "Link"}, Container: wc}
+	wc.Filter(cors.Filter)
+
+	httpRequest := httptest.NewRequest("GET", "/users", nil)
+	httpRequest.Header.Set(HEADER_Origin, "http://api.bob.com")
+	httpWriter := httptest.NewRecorder()
+	wc.Dispatch(httpWriter, httpRequest)
+	// without the safelisted and duplicate headers
+	if got := httpWriter.Header()[HEADER_AccessControlExposeHeaders]; len(got) != 1 || got[0] != "X-Total-Count,Link" {
+		t.Errorf("got exposed headers %v want X-Total-Count,Link", got)
+	}
+
+	httpRequest = httptest.NewRequest("OPTIONS", "/users", nil)
+	httpRequest.Header.Set(HEADER_Origin, "http://api.bob.com")
+	httpRequest.Header.Set(HEADER_AccessControlRequestMethod, "GET")
+	httpWriter = httptest.NewRecorder()
+	wc.Dispatch(httpWriter, httpRequest)
+	if got := httpWriter.Header().Get(HEADER_AccessControlAllowOrigin); got != "http://api.bob.com" {
+		t.Errorf("got allowed origin %q want the preflight response", got)
+	}
+	if got, ok := httpWriter.Header()[HEADER_AccessControlExposeHeaders]; ok {
+		t.Errorf("got exposed headers %v on a preflight response", got)
+	}
+}
+
+func TestCORSFilter_AllowedHeaders(t *testing.T) {
+	for _, each := range []struct {
+		allowed     []string
+		allowOrigin string
+	}{
+		// without AllowedHeaders, no requested header is allowed
+		{nil, ""},
+		{[]string{"X-Custom-Header"}, ""},
+		{[]string{"X-Custom-Header", "Authorization"}, "https://api.bob.com"},
+		{[]string{"*"}, "https://api.bob.com"},
+	} {
+		wc := NewContainer()
+		ws := new(WebService)
+		ws.Route(ws.PUT("/cors").Handler(dummy))
+		wc.Add(ws)
+		wc.Filter(CrossOriginResourceSharing{AllowedHeaders: each.allowed, Container: wc}.Filter)
+
+		httpRequest, _ := http.NewRequest("OPTIONS", "http://api.alice.com/cors", nil)
+		httpRequest.Header.Set(HEADER_Origin, "https://api.bob.com")
+		httpRequest.Header.Set(HEADER_AccessControlRequestMethod, "PUT")
+		httpRequest.Header.Set(HEADER_AccessControlRequestHeaders, "x-custom-header, authorization")
+		httpWriter := httptest.NewRecorder()
+		wc.Dispatch(httpWriter, httpRequest)
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowOrigin); got != each.allowOrigin {
+			t.Errorf("%v: got allowed origin %q want %q", each.allowed, got, each.allowOrigin)
+		}
+	}
+}
+
+func newCORSContainer(opts CORSOptions) *Container {
+	wc := NewContainer()
+	ws := new(WebService)
+	ws.Route(ws.PUT("/cors").Handler(dummy))
+	wc.Add(ws)
+	opts.Container = wc
+	wc.Filter(CORS(opts))
+	return wc
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	wc := newCORSContainer(CORSOptions{
+		AllowedOrigins:   []string{"https://*.bob.com"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"X-Custom-Header"},
+		AllowCredentials: true,
+		MaxAge:           600})
+
+	httpRequest, _ := http.NewRequest("OPTIONS", "http://api.alice.com/cors", nil)
+	httpRequest.Header.Set(HEADER_Origin, "https://api.bob.com")
+	httpRequest.Header.Set(HEADER_AccessControlRequestMethod, "PUT")
+	httpRequest.Header.Set(HEADER_AccessControlRequestHeaders, "x-custom-header")
+	httpWriter := httptest.NewRecorder()
+	wc.Dispatch(httpWriter, httpRequest)
+
+	if httpWriter.Code != http.StatusNoContent {
+		t.Errorf("got code %d want 204", httpWriter.Code)
+	}
+	want := map[string]string{
+		HEADER_AccessControlAllowOrigin:      "https://api.bob.com",
+		HEADER_AccessControlAllowMethods:     "GET,PUT",
+		HEADER_AccessControlAllowHeaders:     "x-custom-header",
+		HEADER_AccessControlAllowCredentials: "true",
+		HEADER_AccessControlMaxAge:           "600",
+	}
+	if got := corsHeaders(httpWriter.Header()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got preflight headers %v want %v", got, want)
+	}
+}
+
+func TestCORS_Actual(t *testing.T) {
+	wc := newCORSContainer(CORSOptions{
+		AllowedOrigins: []string{"https://api.bob.com"},
+		ExposedHeaders: []string{"X-Custom-Header", "X-Total-Count"}})
+
+	for _, each := range []struct {
+		origin, allowOrigin, exposeHeaders string
+	}{
+		{"https://api.bob.com", "https://api.bob.com", "X-Custom-Header,X-Total-Count"},
+		// an allowed origin is not a regular expression
+		{"https://apixbob.com", "", ""},
+		{"https://api.bob.com.evil.com", "", ""},
+	} {
+		httpRequest, _ := http.NewRequest("PUT", "http://api.alice.com/cors", nil)
+		httpRequest.Header.Set(HEADER_Origin, each.origin)
+		httpWriter := httptest.NewRecorder()
+		wc.Dispatch(httpWriter, httpRequest)
+
+		if httpWriter.Code != http.StatusOK {
+			t.Errorf("%s: got code %d want 200", each.origin, httpWriter.Code)
+		}
+		if got := httpWriter.Header().Get(HEADER_AccessControlAllowOrigin); got != each.allowOrigin {
+			t.Errorf("%s: got allow origin %q want %q", each.origin, got, each.allowOrigin)
+		}
+		if got := httpWriter.Header().Get(HEADER_AccessControlExposeHeaders); got != each.exposeHeaders {
+			t.Errorf("%s: got expose headers %q want %q", each.origin, got, each.exposeHeaders)
+		}
 	}
 }
@@ -20,7 +20,7 @@ func (c CurlyRouter) SelectRoute(
 	webServices []*WebService,
 	httpRequest *http.Request) (selectedService *WebService, selected *Route, err error) {
 
-	requestTokens := tokenizePath(httpRequest.URL.Path)
+	requestTokens := requestPathTokens(httpRequest)
 
 	detectedService := c.detectWebService(requestTokens, webServices)
 	if detectedService == nil {
@@ -47,7 +47,7 @@ func (c CurlyRouter) SelectRoute(
 func (c CurlyRouter) selectRoutes(ws *WebService, requestTokens []string) sortableCurlyRoutes {
 	candidates := sortableCurlyRoutes{}
 	for _, each := range ws.routes {
-		matches, paramCount, staticCount := c.matchesRouteByPathTokens(each.pathParts, requestTokens)
+		matches, paramCount, staticCount := c.matchesRouteByPathTokens(each.pathParts, requestTokens, ws.caseInsensitivePaths)
 		if matches {
 			candidates.add(curlyRoute{each, paramCount, staticCount}) // TODO make sure Routes() return pointers?
 		}
@@ -57,7 +57,8 @@ func (c CurlyRouter) selectRoutes(ws *WebService, requestTokens []string) sortab
 }
 
 // matchesRouteByPathTokens computes whether it matches, howmany parameters do match and what the number of static path elements are.
-func (c CurlyRouter) matchesRouteByPathTokens(routeTokens, requestTokens []string) (matches bool, paramCount int, staticCount int) {
+// Static path elements are compared regardless of case if ignoreCase is true.
+func (c CurlyRouter) matchesRouteByPathTokens(routeTokens, requestTokens []string, ignoreCase bool) (matches bool, paramCount int, staticCount int) {
 	if len(routeTokens) < len(requestTokens) {
 		// proceed in matching only if last routeToken is wildcard
 		count := len(routeTokens)
@@ -85,7 +86,7 @@ func (c CurlyRouter) matchesRouteByPathTokens(routeTokens, requestTokens []strin
 				}
 			}
 		} else { // no { prefix
-			if requestToken != routeToken {
+			if !equalPathTokens(requestToken, routeToken, ignoreCase) {
 				return false, 0, 0
 			}
 			staticCount++
@@ -123,7 +124,7 @@ func (c CurlyRouter) detectWebService(requestTokens []string, webServices []*Web
 	var best *WebService
 	score := -1
 	for _, each := range webServices {
-		matches, eachScore := c.computeWebserviceScore(requestTokens, each.pathExpr.tokens)
+		matches, eachScore := c.computeWebserviceScore(requestTokens, each.pathExpr.tokens, each.caseInsensitivePaths)
 		if matches && (eachScore > score) {
 			best = each
 			score = eachScore
@@ -134,7 +135,8 @@ func (c CurlyRouter) detectWebService(requestTokens []string, webServices []*Web
 
 // computeWebserviceScore returns whether tokens match and
 // the weighted score of the longest matching consecutive tokens from the beginning.
-func (c CurlyRouter) computeWebserviceScore(requestTokens []string, tokens []string) (bool, int) {
+// Static path elements are compared regardless of case if ignoreCase is true.
+func (c CurlyRouter) computeWebserviceScore(requestTokens []string, tokens []string, ignoreCase bool) (bool, int) {
 	if len(tokens) > len(requestTokens) {
 		return false, 0
 	}
@@ -154,7 +156,7 @@ func (c CurlyRouter) computeWebserviceScore(requestTokens []string, tokens []str
 			score += 1
 		} else {
 			// not a parameter
-			if each != other {
+			if !equalPathTokens(each, other, ignoreCase) {
 				return false, score
 			}
 			score += (len(tokens) - i) * 10 //fuzzy
@@ -162,3 +164,11 @@ func (c CurlyRouter) computeWebserviceScore(requestTokens []string, tokens []str
 	}
 	return true, score
 }
+
+// equalPathTokens compares static path elements, optionally regardless of case.
+func equalPathTokens(requestToken, routeToken string, ignoreCase bool) bool {
+	if ignoreCase {
+		return strings.EqualFold(requestToken, routeToken)
+	}
+	return requestToken == routeToken
+}
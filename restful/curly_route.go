@@ -48,5 +48,9 @@ func (s sortableCurlyRoutes) Less(i, j int) bool {
 	if ci.paramCount > cj.paramCount {
 		return false
 	}
+	// a catch-all parameter is less specific than any other
+	if ci.route.hasCatchAll() != cj.route.hasCatchAll() {
+		return ci.route.hasCatchAll()
+	}
 	return ci.route.Path < cj.route.Path
 }
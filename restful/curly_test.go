@@ -3,6 +3,7 @@ package restful
 import (
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -80,7 +81,7 @@ func Test_detectWebService(t *testing.T) {
 		requestTokens := tokenizePath(requestPath)
 		for _, ws := range wss {
 			serviceTokens := ws.pathExpr.tokens
-			matches, score := router.computeWebserviceScore(requestTokens, serviceTokens)
+			matches, score := router.computeWebserviceScore(requestTokens, serviceTokens, false)
 			t.Logf("req=%s,toks:%v,ws=%s,toks:%v,score=%d,matches=%v", requestPath, requestTokens, ws.RootPath(), serviceTokens, score, matches)
 		}
 		best := router.detectWebService(requestTokens, wss)
@@ -121,7 +122,7 @@ func Test_matchesRouteByPathTokens(t *testing.T) {
 	for i, each := range routeMatchers {
 		routeToks := tokenizePath(each.route)
 		reqToks := tokenizePath(each.path)
-		matches, pCount, sCount := router.matchesRouteByPathTokens(routeToks, reqToks)
+		matches, pCount, sCount := router.matchesRouteByPathTokens(routeToks, reqToks, false)
 		if matches != each.matches {
 			t.Fatalf("[%d] unexpected matches outcome route:%s, path:%s, matches:%v", i, each.route, each.path, matches)
 		}
@@ -235,4 +236,56 @@ func TestCurly_ISSUE_137_2(t *testing.T) {
 	}
 }
 
+func TestCurly_CatchAllPrefersSpecificRoutes(t *testing.T) {
+	ws1 := new(WebService).Path("/files")
+	ws1.Route(ws1.GET("/{path:*}").Handler(curlyDummy))
+	ws1.Route(ws1.GET("/{name}").Handler(curlyDummy))
+	ws1.Route(ws1.GET("/readme").Handler(curlyDummy))
+	ws1.Route(ws1.GET("/meta/{id}").Handler(curlyDummy))
+	for path, want := range map[string]string{
+		"/files/readme":      "/files/readme",
+		"/files/meta/5":      "/files/meta/{id}",
+		"/files/a":           "/files/{name}",
+		"/files/a/b/c.txt":   "/files/{path:*}",
+		"/files/readme/more": "/files/{path:*}",
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+		_, route, err := CurlyRouter{}.SelectRoute([]*WebService{ws1}, req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", path, err)
+		}
+		if route.Path != want {
+			t.Errorf("%s: got %v want %v", path, route.Path, want)
+		}
+	}
+}
+
+func TestCatchAllRawPathParameter(t *testing.T) {
+	ws1 := new(WebService).Path("/files")
+	ws1.Route(ws1.GET("/{path:*}").Handler(writeFilePath))
+	wc := NewContainer()
+	wc.Add(ws1)
+	req, _ := http.NewRequest("GET", "/files/a%2Fb/c%20d.txt", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, req)
+	if got, want := httpWriter.Body.String(), "a/b/c d.txt|a%2Fb/c%20d.txt"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func writeFilePath(req *Request, resp *Response) {
+	io.WriteString(resp, req.pathParameters["path"]+"|"+req.RawPathParameter("path"))
+}
+
+func TestCatchAllMustBeLast(t *testing.T) {
+	for _, each := range []string{"/files/{path:*}/meta", "/files/{path:*}/meta/{id}"} {
+		if _, err := newPathExpression(each); err == nil {
+			t.Errorf("%s: expected error for catch-all parameter that is not last", each)
+		}
+	}
+	if _, err := newPathExpression("/files/{path:*}"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
 func curlyDummy(req *Request, resp *Response) { io.WriteString(resp.ResponseWriter, "curlyDummy") }
@@ -30,23 +30,105 @@ The (*Request, *Response) arguments provide functions for reading information fr
 
 See the example https://github.com/emicklei/go-restful/blob/master/examples/restful-user-resource.go with a full implementation.
 
+Mounting WebServices
+
+The Routes of a WebService can be mounted under a prefix of another WebService, e.g. to serve them under /api/v1.
+The filters, default MIME types and path parameters of the other WebService apply to the mounted Routes.
+
+	api := new(restful.WebService).Path("/api").Filter(authenticate)
+	api.Mount("/v1", users)
+
+Subroute mounts them relative to the root path of their WebService instead, e.g. a reusable comments WebService.
+
+	posts.Subroute("/{post-id}/comments", comments)
+
+Metadata
+
+The metadata of a WebService is added to the metadata of each of its Routes that does not set the same key,
+e.g. to tag all of them in the OpenAPI documentation.
+
+	ws.Metadata(restful.KeyOpenAPITags, []string{"users"})
+
+Host matching Routes
+
+A Route can be restricted to requests for a host using an exact name, a "*" wildcard or a regular expression starting with "^".
+A WebService can set the host for all its Routes. Requests for other hosts are matched with the other Routes of the path.
+
+	ws.Route(ws.GET("/status").Host("admin.example.com").Handler(adminStatus))
+	ws.Route(ws.GET("/status").Host("*.example.com").Handler(status))
+
+API versions
+
+Routes with the same path and method can serve different versions of the API. A VersionResolver of the Container
+returns the version a request asks for ; if no Route has that version then the one of the ApiVersion of its WebService is selected.
+
+	ws.ApiVersion("v1")
+	ws.Route(ws.GET("/{user-id}").Version("v1").Handler(findUserV1))
+	ws.Route(ws.GET("/{user-id}").Version("v2").Handler(findUserV2))
+	container.VersionResolver(func(r *http.Request) string { return r.Header.Get("X-API-Version") })
+
+Building URLs
+
+The URL of a Route is built from the values of its path parameters, which are validated against their expressions.
+Use RouteByOperation to find the Route, e.g. to add links to a response.
+
+	if route, ok := ws.RouteByOperation("findUser"); ok {
+		href, err := route.URL(map[string]string{"user-id": id}, nil)
+		...
+	}
+
 Regular expression matching Routes
 
 A Route parameter can be specified using the format "uri/{var[:regexp]}" or the special version "uri/{var:*}" for matching the tail of the path.
 For example, /persons/{name:[A-Z][A-Z]} can be used to restrict values for the parameter "name" to only contain capital alphabetic characters.
 Regular expressions must use the standard Go syntax as described in the regexp package. (https://code.google.com/p/re2/wiki/Syntax)
-This feature requires the use of a CurlyRouter.
+This feature requires the use of a CurlyRouter or a TrieRouter.
+The catch-all parameter "{var:*}" must be the last segment of the path ; other Routes that match the request are preferred over it.
+Its value as it appears in the escaped URL path is available using Request.RawPathParameter.
+
+Escaped paths
+
+Routes are matched with the segments of the escaped URL path and the values of path parameters are decoded once,
+e.g. /users/a%2Fb matches /users/{id} with id "a/b". A path with a segment that is not valid UTF-8 once decoded gets 400 Bad Request ;
+so does a path with an escaped slash if the Container rejects them.
+
+	container.EncodedSlashes(restful.EncodedSlashReject)
 
 Containers
 
 A Container holds a collection of WebServices, Filters and a http.ServeMux for multiplexing http requests.
 Using the statements "restful.Add(...) and restful.Filter(...)" will register WebServices and Filters to the Default Container.
 The Default container of go-restful uses the http.DefaultServeMux.
+Adding a WebService returns an error if it has a duplicate root path or an invalid path or Route, see WebService.Err ;
+use PanicOnAddError to panic instead.
 You can create your own Container and create a new http.Server for that particular container.
 
 	container := restful.NewContainer()
 	server := &http.Server{Addr: ":8081", Handler: container}
 
+The MIME types produced and consumed by the Routes of WebServices that do not specify them can be set for the Container.
+
+	container.DefaultProduces(restful.MIME_JSON)
+	container.DefaultConsumes(restful.MIME_JSON)
+
+By default, a request path matches a Route with or without a trailing slash.
+Use TrailingSlash to respond with 404 (TrailingSlashStrict) or to redirect (TrailingSlashRedirect) if it differs from the Route path.
+
+	container.TrailingSlash(restful.TrailingSlashRedirect)
+
+WebServices can be added, removed or replaced while the Container serves requests ; requests in flight complete as usual
+using the WebServices registered when they arrived.
+
+	container.Replace(oldService, newService)
+
+Graceful shutdown
+
+A Container can serve requests itself, using a http.Server with timeouts. Shutdown waits for the requests in flight
+to complete and then calls the functions registered with OnShutdown. RunUntilSignal does both, shutting down on SIGINT or SIGTERM.
+
+	container.OnShutdown(func(ctx context.Context) error { return db.Close() })
+	log.Fatal(restful.RunUntilSignal(container, ":8080"))
+
 Filters
 
 A filter dynamically intercepts requests and responses to transform or use the information contained in the requests or responses.
@@ -82,8 +164,65 @@ These are processed before calling the function associated with the Route.
 	// install 2 chained route filters (processed before calling findUser)
 	ws.Route(ws.GET("/{user-id}").Filter(routeLogging).Filter(NewCountFilter().routeCounter).To(findUser))
 
+Filters are processed in the order Container, WebService and Route ; within each, in the order they were added.
+Use FilterAt on a WebService or RouteBuilder to insert a filter at a given position instead.
+
+	ws.FilterAt(0, tracing) // processed before the other webservice filters
+
+A filter can reject a request using AbortWith ; the next filters and the Route function are skipped and the error
+is written through the ServiceErrorHandler. Filters before it can get the error using Response.Aborted.
+
+	restful.AbortWith(resp, restful.NewError(http.StatusUnauthorized, "401: Unauthorized"))
+
 See the example https://github.com/emicklei/go-restful/blob/master/examples/restful-filters.go with full implementations.
 
+Request IDs
+
+RequestIDFilter sets the ID of each request, taken from the X-Request-Id header or generated, as a request attribute,
+in the request context and in the response header. The access log, the RecoveryFilter and the ServiceErrorHandler include it.
+
+	restful.Filter(restful.RequestIDFilter("", nil))
+	id := restful.RequestIDFromContext(ctx)
+
+Authentication
+
+NewBasicAuthFilter authenticates requests with the Basic scheme using a verifier of the credentials ; the principal it returns
+is set as the KeyPrincipal request attribute. BasicAuth also documents the security requirement and the 401 response of the Route.
+
+	ws.Route(ws.GET("/").Handler(findAllUsers).Do(restful.BasicAuth("users", verifyUser)))
+
+APIKeyAuth does the same for API keys of a header or query parameter ; its options also give the security scheme
+to document, such that the documentation and the filter use the same key.
+
+	apiKey := restful.APIKeyOptions{Name: "X-API-Key", In: "header", Lookup: lookupClient}
+	ws.Route(ws.GET("/").Handler(findAllUsers).Do(restful.APIKeyAuth("ApiKey", apiKey)))
+
+The sub-package restful/jwtauth authenticates requests with JSON Web Tokens the same way.
+ScopesFilter checks the scopes granted to a request, e.g. by its token, against the security requirements of its Route.
+It follows the filter that authenticates the request, e.g. in the Route filters added by RequireScopes of jwtauth.
+
+	ws.Route(ws.GET("/{user-id}").Handler(findUser).Do(jwtAuth.RequireScopes("users.read")))
+
+Rate limiting
+
+RateLimitFilter limits the rate of the requests of each client, identified by its IP address or a key function,
+and responds with 429 Too Many Requests over the limit. A Route can have a limit of its own.
+
+	restful.Filter(restful.RateLimitFilter(restful.RateLimitOptions{Limit: restful.RateLimit{Rate: 10, Burst: 20}}))
+	ws.Route(ws.POST("/login").RateLimit(restful.RateLimit{Rate: 0.2}).Handler(login))
+
+Recovering from panics
+
+RecoveryFilter recovers from a panic in the filters and Route functions after it ; it logs the stack trace and
+responds with a 500 ServiceError that has the request ID, see RecoveryOptions.
+
+	restful.Filter(restful.RecoveryFilter(restful.RecoveryOptions{OnPanic: reportPanic}))
+
+RecoverFilter calls a RecoverHandler instead, e.g. to write a custom response for the Routes of a WebService ;
+it is the RecoveryFilter with the Handler option.
+
+	ws.Filter(restful.RecoverFilter(writeInternalError))
+
 Response Encoding
 
 Two encodings are supported: gzip and deflate. To enable this for all responses:
@@ -95,12 +234,55 @@ Alternatively, you can create a Filter that performs the encoding and install it
 
 See the example https://github.com/emicklei/go-restful/blob/master/examples/restful-encoding-filter.go
 
+Conditional requests
+
+WriteEntityWithETag writes the entity with an ETag header, the hash of the representation unless already set.
+A GET or HEAD request whose If-None-Match header matches it gets 304 Not Modified without body.
+
+	resp.WriteEntityWithETag(http.StatusOK, user)
+
 OPTIONS support
 
 By installing a pre-defined container filter, your Webservice(s) can respond to the OPTIONS Http request.
 
 	Filter(OPTIONSFilter())
 
+Alternatively, a Container can answer OPTIONS requests for paths without an OPTIONS Route with 204 No Content and an Allow header.
+
+	container.EnableAutoOPTIONS(true)
+
+HEAD support
+
+A Container can serve HEAD requests for paths without a HEAD Route by their GET Route ; the body is discarded
+and the Content-Type and Content-Length are the ones of the GET response.
+
+	container.EnableAutoHEAD(true)
+
+Health checks
+
+HealthChecks returns a WebService with the /healthz and /readyz Routes that call the checks of the service.
+
+	restful.Add(restful.HealthChecks(map[string]func() error{"database": db.Ping}))
+
+A HealthService has checks that can be added later and that time out ; only its /readyz Route calls them.
+
+	health := restful.NewHealthService("/").AddCheck("database", db.PingContext).Tags("health")
+	restful.Add(health.WebService)
+
+Static files
+
+StaticService returns a WebService that serves the files of a file system, e.g. embedded using go:embed, under its root path.
+Its Routes are hidden from the OpenAPI documentation, see KeyOpenAPIHidden.
+
+	restful.Add(restful.StaticService("/apidocs", swaggerUI))
+
+Method override
+
+For clients that can only send GET and POST, a Container can let a POST request select a PUT, PATCH or DELETE Route
+named in the X-HTTP-Method-Override header or the _method form field. Only enable it if such clients need it.
+
+	container.EnableMethodOverride(true)
+
 CORS
 
 By installing the filter of a CrossOriginResourceSharing (CORS), your WebService(s) can handle CORS requests.
@@ -108,6 +290,23 @@ By installing the filter of a CrossOriginResourceSharing (CORS), your WebService
 	cors := CrossOriginResourceSharing{ExposeHeaders: []string{"X-My-Header"}, CookiesAllowed: false, Container: DefaultContainer}
 	Filter(cors.Filter)
 
+CORS returns the same filter configured by CORSOptions.
+
+	Filter(CORS(CORSOptions{AllowedOrigins: []string{"https://*.example.com"}, AllowedHeaders: []string{"Content-Type"}}))
+
+A preflight request is answered with 204 No Content ; it may ask for the AllowedHeaders only, any header if they have "*".
+If the allowed origins are not known in advance, e.g. the domains of customers, AllowedOriginFunc decides instead of AllowedDomains.
+
+A Route can have its own CORS policy ; the filter uses it instead for requests, and preflight requests, to that Route.
+
+	ws.Route(ws.GET("/widget").CORS(restful.CrossOriginResourceSharing{}).Handler(widget))
+
+Browsers send preflight requests without credentials. A filter that runs before the CORS filter and would reject them,
+e.g. authentication or rate limiting, is wrapped by SkipOnPreflight such that the CORS filter can answer them.
+
+	restful.Filter(restful.SkipOnPreflight(auth.Filter))
+	restful.Filter(restful.CrossOriginResourceSharing{AllowedDomains: []string{"https://*.example.com"}}.Filter)
+
 Error Handling
 
 Unexpected things happen. If a request cannot be processed because of a failure, your service needs to tell via the response what happened and why.
@@ -156,6 +355,11 @@ Default value is true
 If content encoding is enabled then the default strategy for getting new gzip/zlib writers and readers is to use a sync.Pool.
 Because writers are expensive structures, performance is even more improved when using a preloaded cache. You can also inject your own implementation.
 
+	restful.DefaultContainer.Router(restful.TrieRouter{})
+
+The TrieRouter selects the same Routes as the CurlyRouter but does not match the path of every Route of a WebService.
+It is faster for WebServices with many Routes.
+
 Trouble shooting
 
 This package has the means to produce detail logging of the complete Http request matching process and filter invocation.
@@ -170,6 +374,25 @@ uses the standard library `log` package and logs to stdout. Different logging pa
 long as they conform to `StdLogger` interface defined in the `log` sub-package, writing an adapter for your
 preferred package is simple.
 
+AccessLogFilter logs each request with the path of the selected Route, e.g. /users/{user-id}, such that entries can be aggregated.
+
+	restful.Filter(restful.AccessLogFilter(restful.AccessLogOptions{Render: restful.LogEntry.JSON, Skip: []string{"/healthz"}}))
+
+Parameters holding secrets, e.g. passwords or tokens, are marked Sensitive ; the AccessLogFilter redacts the values
+of such path parameters and other filters that log requests can use SensitiveFields to do the same.
+They are documented with the x-sensitive extension.
+
+	ws.Route(ws.POST("/reset/{token}").Handler(resetPassword).
+		Params(ws.PathParameter("token", "the reset token").Sensitive()))
+
+The sub-package restful/metrics provides a filter that records Prometheus metrics labeled the same way
+and restful/tracing one that traces the requests with OpenTelemetry spans named e.g. GET /users/{user-id}.
+
+To find out why a request gets a 404, 405, 406 or 415 response, a Container can explain for each Route
+why it was rejected ; the explanation is logged and written in the X-Route-Trace header. Only use it for debugging.
+
+	container.EnableRouteTracing(true)
+
 Resources
 
 [project]: https://github.com/emicklei/go-restful
@@ -5,7 +5,9 @@ package restful
 // that can be found in the LICENSE file.
 
 import (
+	"bytes"
 	"encoding/xml"
+	"reflect"
 	"strings"
 	"sync"
 )
@@ -58,6 +60,22 @@ func NewEntityAccessorXML(contentType string) EntityReaderWriter {
 	return entityXMLAccess{ContentType: contentType}
 }
 
+// entityCodecs associates MIME to an EntityReaderWriter for a single Route ; see RouteBuilder.EntityCodec
+type entityCodecs map[string]EntityReaderWriter
+
+// accessorAt returns the ReaderWriter for this MIME type, falling back to the registered ones.
+func (c entityCodecs) accessorAt(mime string) (EntityReaderWriter, bool) {
+	if rw, ok := c[mime]; ok {
+		return rw, true
+	}
+	for k, v := range c {
+		if strings.Contains(mime, k) {
+			return v, true
+		}
+	}
+	return entityAccessRegistry.accessorAt(mime)
+}
+
 // accessorAt returns the registered ReaderWriter for this MIME type.
 func (r *entityReaderWriters) accessorAt(mime string) (EntityReaderWriter, bool) {
 	r.protection.RLock()
@@ -86,37 +104,88 @@ func (e entityXMLAccess) Read(req *Request, v interface{}) error {
 	return xml.NewDecoder(req.Request.Body).Decode(v)
 }
 
-// Write marshalls the value to JSON and set the Content-Type Header.
+// Write marshalls the value to XML and set the Content-Type Header.
 func (e entityXMLAccess) Write(resp *Response, status int, v interface{}) error {
 	return writeXML(resp, status, e.ContentType, v)
 }
 
-// writeXML marshalls the value to JSON and set the Content-Type Header.
+// writeXML marshalls the value to XML and set the Content-Type Header.
+// The root element name and the header are taken from the Response, see KeyXMLRootName and KeyXMLHeader ;
+// by default the header is written with pretty printing only.
 func writeXML(resp *Response, status int, contentType string, v interface{}) error {
+	writeHeader := resp.xmlHeader || (resp.prettyPrint && !resp.omitXMLHeader)
+	return writeXMLWithRoot(resp, status, contentType, v, resp.xmlRootName, writeHeader)
+}
+
+// writeXMLWithRoot marshalls the value to XML using rootName, if not empty, as the name of the root element.
+// Slices and arrays are wrapped in a root element ; if rootName is empty then the pluralized name of the element type is used.
+func writeXMLWithRoot(resp *Response, status int, contentType string, v interface{}, rootName string, writeHeader bool) error {
 	if v == nil {
 		resp.WriteHeader(status)
 		// do not write a nil representation
 		return nil
 	}
+	// encode before writing such that errors can still be reported with a status
+	var buffer bytes.Buffer
+	encoder := xml.NewEncoder(&buffer)
 	if resp.prettyPrint {
-		// pretty output must be created and written explicitly
-		output, err := xml.MarshalIndent(v, " ", " ")
-		if err != nil {
+		encoder.Indent(" ", " ")
+	}
+	if err := encodeXMLWithRoot(encoder, reflect.ValueOf(v), rootName); err != nil {
+		return err
+	}
+	resp.Header().Set(HEADER_ContentType, contentType)
+	resp.WriteHeader(status)
+	if writeHeader {
+		if _, err := resp.Write([]byte(xml.Header)); err != nil {
 			return err
 		}
-		resp.Header().Set(HEADER_ContentType, contentType)
-		resp.WriteHeader(status)
-		_, err = resp.Write([]byte(xml.Header))
-		if err != nil {
+	}
+	_, err := resp.Write(buffer.Bytes())
+	return err
+}
+
+func encodeXMLWithRoot(encoder *xml.Encoder, v reflect.Value, rootName string) error {
+	if kind := v.Kind(); (kind == reflect.Slice || kind == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		if len(rootName) == 0 {
+			rootName = pluralXMLName(v.Type().Elem())
+		}
+		root := xml.StartElement{Name: xml.Name{Local: rootName}}
+		if err := encoder.EncodeToken(root); err != nil {
 			return err
 		}
-		_, err = resp.Write(output)
-		return err
+		for i := 0; i < v.Len(); i++ {
+			if err := encoder.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(root.End()); err != nil {
+			return err
+		}
+		return encoder.Flush()
 	}
-	// not-so-pretty
-	resp.Header().Set(HEADER_ContentType, contentType)
-	resp.WriteHeader(status)
-	return xml.NewEncoder(resp).Encode(v)
+	if len(rootName) == 0 {
+		return encoder.Encode(v.Interface())
+	}
+	return encoder.EncodeElement(v.Interface(), xml.StartElement{Name: xml.Name{Local: rootName}})
+}
+
+// pluralXMLName returns the name of the element that wraps a collection of t, e.g. User -> Users, Entry -> Entries.
+func pluralXMLName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := t.Name()
+	if len(name) == 0 {
+		return "items"
+	}
+	switch {
+	case strings.HasSuffix(name, "y") && !strings.HasSuffix(name, "ay") && !strings.HasSuffix(name, "ey") && !strings.HasSuffix(name, "oy"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	}
+	return name + "s"
 }
 
 // entityJSONAccess is a EntityReaderWriter for JSON encoding
@@ -144,19 +213,25 @@ func writeJSON(resp *Response, status int, contentType string, v interface{}) er
 		// do not write a nil representation
 		return nil
 	}
+	// encode before writing such that errors can still be reported with a status
+	var output []byte
 	if resp.prettyPrint {
 		// pretty output must be created and written explicitly
-		output, err := MarshalIndent(v, "", " ")
+		indented, err := MarshalIndent(v, "", " ")
 		if err != nil {
 			return err
 		}
-		resp.Header().Set(HEADER_ContentType, contentType)
-		resp.WriteHeader(status)
-		_, err = resp.Write(output)
-		return err
+		output = indented
+	} else {
+		// not-so-pretty
+		var buffer bytes.Buffer
+		if err := NewEncoder(&buffer).Encode(v); err != nil {
+			return err
+		}
+		output = buffer.Bytes()
 	}
-	// not-so-pretty
 	resp.Header().Set(HEADER_ContentType, contentType)
 	resp.WriteHeader(status)
-	return NewEncoder(resp).Encode(v)
+	_, err := resp.Write(output)
+	return err
 }
@@ -2,11 +2,13 @@ package restful
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -67,3 +69,133 @@ func TestKeyValueEncoding(t *testing.T) {
 		t.Error("Read never called")
 	}
 }
+
+type User struct {
+	ID   int    `xml:"id"`
+	Name string `xml:"name"`
+	Age  int    `xml:"age"`
+}
+
+func TestWriteAsXmlWithRoot(t *testing.T) {
+	users := []User{{1, "john", 21}, {2, "jane", 22}}
+
+	httpWriter := httptest.NewRecorder()
+	resp := Response{ResponseWriter: httpWriter}
+	resp.WriteAsXmlWithRoot(users, "", false)
+	want := "<Users><User><id>1</id><name>john</name><age>21</age></User><User><id>2</id><name>jane</name><age>22</age></User></Users>"
+	if got := httpWriter.Body.String(); got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	var read struct {
+		Users []User `xml:"User"`
+	}
+	if err := xml.Unmarshal(httpWriter.Body.Bytes(), &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Users, users) {
+		t.Errorf("got %v want %v", read.Users, users)
+	}
+
+	httpWriter = httptest.NewRecorder()
+	resp = Response{ResponseWriter: httpWriter}
+	resp.WriteAsXmlWithRoot(users[0], "person", true)
+	want = xml.Header + "<person><id>1</id><name>john</name><age>21</age></person>"
+	if got := httpWriter.Body.String(); got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	var user User
+	if err := xml.Unmarshal(httpWriter.Body.Bytes(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if user != users[0] {
+		t.Errorf("got %v want %v", user, users[0])
+	}
+}
+
+func TestXMLRouteMetadata(t *testing.T) {
+	wc := NewContainer()
+	wc.OmitXMLHeader(true)
+	ws := new(WebService).Path("/users").Produces(MIME_XML)
+	ws.Route(ws.GET("").Handler(writeUsers).Metadata(KeyXMLRootName, "people"))
+	ws.Route(ws.GET("/header").Handler(writeUsers).Metadata(KeyXMLHeader, true))
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	httpRequest.Header.Set(HEADER_Accept, MIME_XML)
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if body := recorder.Body.String(); !strings.HasPrefix(strings.TrimSpace(body), "<people>") {
+		t.Errorf("expected root element people, got %q", body)
+	}
+
+	httpRequest, _ = http.NewRequest("GET", "/users/header", nil)
+	httpRequest.Header.Set(HEADER_Accept, MIME_XML)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	if body := recorder.Body.String(); !strings.HasPrefix(body, xml.Header) || !strings.HasPrefix(strings.TrimSpace(body[len(xml.Header):]), "<Users>") {
+		t.Errorf("expected xml header and root element Users, got %q", body)
+	}
+
+	// the header opt-in does not depend on pretty printing
+	recorder = httptest.NewRecorder()
+	wc.Filter(noPrettyPrint)
+	wc.ServeHTTP(recorder, httpRequest)
+	if body := recorder.Body.String(); !strings.HasPrefix(body, xml.Header+"<Users>") {
+		t.Errorf("expected xml header and root element Users without indentation, got %q", body)
+	}
+}
+
+func noPrettyPrint(req *Request, resp *Response, next func(*Request, *Response)) {
+	resp.PrettyPrint(false)
+	next(req, resp)
+}
+
+func writeUsers(req *Request, resp *Response) {
+	resp.WriteEntity([]User{{1, "john", 21}})
+}
+
+func TestRouteEntityCodec(t *testing.T) {
+	kv := new(keyvalue)
+	ws := new(WebService).Path("/foods").Produces(MIME_JSON).Consumes(MIME_JSON)
+	ws.Route(ws.GET("/legacy").EntityCodec(MIME_JSON, kv).Handler(writeApple))
+	ws.Route(ws.GET("/standard").Handler(writeApple))
+	ws.Route(ws.POST("/legacy").EntityCodec(MIME_JSON, kv).Handler(readFood))
+	wc := NewContainer()
+	wc.Add(ws)
+
+	if got := ws.Routes()[0].EntityCodecs[MIME_JSON]; got != kv {
+		t.Errorf("expected codec on route, got %v", got)
+	}
+	if got := ws.Routes()[1].EntityCodecs; got != nil {
+		t.Errorf("unexpected codecs %v", got)
+	}
+
+	for path, want := range map[string]string{
+		"/foods/legacy":   "Kind=apple\n",
+		"/foods/standard": "{\n \"Kind\": \"apple\"\n}",
+	} {
+		httpRequest, _ := http.NewRequest("GET", path, nil)
+		httpRequest.Header.Set(HEADER_Accept, MIME_JSON)
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Body.String(); got != want {
+			t.Errorf("%s: got %q want %q", path, got, want)
+		}
+	}
+
+	httpRequest, _ := http.NewRequest("POST", "/foods/legacy", strings.NewReader("Kind=pear\n"))
+	httpRequest.Header.Set(HEADER_ContentType, MIME_JSON)
+	wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	if !kv.readCalled {
+		t.Error("Read never called")
+	}
+}
+
+func writeApple(req *Request, resp *Response) {
+	resp.WriteEntity(food{"apple"})
+}
+
+func readFood(req *Request, resp *Response) {
+	var f food
+	req.ReadEntity(&f)
+}
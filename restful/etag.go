@@ -0,0 +1,63 @@
+package restful
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// WriteEntityWithETag is WriteWithETag for the request of the response.
+func (r *Response) WriteEntityWithETag(status int, entity interface{}) error {
+	return WriteWithETag(r.request, r, status, entity)
+}
+
+// WriteWithETag writes the entity as WriteHeaderAndEntity does, with an ETag header. Unless the response already has one,
+// e.g. a weak ETag W/"42" derived from a version, the ETag is the strong hash of the written representation.
+// If the request is a GET or HEAD whose If-None-Match header matches the ETag, using the weak comparison,
+// then 304 Not Modified is written without body instead.
+func WriteWithETag(req *Request, resp *Response, status int, entity interface{}) error {
+	// the representation depends on the Accept header and the entity writers ; write it in a buffer first
+	buffer := newBufferedWriter(resp)
+	buffered := *resp
+	buffered.ResponseWriter, buffered.hijacker = buffer, nil
+	buffered.writeHooks = nil
+	err := buffered.WriteHeaderAndEntity(status, entity)
+	resp.err = buffered.err
+
+	if buffer.code != status || buffer.buffer.Len() == 0 {
+		// an error or no entity
+		buffer.copyTo(resp)
+		return err
+	}
+	etag := buffer.header.Get(HEADER_ETag)
+	if len(etag) == 0 {
+		sum := sha1.Sum(buffer.buffer.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+		buffer.header.Set(HEADER_ETag, etag)
+	}
+	method := ""
+	if req != nil {
+		method = req.Request.Method
+	}
+	if (method == http.MethodGet || method == http.MethodHead) && status/100 == 2 &&
+		etagMatches(req.Request.Header.Get(HEADER_IfNoneMatch), etag) {
+		buffer.code = http.StatusNotModified
+		buffer.buffer.Reset()
+		buffer.header.Del(HEADER_ContentLength)
+	}
+	buffer.copyTo(resp)
+	return err
+}
+
+// etagMatches returns whether the If-None-Match header lists the ETag or is "*" ; W/ prefixes are ignored.
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, each := range strings.Split(ifNoneMatch, ",") {
+		each = strings.TrimSpace(each)
+		if each == "*" || strings.TrimPrefix(each, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
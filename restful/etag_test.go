@@ -0,0 +1,86 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type etagUser struct {
+	ID string `json:"id"`
+}
+
+func writeUserWithETag(req *Request, resp *Response) {
+	resp.WriteEntityWithETag(http.StatusOK, etagUser{ID: req.pathParameters["id"]})
+}
+
+func writeUserWithWeakETag(req *Request, resp *Response) {
+	resp.Header().Set(HEADER_ETag, `W/"v1"`)
+	resp.WriteEntityWithETag(http.StatusOK, etagUser{ID: req.pathParameters["id"]})
+}
+
+func newETagContainer() *Container {
+	wc := NewContainer()
+	ws := new(WebService).Path("/users").Produces(MIME_JSON)
+	ws.Route(ws.GET("/{id}").Handler(writeUserWithETag))
+	ws.Route(ws.PUT("/{id}").Handler(writeUserWithETag))
+	ws.Route(ws.GET("/{id}/weak").Handler(writeUserWithWeakETag))
+	wc.Add(ws)
+	return wc
+}
+
+func serveWithIfNoneMatch(wc *Container, method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	httpRequest := httptest.NewRequest(method, path, nil)
+	if len(ifNoneMatch) > 0 {
+		httpRequest.Header.Set(HEADER_IfNoneMatch, ifNoneMatch)
+	}
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	return httpWriter
+}
+
+func TestWriteEntityWithETag(t *testing.T) {
+	wc := newETagContainer()
+	httpWriter := serveWithIfNoneMatch(wc, "GET", "/users/1", "")
+	etag := httpWriter.Header().Get(HEADER_ETag)
+	if httpWriter.Code != http.StatusOK || len(etag) != 42 || httpWriter.Body.Len() == 0 {
+		t.Fatalf("got status %d, etag %q and body %q", httpWriter.Code, etag, httpWriter.Body.String())
+	}
+	for _, each := range []struct {
+		method      string
+		path        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"GET", "/users/1", etag, http.StatusNotModified},
+		{"GET", "/users/1", `"other", ` + etag, http.StatusNotModified},
+		{"GET", "/users/1", "W/" + etag, http.StatusNotModified},
+		{"GET", "/users/1", "*", http.StatusNotModified},
+		{"GET", "/users/1", `"other"`, http.StatusOK},
+		{"GET", "/users/2", etag, http.StatusOK},
+		{"PUT", "/users/1", etag, http.StatusOK},
+		{"GET", "/users/1/weak", `"v1"`, http.StatusNotModified},
+		{"GET", "/users/1/weak", `W/"v2"`, http.StatusOK},
+	} {
+		httpWriter := serveWithIfNoneMatch(wc, each.method, each.path, each.ifNoneMatch)
+		if httpWriter.Code != each.want {
+			t.Errorf("%s %s %s: got status %d want %d", each.method, each.path, each.ifNoneMatch, httpWriter.Code, each.want)
+		}
+		if len(httpWriter.Header().Get(HEADER_ETag)) == 0 {
+			t.Errorf("%s %s %s: got no etag", each.method, each.path, each.ifNoneMatch)
+		}
+		if each.want == http.StatusNotModified && httpWriter.Body.Len() != 0 {
+			t.Errorf("%s %s %s: got body %q want none", each.method, each.path, each.ifNoneMatch, httpWriter.Body.String())
+		}
+	}
+}
+
+func TestWriteWithETagNotAcceptable(t *testing.T) {
+	httpRequest := httptest.NewRequest("GET", "/users/1", nil)
+	httpRequest.Header.Set(HEADER_Accept, MIME_XML)
+	httpWriter := httptest.NewRecorder()
+	newETagContainer().ServeHTTP(httpWriter, httpRequest)
+	if httpWriter.Code != http.StatusNotAcceptable || len(httpWriter.Header().Get(HEADER_ETag)) != 0 {
+		t.Errorf("got status %d and etag %q want 406 without etag", httpWriter.Code, httpWriter.Header().Get(HEADER_ETag))
+	}
+}
@@ -122,13 +122,17 @@ func (u *UserResource) removeUser(request *restful.Request, response *restful.Re
 
 func main() {
 	u := UserResource{map[string]User{}}
-	restful.DefaultContainer.Add(u.WebService())
+	if err := restful.DefaultContainer.Add(u.WebService()); err != nil {
+		log.Fatal(err)
+	}
 
 	config := restfulspec.Config{
 		WebServices: restful.RegisteredWebServices(), // you control what services are visible
 		APIPath:     "/apidocs.json",
 		PostBuildSwaggerObjectHandler: enrichSwaggerObject}
-	restful.DefaultContainer.Add(restfulspec.NewOpenAPIService(config))
+	if err := restful.DefaultContainer.Add(restfulspec.NewOpenAPIService(config)); err != nil {
+		log.Fatal(err)
+	}
 
 	// Optionally, you can install the Swagger Service which provides a nice Web UI on your REST API
 	// You need to download the Swagger HTML5 assets and change the FilePath location in the config below.
@@ -0,0 +1,88 @@
+package restful
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WriteFile writes the content using http.ServeContent so that Range, If-Modified-Since
+// and If-None-Match requests are handled. The Content-Type is derived from the extension of name,
+// or sniffed from the content, unless it was set before. A zero modtime omits the Last-Modified header.
+// Content encoding (compression) is disabled for the response because the Content-Length and
+// Content-Range headers describe the content as is.
+// Example:
+//
+//	png, _ := qrcode.Encode(data, qrcode.Medium, 256)
+//	resp.WriteFile("qr.png", time.Time{}, bytes.NewReader(png))
+func (r *Response) WriteFile(name string, modtime time.Time, content io.ReadSeeker) {
+	if c, ok := r.ResponseWriter.(*CompressingResponseWriter); ok {
+		if !c.disable() && trace {
+			traceLogger.Printf("unable to disable compression for file %s ; response already started", name)
+		}
+	}
+	var httpRequest *http.Request
+	if r.request != nil {
+		httpRequest = r.request.Request
+	} else {
+		// not dispatched by a Container ; serve the content as a whole
+		httpRequest = &http.Request{Method: "GET", Header: http.Header{}}
+	}
+	http.ServeContent(r, httpRequest, name, modtime, content)
+}
+
+// WriteAttachment is like WriteFile but sets the Content-Disposition header such that
+// browsers save the content using the filename. Non-ASCII filenames are encoded following RFC 5987.
+func (r *Response) WriteAttachment(filename string, modtime time.Time, content io.ReadSeeker) {
+	r.Header().Set(HEADER_ContentDisposition, contentDisposition("attachment", filename))
+	r.WriteFile(filename, modtime, content)
+}
+
+// contentDisposition returns the header value with a quoted filename parameter.
+// If the filename is not plain ASCII then an ASCII fallback is followed by the encoded filename* parameter.
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+	for _, each := range filename {
+		switch {
+		case each >= 0x80 || each < 0x20 || each == 0x7f:
+			ascii = false
+			fallback.WriteByte('_')
+		case each == '"' || each == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(each)
+		default:
+			fallback.WriteRune(each)
+		}
+	}
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if ascii {
+		return value
+	}
+	return value + "; filename*=UTF-8''" + encodeRFC5987(filename)
+}
+
+// encodeRFC5987 percent-encodes all bytes of s that are not an attr-char as defined by RFC 5987.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+func isAttrChar(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) != -1
+}
@@ -0,0 +1,67 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileRange(t *testing.T) {
+	httpRequest, _ := http.NewRequest("GET", "/report", nil)
+	httpRequest.Header.Set("Range", "bytes=6-10")
+	httpWriter := httptest.NewRecorder()
+	_, resp := newBasicRequestResponse(httpWriter, httpRequest)
+	resp.WriteFile("report.txt", time.Time{}, strings.NewReader("Hello World"))
+	if got, want := httpWriter.Code, http.StatusPartialContent; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := httpWriter.Body.String(), "World"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if got, want := httpWriter.Header().Get(HEADER_ContentType), "text/plain; charset=utf-8"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if resp.StatusCode() != http.StatusPartialContent || resp.ContentLength() != 5 {
+		t.Errorf("unexpected status %d or length %d", resp.StatusCode(), resp.ContentLength())
+	}
+}
+
+func TestWriteFileDisablesCompression(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	c, err := NewCompressingResponseWriter(httpWriter, ENCODING_GZIP)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	resp := NewResponse(c)
+	resp.WriteFile("report.txt", time.Time{}, strings.NewReader("Hello World"))
+	if err := c.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if got := httpWriter.Header().Get(HEADER_ContentEncoding); got != "" {
+		t.Errorf("unexpected content encoding %q", got)
+	}
+	if got, want := httpWriter.Body.String(), "Hello World"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestWriteAttachment(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(httpWriter)
+	resp.WriteAttachment("résumé 1.pdf", time.Time{}, strings.NewReader("%PDF"))
+	want := `attachment; filename="r_sum_ 1.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%201.pdf`
+	if got := httpWriter.Header().Get(HEADER_ContentDisposition); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := httpWriter.Header().Get(HEADER_ContentType), "application/pdf"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestContentDispositionASCII(t *testing.T) {
+	if got, want := contentDisposition("attachment", `my "report".csv`), `attachment; filename="my \"report\".csv"`; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
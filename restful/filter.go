@@ -13,7 +13,11 @@ type FilterChain struct {
 
 // processFilter passes the request,response pair through the next of Filters.
 // Each filter can decide to proceed to the next Filter or handle the Response itself.
+// Once the chain has been aborted, see AbortWith, the next Filters and the Target are skipped.
 func (f *FilterChain) processFilter(request *Request, response *Response) {
+	if response.abortedWith != nil {
+		return
+	}
 	if f.Index < len(f.Filters) {
 		f.Index++
 		f.Filters[f.Index-1](request, response, f.processFilter)
@@ -25,6 +29,36 @@ func (f *FilterChain) processFilter(request *Request, response *Response) {
 // FilterFunction definitions must call processFilter on the FilterChain to pass on the control and eventually call the RouteFunction
 type FilterFunction func(*Request, *Response, func(*Request, *Response))
 
+// AbortWith aborts the filter chain of the response with the error: the next filters and the route function are skipped,
+// also if the filter calls them, and the error is written through the ServiceErrorHandler of the Container.
+// Only the first call has effect. Filters before the aborting one can get the error using Response.Aborted
+// once the chain returns, e.g. to tell a rejected request from a handled one.
+func AbortWith(resp *Response, err ServiceError) {
+	if resp.abortedWith != nil {
+		return
+	}
+	resp.abortedWith = &err
+	resp.err = err
+	if resp.handleServiceError(err) {
+		return
+	}
+	writeServiceError(err.withRequestID(resp.request), resp.request, resp)
+}
+
+// insertFilter returns the filters with the filter inserted at the index, clamped to the bounds of the filters.
+func insertFilter(filters []FilterFunction, index int, filter FilterFunction) []FilterFunction {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(filters) {
+		index = len(filters)
+	}
+	result := make([]FilterFunction, 0, len(filters)+1)
+	result = append(result, filters[:index]...)
+	result = append(result, filter)
+	return append(result, filters[index:]...)
+}
+
 // NoBrowserCacheFilter is a filter function to set HTTP headers that disable browser caching
 // See examples/restful-no-cache-filter.go for usage
 func NoBrowserCacheFilter(req *Request, resp *Response, next func(*Request, *Response)) {
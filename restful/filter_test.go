@@ -124,6 +124,29 @@ func TestAllFiltersBar(t *testing.T) {
 	}
 }
 
+func TestFilterAt(t *testing.T) {
+	ws := new(WebService).Path("")
+	ws.Filter(serviceFilter).Filter(routeFilter).FilterAt(1, globalFilter)
+	ws.Route(ws.GET("/foo").Handler(foo))
+	container := NewContainer()
+	container.Add(ws)
+	actual := sendItTo("http://example.com/foo", container)
+	if "service-global-route-foo" != actual {
+		t.Fatal("expected: service-global-route-foo but got:" + actual)
+	}
+}
+
+func TestRouteFilterAtClamped(t *testing.T) {
+	ws := new(WebService).Path("")
+	ws.Route(ws.GET("/foo").Filter(globalFilter).FilterAt(-1, serviceFilter).FilterAt(10, routeFilter).Handler(foo))
+	container := NewContainer()
+	container.Add(ws)
+	actual := sendItTo("http://example.com/foo", container)
+	if "service-global-route-foo" != actual {
+		t.Fatal("expected: service-global-route-foo but got:" + actual)
+	}
+}
+
 func sendIt(address string) string {
 	httpRequest, _ := http.NewRequest("GET", address, nil)
 	httpRequest.Header.Set("Accept", "*/*")
@@ -139,3 +162,83 @@ func sendItTo(address string, container *Container) string {
 	container.dispatch(httpWriter, httpRequest)
 	return httpWriter.Body.String()
 }
+
+// denyFilter aborts the chain and, wrongly, proceeds anyway
+func denyFilter(req *Request, resp *Response, next func(*Request, *Response)) {
+	AbortWith(resp, NewError(http.StatusForbidden, "403: Forbidden"))
+	AbortWith(resp, NewError(http.StatusUnauthorized, "401: Unauthorized"))
+	next(req, resp)
+}
+
+// abortObserver records the error the chain after it was aborted with
+type abortObserver struct {
+	err     ServiceError
+	aborted bool
+}
+
+func (o *abortObserver) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	next(req, resp)
+	o.err, o.aborted = resp.Aborted()
+}
+
+// errorCounter counts the ServiceErrors it writes
+type errorCounter struct {
+	count int
+}
+
+func (c *errorCounter) write(err ServiceError, req *Request, resp *Response) {
+	c.count++
+	resp.WriteHeader(err.Code)
+	io.WriteString(resp, err.Message)
+}
+
+func TestAbortWith(t *testing.T) {
+	for _, level := range []string{"container", "webservice", "route"} {
+		observer, counter := &abortObserver{}, &errorCounter{}
+		wc := NewContainer()
+		wc.ServiceErrorHandler(counter.write)
+		wc.Filter(observer.filter)
+		if level == "container" {
+			wc.Filter(denyFilter)
+		}
+		ws := new(WebService).Path("/abort")
+		if level == "webservice" {
+			ws.Filter(denyFilter)
+		}
+		rb := ws.GET("").Handler(foo)
+		if level == "route" {
+			rb.Filter(denyFilter)
+		}
+		ws.Route(rb.Filter(routeFilter))
+		wc.Add(ws)
+
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/abort", nil))
+		if got, want := httpWriter.Code, http.StatusForbidden; got != want {
+			t.Errorf("%s: got status %d want %d", level, got, want)
+		}
+		if got, want := httpWriter.Body.String(), "403: Forbidden"; got != want {
+			t.Errorf("%s: got body %q want %q, later filters and the handler skipped", level, got, want)
+		}
+		if counter.count != 1 {
+			t.Errorf("%s: got %d calls of the ServiceErrorHandler want 1", level, counter.count)
+		}
+		if !observer.aborted || observer.err.Code != http.StatusForbidden {
+			t.Errorf("%s: got abort %v %v want 403", level, observer.aborted, observer.err)
+		}
+	}
+}
+
+func TestNotAborted(t *testing.T) {
+	observer := &abortObserver{}
+	wc := NewContainer()
+	wc.Filter(observer.filter)
+	ws := new(WebService).Path("/abort")
+	ws.Route(ws.GET("").Handler(foo))
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/abort", nil))
+	if observer.aborted || httpWriter.Body.String() != "foo" {
+		t.Errorf("got abort %v and body %q", observer.aborted, httpWriter.Body.String())
+	}
+}
@@ -0,0 +1,65 @@
+package restful
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headResponseWriter is the http.ResponseWriter given to a GET Route that serves a HEAD request, see Container.EnableAutoHEAD.
+// It discards the body but counts its length ; the header is written when the request is done such that
+// the Content-Length and Content-Type are the ones of the GET response.
+type headResponseWriter struct {
+	writer      http.ResponseWriter
+	code        int
+	length      int
+	wroteHeader bool
+}
+
+// Header is part of http.ResponseWriter interface
+func (h *headResponseWriter) Header() http.Header {
+	return h.writer.Header()
+}
+
+// WriteHeader is part of http.ResponseWriter interface
+func (h *headResponseWriter) WriteHeader(code int) {
+	if h.wroteHeader {
+		return
+	}
+	h.code, h.wroteHeader = code, true
+}
+
+// Write is part of http.ResponseWriter interface ; the data is counted but not written.
+func (h *headResponseWriter) Write(data []byte) (int, error) {
+	if !h.wroteHeader {
+		h.WriteHeader(http.StatusOK)
+	}
+	if h.length == 0 && len(data) > 0 && len(h.Header().Get(HEADER_ContentType)) == 0 {
+		// like the http.Server does for the first write of a body
+		h.Header().Set(HEADER_ContentType, http.DetectContentType(data))
+	}
+	h.length += len(data)
+	return len(data), nil
+}
+
+// finish writes the header, with the Content-Length of the discarded body unless set otherwise.
+func (h *headResponseWriter) finish() {
+	if !h.wroteHeader {
+		return
+	}
+	header := h.Header()
+	if bodyAllowedForStatus(h.code) && len(header.Get(HEADER_ContentLength)) == 0 {
+		header.Set(HEADER_ContentLength, strconv.Itoa(h.length))
+	}
+	h.writer.WriteHeader(h.code)
+}
+
+// bodyAllowedForStatus returns whether a response with the status can have a body.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
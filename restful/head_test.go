@@ -0,0 +1,109 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type headEntity struct {
+	Name string `json:"name"`
+}
+
+func writeHeadEntity(req *Request, resp *Response) {
+	resp.Header().Set("X-Entity", "yes")
+	resp.WriteEntity(headEntity{Name: strings.Repeat("head", 100)})
+}
+
+func writeExplicitHead(req *Request, resp *Response) {
+	resp.Header().Set("X-Explicit", "yes")
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func newAutoHEADContainer() *Container {
+	wc := NewContainer()
+	wc.EnableAutoHEAD(true)
+	ws := new(WebService).Path("/entities").Produces(MIME_JSON)
+	ws.Route(ws.GET("").Handler(writeHeadEntity))
+	ws.Route(ws.GET("/explicit").Handler(writeHeadEntity))
+	ws.Route(ws.HEAD("/explicit").Handler(writeExplicitHead))
+	wc.Add(ws)
+	return wc
+}
+
+func TestAutoHEAD(t *testing.T) {
+	wc := newAutoHEADContainer()
+	get := httptest.NewRecorder()
+	wc.ServeHTTP(get, httptest.NewRequest("GET", "/entities", nil))
+	head := httptest.NewRecorder()
+	wc.ServeHTTP(head, httptest.NewRequest("HEAD", "/entities", nil))
+
+	if head.Code != get.Code {
+		t.Errorf("got status %d want %d", head.Code, get.Code)
+	}
+	for _, each := range []string{HEADER_ContentType, "X-Entity"} {
+		if got, want := head.Header().Get(each), get.Header().Get(each); got != want {
+			t.Errorf("%s: got %q want %q", each, got, want)
+		}
+	}
+	if got, want := head.Header().Get(HEADER_ContentLength), strconv.Itoa(get.Body.Len()); got != want {
+		t.Errorf("Content-Length: got %q want %q", got, want)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("expected no body, got %q", head.Body.String())
+	}
+}
+
+func TestAutoHEADExplicitRoute(t *testing.T) {
+	wc := newAutoHEADContainer()
+	head := httptest.NewRecorder()
+	wc.ServeHTTP(head, httptest.NewRequest("HEAD", "/entities/explicit", nil))
+	if head.Code != http.StatusNoContent || head.Header().Get("X-Explicit") != "yes" {
+		t.Errorf("expected the HEAD route, got %d %v", head.Code, head.Header())
+	}
+}
+
+func TestAutoHEADDisabled(t *testing.T) {
+	wc := newAutoHEADContainer()
+	wc.EnableAutoHEAD(false)
+	head := httptest.NewRecorder()
+	wc.ServeHTTP(head, httptest.NewRequest("HEAD", "/entities", nil))
+	if head.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d want 405", head.Code)
+	}
+}
+
+func TestAutoHEADWithoutCompression(t *testing.T) {
+	wc := newAutoHEADContainer()
+	wc.EnableContentEncoding(true)
+	get := httptest.NewRecorder()
+	wc.ServeHTTP(get, httptest.NewRequest("GET", "/entities", nil))
+	req := httptest.NewRequest("HEAD", "/entities", nil)
+	req.Header.Set(HEADER_AcceptEncoding, ENCODING_GZIP)
+	head := httptest.NewRecorder()
+	wc.ServeHTTP(head, req)
+
+	if got := head.Header().Get(HEADER_ContentEncoding); got != "" {
+		t.Errorf("expected no content encoding, got %q", got)
+	}
+	if got, want := head.Header().Get(HEADER_ContentLength), strconv.Itoa(get.Body.Len()); got != want {
+		t.Errorf("Content-Length: got %q want %q", got, want)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("expected no body, got %d bytes", head.Body.Len())
+	}
+}
+
+func TestAutoHEADAllowHeader(t *testing.T) {
+	wc := newAutoHEADContainer()
+	put := httptest.NewRecorder()
+	wc.ServeHTTP(put, httptest.NewRequest("PUT", "/entities", nil))
+	if put.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("got status %d want 405", put.Code)
+	}
+	if got := put.Header().Get(HEADER_Allow); !strings.Contains(got, "HEAD") {
+		t.Errorf("expected HEAD in Allow header, got %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+package restful
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthChecks returns a WebService, with root path "/", that exposes the liveness and readiness of a service
+// for load balancers and orchestrators:
+//
+//	GET /healthz  200 "ok" ; 503 with the names of the failing checks if any fails
+//	GET /readyz   200 or, if any check fails, 503 ; with a JSON object of each check name and "ok" or its error
+//
+// A check reports the health of a dependency of the service, e.g. a database, and returns nil if healthy.
+// The checks are called concurrently for each request.
+// It cannot be added to a Container that has another WebService with root path "/" ; mount it instead, see WebService.Mount.
+func HealthChecks(checks map[string]func() error) *WebService {
+	h := healthChecks{}
+	for name, each := range checks {
+		h = h.with(name, ignoreContext(each))
+	}
+
+	ws := new(WebService).Path("/")
+	ws.Route(ws.GET("/healthz").Produces(MIME_TEXT, MIME_JSON).Handler(h.liveness).
+		Doc("liveness of the service").
+		Return(http.StatusOK, "OK", nil).
+		Return(http.StatusServiceUnavailable, "Service Unavailable", nil))
+	ws.Route(ws.GET("/readyz").Produces(MIME_JSON).Handler(h.readiness).
+		Doc("readiness of the service, with the status of each check").
+		Return(http.StatusOK, "OK", map[string]string{}).
+		Return(http.StatusServiceUnavailable, "Service Unavailable", map[string]string{}))
+	return ws
+}
+
+// ignoreContext returns a check that calls the one without a context.
+func ignoreContext(check func() error) func(context.Context) error {
+	return func(context.Context) error { return check() }
+}
+
+// HealthService is a WebService with a liveness and a readiness Route:
+//
+//	GET {path}/healthz  200 "ok" if the service runs
+//	GET {path}/readyz   200 or, if any check fails, 503 ; with a JSON object of each check name and "ok" or its error
+//
+// The checks are added using AddCheck, also while the service runs. Use Tags or Hide to control
+// how the Routes appear in the OpenAPI documentation.
+type HealthService struct {
+	*WebService
+	lock    sync.RWMutex
+	checks  healthChecks
+	timeout time.Duration
+}
+
+// NewHealthService returns a HealthService with the root path, e.g. "/" ; its checks time out after 5 seconds.
+func NewHealthService(path string) *HealthService {
+	h := &HealthService{WebService: new(WebService).Path(path), timeout: 5 * time.Second}
+	h.Route(h.GET("/healthz").Produces(MIME_TEXT, MIME_JSON).Handler(h.liveness).
+		Doc("liveness of the service").
+		Return(http.StatusOK, "OK", nil))
+	h.Route(h.GET("/readyz").Produces(MIME_JSON).Handler(h.readiness).
+		Doc("readiness of the service, with the status of each check").
+		Return(http.StatusOK, "OK", map[string]string{}).
+		Return(http.StatusServiceUnavailable, "Service Unavailable", map[string]string{}))
+	return h
+}
+
+// AddCheck registers a check that reports the readiness of a dependency of the service, e.g. a database ;
+// it returns nil if ready. A check with the same name is replaced. The checks of a request run concurrently
+// and must return when the context is done, see Timeout.
+func (h *HealthService) AddCheck(name string, check func(ctx context.Context) error) *HealthService {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.checks = h.checks.with(name, check)
+	return h
+}
+
+// Timeout sets the time after which the checks of a request fail, if they have not returned.
+func (h *HealthService) Timeout(timeout time.Duration) *HealthService {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.timeout = timeout
+	return h
+}
+
+// Tags sets the tags of the Routes in the OpenAPI documentation, see KeyOpenAPITags.
+func (h *HealthService) Tags(tags ...string) *HealthService {
+	h.setMetadata(KeyOpenAPITags, tags)
+	return h
+}
+
+// Hide leaves the Routes out of the OpenAPI documentation, see KeyOpenAPIHidden.
+func (h *HealthService) Hide() *HealthService {
+	h.setMetadata(KeyOpenAPIHidden, true)
+	return h
+}
+
+func (h *HealthService) setMetadata(key string, value interface{}) {
+	h.routesLock.Lock()
+	defer h.routesLock.Unlock()
+	for i := range h.routes {
+		if h.routes[i].Metadata == nil {
+			h.routes[i].Metadata = map[string]interface{}{}
+		}
+		h.routes[i].Metadata[key] = value
+	}
+}
+
+func (h *HealthService) liveness(req *Request, resp *Response) {
+	resp.Header().Set(HEADER_ContentType, MIME_TEXT)
+	resp.Write([]byte("ok"))
+}
+
+func (h *HealthService) readiness(req *Request, resp *Response) {
+	h.lock.RLock()
+	checks, timeout := h.checks, h.timeout
+	h.lock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(req.Request.Context(), timeout)
+	defer cancel()
+	checks.readinessWithin(ctx, resp)
+}
+
+// healthChecks holds named checks ; it is not changed after being built, see with.
+type healthChecks struct {
+	names  []string
+	checks map[string]func(context.Context) error
+}
+
+// with returns a copy of the checks with the named check added or replaced.
+func (h healthChecks) with(name string, check func(context.Context) error) healthChecks {
+	added := healthChecks{checks: map[string]func(context.Context) error{name: check}}
+	for _, each := range h.names {
+		if each != name {
+			added.names = append(added.names, each)
+			added.checks[each] = h.checks[each]
+		}
+	}
+	added.names = append(added.names, name)
+	sort.Strings(added.names)
+	return added
+}
+
+// run calls all checks concurrently and returns the status of each and the names of the failing ones.
+// A check that has not returned when the context is done fails with the error of the context.
+func (h healthChecks) run(ctx context.Context) (status map[string]string, failing []string) {
+	results := make([]chan error, len(h.names))
+	for i, name := range h.names {
+		results[i] = make(chan error, 1)
+		go func(check func(context.Context) error, result chan<- error) {
+			result <- check(ctx)
+		}(h.checks[name], results[i])
+	}
+	status = map[string]string{}
+	for i, name := range h.names {
+		var err error
+		select {
+		case err = <-results[i]:
+		case <-ctx.Done():
+			select {
+			case err = <-results[i]:
+			default:
+				err = ctx.Err()
+			}
+		}
+		if err != nil {
+			status[name] = err.Error()
+			failing = append(failing, name)
+			continue
+		}
+		status[name] = "ok"
+	}
+	return status, failing
+}
+
+func (h healthChecks) liveness(req *Request, resp *Response) {
+	if _, failing := h.run(req.Request.Context()); len(failing) > 0 {
+		resp.WriteErrorString(http.StatusServiceUnavailable, "failing checks: "+strings.Join(failing, ", "))
+		return
+	}
+	resp.Header().Set(HEADER_ContentType, MIME_TEXT)
+	resp.Write([]byte("ok"))
+}
+
+func (h healthChecks) readiness(req *Request, resp *Response) {
+	h.readinessWithin(req.Request.Context(), resp)
+}
+
+func (h healthChecks) readinessWithin(ctx context.Context, resp *Response) {
+	status, failing := h.run(ctx)
+	if len(failing) > 0 {
+		resp.WriteHeaderAndEntity(http.StatusServiceUnavailable, status)
+		return
+	}
+	resp.WriteEntity(status)
+}
@@ -0,0 +1,146 @@
+package restful
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkPassing() error { return nil }
+
+func checkFailing() error { return errors.New("connection refused") }
+
+func TestHealthChecksPassing(t *testing.T) {
+	wc := NewContainer()
+	if err := wc.Add(HealthChecks(map[string]func() error{"database": checkPassing})); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: got status %d want 200", path, recorder.Code)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	status := map[string]string{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status["database"] != "ok" {
+		t.Errorf("got %v", status)
+	}
+}
+
+func TestHealthChecksFailing(t *testing.T) {
+	wc := NewContainer()
+	wc.Add(HealthChecks(map[string]func() error{"database": checkPassing, "queue": checkFailing}))
+
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d want 503", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "queue") {
+		t.Errorf("expected failing check in body, got %q", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d want 503", recorder.Code)
+	}
+	status := map[string]string{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status["database"] != "ok" || status["queue"] != "connection refused" {
+		t.Errorf("got %v", status)
+	}
+}
+
+func checkContextPassing(ctx context.Context) error { return nil }
+
+func checkContextFailing(ctx context.Context) error { return errors.New("connection refused") }
+
+// checkContextBlocking returns when the context is done, like a check of a dependency that does not respond
+func checkContextBlocking(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHealthService(t *testing.T) {
+	hs := NewHealthService("/status").AddCheck("database", checkContextPassing)
+	wc := NewContainer()
+	if err := wc.Add(hs.WebService); err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/status/readyz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("got status %d want 200", recorder.Code)
+	}
+
+	// checks can be added while serving ; liveness does not depend on them
+	hs.AddCheck("queue", checkContextFailing)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/status/healthz", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "ok" {
+		t.Errorf("got %d %q want 200 \"ok\"", recorder.Code, recorder.Body.String())
+	}
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/status/readyz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d want 503", recorder.Code)
+	}
+	status := map[string]string{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status["database"] != "ok" || status["queue"] != "connection refused" {
+		t.Errorf("got %v", status)
+	}
+}
+
+func TestHealthServiceTimeout(t *testing.T) {
+	hs := NewHealthService("/").
+		AddCheck("database", checkContextPassing).
+		AddCheck("search", checkContextBlocking).
+		Timeout(20 * time.Millisecond)
+	wc := NewContainer()
+	wc.Add(hs.WebService)
+
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d want 503", recorder.Code)
+	}
+	status := map[string]string{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status["database"] != "ok" || status["search"] != context.DeadlineExceeded.Error() {
+		t.Errorf("got %v", status)
+	}
+}
+
+func TestHealthServiceMetadata(t *testing.T) {
+	hs := NewHealthService("/").Tags("health")
+	for _, each := range hs.Routes() {
+		if got, _ := each.Metadata[KeyOpenAPITags].([]string); len(got) != 1 || got[0] != "health" {
+			t.Errorf("%s: got tags %v", each.String(), got)
+		}
+	}
+	hs.Hide()
+	for _, each := range hs.Routes() {
+		if hidden, _ := each.Metadata[KeyOpenAPIHidden].(bool); !hidden {
+			t.Errorf("%s: expected hidden", each.String())
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package restful
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// hostPattern is the compiled form of a host constraint of a Route, see RouteBuilder.Host
+type hostPattern struct {
+	exact string         // lower-cased host name if the pattern has no wildcards
+	re    *regexp.Regexp // compiled wildcard or regular expression otherwise
+}
+
+// newHostPattern compiles a host name, a host name with "*" wildcards or a regular expression starting with "^".
+func newHostPattern(pattern string) (*hostPattern, error) {
+	if strings.HasPrefix(pattern, "^") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return &hostPattern{re: re}, nil
+	}
+	pattern = strings.ToLower(pattern)
+	if !strings.Contains(pattern, "*") {
+		return &hostPattern{exact: pattern}, nil
+	}
+	// a wildcard matches a single label
+	expr := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, `[^.]+`, -1) + "$"
+	return &hostPattern{re: regexp.MustCompile(expr)}, nil
+}
+
+// matches returns whether the host of a request, with or without port, matches the pattern.
+func (h *hostPattern) matches(requestHost string) bool {
+	host := requestHost
+	if name, _, err := net.SplitHostPort(requestHost); err == nil {
+		host = name
+	}
+	host = strings.ToLower(host)
+	if h.re != nil {
+		return h.re.MatchString(host)
+	}
+	return host == h.exact
+}
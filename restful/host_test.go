@@ -0,0 +1,68 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostPatternMatches(t *testing.T) {
+	for i, each := range []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "API.Example.com:8080", true},
+		{"api.example.com", "admin.example.com", false},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.b.example.com", false},
+		{`^(api|admin)\.example\.com$`, "admin.example.com:443", true},
+		{`^(api|admin)\.example\.com$`, "www.example.com", false},
+		{"::1", "[::1]:8080", true},
+	} {
+		p, err := newHostPattern(each.pattern)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got := p.matches(each.host); got != each.want {
+			t.Errorf("%d: %q matches %q: got %v want %v", i, each.pattern, each.host, got, each.want)
+		}
+	}
+	if _, err := newHostPattern("^(api"); err == nil {
+		t.Error("expected error for invalid regular expression")
+	}
+}
+
+func TestRouteHost(t *testing.T) {
+	ws := new(WebService).Path("/status")
+	ws.Route(ws.GET("").Host("admin.example.com").Handler(dummy))
+	ws.Route(ws.GET("").Host("*.example.com").Handler(curlyDummy))
+	wc := NewContainer()
+	wc.Add(ws)
+	for host, want := range map[string]string{
+		"admin.example.com": "dummy",
+		"api.example.com":   "curlyDummy",
+		"example.org":       "404: Not Found",
+	} {
+		httpRequest, _ := http.NewRequest("GET", "/status", nil)
+		httpRequest.Host = host
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Body.String(); got != want {
+			t.Errorf("%s: got %q want %q", host, got, want)
+		}
+	}
+}
+
+func TestWebServiceHost(t *testing.T) {
+	ws := new(WebService).Path("/status").Host("admin.example.com")
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.POST("").Host("api.example.com").Handler(dummy))
+	if got, want := ws.Routes()[0].Host, "admin.example.com"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := ws.Routes()[1].Host, "api.example.com"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 )
 
 // RouterJSR311 implements the flow for matching Requests to Routes (and consequently Resource Functions)
@@ -43,10 +44,10 @@ func (r RouterJSR311) SelectRoute(
 // engine as the JSR 311 router.
 func (r RouterJSR311) ExtractParameters(route *Route, webService *WebService, urlPath string) map[string]string {
 	webServiceExpr := webService.pathExpr
-	webServiceMatches := webServiceExpr.Matcher.FindStringSubmatch(urlPath)
+	webServiceMatches := webServiceExpr.matcher(webService.caseInsensitivePaths).FindStringSubmatch(urlPath)
 	pathParameters := r.extractParams(webServiceExpr, webServiceMatches)
 	routeExpr := route.pathExpr
-	routeMatches := routeExpr.Matcher.FindStringSubmatch(webServiceMatches[len(webServiceMatches)-1])
+	routeMatches := routeExpr.matcher(webService.caseInsensitivePaths).FindStringSubmatch(webServiceMatches[len(webServiceMatches)-1])
 	routeParams := r.extractParams(routeExpr, routeMatches)
 	for key, value := range routeParams {
 		pathParameters[key] = value
@@ -54,6 +55,12 @@ func (r RouterJSR311) ExtractParameters(route *Route, webService *WebService, ur
 	return pathParameters
 }
 
+// ExtractEscapedParameters is part of EscapedPathProcessor ; the RouterJSR311 matches the decoded path,
+// the values of the escaped path are those of a catch-all parameter as they appear in it.
+func (r RouterJSR311) ExtractEscapedParameters(route *Route, webService *WebService, escapedPath string) map[string]string {
+	return r.ExtractParameters(route, webService, escapedPath)
+}
+
 func (RouterJSR311) extractParams(pathExpr *pathExpression, matches []string) map[string]string {
 	params := map[string]string{}
 	for i := 1; i < len(matches); i++ {
@@ -68,6 +75,9 @@ func (RouterJSR311) extractParams(pathExpr *pathExpression, matches []string) ma
 func (r RouterJSR311) detectRoute(routes []Route, httpRequest *http.Request) (*Route, error) {
 	ifOk := []Route{}
 	for _, each := range routes {
+		if !each.matchesHost(httpRequest) || !each.matchesVersion(httpRequest) {
+			continue
+		}
 		ok := true
 		for _, fn := range each.If {
 			if !fn(httpRequest) {
@@ -97,7 +107,8 @@ func (r RouterJSR311) detectRoute(routes []Route, httpRequest *http.Request) (*R
 		if trace {
 			traceLogger.Printf("no Route found (in %d routes) that matches HTTP method %s\n", len(routes), httpRequest.Method)
 		}
-		return nil, NewError(http.StatusMethodNotAllowed, "405: Method Not Allowed")
+		return nil, NewErrorWithHeader(http.StatusMethodNotAllowed, "405: Method Not Allowed",
+			http.Header{HEADER_Allow: []string{strings.Join(allowedMethods(ifOk), ", ")}})
 	}
 	inputMediaOk := methodOk
 
@@ -131,12 +142,36 @@ func (r RouterJSR311) detectRoute(routes []Route, httpRequest *http.Request) (*R
 		if trace {
 			traceLogger.Printf("no Route found (from %d) that matches HTTP Accept: %s\n", len(inputMediaOk), accept)
 		}
-		return nil, NewError(http.StatusNotAcceptable, "406: Not Acceptable")
+		return nil, notAcceptableError(producibleTypes(inputMediaOk))
 	}
 	// return r.bestMatchByMedia(outputMediaOk, contentType, accept), nil
 	return &outputMediaOk[0], nil
 }
 
+// allowedMethods returns the distinct HTTP methods of the routes, in order of declaration.
+func allowedMethods(routes []Route) []string {
+	methods := []string{}
+	for _, each := range routes {
+		if !containsString(methods, each.Method) {
+			methods = append(methods, each.Method)
+		}
+	}
+	return methods
+}
+
+// producibleTypes returns the distinct MIME types the routes can produce, in order of declaration.
+func producibleTypes(routes []Route) []string {
+	types := []string{}
+	for _, each := range routes {
+		for _, mime := range each.Produces {
+			if !containsString(types, mime) {
+				types = append(types, mime)
+			}
+		}
+	}
+	return types
+}
+
 // http://jsr311.java.net/nonav/releases/1.1/spec/spec3.html#x3-360003.7.2
 // n/m > n/* > */*
 func (r RouterJSR311) bestMatchByMedia(routes []Route, contentType string, accept string) *Route {
@@ -149,7 +184,7 @@ func (r RouterJSR311) selectRoutes(dispatcher *WebService, pathRemainder string)
 	filtered := &sortableRouteCandidates{}
 	for _, each := range dispatcher.Routes() {
 		pathExpr := each.pathExpr
-		matches := pathExpr.Matcher.FindStringSubmatch(pathRemainder)
+		matches := pathExpr.matcher(dispatcher.caseInsensitivePaths).FindStringSubmatch(pathRemainder)
 		if matches != nil {
 			lastMatch := matches[len(matches)-1]
 			if len(lastMatch) == 0 || lastMatch == "/" { // do not include if value is neither empty nor ‘/’.
@@ -170,7 +205,7 @@ func (r RouterJSR311) selectRoutes(dispatcher *WebService, pathRemainder string)
 	matchingRoutes := []Route{filtered.candidates[0].route}
 	for c := 1; c < len(filtered.candidates); c++ {
 		each := filtered.candidates[c]
-		if each.route.pathExpr.Matcher.MatchString(pathRemainder) {
+		if each.route.pathExpr.matcher(dispatcher.caseInsensitivePaths).MatchString(pathRemainder) {
 			matchingRoutes = append(matchingRoutes, each.route)
 		}
 	}
@@ -181,7 +216,7 @@ func (r RouterJSR311) selectRoutes(dispatcher *WebService, pathRemainder string)
 func (r RouterJSR311) detectDispatcher(requestPath string, dispatchers []*WebService) (*WebService, string, error) {
 	filtered := &sortableDispatcherCandidates{}
 	for _, each := range dispatchers {
-		matches := each.pathExpr.Matcher.FindStringSubmatch(requestPath)
+		matches := each.pathExpr.matcher(each.caseInsensitivePaths).FindStringSubmatch(requestPath)
 		if matches != nil {
 			filtered.candidates = append(filtered.candidates,
 				dispatcherCandidate{each, matches[len(matches)-1], len(matches), each.pathExpr.LiteralCount, each.pathExpr.VarCount})
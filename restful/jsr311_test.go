@@ -225,21 +225,21 @@ func TestDetectRouteReturns404IfNoRoutePassesConditions(t *testing.T) {
 	routes := []Route{
 		new(RouteBuilder).Handler(dummy).
 			If(func(req *http.Request) bool { return false }).
-			Build(),
+			MustBuild(),
 
 		// check that condition functions are called in order
 		new(RouteBuilder).
 			Handler(dummy).
 			If(func(req *http.Request) bool { return true }).
 			If(func(req *http.Request) bool { called = true; return false }).
-			Build(),
+			MustBuild(),
 
 		// check that condition functions short circuit
 		new(RouteBuilder).
 			Handler(dummy).
 			If(func(req *http.Request) bool { return false }).
 			If(func(req *http.Request) bool { shouldNotBeCalledButWas = true; return false }).
-			Build(),
+			MustBuild(),
 	}
 
 	_, err := RouterJSR311{}.detectRoute(routes, (*http.Request)(nil))
@@ -265,8 +265,6 @@ var extractParams = []struct {
 	{"wildcardLastPart", "/fixed/{var:*}", "/fixed/remainder", map[string]string{"var": "remainder"}},
 	{"wildcardMultipleParts", "/fixed/{var:*}", "/fixed/remain/der", map[string]string{"var": "remain/der"}},
 	{"wildcardManyParts", "/fixed/{var:*}", "/fixed/test/sub/hi.html", map[string]string{"var": "test/sub/hi.html"}},
-	{"wildcardInMiddle", "/fixed/{var:*}/morefixed", "/fixed/middle/stuff/morefixed", map[string]string{"var": "middle/stuff"}},
-	{"wildcardFollowedByVar", "/fixed/{var:*}/morefixed/{otherVar}", "/fixed/middle/stuff/morefixed/end", map[string]string{"var": "middle/stuff", "otherVar": "end"}},
 	{"singleParam", "/fixed/{var}", "/fixed/remainder", map[string]string{"var": "remainder"}},
 	{"slash", "/", "/", map[string]string{}},
 	{"NoVars", "/fixed", "/fixed", map[string]string{}},
@@ -0,0 +1,220 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// minJWKSRefresh is the minimum time between two fetches of the key set for tokens with unknown key IDs.
+const minJWKSRefresh = time.Minute
+
+// jwksTimeout is the timeout of the default client fetching the key set.
+const jwksTimeout = 10 * time.Second
+
+// keySet caches the public keys of a JSON Web Key Set by key ID.
+type keySet struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetched   time.Time
+	attempted time.Time // when the last fetch ended, also if it failed
+	err       error     // the error of the last fetch, if any
+	fetching  *keyFetch // the fetch in progress, if any
+}
+
+// keyFetch is a fetch of the key set shared by the lookups that wait for it.
+type keyFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// jsonWebKey is a key of a JSON Web Key Set, RFC 7517 ; the RSA and EC public keys are used.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newKeySet(config Config, now func() time.Time) *keySet {
+	refresh := config.JWKSRefresh
+	if refresh <= 0 {
+		refresh = time.Hour
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: jwksTimeout}
+	}
+	return &keySet{url: config.JWKSURL, refresh: refresh, client: client, now: now}
+}
+
+// key is the jwt.Keyfunc of the key set ; the RSA and ECDSA methods only, the key of the kid header must match the method.
+func (s *keySet) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := s.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+}
+
+// lookup returns the key of the ID ; the key set is fetched if it is stale or, at most once per minute, if it has no such key.
+// After a failed fetch, the key set is not fetched again for a minute either. The key set is fetched without holding the lock,
+// once for all the lookups that need it.
+func (s *keySet) lookup(kid string) (interface{}, error) {
+	s.mu.Lock()
+	now := s.now()
+	stale := s.keys == nil || now.Sub(s.fetched) >= s.refresh
+	key, ok := s.keys[kid]
+	if ok && !stale {
+		s.mu.Unlock()
+		return key, nil
+	}
+	call := s.fetching
+	if call == nil && now.Sub(s.attempted) < minJWKSRefresh && (!stale || s.err != nil) {
+		defer s.mu.Unlock()
+		// keep using the cached key, if any, while the key set cannot be fetched
+		if ok {
+			return key, nil
+		}
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if call == nil {
+		call = &keyFetch{done: make(chan struct{})}
+		s.fetching = call
+		s.mu.Unlock()
+		keys, err := s.fetch()
+		s.mu.Lock()
+		if err == nil {
+			s.keys, s.fetched = keys, now
+		}
+		s.attempted, s.err = now, err
+		call.err = err
+		s.fetching = nil
+		close(call.done)
+	} else {
+		s.mu.Unlock()
+		<-call.done
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+	// keep using the cached key, if any, while the key set cannot be fetched
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// fetch returns the signing public keys of the key set by ID.
+func (s *keySet) fetch() (map[string]interface{}, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the key set %s: %s", s.url, resp.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding the key set %s: %v", s.url, err)
+	}
+	keys := map[string]interface{}{}
+	for _, each := range set.Keys {
+		if len(each.Use) > 0 && each.Use != "sig" {
+			continue
+		}
+		// keys of unsupported types are left out
+		if key, err := each.publicKey(); err == nil {
+			keys[each.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey returns the *rsa.PublicKey or *ecdsa.PublicKey of the key.
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// decodeBigInt decodes the base64url encoded big-endian integer.
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
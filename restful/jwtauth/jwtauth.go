@@ -0,0 +1,299 @@
+// Package jwtauth provides a restful.FilterFunction that authenticates requests with JSON Web Tokens.
+//
+// The token is taken from the Authorization header with the Bearer scheme or, if configured, from a cookie.
+// Its signature is verified with an HMAC secret or with the public keys of a JSON Web Key Set, and its
+// exp, nbf, iss and aud claims are validated. The claims and the subject of a valid token are set as
+// attributes of the Request ; other requests get 401 Unauthorized through the ServiceErrorHandler of the Container.
+//
+//	auth, err := jwtauth.New(jwtauth.Config{Secret: []byte(secret), Issuer: "https://login.example.com"})
+//	...
+//	ws.Route(ws.PUT("/{user-id}").Handler(updateUser).Do(auth.Require))
+//
+//	func updateUser(req *restful.Request, resp *restful.Response) {
+//		subject := jwtauth.Subject(req)
+//		...
+//	}
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tangblue/goapi/restful"
+)
+
+const (
+	// KeyClaims is a Request attribute key ; its jwt.MapClaims value has the claims of the token of the request.
+	// The subject is the restful.KeyPrincipal attribute.
+	KeyClaims = "jwt.claims"
+	// SecurityBearer is the name of the security scheme of the Routes authenticated by an Authenticator, see Require.
+	SecurityBearer = "Bearer"
+)
+
+// Config configures an Authenticator ; one of Secret, JWKSURL and KeyFunc is required.
+type Config struct {
+	// Secret is the key of the tokens signed with HMAC.
+	Secret []byte
+	// JWKSURL is the URL of the JSON Web Key Set with the RSA and EC public keys of the tokens, selected by their kid header.
+	JWKSURL string
+	// JWKSRefresh is the time after which the key set is fetched again ; default is 1 hour.
+	// A token with an unknown key ID also fetches it again, at most once per minute.
+	JWKSRefresh time.Duration
+	// HTTPClient fetches the key set ; default is a client with a timeout of 10 seconds.
+	HTTPClient *http.Client
+	// KeyFunc, if set, returns the key of the tokens instead of Secret and JWKSURL.
+	KeyFunc jwt.Keyfunc
+	// Algorithms lists the accepted signing algorithms, e.g. RS256 ; default is HS256 for a Secret,
+	// RS256 and ES256 for a JWKSURL. Required with a KeyFunc.
+	Algorithms []string
+	// Issuer, if set, is the required iss claim.
+	Issuer string
+	// Audience, if set, must be in the aud claim.
+	Audience string
+	// ClockSkew is the leeway of the exp, nbf and iat claims.
+	ClockSkew time.Duration
+	// Cookie, if set, is the name of the cookie with the token of the requests without an Authorization header.
+	Cookie string
+	// Realm, if set, is the realm of the WWW-Authenticate header of the 401 responses.
+	Realm string
+}
+
+// Authenticator authenticates requests with JSON Web Tokens, see Filter and Require.
+type Authenticator struct {
+	config  Config
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+	now     func() time.Time
+}
+
+// New returns an Authenticator for the configuration.
+func New(config Config) (*Authenticator, error) {
+	a := &Authenticator{config: config, now: time.Now}
+	algorithms := config.Algorithms
+	switch {
+	case config.KeyFunc != nil:
+		if len(algorithms) == 0 {
+			return nil, errors.New("jwtauth: the algorithms are required with a key function")
+		}
+		a.keyFunc = config.KeyFunc
+	case len(config.Secret) > 0:
+		if len(algorithms) == 0 {
+			algorithms = []string{jwt.SigningMethodHS256.Alg()}
+		}
+		a.keyFunc = a.secretKey
+	case len(config.JWKSURL) > 0:
+		if len(algorithms) == 0 {
+			algorithms = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+		}
+		a.keyFunc = newKeySet(config, a.clock).key
+	default:
+		return nil, errors.New("jwtauth: one of the secret, the key set URL and the key function is required")
+	}
+	for _, each := range algorithms {
+		if jwt.GetSigningMethod(each) == nil || each == "none" {
+			return nil, fmt.Errorf("jwtauth: unknown algorithm %q", each)
+		}
+	}
+	a.parser = &jwt.Parser{ValidMethods: algorithms, SkipClaimsValidation: true}
+	return a, nil
+}
+
+// Filter is a restful.FilterFunction that sets the KeyClaims and restful.KeyPrincipal attributes of the Request
+// if it has a valid token ; otherwise the chain is aborted with 401 Unauthorized, see restful.AbortWith.
+func (a *Authenticator) Filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	raw := a.token(req)
+	if len(raw) == 0 {
+		a.unauthorized(resp, "")
+		return
+	}
+	claims, err := a.Validate(raw)
+	if err != nil {
+		a.unauthorized(resp, `error="invalid_token"`)
+		return
+	}
+	req.SetAttribute(KeyClaims, claims)
+	if subject, ok := claims["sub"].(string); ok {
+		req.SetAttribute(restful.KeyPrincipal, subject)
+	}
+	next(req, resp)
+}
+
+// Require is a function for restful.RouteBuilder.Do that adds the Filter to the Route,
+// the SecurityBearer security requirement and the restful.ErrorUnauthorized response. See RequireScopes.
+func (a *Authenticator) Require(b *restful.RouteBuilder) {
+	a.RequireScopes()(b)
+}
+
+// RequireScopes returns a function for restful.RouteBuilder.Do that does what Require does and requires the token
+// to grant the scopes : the Filter is followed by a restful.ScopesFilter of the Scopes of the token, and the security
+// requirement lists the scopes, with the restful.ErrorForbidden response if any.
+//
+//	ws.Route(ws.DELETE("/{user-id}").Handler(removeUser).Do(auth.RequireScopes("users.write")))
+func (a *Authenticator) RequireScopes(scopes ...string) func(b *restful.RouteBuilder) {
+	return func(b *restful.RouteBuilder) {
+		b.Filter(a.Filter).
+			Filter(restful.ScopesFilter(Scopes)).
+			Security(SecurityBearer, scopes).
+			ReturnResponses(restful.ErrorUnauthorized)
+		if len(scopes) > 0 {
+			b.ReturnResponses(restful.ErrorForbidden)
+		}
+	}
+}
+
+// Validate returns the claims of the token if its signature and claims are valid.
+func (a *Authenticator) Validate(raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc); err != nil {
+		return nil, err
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Claims returns the claims of the token of the request set by the Filter, nil if none.
+func Claims(req *restful.Request) jwt.MapClaims {
+	claims, _ := req.Attribute(KeyClaims).(jwt.MapClaims)
+	return claims
+}
+
+// Subject returns the sub claim of the token of the request set by the Filter, empty if none.
+func Subject(req *restful.Request) string {
+	subject, _ := req.Attribute(restful.KeyPrincipal).(string)
+	return subject
+}
+
+// Scopes is a restful.ScopesFunction that returns the scopes of the token of the request set by the Filter,
+// from its space-separated scope claim or its scp claim ; an error if the request has no token.
+func Scopes(req *restful.Request) ([]string, error) {
+	claims := Claims(req)
+	if claims == nil {
+		return nil, errors.New("no token")
+	}
+	if scopes, ok := claims["scope"].(string); ok {
+		return strings.Fields(scopes), nil
+	}
+	switch scopes := claims["scp"].(type) {
+	case string:
+		return strings.Fields(scopes), nil
+	case []interface{}:
+		result := []string{}
+		for _, each := range scopes {
+			if scope, ok := each.(string); ok {
+				result = append(result, scope)
+			}
+		}
+		return result, nil
+	}
+	return []string{}, nil
+}
+
+// clock returns the current time.
+func (a *Authenticator) clock() time.Time {
+	return a.now()
+}
+
+// token returns the token of the Authorization header or of the cookie, empty if none.
+func (a *Authenticator) token(req *restful.Request) string {
+	if header := req.Request.Header.Get("Authorization"); len(header) > 0 {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+	if len(a.config.Cookie) > 0 {
+		if cookie, err := req.Request.Cookie(a.config.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func (a *Authenticator) unauthorized(resp *restful.Response, errorParam string) {
+	params := []string{}
+	if len(a.config.Realm) > 0 {
+		params = append(params, "realm="+strconv.Quote(a.config.Realm))
+	}
+	if len(errorParam) > 0 {
+		params = append(params, errorParam)
+	}
+	challenge := SecurityBearer
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	resp.Header().Set(restful.HEADER_WWWAuthenticate, challenge)
+	restful.AbortWith(resp, restful.NewError(http.StatusUnauthorized, "401: Unauthorized"))
+}
+
+// secretKey is the jwt.Keyfunc of the Secret ; the HMAC methods only, such that a public key is never used as a secret.
+func (a *Authenticator) secretKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return a.config.Secret, nil
+}
+
+// validateClaims validates the registered claims of the token.
+func (a *Authenticator) validateClaims(claims jwt.MapClaims) error {
+	now, skew := a.now(), a.config.ClockSkew
+	if exp, ok, err := numericDate(claims, "exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(skew)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok, err := numericDate(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(skew).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+	if iat, ok, err := numericDate(claims, "iat"); err != nil {
+		return err
+	} else if ok && now.Add(skew).Before(iat) {
+		return errors.New("token is issued in the future")
+	}
+	if len(a.config.Issuer) > 0 {
+		if issuer, _ := claims["iss"].(string); issuer != a.config.Issuer {
+			return fmt.Errorf("unexpected issuer %q", issuer)
+		}
+	}
+	if len(a.config.Audience) > 0 && !hasAudience(claims["aud"], a.config.Audience) {
+		return fmt.Errorf("token is not for the audience %q", a.config.Audience)
+	}
+	return nil
+}
+
+// numericDate returns the time of the claim in seconds since the epoch and whether the token has it.
+func numericDate(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("invalid %s claim %v", name, value)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true, nil
+}
+
+// hasAudience returns whether the aud claim, a string or an array of strings, has the audience.
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, each := range aud {
+			if each == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
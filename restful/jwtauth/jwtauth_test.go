@@ -0,0 +1,424 @@
+package jwtauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tangblue/goapi/restful"
+)
+
+var secret = []byte("secret")
+
+func writeSubject(req *restful.Request, resp *restful.Response) {
+	io.WriteString(resp, Subject(req)+" "+Claims(req)["scope"].(string))
+}
+
+func newContainer(auth *Authenticator) *restful.Container {
+	wc := restful.NewContainer()
+	ws := new(restful.WebService).Path("/me")
+	ws.Route(ws.GET("").Handler(writeSubject).Do(auth.Require))
+	wc.Add(ws)
+	return wc
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if len(kid) > 0 {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func serve(wc *restful.Container, token string) *httptest.ResponseRecorder {
+	httpRequest := httptest.NewRequest("GET", "/me", nil)
+	if len(token) > 0 {
+		httpRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	return httpWriter
+}
+
+func TestFilter(t *testing.T) {
+	auth, err := New(Config{Secret: secret, Issuer: "issuer", Audience: "api", ClockSkew: time.Minute, Realm: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := newContainer(auth)
+	now := time.Now().Unix()
+	valid := jwt.MapClaims{"sub": "alice", "scope": "read", "iss": "issuer", "aud": []string{"web", "api"}, "exp": now + 60}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, each := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"valid", sign(t, jwt.SigningMethodHS256, secret, "", valid), http.StatusOK},
+		{"expired", sign(t, jwt.SigningMethodHS256, secret, "", with(valid, "exp", now-120)), http.StatusUnauthorized},
+		{"expired within the clock skew", sign(t, jwt.SigningMethodHS256, secret, "", with(valid, "exp", now-30)), http.StatusOK},
+		{"not yet valid", sign(t, jwt.SigningMethodHS256, secret, "", with(valid, "nbf", now+120)), http.StatusUnauthorized},
+		{"wrong issuer", sign(t, jwt.SigningMethodHS256, secret, "", with(valid, "iss", "other")), http.StatusUnauthorized},
+		{"wrong audience", sign(t, jwt.SigningMethodHS256, secret, "", with(valid, "aud", "web")), http.StatusUnauthorized},
+		{"wrong secret", sign(t, jwt.SigningMethodHS256, []byte("other"), "", valid), http.StatusUnauthorized},
+		{"wrong algorithm", sign(t, jwt.SigningMethodHS384, secret, "", valid), http.StatusUnauthorized},
+		{"RSA algorithm", sign(t, jwt.SigningMethodRS256, rsaKey, "", valid), http.StatusUnauthorized},
+		{"none algorithm", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", valid), http.StatusUnauthorized},
+		{"malformed", "not.a.token", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	} {
+		httpWriter := serve(wc, each.token)
+		if httpWriter.Code != each.want {
+			t.Errorf("%s: got status %d want %d", each.name, httpWriter.Code, each.want)
+			continue
+		}
+		challenge := httpWriter.Header().Get(restful.HEADER_WWWAuthenticate)
+		switch {
+		case each.want == http.StatusOK:
+			if got := httpWriter.Body.String(); got != "alice read" {
+				t.Errorf("%s: got %q want the subject and scope", each.name, got)
+			}
+		case len(each.token) == 0:
+			if want := `Bearer realm="api"`; challenge != want {
+				t.Errorf("%s: got challenge %q want %q", each.name, challenge, want)
+			}
+		default:
+			if want := `Bearer realm="api", error="invalid_token"`; challenge != want {
+				t.Errorf("%s: got challenge %q want %q", each.name, challenge, want)
+			}
+		}
+	}
+}
+
+func with(claims jwt.MapClaims, name string, value interface{}) jwt.MapClaims {
+	result := jwt.MapClaims{name: value}
+	for key, each := range claims {
+		if key != name {
+			result[key] = each
+		}
+	}
+	return result
+}
+
+func TestFilterCookie(t *testing.T) {
+	auth, _ := New(Config{Secret: secret, Cookie: "token"})
+	httpRequest := httptest.NewRequest("GET", "/me", nil)
+	httpRequest.AddCookie(&http.Cookie{Name: "token", Value: sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "bob", "scope": "all"})})
+	httpWriter := httptest.NewRecorder()
+	newContainer(auth).ServeHTTP(httpWriter, httpRequest)
+	if got := httpWriter.Body.String(); httpWriter.Code != http.StatusOK || got != "bob all" {
+		t.Errorf("got status %d and %q want the subject of the cookie", httpWriter.Code, got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	auth, _ := New(Config{Secret: secret})
+	ws := new(restful.WebService).Path("/me")
+	route, _ := ws.GET("").Handler(writeSubject).Do(auth.Require).Build()
+	if want := []map[string][]string{{SecurityBearer: {}}}; !reflect.DeepEqual(route.Security, want) {
+		t.Errorf("got security %v want %v", route.Security, want)
+	}
+	if got := route.ResponseErrors[http.StatusUnauthorized]; got != restful.ErrorUnauthorized {
+		t.Errorf("got 401 response %v want restful.ErrorUnauthorized", got)
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	auth, _ := New(Config{Secret: secret})
+	wc := restful.NewContainer()
+	ws := new(restful.WebService).Path("/me")
+	ws.Route(ws.GET("").Handler(writeSubject).Do(auth.Require))
+	ws.Route(ws.DELETE("").Handler(writeSubject).Do(auth.RequireScopes("users.write")))
+	wc.Add(ws)
+
+	read := sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice", "scope": "users.read"})
+	write := sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice", "scope": "users.read users.write"})
+	for _, each := range []struct {
+		method string
+		token  string
+		want   int
+	}{
+		{"GET", read, http.StatusOK},
+		{"DELETE", "", http.StatusUnauthorized},
+		{"DELETE", read, http.StatusForbidden},
+		{"DELETE", write, http.StatusOK},
+	} {
+		httpRequest := httptest.NewRequest(each.method, "/me", nil)
+		if len(each.token) > 0 {
+			httpRequest.Header.Set("Authorization", "Bearer "+each.token)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if httpWriter.Code != each.want {
+			t.Errorf("%s with %q: got status %d want %d", each.method, each.token, httpWriter.Code, each.want)
+		}
+	}
+
+	route, _ := ws.DELETE("").Handler(writeSubject).Do(auth.RequireScopes("users.write")).Build()
+	if want := []map[string][]string{{SecurityBearer: {"users.write"}}}; !reflect.DeepEqual(route.Security, want) {
+		t.Errorf("got security %v want %v", route.Security, want)
+	}
+	if got := route.ResponseErrors[http.StatusForbidden]; got != restful.ErrorForbidden {
+		t.Errorf("got 403 response %v want restful.ErrorForbidden", got)
+	}
+}
+
+func TestFilterSkipOnPreflight(t *testing.T) {
+	auth, _ := New(Config{Secret: secret})
+	wc := restful.NewContainer()
+	wc.Filter(restful.SkipOnPreflight(auth.Filter))
+	wc.Filter(restful.CrossOriginResourceSharing{AllowedDomains: []string{"https://app.example.com"}, AllowedHeaders: []string{"Authorization", "Content-Type"}, Container: wc}.Filter)
+	ws := new(restful.WebService).Path("/users")
+	ws.Route(ws.PUT("/{user-id}").Handler(writeSubject).Do(auth.Require))
+	wc.Add(ws)
+
+	// the browser asks whether it may send the PUT with an Authorization header, without one
+	httpRequest := httptest.NewRequest("OPTIONS", "/users/42", nil)
+	httpRequest.Header.Set(restful.HEADER_Origin, "https://app.example.com")
+	httpRequest.Header.Set(restful.HEADER_AccessControlRequestMethod, "PUT")
+	httpRequest.Header.Set(restful.HEADER_AccessControlRequestHeaders, "authorization,content-type")
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if httpWriter.Code != http.StatusNoContent || httpWriter.Header().Get(restful.HEADER_AccessControlAllowOrigin) != "https://app.example.com" {
+		t.Errorf("got status %d and headers %v want the preflight response", httpWriter.Code, httpWriter.Header())
+	}
+
+	// the actual request and an OPTIONS request that is not a preflight are authenticated
+	for _, method := range []string{"PUT", "OPTIONS"} {
+		httpRequest = httptest.NewRequest(method, "/users/42", nil)
+		httpRequest.Header.Set(restful.HEADER_Origin, "https://app.example.com")
+		httpWriter = httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if httpWriter.Code != http.StatusUnauthorized {
+			t.Errorf("%s: got status %d want 401", method, httpWriter.Code)
+		}
+	}
+	httpRequest = httptest.NewRequest("PUT", "/users/42", nil)
+	httpRequest.Header.Set(restful.HEADER_Origin, "https://app.example.com")
+	httpRequest.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice", "scope": "users"}))
+	httpWriter = httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got := httpWriter.Body.String(); httpWriter.Code != http.StatusOK || got != "alice users" {
+		t.Errorf("got status %d and %q want the subject of the token", httpWriter.Code, got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, each := range []Config{
+		{},
+		{KeyFunc: func(*jwt.Token) (interface{}, error) { return secret, nil }},
+		{Secret: secret, Algorithms: []string{"none"}},
+		{Secret: secret, Algorithms: []string{"XX256"}},
+	} {
+		if _, err := New(each); err == nil {
+			t.Errorf("%+v: got no error", each)
+		}
+	}
+}
+
+// keyServer serves a JSON Web Key Set with the public keys by ID
+type keyServer struct {
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetches int
+	started chan bool // if set, receives a value when a fetch starts, which then waits for release
+	release chan bool
+	failing bool // if set, the fetches fail with 503
+}
+
+func (s *keyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.started != nil {
+		s.started <- true
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	if s.failing {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (s *keyServer) rotate(kid string, key *rsa.PublicKey) {
+	s.mu.Lock()
+	s.keys = map[string]*rsa.PublicKey{kid: key}
+	s.mu.Unlock()
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := &keyServer{keys: map[string]*rsa.PublicKey{"old": &oldKey.PublicKey}}
+	server := httptest.NewServer(keys)
+	defer server.Close()
+
+	auth, err := New(Config{JWKSURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	auth.now = func() time.Time { return now }
+	wc := newContainer(auth)
+	claims := jwt.MapClaims{"sub": "alice", "scope": "read", "exp": now.Add(time.Hour).Unix()}
+	oldToken := sign(t, jwt.SigningMethodRS256, oldKey, "old", claims)
+	newToken := sign(t, jwt.SigningMethodRS256, newKey, "new", claims)
+
+	if got := serve(wc, oldToken).Code; got != http.StatusOK {
+		t.Errorf("got status %d for the old key want 200", got)
+	}
+	if got := serve(wc, oldToken).Code; got != http.StatusOK || keys.fetches != 1 {
+		t.Errorf("got status %d and %d fetches want 200 and the cached key set", got, keys.fetches)
+	}
+
+	keys.rotate("new", &newKey.PublicKey)
+	// the unknown key fetches the key set again, once per minute
+	now = now.Add(2 * time.Minute)
+	if got := serve(wc, newToken).Code; got != http.StatusOK || keys.fetches != 2 {
+		t.Errorf("got status %d and %d fetches for the new key want 200 and a new fetch", got, keys.fetches)
+	}
+	if got := serve(wc, sign(t, jwt.SigningMethodRS256, newKey, "other", claims)).Code; got != http.StatusUnauthorized || keys.fetches != 2 {
+		t.Errorf("got status %d and %d fetches for an unknown key want 401 without fetch", got, keys.fetches)
+	}
+	// the stale key set is fetched again, without the old key
+	now = now.Add(time.Hour)
+	claims["exp"] = now.Add(time.Hour).Unix()
+	if got := serve(wc, sign(t, jwt.SigningMethodRS256, oldKey, "old", claims)).Code; got != http.StatusUnauthorized || keys.fetches != 3 {
+		t.Errorf("got status %d and %d fetches for the old key want 401 and a new fetch", got, keys.fetches)
+	}
+	if got := serve(wc, sign(t, jwt.SigningMethodHS256, secret, "new", claims)).Code; got != http.StatusUnauthorized {
+		t.Errorf("got status %d for an HMAC token want 401", got)
+	}
+}
+
+func TestJWKSFetchWithoutLock(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := &keyServer{keys: map[string]*rsa.PublicKey{"old": &key.PublicKey}}
+	server := httptest.NewServer(keys)
+	defer server.Close()
+	now := time.Now()
+	set := newKeySet(Config{JWKSURL: server.URL}, func() time.Time { return now })
+	if _, err := set.lookup("old"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cached key is found while the key set is fetched for an unknown key
+	now = now.Add(2 * time.Minute)
+	keys.started, keys.release = make(chan bool), make(chan bool)
+	looked := make(chan error, 1)
+	go func() {
+		_, err := set.lookup("new")
+		looked <- err
+	}()
+	<-keys.started
+	if got, err := set.lookup("old"); err != nil || got.(*rsa.PublicKey).N.Cmp(key.N) != 0 {
+		t.Errorf("got %v, %v want the cached key", got, err)
+	}
+	close(keys.release)
+	if err := <-looked; err == nil {
+		t.Error("got no error for the unknown key")
+	}
+	if keys.fetches != 2 {
+		t.Errorf("got %d fetches want 2", keys.fetches)
+	}
+	if got := set.client.Timeout; got != jwksTimeout {
+		t.Errorf("got client timeout %v want %v", got, jwksTimeout)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	for _, each := range []struct {
+		claims jwt.MapClaims
+		want   []string
+	}{
+		{jwt.MapClaims{"scope": "users.read users.write"}, []string{"users.read", "users.write"}},
+		{jwt.MapClaims{"scp": []interface{}{"users.read"}}, []string{"users.read"}},
+		{jwt.MapClaims{}, []string{}},
+	} {
+		req := restful.NewRequest(httptest.NewRequest("GET", "/me", nil))
+		req.SetAttribute(KeyClaims, each.claims)
+		if got, err := Scopes(req); err != nil || !reflect.DeepEqual(got, each.want) {
+			t.Errorf("%v: got %v, %v want %v", each.claims, got, err, each.want)
+		}
+	}
+	if _, err := Scopes(restful.NewRequest(httptest.NewRequest("GET", "/me", nil))); err == nil {
+		t.Error("got no error without token")
+	}
+}
+
+func TestJWKSFailedFetch(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := &keyServer{keys: map[string]*rsa.PublicKey{"old": &key.PublicKey}, failing: true}
+	server := httptest.NewServer(keys)
+	defer server.Close()
+	now := time.Now()
+	set := newKeySet(Config{JWKSURL: server.URL}, func() time.Time { return now })
+
+	// the failing key set is fetched once per minute
+	for _, kid := range []string{"old", "new", "other"} {
+		if _, err := set.lookup(kid); err == nil {
+			t.Errorf("%s: got no error want the failed fetch", kid)
+		}
+	}
+	if keys.fetches != 1 {
+		t.Errorf("got %d fetches want 1", keys.fetches)
+	}
+	keys.mu.Lock()
+	keys.failing = false
+	keys.mu.Unlock()
+	now = now.Add(30 * time.Second)
+	if _, err := set.lookup("old"); err == nil || keys.fetches != 1 {
+		t.Errorf("got %v and %d fetches want the failed fetch within the minute", err, keys.fetches)
+	}
+	now = now.Add(time.Minute)
+	if got, err := set.lookup("old"); err != nil || got.(*rsa.PublicKey).N.Cmp(key.N) != 0 || keys.fetches != 2 {
+		t.Errorf("got %v, %v and %d fetches want the key after a new fetch", got, err, keys.fetches)
+	}
+
+	// the cached key is used while the stale key set cannot be fetched again
+	keys.mu.Lock()
+	keys.failing = true
+	keys.mu.Unlock()
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 2; i++ {
+		if got, err := set.lookup("old"); err != nil || got == nil {
+			t.Errorf("%d: got %v, %v want the cached key", i, got, err)
+		}
+	}
+	if _, err := set.lookup("new"); err == nil {
+		t.Error("got no error for the unknown key")
+	}
+	if keys.fetches != 3 {
+		t.Errorf("got %d fetches want 3", keys.fetches)
+	}
+}
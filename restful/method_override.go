@@ -0,0 +1,50 @@
+package restful
+
+import (
+	"net/http"
+	"strings"
+)
+
+// overridableMethods are the methods a POST request can be overridden with
+var overridableMethods = []string{"PUT", "PATCH", "DELETE"}
+
+// overrideMethod returns the request with the method named by the override header or the _method form field
+// if method override is enabled and it is a POST request. Returns an error if the method is not one of overridableMethods.
+func (c *Container) overrideMethod(httpRequest *http.Request) (*http.Request, error) {
+	if len(c.methodOverrideHeader) == 0 || httpRequest.Method != "POST" {
+		return httpRequest, nil
+	}
+	method := httpRequest.Header.Get(c.methodOverrideHeader)
+	if len(method) == 0 && isFormURLEncoded(httpRequest) {
+		// the form remains available as httpRequest.PostForm
+		if err := httpRequest.ParseForm(); err == nil {
+			method = httpRequest.PostForm.Get("_method")
+		}
+	}
+	if len(method) == 0 {
+		return httpRequest, nil
+	}
+	method = strings.ToUpper(method)
+	if !containsString(overridableMethods, method) {
+		if trace {
+			traceLogger.Printf("method override of POST to %s is not allowed", method)
+		}
+		return httpRequest, NewError(http.StatusBadRequest, "400: Bad Request")
+	}
+	overridden := httpRequest.WithContext(httpRequest.Context())
+	overridden.Method = method
+	return overridden, nil
+}
+
+// isFormURLEncoded returns whether the content of the request is an application/x-www-form-urlencoded form.
+func isFormURLEncoded(httpRequest *http.Request) bool {
+	mediaType := strings.Split(httpRequest.Header.Get(HEADER_ContentType), ";")[0]
+	return strings.EqualFold(strings.TrimSpace(mediaType), "application/x-www-form-urlencoded")
+}
+
+// keepOriginalMethod records the method if the method of the request was overridden.
+func (r *Request) keepOriginalMethod(method string) {
+	if r.Request.Method != method {
+		r.SetAttribute(KeyOriginalMethod, method)
+	}
+}
@@ -0,0 +1,90 @@
+package restful
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// writeMethods writes the method of the request and the original method, if overridden
+func writeMethods(req *Request, resp *Response) {
+	fmt.Fprintf(resp, "%s %v", req.Request.Method, req.Attribute(KeyOriginalMethod))
+}
+
+func newMethodOverrideContainer() *Container {
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.POST("").Handler(writeMethods))
+	ws.Route(ws.DELETE("").Handler(writeMethods))
+	ws.Route(ws.GET("").Handler(writeMethods))
+	wc := NewContainer()
+	wc.Add(ws)
+	wc.EnableMethodOverride(true)
+	return wc
+}
+
+func TestMethodOverride(t *testing.T) {
+	wc := newMethodOverrideContainer()
+	for i, each := range []struct {
+		method, override string
+		wantCode         int
+		wantBody         string
+	}{
+		{"POST", "DELETE", 200, "DELETE POST"},
+		{"POST", "delete", 200, "DELETE POST"},
+		{"POST", "", 200, "POST <nil>"},
+		{"POST", "PUT", 405, "405: Method Not Allowed"},
+		{"POST", "GET", 400, "400: Bad Request"},
+		{"GET", "DELETE", 200, "GET <nil>"},
+	} {
+		httpRequest, _ := http.NewRequest(each.method, "/users", nil)
+		if len(each.override) > 0 {
+			httpRequest.Header.Set(HEADER_XHTTPMethodOverride, each.override)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Code; got != each.wantCode {
+			t.Errorf("%d: got %v want %v", i, got, each.wantCode)
+		}
+		if got := httpWriter.Body.String(); got != each.wantBody {
+			t.Errorf("%d: got %q want %q", i, got, each.wantBody)
+		}
+	}
+}
+
+func TestMethodOverrideFormField(t *testing.T) {
+	wc := newMethodOverrideContainer()
+	httpRequest, _ := http.NewRequest("POST", "/users", strings.NewReader("_method=DELETE&name=x"))
+	httpRequest.Header.Set(HEADER_ContentType, "application/x-www-form-urlencoded")
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "DELETE POST"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestMethodOverrideHeaderName(t *testing.T) {
+	wc := newMethodOverrideContainer()
+	wc.MethodOverrideHeader("X-Method")
+	httpRequest, _ := http.NewRequest("POST", "/users", nil)
+	httpRequest.Header.Set("X-Method", "DELETE")
+	httpRequest.Header.Set(HEADER_XHTTPMethodOverride, "PUT")
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "DELETE POST"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestMethodOverrideDisabled(t *testing.T) {
+	wc := newMethodOverrideContainer()
+	wc.EnableMethodOverride(false)
+	httpRequest, _ := http.NewRequest("POST", "/users", nil)
+	httpRequest.Header.Set(HEADER_XHTTPMethodOverride, "DELETE")
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "POST <nil>"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+// Package metrics provides a restful.FilterFunction that records Prometheus metrics of the requests,
+// labeled with the path of the selected Route such that the number of series does not grow with the paths.
+//
+//	filter, err := metrics.NewInstrumentFilter(prometheus.DefaultRegisterer, metrics.Options{Namespace: "myapp"})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	restful.DefaultContainer.Filter(filter)
+//	http.Handle("/metrics", promhttp.Handler())
+//
+// The metrics are, using the Namespace and Subsystem of the Options as prefix:
+//
+//	http_requests_total              counter    route, method, code
+//	http_request_duration_seconds    histogram  route, method, code
+//	http_requests_in_flight          gauge      route, method
+//	http_request_size_bytes          histogram  route, method
+//	http_response_size_bytes         histogram  route, method
+//
+// The route label is the path of the selected Route, e.g. /users/{userID}, or UnmatchedRoute if none.
+// The method label is one of the standard HTTP methods or "other".
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tangblue/goapi/restful"
+)
+
+// UnmatchedRoute is the route label of the requests for which no Route was selected, e.g. a 404.
+const UnmatchedRoute = "unmatched"
+
+// Options configures the FilterFunction created by NewInstrumentFilter.
+type Options struct {
+	// Namespace and Subsystem prefix the names of the metrics ; default Subsystem is http.
+	Namespace string
+	Subsystem string
+	// ConstLabels are added to all metrics, e.g. the name of the service.
+	ConstLabels prometheus.Labels
+	// DurationBuckets are the buckets in seconds of the duration histogram ; default is prometheus.DefBuckets.
+	DurationBuckets []float64
+	// SizeBuckets are the buckets in bytes of the size histograms ; default is 100B up to 100MB.
+	SizeBuckets []float64
+	// StatusClass labels the status by its class, e.g. 2xx, instead of its code, e.g. 200.
+	StatusClass bool
+}
+
+type instrumentFilter struct {
+	statusClass  bool
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// NewInstrumentFilter registers the metrics with the registerer and returns a FilterFunction that records them.
+// Install it as a Container filter, after the RecoveryFilter if any ; a request whose handling panicked is counted as a 500.
+func NewInstrumentFilter(registerer prometheus.Registerer, opts Options) (restful.FilterFunction, error) {
+	if len(opts.Subsystem) == 0 {
+		opts.Subsystem = "http"
+	}
+	if len(opts.DurationBuckets) == 0 {
+		opts.DurationBuckets = prometheus.DefBuckets
+	}
+	if len(opts.SizeBuckets) == 0 {
+		opts.SizeBuckets = prometheus.ExponentialBuckets(100, 10, 7)
+	}
+	f := &instrumentFilter{
+		statusClass: opts.StatusClass,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "requests_total",
+			Help:        "Number of requests by route, method and status.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "request_duration_seconds",
+			Help:        "Duration of the requests by route, method and status.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.DurationBuckets,
+		}, []string{"route", "method", "code"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "requests_in_flight",
+			Help:        "Number of requests being served by route and method.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"route", "method"}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "request_size_bytes",
+			Help:        "Size of the request bodies by route and method.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.SizeBuckets,
+		}, []string{"route", "method"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "response_size_bytes",
+			Help:        "Size of the response bodies by route and method.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.SizeBuckets,
+		}, []string{"route", "method"}),
+	}
+	for _, each := range []prometheus.Collector{f.requests, f.duration, f.inFlight, f.requestSize, f.responseSize} {
+		if err := registerer.Register(each); err != nil {
+			return nil, err
+		}
+	}
+	return f.filter, nil
+}
+
+func (f *instrumentFilter) filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	route := req.SelectedRoutePath()
+	if len(route) == 0 {
+		route = UnmatchedRoute
+	}
+	method := methodLabel(req.Request.Method)
+	inFlight := f.inFlight.WithLabelValues(route, method)
+	inFlight.Inc()
+	start := time.Now()
+	// stays true if next panics ; the write hooks are called nevertheless
+	panicked := true
+	resp.OnWrite(func(stats restful.ResponseStats) {
+		inFlight.Dec()
+		status := stats.StatusCode
+		if panicked {
+			status = http.StatusInternalServerError
+		}
+		code := f.codeLabel(status)
+		f.requests.WithLabelValues(route, method, code).Inc()
+		f.duration.WithLabelValues(route, method, code).Observe(time.Since(start).Seconds())
+		size := req.Request.ContentLength
+		if size < 0 {
+			// unknown, e.g. chunked
+			size = 0
+		}
+		f.requestSize.WithLabelValues(route, method).Observe(float64(size))
+		f.responseSize.WithLabelValues(route, method).Observe(float64(stats.ContentLength))
+	})
+	next(req, resp)
+	panicked = false
+}
+
+// codeLabel returns the status code, e.g. 404, or its class, e.g. 4xx.
+func (f *instrumentFilter) codeLabel(status int) string {
+	if f.statusClass {
+		return strconv.Itoa(status/100) + "xx"
+	}
+	return strconv.Itoa(status)
+}
+
+// methodLabel returns the method if it is a standard one ; others are not labeled by name to bound the number of series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
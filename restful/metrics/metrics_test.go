@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/tangblue/goapi/restful"
+)
+
+func echo(req *restful.Request, resp *restful.Response) {
+	io.Copy(resp, req.Request.Body)
+}
+
+func panicking(req *restful.Request, resp *restful.Response) {
+	panic("boom")
+}
+
+func newInstrumentedContainer(t *testing.T, registry *prometheus.Registry, opts Options) *restful.Container {
+	filter, err := NewInstrumentFilter(registry, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := restful.NewContainer()
+	wc.DoNotRecover(false)
+	wc.Filter(filter)
+	ws := new(restful.WebService).Path("/users")
+	ws.Route(ws.POST("/{id}").Handler(echo))
+	ws.Route(ws.GET("/{id}/panic").Handler(panicking))
+	if err := wc.Add(ws); err != nil {
+		t.Fatal(err)
+	}
+	return wc
+}
+
+func serve(wc *restful.Container, method, path, body string) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	wc.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// find returns the metric of the family with the labels, or nil.
+func find(families []*dto.MetricFamily, name string, labels map[string]string) *dto.Metric {
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if value, ok := labels[pair.GetName()]; ok && value != pair.GetValue() {
+					continue metrics
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+func TestInstrumentFilter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	wc := newInstrumentedContainer(t, registry, Options{Namespace: "test"})
+	serve(wc, http.MethodPost, "/users/1", "hello")
+	serve(wc, http.MethodPost, "/users/2", "world!")
+	serve(wc, http.MethodGet, "/users/3/panic", "")
+	serve(wc, http.MethodGet, "/users/3/nothing", "")
+	serve(wc, "BREW", "/users/4", "")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, each := range []struct {
+		route, method, code string
+		count               float64
+	}{
+		{"/users/{id}", "POST", "200", 2},
+		{"/users/{id}/panic", "GET", "500", 1},
+		{UnmatchedRoute, "GET", "404", 1},
+		{UnmatchedRoute, "other", "405", 1},
+	} {
+		labels := map[string]string{"route": each.route, "method": each.method, "code": each.code}
+		counter := find(families, "test_http_requests_total", labels)
+		if counter == nil {
+			t.Errorf("no requests_total for %v", labels)
+			continue
+		}
+		if got, want := counter.GetCounter().GetValue(), each.count; got != want {
+			t.Errorf("got %v requests for %v want %v", got, labels, want)
+		}
+		duration := find(families, "test_http_request_duration_seconds", labels)
+		if got, want := duration.GetHistogram().GetSampleCount(), uint64(each.count); got != want {
+			t.Errorf("got %v durations for %v want %v", got, labels, want)
+		}
+	}
+
+	route := map[string]string{"route": "/users/{id}", "method": "POST"}
+	if got, want := find(families, "test_http_request_size_bytes", route).GetHistogram().GetSampleSum(), 11.0; got != want {
+		t.Errorf("got request size %v want %v", got, want)
+	}
+	if got, want := find(families, "test_http_response_size_bytes", route).GetHistogram().GetSampleSum(), 11.0; got != want {
+		t.Errorf("got response size %v want %v", got, want)
+	}
+	if got := find(families, "test_http_requests_in_flight", route).GetGauge().GetValue(); got != 0 {
+		t.Errorf("got %v requests in flight want 0", got)
+	}
+}
+
+func TestInstrumentFilterStatusClass(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	wc := newInstrumentedContainer(t, registry, Options{StatusClass: true})
+	serve(wc, http.MethodPost, "/users/1", "hello")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if find(families, "http_requests_total", map[string]string{"code": "2xx"}) == nil {
+		t.Error("no requests_total with code 2xx")
+	}
+}
+
+func TestInstrumentFilterRegisteredTwice(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := NewInstrumentFilter(registry, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewInstrumentFilter(registry, Options{}); err == nil {
+		t.Error("expected an error registering the metrics twice")
+	}
+}
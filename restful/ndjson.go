@@ -0,0 +1,128 @@
+package restful
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+// NDJSONWriter writes a (possibly large) collection of entities as newline delimited JSON ;
+// one JSON document per line using the MIME_NDJSON Content-Type.
+// Routes using it should declare Produces(restful.MIME_NDJSON) ; the OpenAPI spec marks
+// such operations with the "x-streaming" extension.
+// Example:
+//
+//	stream := resp.NDJSON()
+//	for _, each := range users {
+//		if err := stream.WriteEntity(each); err != nil {
+//			return
+//		}
+//	}
+//	stream.Close()
+type NDJSONWriter struct {
+	resp          *Response
+	FlushInterval int // number of entities between flushes ; zero or less flushes after each entity
+	count         int
+	started       bool
+	err           error
+}
+
+// NDJSON returns a new NDJSONWriter that streams entities on this response.
+func (r *Response) NDJSON() *NDJSONWriter {
+	return &NDJSONWriter{resp: r, FlushInterval: StreamFlushInterval}
+}
+
+// WriteEntity encodes the value as a single line of JSON.
+// It returns an error if the request was canceled by the client or a previous write failed.
+// If the value cannot be encoded once the stream has started then the connection is aborted
+// so that the client does not mistake a truncated stream for a complete one.
+// If the first value cannot be encoded then nothing is written and the error is returned.
+func (w *NDJSONWriter) WriteEntity(value interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.resp.ctx != nil {
+		if err := w.resp.ctx.Err(); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	// encode before writing ; the Encoder appends the newline
+	var buffer bytes.Buffer
+	if err := NewEncoder(&buffer).Encode(value); err != nil {
+		w.err = err
+		if !w.started {
+			// nothing written yet ; the caller can still respond with an error
+			return err
+		}
+		w.abort()
+	}
+	if !w.started {
+		w.started = true
+		w.resp.Header().Set(HEADER_ContentType, MIME_NDJSON)
+		w.resp.WriteHeader(http.StatusOK)
+	}
+	if _, err := w.resp.Write(buffer.Bytes()); err != nil {
+		w.err = err
+		return err
+	}
+	w.count++
+	if w.FlushInterval <= 0 || w.count%w.FlushInterval == 0 {
+		w.resp.Flush()
+	}
+	return nil
+}
+
+// Close flushes any buffered entities. The writer cannot be used afterwards.
+func (w *NDJSONWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.started {
+		// empty stream is valid NDJSON
+		w.started = true
+		w.resp.Header().Set(HEADER_ContentType, MIME_NDJSON)
+		w.resp.WriteHeader(http.StatusOK)
+	}
+	w.resp.Flush()
+	w.err = errNDJSONClosed
+	return nil
+}
+
+// Count returns the number of entities written so far.
+func (w *NDJSONWriter) Count() int {
+	return w.count
+}
+
+var errNDJSONClosed = errors.New("ndjson stream is closed")
+
+// abort terminates the connection ; net/http recognizes this value and does not log a stack.
+func (w *NDJSONWriter) abort() {
+	if trace {
+		traceLogger.Printf("aborting ndjson stream after %d entities: %v", w.count, w.err)
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// StreamEntities writes each value received from the channel using an NDJSONWriter
+// until the channel is closed or the request is canceled.
+func (r *Response) StreamEntities(entities <-chan interface{}) error {
+	stream := r.NDJSON()
+	var done <-chan struct{}
+	if r.ctx != nil {
+		done = r.ctx.Done()
+	}
+	for {
+		select {
+		case each, ok := <-entities:
+			if !ok {
+				return stream.Close()
+			}
+			if err := stream.WriteEntity(each); err != nil {
+				return err
+			}
+		case <-done:
+			return r.ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package restful
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNDJSONWriteEntity(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(httpWriter)
+	stream := resp.NDJSON()
+	stream.WriteEntity(food{"apple"})
+	stream.WriteEntity(food{"pear"})
+	if err := stream.Close(); err != nil {
+		t.Fatalf("unexpected error:%v", err)
+	}
+	if got, want := httpWriter.Body.String(), "{\"Kind\":\"apple\"}\n{\"Kind\":\"pear\"}\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if got, want := httpWriter.Header().Get(HEADER_ContentType), MIME_NDJSON; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if stream.Count() != 2 {
+		t.Errorf("unexpected count:%d", stream.Count())
+	}
+}
+
+func TestNDJSONCanceledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(httpWriter)
+	resp.ctx = ctx
+	stream := resp.NDJSON()
+	stream.WriteEntity(food{"apple"})
+	cancel()
+	if err := stream.WriteEntity(food{"pear"}); err != context.Canceled {
+		t.Errorf("expected canceled, got:%v", err)
+	}
+	if stream.Count() != 1 {
+		t.Errorf("unexpected count:%d", stream.Count())
+	}
+}
+
+func TestNDJSONUnencodableFirstEntity(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(httpWriter)
+	if err := resp.NDJSON().WriteEntity(make(chan int)); err == nil {
+		t.Error("expected encoding error")
+	}
+	if httpWriter.Body.Len() != 0 {
+		t.Errorf("unexpected body:%q", httpWriter.Body.String())
+	}
+}
+
+func TestNDJSONUnencodableEntityAborts(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(httpWriter)
+	stream := resp.NDJSON()
+	stream.WriteEntity(food{"apple"})
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("expected abort, got:%v", r)
+		}
+	}()
+	stream.WriteEntity(make(chan int))
+}
+
+func TestStreamEntities(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(httpWriter)
+	entities := make(chan interface{}, 3)
+	entities <- food{"apple"}
+	entities <- food{"pear"}
+	entities <- food{"plum"}
+	close(entities)
+	if err := resp.StreamEntities(entities); err != nil {
+		t.Fatalf("unexpected error:%v", err)
+	}
+	if got, want := httpWriter.Body.String(), "{\"Kind\":\"apple\"}\n{\"Kind\":\"pear\"}\n{\"Kind\":\"plum\"}\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -1,10 +1,13 @@
 package restful
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/tangblue/goapi/spec"
 )
@@ -56,9 +59,13 @@ func (cf CollectionFormat) String() string {
 // ParameterData kinds are Path,Query and Body
 type Parameter struct {
 	spec.Parameter
-	Model   interface{}
-	regex   *regexp.Regexp
-	RefName string
+	Model       interface{}
+	regex       *regexp.Regexp
+	RefName     string
+	defaultFunc func() interface{} // see DefaultFunc
+	encoding    *base64.Encoding   // see Base64Encoding
+	sensitive   bool               // see Sensitive
+	partial     bool               // see Partial
 }
 
 func (p *Parameter) String() string {
@@ -111,11 +118,106 @@ func (p *Parameter) WithCollectionFormat(format CollectionFormat) *Parameter {
 	return p
 }
 
+// ArrayOf sets the parameter to be an array of items of the type of the model, e.g. "" for strings,
+// separated as specified by the collection format, e.g. colors=red,green using CollectionFormatCSV.
+// GetParameter validates each item, e.g. against the values of EnumFromValues.
+func (p *Parameter) ArrayOf(itemType interface{}, format CollectionFormat) *Parameter {
+	p.Model = itemType
+	return p.WithCollectionFormat(format)
+}
+
+// DataType sets the model whose type documents the parameter.
+// Its value is the default of an optional parameter and, unless WithExampleValue is used, the example of a required one.
 func (p *Parameter) DataType(model interface{}) *Parameter {
 	p.Model = model
 	return p
 }
 
+// DefaultFunc sets the function that returns the value of the optional parameter if it is missing from a request,
+// e.g. the current time ; GetParameter calls it for each such request instead of using the Default.
+func (p *Parameter) DefaultFunc(f func() interface{}) *Parameter {
+	p.defaultFunc = f
+	return p
+}
+
+// Base64Encoding sets the encoding of the values bound to []byte by GetParameter ; default is base64.StdEncoding.
+func (p *Parameter) Base64Encoding(encoding *base64.Encoding) *Parameter {
+	p.encoding = encoding
+	return p
+}
+
+// Sensitive marks the parameter as holding a secret, e.g. a password or a token, whose values must not be logged.
+// See SensitiveFields ; the AccessLogFilter redacts the values of sensitive path parameters.
+func (p *Parameter) Sensitive() *Parameter {
+	p.sensitive = true
+	return p
+}
+
+// IsSensitive returns whether the parameter holds a secret, see Sensitive.
+func (p *Parameter) IsSensitive() bool {
+	return p.sensitive
+}
+
+// Partial documents the model of the body parameter with all its fields optional, e.g. for a PATCH request
+// that only has the fields to update. See RouteBuilder.ReadPartial.
+func (p *Parameter) Partial() *Parameter {
+	p.partial = true
+	return p
+}
+
+// IsPartial returns whether the model of the body parameter has all its fields optional, see Partial.
+func (p *Parameter) IsPartial() bool {
+	return p.partial
+}
+
+// defaultValue returns the value of the parameter if it is missing from a request, nil if none.
+func (p *Parameter) defaultValue() interface{} {
+	if p.defaultFunc != nil {
+		return p.defaultFunc()
+	}
+	return p.Default
+}
+
+// setDefault sets out to the value of the parameter if it is missing from a request, if any.
+// The value must be assignable or convertible to the type of out.
+func (p *Parameter) setDefault(out interface{}) error {
+	value := p.defaultValue()
+	if value == nil {
+		return nil
+	}
+	target := reflect.ValueOf(out).Elem()
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(target.Type()):
+		target.Set(v)
+	case v.Type().ConvertibleTo(target.Type()) && (target.Kind() != reflect.String || v.Kind() == reflect.String):
+		// e.g. an int to an int64 or a string to a named string type, not an int to a string
+		target.Set(v.Convert(target.Type()))
+	default:
+		return fmt.Errorf("default of %s: %s is not assignable to %s", p.Name, v.Type(), target.Type())
+	}
+	return nil
+}
+
+// WithExampleValue sets the example value documented for the parameter, independent of its default.
+func (p *Parameter) WithExampleValue(example interface{}) *Parameter {
+	p.Example = example
+	return p
+}
+
+// EnumFromValues sets the values the parameter is restricted to, e.g. the typed constants of an iota-based type.
+// They are documented as the enum of the parameter and GetParameter rejects any other value.
+// All values must have the same kind ; use DataType to document the type of the parameter.
+func (p *Parameter) EnumFromValues(values ...interface{}) *Parameter {
+	for _, each := range values {
+		if reflect.ValueOf(each).Kind() != reflect.ValueOf(values[0]).Kind() {
+			panic("Enum values of different kinds: " + p.Name)
+		}
+	}
+	p.WithEnum(values...)
+	return p
+}
+
 func (p *Parameter) Regex(regex string) *Parameter {
 	r, err := regexp.Compile(regex)
 	if err != nil {
@@ -131,6 +233,21 @@ func (p *Parameter) SetRefName(refName string) *Parameter {
 	return p
 }
 
+// Extension adds a vendor extension to the documentation of the parameter, e.g. x-internal.
+// The key must start with "x-".
+func (p *Parameter) Extension(key string, value interface{}) *Parameter {
+	checkExtensionKey(key)
+	p.AddExtension(key, value)
+	return p
+}
+
+// checkExtensionKey panics if the key is not the name of a vendor extension.
+func checkExtensionKey(key string) {
+	if !strings.HasPrefix(strings.ToLower(key), "x-") {
+		panic("Bad extension key, must start with x-: " + key)
+	}
+}
+
 var (
 	errLTMin      = errors.New("less than minimum")
 	errGTMax      = errors.New("great than maximum")
@@ -144,6 +261,12 @@ func (p *Parameter) getValue(s []string, out interface{}) error {
 	t := reflect.TypeOf(out).Elem()
 	v := reflect.ValueOf(out).Elem()
 
+	if isBytes(t) {
+		return p.getElemValue(s[0], v)
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		s = p.splitCollection(s)
+	}
 	switch t.Kind() {
 	case reflect.Slice:
 		l := len(s)
@@ -168,6 +291,28 @@ func (p *Parameter) getValue(s []string, out interface{}) error {
 	return nil
 }
 
+// splitCollection returns the items of the values, separated as specified by the collection format.
+func (p *Parameter) splitCollection(values []string) []string {
+	var separator string
+	switch CollectionFormat(p.CollectionFormat) {
+	case CollectionFormatCSV:
+		separator = ","
+	case CollectionFormatSSV:
+		separator = " "
+	case CollectionFormatTSV:
+		separator = "\t"
+	case CollectionFormatPipes:
+		separator = "|"
+	default:
+		return values
+	}
+	items := []string{}
+	for _, each := range values {
+		items = append(items, strings.Split(each, separator)...)
+	}
+	return items
+}
+
 func (p *Parameter) getElemValue(s string, out reflect.Value) error {
 	switch out.Type().Kind() {
 	case reflect.String:
@@ -198,6 +343,11 @@ func (p *Parameter) getElemValue(s string, out reflect.Value) error {
 		return p.validateValueFloat(s, 32, out)
 	case reflect.Float64:
 		return p.validateValueFloat(s, 64, out)
+
+	case reflect.Slice:
+		if isBytes(out.Type()) {
+			return p.validateValueBytes(s, out)
+		}
 	}
 
 	return errors.New("unknown type")
@@ -208,9 +358,14 @@ func (p *Parameter) validateEnum(v reflect.Value) error {
 		return nil
 	}
 
+	// compare values of the same kind, such as Status(1) and int(1), as values of the type of v
 	vi := v.Interface()
 	for _, e := range p.Enum {
-		if vi == e {
+		ev := reflect.ValueOf(e)
+		if ev.Kind() != v.Kind() || !ev.Type().ConvertibleTo(v.Type()) {
+			continue
+		}
+		if ev.Convert(v.Type()).Interface() == vi {
 			return nil
 		}
 	}
@@ -283,3 +438,28 @@ func (p *Parameter) validateValueFloat(s string, bits int, out reflect.Value) er
 
 	return p.validateEnum(out)
 }
+
+// validateValueBytes decodes the base64 value, see Base64Encoding ; the length of the value is validated as for a string.
+func (p *Parameter) validateValueBytes(s string, out reflect.Value) error {
+	if p.MinLength != nil && len(s) < *p.MinLength {
+		return errTooShort
+	} else if p.MaxLength != nil && len(s) > *p.MaxLength {
+		return errTooLong
+	}
+	encoding := p.encoding
+	if encoding == nil {
+		encoding = base64.StdEncoding
+	}
+	v, err := encoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	out.SetBytes(v)
+
+	return nil
+}
+
+// isBytes returns whether the type is a slice of bytes, e.g. []byte, bound to a base64 value.
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
@@ -0,0 +1,182 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type testStatus int
+
+const (
+	testStatusActive testStatus = iota + 1
+	testStatusSuspended
+)
+
+func TestParameterEnumFromValues(t *testing.T) {
+	p := QueryParameter("status", "status of the user").
+		DataType(0).
+		EnumFromValues(testStatusActive, testStatusSuspended)
+	if got, want := len(p.Enum), 2; got != want {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for _, each := range []struct {
+		query string
+		ok    bool
+	}{
+		{"status=1", true},
+		{"status=2", true},
+		{"status=3", false},
+	} {
+		httpRequest, _ := http.NewRequest("GET", "/users?"+each.query, nil)
+		request := NewRequest(httpRequest)
+		var typed testStatus
+		if err := request.GetParameter(p, &typed); (err == nil) != each.ok {
+			t.Errorf("%s: unexpected error %v", each.query, err)
+		}
+		var plain int
+		if err := request.GetParameter(p, &plain); (err == nil) != each.ok {
+			t.Errorf("%s: unexpected error %v for int", each.query, err)
+		}
+	}
+}
+
+func TestParameterEnumFromValuesOfDifferentKinds(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for enum values of different kinds")
+		}
+	}()
+	QueryParameter("status", "").EnumFromValues(testStatusActive, "suspended")
+}
+
+func TestParameterExtensionKey(t *testing.T) {
+	p := QueryParameter("debug", "").Extension("X-Internal", true)
+	if internal, _ := p.Extensions.GetBool("x-internal"); !internal {
+		t.Errorf("expected x-internal extension, got %v", p.Extensions)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a key without x- prefix")
+		}
+	}()
+	p.Extension("internal", true)
+}
+
+func TestGetParameterMultiValuedHeader(t *testing.T) {
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	httpRequest.Header.Add("X-Forwarded-For", "10.0.0.1")
+	httpRequest.Header.Add("x-forwarded-for", "10.0.0.2")
+	request := NewRequest(httpRequest)
+
+	var all []string
+	multi := HeaderParameter("x-Forwarded-for", "").WithCollectionFormat(CollectionFormatMulti)
+	if err := request.GetParameter(multi, &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0] != "10.0.0.1" || all[1] != "10.0.0.2" {
+		t.Errorf("got %v want [10.0.0.1 10.0.0.2]", all)
+	}
+
+	var first string
+	if err := request.GetParameter(HeaderParameter("X-Forwarded-For", ""), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first != "10.0.0.1" {
+		t.Errorf("got %q want 10.0.0.1", first)
+	}
+}
+
+func TestParameterArrayOfEnum(t *testing.T) {
+	p := QueryParameter("colors", "colors of the item").
+		ArrayOf("", CollectionFormatCSV).
+		EnumFromValues("red", "green", "blue")
+	for _, each := range []struct {
+		query string
+		want  []string
+		ok    bool
+	}{
+		{"colors=red,green", []string{"red", "green"}, true},
+		{"colors=blue", []string{"blue"}, true},
+		{"colors=red,green,purple", nil, false},
+		{"", nil, true},
+	} {
+		httpRequest, _ := http.NewRequest("GET", "/items?"+each.query, nil)
+		var colors []string
+		err := NewRequest(httpRequest).GetParameter(p, &colors)
+		if (err == nil) != each.ok {
+			t.Errorf("%s: unexpected error %v", each.query, err)
+			continue
+		}
+		if each.ok && !reflect.DeepEqual(colors, each.want) {
+			t.Errorf("%s: got %v want %v", each.query, colors, each.want)
+		}
+	}
+}
+
+// counter returns the next value each call, as a default of a parameter
+type counter struct {
+	calls int
+}
+
+func (c *counter) next() interface{} {
+	c.calls++
+	return c.calls
+}
+
+func TestGetParameterDefaultFunc(t *testing.T) {
+	request := NewRequest(httptest.NewRequest("GET", "/events", nil))
+	c := &counter{}
+	asOf := QueryParameter("asOf", "").DefaultFunc(c.next)
+	for _, want := range []int64{1, 2} {
+		var got int64
+		if err := request.GetParameter(asOf, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %d want %d", got, want)
+		}
+	}
+
+	var given int64
+	request = NewRequest(httptest.NewRequest("GET", "/events?asOf=7", nil))
+	if err := request.GetParameter(asOf, &given); err != nil || given != 7 {
+		t.Errorf("got %d, %v want 7", given, err)
+	}
+	if c.calls != 2 {
+		t.Errorf("got %d calls want 2", c.calls)
+	}
+
+	var name string
+	if err := request.GetParameter(QueryParameter("name", "").DefaultFunc(c.next), &name); err == nil {
+		t.Errorf("expected error for an int default of a string, got %q", name)
+	}
+}
+
+func TestGetParameterBase64(t *testing.T) {
+	httpRequest, _ := http.NewRequest("GET", "/files?digest=aGVsbG8gd29ybGQ%3D&id=-_8&bad=!!", nil)
+	request := NewRequest(httpRequest)
+
+	var query struct {
+		Digest []byte
+		ID     []byte
+	}
+	if err := request.GetParameter(QueryParameter("digest", ""), &query.Digest); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(query.Digest); got != "hello world" {
+		t.Errorf("got %q want hello world", got)
+	}
+	if err := request.GetParameter(QueryParameter("id", "").Base64Encoding(base64.RawURLEncoding), &query.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(query.ID, []byte{0xfb, 0xff}) {
+		t.Errorf("got %v want [251 255]", query.ID)
+	}
+	if err := request.GetParameter(QueryParameter("bad", ""), &query.Digest); err == nil {
+		t.Error("got no error for a value that is not base64")
+	}
+}
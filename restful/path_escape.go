@@ -0,0 +1,98 @@
+package restful
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// EncodedSlashPolicy tells a Container how to handle an escaped slash (%2F) in the path of a request.
+//
+// Routes are matched with the segments of the escaped path, each decoded once ; an escaped slash does not
+// separate segments. This requires a CurlyRouter or a TrieRouter. The value of a path parameter is its decoded
+// segment, e.g. "a/b" for a%2Fb and "%41" for %2541, unless the Router has a PathProcessor that is not
+// an EscapedPathProcessor.
+type EncodedSlashPolicy int
+
+const (
+	// EncodedSlashAccept makes an escaped slash part of its segment, e.g. of the value of a path parameter.
+	EncodedSlashAccept EncodedSlashPolicy = iota
+	// EncodedSlashReject responds with 400 Bad Request to a request with an escaped slash in its path.
+	EncodedSlashReject
+)
+
+// EncodedSlashes sets how to handle an escaped slash (%2F) in the path of a request ; default is EncodedSlashAccept.
+func (c *Container) EncodedSlashes(policy EncodedSlashPolicy) {
+	c.encodedSlashes = policy
+}
+
+// checkEscapedPath returns a 400 ServiceError if the path of the request has an escaped slash that the Container
+// rejects or a segment that is not valid UTF-8 once decoded, e.g. the over-long encoding %C0%AF of a slash.
+func (c *Container) checkEscapedPath(httpRequest *http.Request) error {
+	escaped := httpRequest.URL.EscapedPath()
+	if c.encodedSlashes == EncodedSlashReject && strings.Contains(strings.ToUpper(escaped), "%2F") {
+		return NewError(http.StatusBadRequest, "400: Bad Request, escaped slash in path")
+	}
+	if _, err := decodePathTokens(escaped); err != nil {
+		return NewError(http.StatusBadRequest, "400: Bad Request, "+err.Error())
+	}
+	return nil
+}
+
+// decodePathTokens returns the segments of the escaped path, each decoded once.
+func decodePathTokens(escapedPath string) ([]string, error) {
+	tokens := tokenizePath(escapedPath)
+	for i, each := range tokens {
+		decoded, err := url.PathUnescape(each)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path segment %q: %v", each, err)
+		}
+		if !utf8.ValidString(decoded) {
+			return nil, fmt.Errorf("path segment %q is not valid UTF-8", each)
+		}
+		tokens[i] = decoded
+	}
+	return tokens, nil
+}
+
+// requestPathTokens returns the segments of the path of the request, see EncodedSlashPolicy.
+// If the escaped path cannot be decoded, they are the segments of the decoded path.
+func requestPathTokens(httpRequest *http.Request) []string {
+	if tokens, err := decodePathTokens(httpRequest.URL.EscapedPath()); err == nil {
+		return tokens
+	}
+	return tokenizePath(httpRequest.URL.Path)
+}
+
+// pathParameters returns the values of the path parameters of the Route, decoded once, and as they appear
+// in the escaped path. The RouterJSR311 matches the decoded path ; its values are not decoded again.
+// A PathProcessor that is not an EscapedPathProcessor is given the decoded path only.
+func (c *Container) pathParameters(pathProcessor PathProcessor, route *Route, webService *WebService, httpRequest *http.Request) (decoded, raw map[string]string) {
+	escapedProcessor, extractsEscaped := pathProcessor.(EscapedPathProcessor)
+	if !extractsEscaped {
+		return pathProcessor.ExtractParameters(route, webService, httpRequest.URL.Path), nil
+	}
+	if _, matchesDecoded := c.router.(RouterJSR311); matchesDecoded {
+		decoded = pathProcessor.ExtractParameters(route, webService, httpRequest.URL.Path)
+		if route.hasCatchAll() {
+			raw = escapedProcessor.ExtractEscapedParameters(route, webService, httpRequest.URL.EscapedPath())
+		}
+		return decoded, raw
+	}
+	raw = escapedProcessor.ExtractEscapedParameters(route, webService, httpRequest.URL.EscapedPath())
+	return decodePathParameters(raw), raw
+}
+
+// decodePathParameters returns the values of the path parameters, extracted from the escaped path, decoded once.
+func decodePathParameters(rawParameters map[string]string) map[string]string {
+	decoded := make(map[string]string, len(rawParameters))
+	for name, value := range rawParameters {
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		decoded[name] = value
+	}
+	return decoded
+}
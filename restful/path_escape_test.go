@@ -0,0 +1,92 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newEscapedPathContainer(router RouteSelector) *Container {
+	wc := NewContainer()
+	wc.Router(router)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{id}").Handler(writeUserId))
+	ws.Route(ws.GET("/{id}/files/{path:*}").Handler(writeFilePath))
+	wc.Add(ws)
+	return wc
+}
+
+func TestEscapedPathParameters(t *testing.T) {
+	for _, router := range []RouteSelector{CurlyRouter{}, TrieRouter{}} {
+		wc := newEscapedPathContainer(router)
+		for _, each := range []struct {
+			path, body string
+		}{
+			{"/users/a%2Fb", "a/b"},
+			{"/users/a%2fb", "a/b"},
+			{"/users/100%25", "100%"},
+			{"/users/%2541", "%41"}, // decoded once
+			{"/users/Jos%C3%A9", "José"},
+			{"/users/José", "José"},
+			{"/users/%E6%9D%B1%E4%BA%AC", "東京"},
+			{"/users/1/files/a%2Fb/c", "a/b/c|a%2Fb/c"},
+		} {
+			recorder := httptest.NewRecorder()
+			wc.ServeHTTP(recorder, httptest.NewRequest("GET", each.path, nil))
+			if recorder.Code != http.StatusOK {
+				t.Errorf("%T %s: got status %d want 200", router, each.path, recorder.Code)
+				continue
+			}
+			if got := recorder.Body.String(); got != each.body {
+				t.Errorf("%T %s: got %q want %q", router, each.path, got, each.body)
+			}
+		}
+	}
+}
+
+// decodedPathRouter has a PathProcessor that is not an EscapedPathProcessor ; the id is the path it is given
+type decodedPathRouter struct {
+	CurlyRouter
+}
+
+func (decodedPathRouter) ExtractParameters(route *Route, webService *WebService, urlPath string) map[string]string {
+	return map[string]string{"id": urlPath}
+}
+
+func TestPathProcessorDecodedPath(t *testing.T) {
+	wc := newEscapedPathContainer(decodedPathRouter{})
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/Jos%C3%A9", nil))
+	if got, want := recorder.Body.String(), "/users/José"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestEscapedPathRejected(t *testing.T) {
+	wc := newEscapedPathContainer(CurlyRouter{})
+	for _, each := range []string{
+		"/users/%C0%AF",       // over-long encoding of a slash
+		"/users/%C0%AE%C0%AE", // over-long encoding of ..
+		"/users/%FF",
+	} {
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httptest.NewRequest("GET", each, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d want 400", each, recorder.Code)
+		}
+	}
+
+	wc.EncodedSlashes(EncodedSlashReject)
+	for _, each := range []string{"/users/a%2Fb", "/users/a%2fb", "/users/1/files/a%2Fb/c"} {
+		recorder := httptest.NewRecorder()
+		wc.ServeHTTP(recorder, httptest.NewRequest("GET", each, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d want 400", each, recorder.Code)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/100%25", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "100%" {
+		t.Errorf("got %d %q want 200 \"100%%\"", recorder.Code, recorder.Body.String())
+	}
+}
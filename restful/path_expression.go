@@ -20,17 +20,68 @@ type pathExpression struct {
 	Matcher      *regexp.Regexp
 	Source       string // Path as defined by the RouteBuilder
 	tokens       []string
+	foldMatcher  *regexp.Regexp // like Matcher but with case-insensitive literals
+	// the anchored expressions of the parameter tokens with one, e.g. ^(?:[0-9]+)$ for {id:[0-9]+}, by parameter name
+	valueMatchers map[string]*regexp.Regexp
 }
 
 // NewPathExpression creates a PathExpression from the input URL path.
 // Returns an error if the path is invalid.
 func newPathExpression(path string) (*pathExpression, error) {
 	expression, literalCount, varNames, varCount, tokens := templateToRegularExpression(path)
+	if err := validateCatchAll(tokens); err != nil {
+		return nil, err
+	}
 	compiled, err := regexp.Compile(expression)
 	if err != nil {
 		return nil, err
 	}
-	return &pathExpression{literalCount, varNames, varCount, compiled, expression, tokens}, nil
+	folded, err := regexp.Compile(templateToFoldedExpression(tokens))
+	if err != nil {
+		return nil, err
+	}
+	valueMatchers := map[string]*regexp.Regexp{}
+	for _, each := range tokens {
+		colon := strings.Index(each, ":")
+		if !strings.HasPrefix(each, "{") || colon == -1 || isCatchAllToken(each) {
+			continue
+		}
+		matcher, err := regexp.Compile("^(?:" + strings.TrimSpace(each[colon+1:len(each)-1]) + ")$")
+		if err != nil {
+			return nil, err
+		}
+		valueMatchers[strings.TrimSpace(each[1:colon])] = matcher
+	}
+	return &pathExpression{literalCount, varNames, varCount, compiled, expression, tokens, folded, valueMatchers}, nil
+}
+
+// matcher returns the regular expression to match paths with, optionally ignoring the case of literals.
+// Parameter expressions are always case-sensitive.
+func (p *pathExpression) matcher(ignoreCase bool) *regexp.Regexp {
+	if ignoreCase {
+		return p.foldMatcher
+	}
+	return p.Matcher
+}
+
+// isCatchAllToken returns whether the path token is a parameter that greedily matches
+// the remainder of the path, including slashes, e.g. {path:*}
+func isCatchAllToken(token string) bool {
+	if !strings.HasPrefix(token, "{") || !strings.HasSuffix(token, "}") {
+		return false
+	}
+	colon := strings.Index(token, ":")
+	return colon != -1 && strings.TrimSpace(token[colon+1:len(token)-1]) == "*"
+}
+
+// validateCatchAll returns an error if a catch-all parameter is not the last of the path tokens.
+func validateCatchAll(tokens []string) error {
+	for i, each := range tokens {
+		if isCatchAllToken(each) && i != len(tokens)-1 {
+			return fmt.Errorf("catch-all parameter %s must be the last path segment", each)
+		}
+	}
+	return nil
 }
 
 // http://jsr311.java.net/nonav/releases/1.1/spec/spec3.html#x3-370003.7.3
@@ -45,23 +96,8 @@ func templateToRegularExpression(template string) (expression string, literalCou
 		}
 		buffer.WriteString("/")
 		if strings.HasPrefix(each, "{") {
-			// check for regular expression in variable
-			colon := strings.Index(each, ":")
-			var varName string
-			if colon != -1 {
-				// extract expression
-				varName = strings.TrimSpace(each[1:colon])
-				paramExpr := strings.TrimSpace(each[colon+1 : len(each)-1])
-				if paramExpr == "*" { // special case
-					buffer.WriteString("(.*)")
-				} else {
-					buffer.WriteString(fmt.Sprintf("(%s)", paramExpr)) // between colon and closing moustache
-				}
-			} else {
-				// plain var
-				varName = strings.TrimSpace(each[1 : len(each)-1])
-				buffer.WriteString("([^/]+?)")
-			}
+			varName, paramExpr := parameterToRegularExpression(each)
+			buffer.WriteString(paramExpr)
 			varNames = append(varNames, varName)
 			varCount += 1
 		} else {
@@ -72,3 +108,39 @@ func templateToRegularExpression(template string) (expression string, literalCou
 	}
 	return strings.TrimRight(buffer.String(), "/") + "(/.*)?$", literalCount, varNames, varCount, tokens
 }
+
+// parameterToRegularExpression returns the name and the regular expression of a path parameter token, e.g. {id} or {id:[0-9]+}
+func parameterToRegularExpression(token string) (varName string, expression string) {
+	// check for regular expression in variable
+	colon := strings.Index(token, ":")
+	if colon == -1 {
+		// plain var
+		return strings.TrimSpace(token[1 : len(token)-1]), "([^/]+?)"
+	}
+	// extract expression
+	varName = strings.TrimSpace(token[1:colon])
+	paramExpr := strings.TrimSpace(token[colon+1 : len(token)-1])
+	if paramExpr == "*" { // special case
+		return varName, "(.*)"
+	}
+	return varName, fmt.Sprintf("(%s)", paramExpr) // between colon and closing moustache
+}
+
+// templateToFoldedExpression is like templateToRegularExpression but literals are matched case-insensitively.
+func templateToFoldedExpression(tokens []string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("^")
+	for _, each := range tokens {
+		if each == "" {
+			continue
+		}
+		buffer.WriteString("/")
+		if strings.HasPrefix(each, "{") {
+			_, paramExpr := parameterToRegularExpression(each)
+			buffer.WriteString(paramExpr)
+		} else {
+			buffer.WriteString("(?i:" + regexp.QuoteMeta(each) + ")")
+		}
+	}
+	return strings.TrimRight(buffer.String(), "/") + "(/.*)?$"
+}
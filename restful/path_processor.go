@@ -12,12 +12,27 @@ import (
 // PathProcessor is extra behaviour that a Router can provide to extract path parameters from the path.
 // If a Router does not implement this interface then the default behaviour will be used.
 type PathProcessor interface {
-	// ExtractParameters gets the path parameters defined in the route and webService from the urlPath
+	// ExtractParameters gets the path parameters defined in the route and webService from the urlPath.
+	// The urlPath is decoded, see EscapedPathProcessor.
 	ExtractParameters(route *Route, webService *WebService, urlPath string) map[string]string
 }
 
+// EscapedPathProcessor is a PathProcessor that also extracts the path parameters from the escaped path,
+// e.g. /files/a%2Fb, such that an escaped slash stays part of a value ; the Container decodes the values once.
+// The path parameters of a PathProcessor that does not implement it are extracted from the decoded path.
+type EscapedPathProcessor interface {
+	PathProcessor
+	// ExtractEscapedParameters is like ExtractParameters but the escapedPath is escaped, see EncodedSlashPolicy.
+	ExtractEscapedParameters(route *Route, webService *WebService, escapedPath string) map[string]string
+}
+
 type defaultPathProcessor struct{}
 
+// ExtractEscapedParameters is part of EscapedPathProcessor ; the segments of the path are the same.
+func (d defaultPathProcessor) ExtractEscapedParameters(r *Route, webService *WebService, escapedPath string) map[string]string {
+	return d.ExtractParameters(r, webService, escapedPath)
+}
+
 // Extract the parameters from the request url path
 func (d defaultPathProcessor) ExtractParameters(r *Route, _ *WebService, urlPath string) map[string]string {
 	urlParts := tokenizePath(urlPath)
@@ -0,0 +1,218 @@
+package restful
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tangblue/goapi/restful/log"
+)
+
+// RateLimit is the rate of the requests of a client allowed by the RateLimitFilter.
+type RateLimit struct {
+	// Rate is the number of requests per second in the long run, e.g. 0.5 for a request every 2 seconds.
+	// A zero Rate does not limit the requests.
+	Rate float64
+	// Burst is the number of requests allowed at once ; default is the Rate rounded up.
+	Burst int
+}
+
+// burst returns the size of the token bucket, at least 1.
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// RateLimitOptions configures the FilterFunction created by RateLimitFilter.
+type RateLimitOptions struct {
+	// Limit applies to the Routes without a RateLimit of their own, see RouteBuilder.RateLimit.
+	Limit RateLimit
+	// Key, if set, returns the client of a request, e.g. the subject set by an authentication filter.
+	// If not set, or if it returns an empty key, the client is identified by its IP address.
+	Key func(req *Request) string
+	// TrustedProxies are the addresses or CIDRs, e.g. 10.0.0.0/8, of the proxies in front of the service.
+	// The client IP of a request from a trusted proxy is the rightmost address of the X-Forwarded-For header
+	// that is not a trusted proxy ; the addresses before it are set by the client and are not used.
+	TrustedProxies []string
+	// IdleTimeout is the time after which the state of a client without requests is removed ; default is 10 minutes.
+	// The state is kept until its requests are allowed at once again.
+	IdleTimeout time.Duration
+}
+
+// RateLimitFilter returns a FilterFunction that limits the rate of the requests of each client using a token bucket.
+// A request over the limit gets 429 Too Many Requests, through the ServiceErrorHandler of the Container,
+// with the Retry-After header. The responses to the limited requests have the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers. A Route with a RateLimit of its own, see RouteBuilder.RateLimit,
+// has a token bucket per client of its own. Install it as a Container filter.
+func RateLimitFilter(opts RateLimitOptions) FilterFunction {
+	return newRateLimiter(opts).filter
+}
+
+type rateLimiter struct {
+	opts      RateLimitOptions
+	proxies   []*net.IPNet
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket has the tokens of a client ; each request takes one.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time // when the tokens were last updated
+	full   time.Time // when the bucket is full again
+}
+
+func newRateLimiter(opts RateLimitOptions) *rateLimiter {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 10 * time.Minute
+	}
+	return &rateLimiter{opts: opts, proxies: parseTrustedProxies(opts.TrustedProxies), now: time.Now, buckets: map[string]*tokenBucket{}}
+}
+
+// parseTrustedProxies returns the networks of the addresses and CIDRs ; an invalid one is logged and skipped.
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, each := range proxies {
+		if !strings.Contains(each, "/") {
+			ip := net.ParseIP(each)
+			if ip == nil {
+				log.Printf("warning: invalid trusted proxy %q is ignored", each)
+				continue
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(each)
+		if err != nil {
+			log.Printf("warning: invalid trusted proxy %q is ignored: %v", each, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// trusted returns whether the address is the one of a trusted proxy.
+func (l *rateLimiter) trusted(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, each := range l.proxies {
+		if each.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client without the port. If the request comes from a trusted proxy,
+// it is the rightmost address of the X-Forwarded-For header that is not a trusted proxy.
+func (l *rateLimiter) clientIP(req *Request) string {
+	client := clientIP(req, false)
+	if !l.trusted(client) {
+		return client
+	}
+	forwarded := strings.Split(strings.Join(req.Request.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwarded[i])
+		if len(address) == 0 {
+			continue
+		}
+		client = address
+		if !l.trusted(address) {
+			break
+		}
+	}
+	return client
+}
+
+func (l *rateLimiter) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	limit, key := l.opts.Limit, ""
+	if routeLimit, ok := req.routeMetadata[KeyRateLimit].(RateLimit); ok {
+		limit, key = routeLimit, req.Request.Method+" "+req.SelectedRoutePath()+" "
+	}
+	if limit.Rate <= 0 {
+		next(req, resp)
+		return
+	}
+	client := ""
+	if l.opts.Key != nil {
+		client = l.opts.Key(req)
+	}
+	if len(client) == 0 {
+		client = l.clientIP(req)
+	}
+	allowed, remaining, reset, retry := l.take(key+client, limit)
+	header := resp.Header()
+	header.Set(HEADER_RateLimitLimit, strconv.Itoa(int(limit.burst())))
+	header.Set(HEADER_RateLimitRemaining, strconv.Itoa(remaining))
+	header.Set(HEADER_RateLimitReset, strconv.Itoa(ceilSeconds(reset)))
+	if !allowed {
+		header.Set(HEADER_RetryAfter, strconv.Itoa(ceilSeconds(retry)))
+		resp.WriteErrorString(http.StatusTooManyRequests, "429: Too Many Requests")
+		return
+	}
+	next(req, resp)
+}
+
+// take takes a token from the bucket of the key, if it has one. Returns whether it did, the number of tokens left,
+// the time until the bucket is full and, if not allowed, the time until it has a token.
+func (l *rateLimiter) take(key string, limit RateLimit) (allowed bool, remaining int, reset, retry time.Duration) {
+	now := l.now()
+	burst := limit.burst()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*limit.Rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		allowed = true
+		bucket.tokens--
+	} else {
+		retry = secondsDuration((1 - bucket.tokens) / limit.Rate)
+	}
+	reset = secondsDuration((burst - bucket.tokens) / limit.Rate)
+	bucket.full = now.Add(reset)
+	return allowed, int(bucket.tokens), reset, retry
+}
+
+// sweep removes the buckets that are full and without requests for the IdleTimeout ; at most once per IdleTimeout.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.opts.IdleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, each := range l.buckets {
+		if now.Sub(each.last) >= l.opts.IdleTimeout && !now.Before(each.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// secondsDuration returns the seconds as a Duration.
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ceilSeconds returns the Duration in seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
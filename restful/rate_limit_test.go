@@ -0,0 +1,211 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is the time of a rateLimiter in tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newRateLimitContainer(opts RateLimitOptions) (*Container, *rateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	limiter := newRateLimiter(opts)
+	limiter.now = clock.Now
+	wc := NewContainer()
+	wc.Filter(setSubject)
+	wc.Filter(limiter.filter)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{id}").Handler(writeUserId))
+	ws.Route(ws.POST("/{id}/login").Handler(writeUserId).RateLimit(RateLimit{Rate: 0.1}))
+	wc.Add(ws)
+	return wc, limiter, clock
+}
+
+func serveFrom(wc *Container, method, path, remoteAddr string) *httptest.ResponseRecorder {
+	httpRequest := httptest.NewRequest(method, path, nil)
+	httpRequest.RemoteAddr = remoteAddr
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	return httpWriter
+}
+
+func TestRateLimitFilter(t *testing.T) {
+	wc, _, clock := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 1, Burst: 2}})
+	for i, want := range []struct {
+		code      int
+		remaining string
+		reset     string
+	}{
+		{200, "1", "1"},
+		{200, "0", "2"},
+		{429, "0", "2"},
+	} {
+		httpWriter := serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234")
+		if got := httpWriter.Code; got != want.code {
+			t.Errorf("%d: got status %d want %d", i, got, want.code)
+		}
+		header := httpWriter.Header()
+		if got := header.Get(HEADER_RateLimitLimit); got != "2" {
+			t.Errorf("%d: got limit %q want 2", i, got)
+		}
+		if got := header.Get(HEADER_RateLimitRemaining); got != want.remaining {
+			t.Errorf("%d: got remaining %q want %q", i, got, want.remaining)
+		}
+		if got := header.Get(HEADER_RateLimitReset); got != want.reset {
+			t.Errorf("%d: got reset %q want %q", i, got, want.reset)
+		}
+		if got, retry := header.Get(HEADER_RetryAfter), want.code == 429; (got == "1") != retry {
+			t.Errorf("%d: got Retry-After %q", i, got)
+		}
+	}
+	// other clients have buckets of their own
+	if got := serveFrom(wc, "GET", "/users/1", "192.0.2.2:1234").Code; got != 200 {
+		t.Errorf("got status %d for another client want 200", got)
+	}
+	clock.advance(time.Second)
+	if got := serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234").Code; got != 200 {
+		t.Errorf("got status %d after a second want 200", got)
+	}
+}
+
+func TestRateLimitFilterRouteLimit(t *testing.T) {
+	wc, _, clock := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 10}})
+	if got := serveFrom(wc, "POST", "/users/1/login", "192.0.2.1:1234").Code; got != 200 {
+		t.Errorf("got status %d want 200", got)
+	}
+	httpWriter := serveFrom(wc, "POST", "/users/1/login", "192.0.2.1:1234")
+	if got := httpWriter.Code; got != 429 {
+		t.Errorf("got status %d want 429", got)
+	}
+	if got := httpWriter.Header().Get(HEADER_RetryAfter); got != "10" {
+		t.Errorf("got Retry-After %q want 10", got)
+	}
+	// the other routes are not limited by the login route
+	if got := serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234").Code; got != 200 {
+		t.Errorf("got status %d want 200", got)
+	}
+	clock.advance(10 * time.Second)
+	if got := serveFrom(wc, "POST", "/users/1/login", "192.0.2.1:1234").Code; got != 200 {
+		t.Errorf("got status %d after 10 seconds want 200", got)
+	}
+}
+
+func subjectKey(req *Request) string {
+	subject, _ := req.Attribute("subject").(string)
+	return subject
+}
+
+func TestRateLimitFilterKey(t *testing.T) {
+	wc, _, _ := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 1}, Key: subjectKey})
+	if got := serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234").Code; got != 200 {
+		t.Errorf("got status %d want 200", got)
+	}
+	// same subject from another address
+	if got := serveFrom(wc, "GET", "/users/1", "192.0.2.2:1234").Code; got != 429 {
+		t.Errorf("got status %d want 429", got)
+	}
+}
+
+func TestRateLimitFilterServiceErrorHandler(t *testing.T) {
+	handled := &serviceErrorRecorder{}
+	wc, _, _ := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 1}})
+	wc.ServiceErrorHandler(handled.write)
+	serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234")
+	httpWriter := serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234")
+	if handled.err.Code != http.StatusTooManyRequests || httpWriter.Code != http.StatusTooManyRequests {
+		t.Errorf("got error %v and status %d want 429", handled.err, httpWriter.Code)
+	}
+}
+
+func TestRateLimitFilterSweepsIdleBuckets(t *testing.T) {
+	wc, limiter, clock := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 1, Burst: 5}, IdleTimeout: 5 * time.Second})
+	serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234")
+	serveFrom(wc, "POST", "/users/1/login", "192.0.2.2:1234")
+	clock.advance(5 * time.Second)
+	serveFrom(wc, "GET", "/users/1", "192.0.2.3:1234")
+	// the login bucket is not full yet
+	if got, want := len(limiter.buckets), 2; got != want {
+		t.Errorf("got %d buckets want %d", got, want)
+	}
+	clock.advance(5 * time.Second)
+	serveFrom(wc, "GET", "/users/1", "192.0.2.3:1234")
+	if got, want := len(limiter.buckets), 1; got != want {
+		t.Errorf("got %d buckets want %d", got, want)
+	}
+}
+
+func TestRateLimitFilterConcurrent(t *testing.T) {
+	wc, _, _ := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 0.001, Burst: 20}})
+	var allowed, limited int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				switch serveFrom(wc, "GET", "/users/1", "192.0.2.1:1234").Code {
+				case http.StatusOK:
+					atomic.AddInt64(&allowed, 1)
+				case http.StatusTooManyRequests:
+					atomic.AddInt64(&limited, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 20 || limited != 180 {
+		t.Errorf("got %d allowed and %d limited want 20 and 180", allowed, limited)
+	}
+}
+
+func serveForwarded(wc *Container, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	httpRequest := httptest.NewRequest("GET", "/users/1", nil)
+	httpRequest.RemoteAddr = remoteAddr
+	httpRequest.Header.Set("X-Forwarded-For", forwardedFor)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	return httpWriter
+}
+
+func TestRateLimitFilterTrustedProxies(t *testing.T) {
+	wc, limiter, _ := newRateLimitContainer(RateLimitOptions{Limit: RateLimit{Rate: 1}, TrustedProxies: []string{"10.0.0.0/8", "192.0.2.9"}})
+	if got := serveForwarded(wc, "10.0.0.1:1234", "198.51.100.7, 192.0.2.9").Code; got != 200 {
+		t.Errorf("got status %d want 200", got)
+	}
+	// a spoofed leading address does not get a bucket of its own
+	if got := serveForwarded(wc, "10.0.0.2:1234", "203.0.113.66, 198.51.100.7").Code; got != 429 {
+		t.Errorf("got status %d with a spoofed address want 429", got)
+	}
+	if got, want := len(limiter.buckets), 1; got != want {
+		t.Errorf("got %d buckets want %d", got, want)
+	}
+	// the header of a client that is not a trusted proxy is not used
+	if got := serveForwarded(wc, "198.51.100.8:1234", "198.51.100.7").Code; got != 200 {
+		t.Errorf("got status %d from another client want 200", got)
+	}
+	for address, want := range map[string]bool{"10.1.2.3": true, "192.0.2.9": true, "192.0.2.10": false, "bad": false} {
+		if got := limiter.trusted(address); got != want {
+			t.Errorf("%s: got trusted %v want %v", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package restful
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/tangblue/goapi/restful/log"
+)
+
+// RecoveryOptions configures the FilterFunction created by RecoveryFilter.
+type RecoveryOptions struct {
+	// OnPanic, if set, is called with the value of the panic, the stack trace and the request,
+	// e.g. to report the error to an error tracking service.
+	OnPanic func(panicValue interface{}, stack []byte, req *Request)
+	// RequestIDHeader is the request header with the correlation ID of the request ; default is X-Request-Id.
+	// The ID set by the RequestIDFilter, if any, is used instead. If the request has none, or an invalid one,
+	// then a new ID is generated.
+	RequestIDHeader string
+	// Handler, if set, is called with the value of the panic instead of writing the 500 ServiceError,
+	// e.g. to write a custom response for the Routes of a WebService. The panic is logged and reported to OnPanic first.
+	Handler func(panicValue interface{}, req *Request, resp *Response)
+}
+
+// RecoveryFilter returns a FilterFunction that recovers from a panic in the filters and the route function after it.
+// It logs the panic with the stack trace and the request ID and writes a 500 ServiceError with the request ID,
+// not the stack trace, through the ServiceErrorHandler of the Container or else as JSON.
+// The request ID is also written in the response header. If the response has been partly written
+// then the connection is aborted instead. A panic with http.ErrAbortHandler is not recovered.
+// Install it as the first Container filter, or as a WebService or Route filter with a Handler of its own.
+func RecoveryFilter(opts RecoveryOptions) FilterFunction {
+	if len(opts.RequestIDHeader) == 0 {
+		opts.RequestIDHeader = HEADER_XRequestID
+	}
+	return opts.filter
+}
+
+func (o RecoveryOptions) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	defer func() {
+		panicValue := recover()
+		if panicValue == nil {
+			return
+		}
+		if panicValue == http.ErrAbortHandler {
+			// deliberate abort of the connection
+			panic(panicValue)
+		}
+		stack := debug.Stack()
+		id := requestID(req)
+		if len(id) == 0 {
+			// the ID of the header is written in the log and the response, such that it must be valid
+			if id = req.Request.Header.Get(o.RequestIDHeader); !validRequestID(id) {
+				id = newRequestID()
+			}
+		}
+		log.Printf("recovered from panic in %s %s, request id %s: %v\n%s", req.Request.Method, req.Request.URL.Path, id, panicValue, stack)
+		if o.OnPanic != nil {
+			o.OnPanic(panicValue, stack, req)
+		}
+		if resp.wroteHeader || resp.contentLength > 0 {
+			// the status cannot be changed ; do not let the client take the partial response as complete
+			panic(http.ErrAbortHandler)
+		}
+		if o.Handler != nil {
+			o.Handler(panicValue, req, resp)
+			return
+		}
+		header := http.Header{}
+		header.Set(o.RequestIDHeader, id)
+		serviceError := NewErrorWithHeader(http.StatusInternalServerError, "500: Internal Server Error, request id "+id, header)
+		serviceError.RequestID = id
+		resp.err = serviceError
+		resp.Header().Set(o.RequestIDHeader, id)
+		if resp.handleServiceError(serviceError) {
+			return
+		}
+		writeJSON(resp, serviceError.Code, MIME_JSON, serviceError)
+	}()
+	next(req, resp)
+}
+
+// RecoverHandler handles the value of a recovered panic, e.g. by writing a response, see RecoveryOptions.Handler.
+type RecoverHandler func(panicValue interface{}, req *Request, resp *Response)
+
+// RecoverFilter returns a RecoveryFilter that calls the handler with the value of the panic.
+// A nil handler logs the stack trace and writes a 500 ServiceError, as RecoveryFilter does.
+// Install it as a Container, WebService or Route filter.
+func RecoverFilter(handler RecoverHandler) FilterFunction {
+	return RecoveryFilter(RecoveryOptions{Handler: handler})
+}
+
+// newRequestID returns a random ID for a request that has none.
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,215 @@
+package restful
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panickingHandler(req *Request, resp *Response) {
+	panic("boom")
+}
+
+func partialHandler(req *Request, resp *Response) {
+	io.WriteString(resp, "partial")
+	panic("boom")
+}
+
+func abortingHandler(req *Request, resp *Response) {
+	panic(http.ErrAbortHandler)
+}
+
+func panickingFilter(req *Request, resp *Response, next func(*Request, *Response)) {
+	panic("filter boom")
+}
+
+// panicReport records the arguments of RecoveryOptions.OnPanic
+type panicReport struct {
+	value interface{}
+	stack []byte
+	path  string
+}
+
+func (p *panicReport) onPanic(panicValue interface{}, stack []byte, req *Request) {
+	p.value, p.stack, p.path = panicValue, stack, req.Request.URL.Path
+}
+
+func newRecoveryContainer(report *panicReport) *Container {
+	wc := NewContainer()
+	wc.Filter(RecoveryFilter(RecoveryOptions{OnPanic: report.onPanic}))
+	ws := new(WebService).Path("/recover").Produces(MIME_JSON)
+	ws.Route(ws.GET("/handler").Handler(panickingHandler))
+	ws.Route(ws.GET("/partial").Handler(partialHandler))
+	ws.Route(ws.GET("/abort").Handler(abortingHandler))
+	ws.Route(ws.GET("/filter").Filter(panickingFilter).Handler(dummy))
+	wc.Add(ws)
+	return wc
+}
+
+func TestRecoveryFilter(t *testing.T) {
+	for _, each := range []struct {
+		path  string
+		value interface{}
+	}{
+		{"/recover/handler", "boom"},
+		{"/recover/filter", "filter boom"},
+	} {
+		report := &panicReport{}
+		wc := newRecoveryContainer(report)
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", each.path, nil))
+
+		if got, want := httpWriter.Code, http.StatusInternalServerError; got != want {
+			t.Errorf("%s: got status %d want %d", each.path, got, want)
+		}
+		requestID := httpWriter.Header().Get(HEADER_XRequestID)
+		if len(requestID) == 0 {
+			t.Errorf("%s: missing request id", each.path)
+		}
+		var serviceError ServiceError
+		if err := json.Unmarshal(httpWriter.Body.Bytes(), &serviceError); err != nil {
+			t.Fatalf("%s: %v in %q", each.path, err, httpWriter.Body.String())
+		}
+		if serviceError.Code != 500 || !strings.Contains(serviceError.Message, requestID) {
+			t.Errorf("%s: got %v want code 500 and request id %s", each.path, serviceError, requestID)
+		}
+		if strings.Contains(httpWriter.Body.String(), "goroutine") {
+			t.Errorf("%s: stack trace written to the client: %s", each.path, httpWriter.Body.String())
+		}
+		if report.value != each.value || report.path != each.path || len(report.stack) == 0 {
+			t.Errorf("%s: got report %v %s", each.path, report.value, report.path)
+		}
+	}
+}
+
+func TestRecoveryFilterRequestID(t *testing.T) {
+	wc := newRecoveryContainer(&panicReport{})
+	httpRequest := httptest.NewRequest("GET", "/recover/handler", nil)
+	httpRequest.Header.Set(HEADER_XRequestID, "req-42")
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got := httpWriter.Header().Get(HEADER_XRequestID); got != "req-42" {
+		t.Errorf("got request id %q want req-42", got)
+	}
+	if !strings.Contains(httpWriter.Body.String(), "req-42") {
+		t.Errorf("got %q want the request id", httpWriter.Body.String())
+	}
+
+	// an invalid ID is not written in the log and the response
+	httpRequest = httptest.NewRequest("GET", "/recover/handler", nil)
+	httpRequest.Header.Set(HEADER_XRequestID, "req-42\nforged log line")
+	httpWriter = httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got := httpWriter.Header().Get(HEADER_XRequestID); !validRequestID(got) {
+		t.Errorf("got request id %q want a new one", got)
+	}
+	if strings.Contains(httpWriter.Body.String(), "forged") {
+		t.Errorf("got %q want a new request id", httpWriter.Body.String())
+	}
+}
+
+func TestRecoveryFilterServiceErrorHandler(t *testing.T) {
+	wc := newRecoveryContainer(&panicReport{})
+	wc.ServiceErrorHandler(writePlainServiceError)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/recover/handler", nil))
+	if got := httpWriter.Body.String(); !strings.HasPrefix(got, "plain 500: Internal Server Error, request id ") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func writePlainServiceError(err ServiceError, req *Request, resp *Response) {
+	resp.WriteHeader(err.Code)
+	io.WriteString(resp, "plain "+err.Message)
+}
+
+func TestRecoveryFilterAborts(t *testing.T) {
+	for _, path := range []string{"/recover/partial", "/recover/abort"} {
+		report := &panicReport{}
+		wc := newRecoveryContainer(report)
+		httpWriter := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if got := recover(); got != http.ErrAbortHandler {
+					t.Errorf("%s: got panic %v want http.ErrAbortHandler", path, got)
+				}
+			}()
+			wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", path, nil))
+		}()
+		if path == "/recover/partial" && report.value != "boom" {
+			t.Errorf("%s: got report %v want boom", path, report.value)
+		}
+		if path == "/recover/abort" && report.value != nil {
+			t.Errorf("%s: got report %v want none", path, report.value)
+		}
+	}
+}
+
+// recovered records the panic value passed to a RecoverHandler
+type recovered struct {
+	value interface{}
+}
+
+func (r *recovered) handle(panicValue interface{}, req *Request, resp *Response) {
+	r.value = panicValue
+	resp.WriteErrorString(http.StatusInternalServerError, "recovered")
+}
+
+func TestRecoveryFilterHandler(t *testing.T) {
+	record := &recovered{}
+	report := &panicReport{}
+	wc := NewContainer()
+	ws := new(WebService).Path("/recover").Filter(RecoveryFilter(RecoveryOptions{OnPanic: report.onPanic, Handler: record.handle}))
+	ws.Route(ws.GET("/handler").Handler(panickingHandler))
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/recover/handler", nil))
+	if got, want := httpWriter.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("got status %d want %d", got, want)
+	}
+	if got := httpWriter.Body.String(); got != "recovered" {
+		t.Errorf("got body %q want recovered", got)
+	}
+	if record.value != "boom" {
+		t.Errorf("got panic value %v want boom", record.value)
+	}
+	if report.value != "boom" {
+		t.Errorf("got reported panic value %v want boom", report.value)
+	}
+}
+
+func TestRecoverFilter(t *testing.T) {
+	record := &recovered{}
+	wc := NewContainer()
+	ws := new(WebService).Path("/recover").Filter(RecoverFilter(record.handle))
+	ws.Route(ws.GET("/handler").Handler(panickingHandler))
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/recover/handler", nil))
+	if got := httpWriter.Body.String(); got != "recovered" {
+		t.Errorf("got body %q want recovered", got)
+	}
+	if record.value != "boom" {
+		t.Errorf("got panic value %v want boom", record.value)
+	}
+}
+
+func TestRecoverFilterDefault(t *testing.T) {
+	wc := NewContainer()
+	wc.Filter(RecoverFilter(nil))
+	ws := new(WebService).Path("/recover").Produces(MIME_JSON)
+	ws.Route(ws.GET("/handler").Handler(panickingHandler))
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/recover/handler", nil))
+	if got, want := httpWriter.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("got status %d want %d", got, want)
+	}
+	var serviceError ServiceError
+	if err := json.Unmarshal(httpWriter.Body.Bytes(), &serviceError); err != nil || serviceError.Code != 500 {
+		t.Errorf("got %q want a 500 ServiceError", httpWriter.Body.String())
+	}
+}
@@ -5,10 +5,14 @@ package restful
 // that can be found in the LICENSE file.
 
 import (
+	"bytes"
 	"compress/zlib"
+	"encoding/xml"
 	"errors"
+	"io/ioutil"
 	"net/http"
-	"reflect"
+	"strings"
+	"time"
 )
 
 var defaultRequestContentType string
@@ -17,8 +21,16 @@ var defaultRequestContentType string
 type Request struct {
 	Request           *http.Request
 	pathParameters    map[string]string
+	rawPathParameters map[string]string      // path parameters as they appear in the escaped URL path, see RawPathParameter
 	attributes        map[string]interface{} // for storing request-scoped values
 	selectedRoutePath string                 // root path + route path that matched the request, e.g. /meetings/{id}/attendees
+	routeMetadata     map[string]interface{} // Metadata of the selected Route, if any
+	routeSecurity     []map[string][]string  // Security of the selected Route, if any
+	routeParams       []*Parameter           // ParameterDocs of the selected Route, if any
+	startTime         time.Time              // when the Container started dispatching the request, zero if unknown
+	codecs            entityCodecs           // EntityReaderWriters of the selected Route, if any
+	rawBody           []byte                 // the decompressed body, see RawBody
+	bodyBuffered      bool                   // whether the body was read into rawBody
 }
 
 func NewRequest(httpRequest *http.Request) *Request {
@@ -54,20 +66,44 @@ func (r *Request) GetParameter(p *Parameter, out interface{}) error {
 	case "body":
 		va, ok = r.Request.PostForm[p.Name]
 	case "header":
-		va[0], ok = r.Request.Header.Get(p.Name), true
+		if p.CollectionFormat == CollectionFormatMulti.String() {
+			// all values of a repeated header, e.g. X-Forwarded-For
+			va = r.Request.Header.Values(p.Name)
+			ok = len(va) > 0
+		} else {
+			va[0], ok = r.Request.Header.Get(p.Name), true
+		}
 	}
 
 	if !ok {
 		if p.Required {
 			return errors.New("not available")
 		}
-		reflect.ValueOf(out).Elem().Set(reflect.ValueOf(p.Default))
-		return nil
+		return p.setDefault(out)
 	}
 
 	return p.getValue(va, out)
 }
 
+// RawPathParameter returns the value of the path parameter as it appears in the escaped URL path, e.g. a%2Fb/c.
+// It is only available if the Route has a catch-all parameter, such as {path:*}, whose decoded value
+// cannot tell escaped slashes from path separators. Returns empty if not available.
+func (r *Request) RawPathParameter(name string) string {
+	return r.rawPathParameters[name]
+}
+
+// SensitiveFields returns the names of the parameters of the selected Route marked as Sensitive, e.g. password,
+// such that a filter that logs requests can redact their values. Returns empty if none.
+func SensitiveFields(req *Request) []string {
+	names := []string{}
+	for _, each := range req.routeParams {
+		if each.IsSensitive() {
+			names = append(names, each.Name)
+		}
+	}
+	return names
+}
+
 // HeaderParameter returns the HTTP Header value of a Header name or empty if missing
 func (r *Request) HeaderParameter(name string) string {
 	return r.Request.Header.Get(name)
@@ -76,33 +112,104 @@ func (r *Request) HeaderParameter(name string) string {
 // ReadEntity checks the Accept header and reads the content into the entityPointer.
 func (r *Request) ReadEntity(entityPointer interface{}) (err error) {
 	contentType := r.Request.Header.Get(HEADER_ContentType)
-	contentEncoding := r.Request.Header.Get(HEADER_ContentEncoding)
+	release, err := r.decompressBody()
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	// check if the request body needs decompression
+	// lookup the EntityReader, use defaultRequestContentType if needed and provided
+	entityReader, ok := r.codecs.accessorAt(contentType)
+	if !ok {
+		if len(defaultRequestContentType) != 0 {
+			entityReader, ok = r.codecs.accessorAt(defaultRequestContentType)
+		}
+		if !ok {
+			return NewError(http.StatusBadRequest, "Unable to unmarshal content of type:"+contentType)
+		}
+	}
+	return entityReader.Read(r, entityPointer)
+}
+
+// RawBody reads and returns the body of the request, decompressed if its Content-Encoding is gzip or deflate,
+// e.g. to verify its signature. The body is buffered such that it can be read again, e.g. using ReadEntity ;
+// each call replaces the body of the http.Request by a new reader of the bytes.
+func (r *Request) RawBody() ([]byte, error) {
+	if !r.bodyBuffered {
+		release, err := r.decompressBody()
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(r.Request.Body)
+		release()
+		if err != nil {
+			return nil, err
+		}
+		r.rawBody, r.bodyBuffered = body, true
+	}
+	r.Request.Body = ioutil.NopCloser(bytes.NewReader(r.rawBody))
+	return r.rawBody, nil
+}
+
+// decompressBody replaces the body by a reader that decompresses it if the request body needs decompression.
+// The returned function must be called when the body has been read.
+func (r *Request) decompressBody() (func(), error) {
+	if r.bodyBuffered {
+		// decompressed by RawBody
+		return func() {}, nil
+	}
+	contentEncoding := r.Request.Header.Get(HEADER_ContentEncoding)
 	if ENCODING_GZIP == contentEncoding {
 		gzipReader := currentCompressorProvider.AcquireGzipReader()
-		defer currentCompressorProvider.ReleaseGzipReader(gzipReader)
 		gzipReader.Reset(r.Request.Body)
 		r.Request.Body = gzipReader
+		return func() { currentCompressorProvider.ReleaseGzipReader(gzipReader) }, nil
 	} else if ENCODING_DEFLATE == contentEncoding {
 		zlibReader, err := zlib.NewReader(r.Request.Body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		r.Request.Body = zlibReader
 	}
+	return func() {}, nil
+}
 
-	// lookup the EntityReader, use defaultRequestContentType if needed and provided
-	entityReader, ok := entityAccessRegistry.accessorAt(contentType)
-	if !ok {
-		if len(defaultRequestContentType) != 0 {
-			entityReader, ok = entityAccessRegistry.accessorAt(defaultRequestContentType)
+// ReadGeneric reads the content without knowing its schema in advance and returns the decoded value.
+// XML content (MIME_XML, text/xml or a +xml type) is decoded into an *XMLNode tree, whatever EntityReaderWriter is registered.
+// Other content is decoded by the EntityReaderWriter for its Content-Type, as ReadEntity does, into an interface{} ;
+// for JSON this is a map[string]interface{}, []interface{} or a primitive, with numbers as json.Number.
+func (r *Request) ReadGeneric() (interface{}, error) {
+	if isXMLContentType(r.Request.Header.Get(HEADER_ContentType)) {
+		release, err := r.decompressBody()
+		if err != nil {
+			return nil, err
 		}
-		if !ok {
-			return NewError(http.StatusBadRequest, "Unable to unmarshal content of type:"+contentType)
+		defer release()
+		node := new(XMLNode)
+		if err := xml.NewDecoder(r.Request.Body).Decode(node); err != nil {
+			return nil, err
 		}
+		return node, nil
 	}
-	return entityReader.Read(r, entityPointer)
+	var value interface{}
+	if err := r.ReadEntity(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// XMLNode is an element of XML content read by ReadGeneric.
+type XMLNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Content  string     `xml:",chardata"` // the text directly inside the element
+	Children []XMLNode  `xml:",any"`
+}
+
+// isXMLContentType returns whether the Content-Type, ignoring its parameters, is an XML type.
+func isXMLContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == MIME_XML || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
 }
 
 // SetAttribute adds or replaces the attribute with the given value.
@@ -119,3 +226,8 @@ func (r Request) Attribute(name string) interface{} {
 func (r Request) SelectedRoutePath() string {
 	return r.selectedRoutePath
 }
+
+// StartTime returns when the Container started dispatching this request. It is zero if the request was not dispatched by a Container.
+func (r Request) StartTime() time.Time {
+	return r.startTime
+}
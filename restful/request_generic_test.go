@@ -0,0 +1,84 @@
+package restful
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadGenericJSON(t *testing.T) {
+	bodyReader := strings.NewReader(`{"name":"gopher","age":7,"tags":["a","b"]}`)
+	httpRequest, _ := http.NewRequest("POST", "/users", bodyReader)
+	httpRequest.Header.Set(HEADER_ContentType, MIME_JSON+"; charset=utf-8")
+	value, err := NewRequest(httpRequest).ReadGeneric()
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %T want map[string]interface{}", value)
+	}
+	if got, want := object["name"], "gopher"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := object["age"], json.Number("7"); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if tags, ok := object["tags"].([]interface{}); !ok || len(tags) != 2 {
+		t.Errorf("unexpected tags %v", object["tags"])
+	}
+}
+
+func TestReadGenericJSONArrayGzip(t *testing.T) {
+	var buffer bytes.Buffer
+	w := gzip.NewWriter(&buffer)
+	w.Write([]byte(`[1,2,3]`))
+	w.Close()
+	httpRequest, _ := http.NewRequest("POST", "/users", &buffer)
+	httpRequest.Header.Set(HEADER_ContentType, MIME_JSON)
+	httpRequest.Header.Set(HEADER_ContentEncoding, ENCODING_GZIP)
+	value, err := NewRequest(httpRequest).ReadGeneric()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, ok := value.([]interface{}); !ok || len(list) != 3 {
+		t.Errorf("unexpected value %#v", value)
+	}
+}
+
+func TestReadGenericXML(t *testing.T) {
+	bodyReader := strings.NewReader(`<user id="1"><name>gopher</name><tag>a</tag><tag>b</tag></user>`)
+	httpRequest, _ := http.NewRequest("POST", "/users", bodyReader)
+	httpRequest.Header.Set(HEADER_ContentType, "text/xml")
+	value, err := NewRequest(httpRequest).ReadGeneric()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, ok := value.(*XMLNode)
+	if !ok {
+		t.Fatalf("got %T want *XMLNode", value)
+	}
+	if got, want := node.XMLName.Local, "user"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if len(node.Attrs) != 1 || node.Attrs[0].Name.Local != "id" || node.Attrs[0].Value != "1" {
+		t.Errorf("unexpected attributes %v", node.Attrs)
+	}
+	if got, want := len(node.Children), 3; got != want {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if got, want := node.Children[0].Content, "gopher"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestReadGenericUnknownType(t *testing.T) {
+	httpRequest, _ := http.NewRequest("POST", "/users", strings.NewReader("hello"))
+	httpRequest.Header.Set(HEADER_ContentType, "text/plain")
+	if _, err := NewRequest(httpRequest).ReadGeneric(); err == nil {
+		t.Error("expected error for content without EntityReaderWriter")
+	}
+}
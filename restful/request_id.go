@@ -0,0 +1,80 @@
+package restful
+
+import (
+	"context"
+	"strings"
+)
+
+// maxRequestIDLength is the maximum length of a request ID taken from a request header.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key of the ID of a request, see RequestIDFilter.
+type requestIDKey struct{}
+
+type requestIDFilter struct {
+	headerName string
+	generator  func() string
+}
+
+// RequestIDFilter returns a FilterFunction that sets the ID of each request as the KeyRequestID attribute of the Request,
+// in the context of the http.Request and in the response header ; default headerName is X-Request-Id.
+// The ID of the request header is used if it has at most 128 letters, digits and -_.:+/= characters ;
+// otherwise a new one is returned by the generator, default a random hexadecimal ID.
+// The AccessLogFilter, the RecoveryFilter and the ServiceErrorHandler of the Container include the ID.
+// Install it as the first Container filter.
+func RequestIDFilter(headerName string, generator func() string) FilterFunction {
+	if len(headerName) == 0 {
+		headerName = HEADER_XRequestID
+	}
+	if generator == nil {
+		generator = newRequestID
+	}
+	return requestIDFilter{headerName: headerName, generator: generator}.filter
+}
+
+func (f requestIDFilter) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	id := req.Request.Header.Get(f.headerName)
+	if !validRequestID(id) {
+		id = f.generator()
+	}
+	req.SetAttribute(KeyRequestID, id)
+	req.Request = req.Request.WithContext(context.WithValue(req.Request.Context(), requestIDKey{}, id))
+	resp.Header().Set(f.headerName, id)
+	next(req, resp)
+}
+
+// RequestIDFromContext returns the ID of the request set by the RequestIDFilter, empty if none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID returns the ID of the request set by the RequestIDFilter, empty if none.
+func requestID(req *Request) string {
+	if req == nil {
+		return ""
+	}
+	id, _ := req.Attribute(KeyRequestID).(string)
+	return id
+}
+
+// validRequestID returns whether the ID of a request header can be used, e.g. written in logs as is.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, each := range id {
+		if !('a' <= each && each <= 'z' || 'A' <= each && each <= 'Z' || '0' <= each && each <= '9' || strings.ContainsRune("-_.:+/=", each)) {
+			return false
+		}
+	}
+	return true
+}
+
+// withRequestID returns the ServiceError with the ID of the request, if any.
+func (s ServiceError) withRequestID(req *Request) ServiceError {
+	if len(s.RequestID) == 0 {
+		s.RequestID = requestID(req)
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package restful
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func writeRequestID(req *Request, resp *Response) {
+	io.WriteString(resp, RequestIDFromContext(req.Request.Context())+" "+requestID(req))
+}
+
+func fixedRequestID() string {
+	return "generated"
+}
+
+func newRequestIDContainer() *Container {
+	wc := NewContainer()
+	wc.Filter(RequestIDFilter("", fixedRequestID))
+	ws := new(WebService).Path("/ids")
+	ws.Route(ws.GET("").Handler(writeRequestID))
+	wc.Add(ws)
+	return wc
+}
+
+func TestRequestIDFilter(t *testing.T) {
+	for _, each := range []struct {
+		header string
+		want   string
+	}{
+		{"", "generated"},
+		{"req-42", "req-42"},
+		{"4bf92f35-77b3-4da6-a3ce-929d0e0e4736", "4bf92f35-77b3-4da6-a3ce-929d0e0e4736"},
+		{strings.Repeat("x", 129), "generated"},
+		{"req 42\nforged log line", "generated"},
+	} {
+		httpRequest := httptest.NewRequest("GET", "/ids", nil)
+		if len(each.header) > 0 {
+			httpRequest.Header.Set(HEADER_XRequestID, each.header)
+		}
+		httpWriter := httptest.NewRecorder()
+		newRequestIDContainer().ServeHTTP(httpWriter, httpRequest)
+		if got := httpWriter.Header().Get(HEADER_XRequestID); got != each.want {
+			t.Errorf("%q: got header %q want %q", each.header, got, each.want)
+		}
+		if got, want := httpWriter.Body.String(), each.want+" "+each.want; got != want {
+			t.Errorf("%q: got context and attribute %q want %q", each.header, got, want)
+		}
+	}
+}
+
+func TestRequestIDFilterDefaultGenerator(t *testing.T) {
+	wc := NewContainer()
+	wc.Filter(RequestIDFilter("X-Correlation-Id", nil))
+	ws := new(WebService).Path("/ids")
+	ws.Route(ws.GET("").Handler(writeRequestID))
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/ids", nil))
+	if got := httpWriter.Header().Get("X-Correlation-Id"); len(got) != 32 {
+		t.Errorf("got generated id %q want 32 hexadecimal characters", got)
+	}
+}
+
+// serviceErrorRecorder records the ServiceError it writes as JSON
+type serviceErrorRecorder struct {
+	err ServiceError
+}
+
+func (s *serviceErrorRecorder) write(err ServiceError, req *Request, resp *Response) {
+	s.err = err
+	resp.WriteHeaderAndJson(err.Code, err, MIME_JSON)
+}
+
+func TestRequestIDInServiceError(t *testing.T) {
+	httpRequest := httptest.NewRequest("DELETE", "/ids", nil)
+	httpRequest.Header.Set(HEADER_XRequestID, "req-42")
+	httpWriter := httptest.NewRecorder()
+	newRequestIDContainer().ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "405: Method Not Allowed, request id req-42"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+
+	handled := &serviceErrorRecorder{}
+	wc := newRequestIDContainer()
+	wc.ServiceErrorHandler(handled.write)
+	httpWriter = httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if handled.err.RequestID != "req-42" {
+		t.Errorf("got request id %q want req-42", handled.err.RequestID)
+	}
+	var written ServiceError
+	if err := json.Unmarshal(httpWriter.Body.Bytes(), &written); err != nil || written.RequestID != "req-42" {
+		t.Errorf("got %s want the request id", httpWriter.Body.String())
+	}
+}
+
+func TestRequestIDInAccessLog(t *testing.T) {
+	recorder := &entryRecorder{}
+	wc := newRequestIDContainer()
+	wc.Filter(AccessLogFilter(AccessLogOptions{Format: "{method} {path} {status}", Logger: recorder}))
+	httpRequest := httptest.NewRequest("GET", "/ids", nil)
+	httpRequest.Header.Set(HEADER_XRequestID, "req-42")
+	wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	if got, want := strings.Join(recorder.lines, "\n"), "GET /ids 200 request_id=req-42"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	entry := LogEntry{RequestID: "req-42"}
+	if !strings.Contains(entry.JSON(), `"request_id":"req-42"`) {
+		t.Errorf("got %s want the request id", entry.JSON())
+	}
+}
+
+func TestRecoveryFilterUsesRequestID(t *testing.T) {
+	wc := NewContainer()
+	wc.Filter(RequestIDFilter("", fixedRequestID))
+	wc.Filter(RecoveryFilter(RecoveryOptions{}))
+	ws := new(WebService).Path("/recover").Produces(MIME_JSON)
+	ws.Route(ws.GET("/handler").Handler(panickingHandler))
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/recover/handler", nil))
+	if got := httpWriter.Header().Get(HEADER_XRequestID); got != "generated" {
+		t.Errorf("got request id %q want generated", got)
+	}
+	if !strings.Contains(httpWriter.Body.String(), "generated") {
+		t.Errorf("got %q want the request id", httpWriter.Body.String())
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	if validRequestID("") || validRequestID("a b") || !validRequestID("abc:123/=+_.") {
+		t.Error("unexpected validation")
+	}
+	if got := (ServiceError{Code: http.StatusNotFound}).withRequestID(nil).RequestID; got != "" {
+		t.Errorf("got %q want none", got)
+	}
+}
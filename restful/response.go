@@ -6,9 +6,12 @@ package restful
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // DefaultResponseMimeType is DEPRECATED, use DefaultResponseContentType(mime)
@@ -21,13 +24,35 @@ var PrettyPrintResponses = true
 // It provides several convenience methods to prepare and write response content.
 type Response struct {
 	http.ResponseWriter
-	requestAccept string        // mime-type what the Http Request says it wants to receive
-	routeProduces []string      // mime-types what the Route says it can produce
-	statusCode    int           // HTTP status code that has been written explicitly (if zero then net/http has written 200)
-	contentLength int           // number of bytes written for the response body
-	prettyPrint   bool          // controls the indentation feature of XML and JSON serialization. It is initialized using var PrettyPrintResponses.
-	err           error         // err property is kept when WriteError is called
-	hijacker      http.Hijacker // if underlying ResponseWriter supports it
+	requestAccept string          // mime-type what the Http Request says it wants to receive
+	routeProduces []string        // mime-types what the Route says it can produce
+	statusCode    int             // HTTP status code that has been written explicitly (if zero then net/http has written 200)
+	wroteHeader   bool            // whether WriteHeader has been called
+	contentLength int             // number of bytes written for the response body
+	prettyPrint   bool            // controls the indentation feature of XML and JSON serialization. It is initialized using var PrettyPrintResponses.
+	err           error           // err property is kept when WriteError is called
+	hijacker      http.Hijacker   // if underlying ResponseWriter supports it
+	codecs        entityCodecs    // EntityReaderWriters of the selected Route, if any
+	ctx           context.Context // context of the Http Request this is the response for, if known
+	xmlRootName   string          // name of the root element written by the XML writer, see KeyXMLRootName
+	omitXMLHeader bool            // controls whether the XML writer writes xml.Header, see Container.OmitXMLHeader
+	xmlHeader     bool            // makes the XML writer write xml.Header also without pretty printing, see KeyXMLHeader
+	errorType     string          // MIME type negotiated with the selected Route, used by WriteErrorString
+
+	successStatus       int                        // status code written by WriteEntity if not zero, see Container.EnableDeclaredSuccessStatus
+	request             *Request                   // the request this is the response for, if known
+	serviceErrorHandler ServiceErrorHandleFunction // if set then errors written by WriteError* are passed to it
+	writeHooks          []func(ResponseStats)      // called once when the response is complete
+	abortedWith         *ServiceError              // the error the filter chain was aborted with, see AbortWith
+}
+
+// ResponseStats describes a completed response. It is passed to the functions registered using OnWrite.
+type ResponseStats struct {
+	StatusCode    int
+	ContentLength int
+	ContentType   string
+	Err           error         // the error written using WriteError, if any
+	Elapsed       time.Duration // time since the Container started dispatching the request, zero if unknown
 }
 
 // NewResponse creates a new response based on a http ResponseWriter.
@@ -86,34 +111,36 @@ func (r *Response) EntityWriter() (EntityReaderWriter, bool) {
 	for _, eachAccept := range sorted {
 		for _, eachProduce := range r.routeProduces {
 			if eachProduce == eachAccept.media {
-				if w, ok := entityAccessRegistry.accessorAt(eachAccept.media); ok {
+				if w, ok := r.codecs.accessorAt(eachAccept.media); ok {
 					return w, true
 				}
 			}
 		}
 		if eachAccept.media == "*/*" {
 			for _, each := range r.routeProduces {
-				if w, ok := entityAccessRegistry.accessorAt(each); ok {
+				if w, ok := r.codecs.accessorAt(each); ok {
 					return w, true
 				}
 			}
 		}
 	}
 	// if requestAccept is empty
-	writer, ok := entityAccessRegistry.accessorAt(r.requestAccept)
+	writer, ok := r.codecs.accessorAt(r.requestAccept)
 	if !ok {
 		// if not registered then fallback to the defaults (if set)
 		if DefaultResponseMimeType == MIME_JSON {
-			return entityAccessRegistry.accessorAt(MIME_JSON)
+			return r.codecs.accessorAt(MIME_JSON)
 		}
 		if DefaultResponseMimeType == MIME_XML {
-			return entityAccessRegistry.accessorAt(MIME_XML)
+			return r.codecs.accessorAt(MIME_XML)
 		}
-		// Fallback to whatever the route says it can produce.
+		// Fallback to whatever the route says it can produce if the request did not say what it accepts.
 		// https://www.w3.org/Protocols/rfc2616/rfc2616-sec14.html
-		for _, each := range r.routeProduces {
-			if w, ok := entityAccessRegistry.accessorAt(each); ok {
-				return w, true
+		if len(strings.TrimSpace(r.requestAccept)) == 0 {
+			for _, each := range r.routeProduces {
+				if w, ok := r.codecs.accessorAt(each); ok {
+					return w, true
+				}
 			}
 		}
 		if trace {
@@ -124,7 +151,11 @@ func (r *Response) EntityWriter() (EntityReaderWriter, bool) {
 }
 
 // WriteEntity calls WriteHeaderAndEntity with Http Status OK (200)
+// or with the success status declared by the Route if enabled by the Container.
 func (r *Response) WriteEntity(value interface{}) error {
+	if r.successStatus != 0 {
+		return r.WriteHeaderAndEntity(r.successStatus, value)
+	}
 	return r.WriteHeaderAndEntity(http.StatusOK, value)
 }
 
@@ -134,14 +165,37 @@ func (r *Response) WriteEntity(value interface{}) error {
 // If the value is nil then no response is send except for the Http status. You may want to call WriteHeader(http.StatusNotFound) instead.
 // If there is no writer available that can represent the value in the requested MIME type then Http Status NotAcceptable is written.
 // Current implementation ignores any q-parameters in the Accept Header.
-// Returns an error if the value could not be written on the response.
+// Returns an error if the value could not be written on the response ; if the value could not be marshalled
+// and nothing was written yet then Http Status InternalServerError is written instead of the status.
 func (r *Response) WriteHeaderAndEntity(status int, value interface{}) error {
 	writer, ok := r.EntityWriter()
 	if !ok {
-		r.WriteHeader(http.StatusNotAcceptable)
+		err := notAcceptableError(r.routeProduces)
+		if r.handleServiceError(err) {
+			return nil
+		}
+		writeServiceError(err, r.request, r)
 		return nil
 	}
-	return writer.Write(r, status, value)
+	err := writer.Write(r, status, value)
+	if err != nil && !r.wroteHeader && r.contentLength == 0 {
+		r.err = err
+		r.WriteErrorString(http.StatusInternalServerError, "500: Internal Server Error")
+	}
+	return err
+}
+
+// Envelope wraps the payload of a success response with its metadata, see WriteEnveloped.
+type Envelope struct {
+	Data interface{} `json:"data" xml:"data"`
+	Meta interface{} `json:"meta,omitempty" xml:"meta,omitempty"`
+}
+
+// WriteEnveloped calls WriteHeaderAndEntity with the data and meta wrapped in an Envelope,
+// e.g. {"data": {...}, "meta": {"total": 42}}. A nil meta is left out.
+// Document the wrapped responses using the ResponseEnvelope of the restfulspec.Config.
+func (r *Response) WriteEnveloped(status int, data interface{}, meta interface{}) error {
+	return r.WriteHeaderAndEntity(status, Envelope{Data: data, Meta: meta})
 }
 
 // WriteAsXml is a convenience method for writing a value in xml (requires Xml tags on the value)
@@ -150,6 +204,14 @@ func (r *Response) WriteAsXml(value interface{}) error {
 	return writeXML(r, http.StatusOK, MIME_XML, value)
 }
 
+// WriteAsXmlWithRoot is a convenience method for writing a value in xml using rootName as the name of the root element.
+// A slice or array is written as a sequence of elements wrapped by the root element ; if rootName is empty then
+// the pluralized name of the element type is used, e.g. <Users><User>..</User></Users>.
+// writeHeader controls whether xml.Header is written before the root element.
+func (r *Response) WriteAsXmlWithRoot(value interface{}, rootName string, writeHeader bool) error {
+	return writeXMLWithRoot(r, http.StatusOK, MIME_XML, value, rootName, writeHeader)
+}
+
 // WriteHeaderAndXml is a convenience method for writing a status and value in xml (requires Xml tags on the value)
 // It uses the standard encoding/xml package for marshalling the value ; not using a registered EntityReaderWriter.
 func (r *Response) WriteHeaderAndXml(status int, value interface{}) error {
@@ -175,6 +237,7 @@ func (r *Response) WriteHeaderAndJson(status int, value interface{}, contentType
 }
 
 // WriteError write the http status and the error string on the response.
+// See WriteErrorString for the representation of the error.
 func (r *Response) WriteError(httpStatus int, err error) error {
 	r.err = err
 	return r.WriteErrorString(httpStatus, err.Error())
@@ -191,11 +254,24 @@ func (r *Response) WriteErrorResponse(e *ResponseError) error {
 }
 
 // WriteErrorString is a convenience method for an error status with the actual error
+// If the Container has a custom ServiceErrorHandler then that function writes the response instead.
+// If the Route produces a MIME type that the request accepts and for which an EntityReaderWriter is registered,
+// e.g. MIME_JSON or MIME_XML, then a ServiceError with the status and reason is written using that type.
+// Otherwise the reason is written as plain text.
 func (r *Response) WriteErrorString(httpStatus int, errorReason string) error {
 	if r.err == nil {
 		// if not called from WriteError
 		r.err = errors.New(errorReason)
 	}
+	serviceError := NewError(httpStatus, errorReason)
+	if r.handleServiceError(serviceError) {
+		return nil
+	}
+	if len(r.errorType) > 0 {
+		if writer, ok := r.codecs.accessorAt(r.errorType); ok {
+			return writer.Write(r, httpStatus, serviceError)
+		}
+	}
 	r.WriteHeader(httpStatus)
 	if _, err := r.Write([]byte(errorReason)); err != nil {
 		return err
@@ -203,6 +279,75 @@ func (r *Response) WriteErrorString(httpStatus int, errorReason string) error {
 	return nil
 }
 
+// notAcceptable is the entity written by the default ServiceErrorHandleFunction for Http Status NotAcceptable.
+type notAcceptable struct {
+	Message    string   `json:"message"`
+	Acceptable []string `json:"acceptable"`
+}
+
+// notAcceptableError returns the ServiceError for a request whose Accept header matches none of the producible MIME types.
+// These are listed in the X-Acceptable header for clients that do not parse the body.
+func notAcceptableError(produces []string) ServiceError {
+	header := http.Header{}
+	header.Set(HEADER_XAcceptable, strings.Join(produces, ","))
+	return NewErrorWithHeader(http.StatusNotAcceptable, "406: Not Acceptable", header)
+}
+
+// writeNotAcceptable writes the error in JSON with the list of MIME types from the X-Acceptable header ;
+// JSON is used whatever the request accepts because none of the producible types is.
+func writeNotAcceptable(err ServiceError, resp *Response) {
+	if resp.err == nil {
+		resp.err = err
+	}
+	entity := notAcceptable{Message: err.Message, Acceptable: []string{}}
+	if acceptable := err.Header.Get(HEADER_XAcceptable); len(acceptable) > 0 {
+		entity.Acceptable = strings.Split(acceptable, ",")
+	}
+	writeJSON(resp, err.Code, MIME_JSON, entity)
+}
+
+// handleServiceError passes the error to the ServiceErrorHandleFunction, if any.
+// The handler is called at most once ; errors it writes itself are written as is.
+// Returns whether the handler was called.
+func (r *Response) handleServiceError(err ServiceError) bool {
+	handler := r.serviceErrorHandler
+	if handler == nil {
+		return false
+	}
+	r.serviceErrorHandler = nil
+	setErrorHeader(err, r)
+	handler(err.withRequestID(r.request), r.request, r)
+	return true
+}
+
+// OnWrite registers a function that is called once the filters and the route function have finished,
+// with statistics of the complete response. Filters can use this to record metrics or access logs.
+// Functions are called in the order they were registered, also if the route function panicked and the Container recovered.
+func (r *Response) OnWrite(hook func(stats ResponseStats)) {
+	r.writeHooks = append(r.writeHooks, hook)
+}
+
+// callWriteHooks calls and removes all functions registered using OnWrite.
+func (r *Response) callWriteHooks() {
+	if len(r.writeHooks) == 0 {
+		return
+	}
+	stats := ResponseStats{
+		StatusCode:    r.StatusCode(),
+		ContentLength: r.contentLength,
+		ContentType:   r.Header().Get(HEADER_ContentType),
+		Err:           r.err,
+	}
+	if r.request != nil && !r.request.startTime.IsZero() {
+		stats.Elapsed = time.Since(r.request.startTime)
+	}
+	hooks := r.writeHooks
+	r.writeHooks = nil
+	for _, each := range hooks {
+		each(stats)
+	}
+}
+
 // Flush implements http.Flusher interface, which sends any buffered data to the client.
 func (r *Response) Flush() {
 	if f, ok := r.ResponseWriter.(http.Flusher); ok {
@@ -216,6 +361,7 @@ func (r *Response) Flush() {
 // Changes to the Header of the response have no effect after this.
 func (r *Response) WriteHeader(httpStatus int) {
 	r.statusCode = httpStatus
+	r.wroteHeader = true
 	r.ResponseWriter.WriteHeader(httpStatus)
 }
 
@@ -252,3 +398,11 @@ func (r *Response) CloseNotify() <-chan bool {
 func (r *Response) Error() error {
 	return r.err
 }
+
+// Aborted returns the error the filter chain was aborted with, if any, see AbortWith.
+func (r *Response) Aborted() (ServiceError, bool) {
+	if r.abortedWith == nil {
+		return ServiceError{}, false
+	}
+	return *r.abortedWith, true
+}
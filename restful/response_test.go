@@ -196,7 +196,22 @@ func TestWriteHeaderAndEntity_Issue235(t *testing.T) {
 
 func TestWriteEntityNoAcceptMatchWithProduces(t *testing.T) {
 	httpWriter := httptest.NewRecorder()
-	resp := Response{ResponseWriter: httpWriter, requestAccept: "application/bogus", routeProduces: []string{"application/json"}, prettyPrint: true}
+	resp := Response{ResponseWriter: httpWriter, requestAccept: "application/bogus", routeProduces: []string{"application/json", "application/xml"}, prettyPrint: false}
+	resp.WriteEntity("done")
+	if httpWriter.Code != http.StatusNotAcceptable {
+		t.Errorf("got %d want %d", httpWriter.Code, http.StatusNotAcceptable)
+	}
+	if got, want := httpWriter.Header().Get(HEADER_XAcceptable), "application/json,application/xml"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := httpWriter.Body.String(), `{"message":"406: Not Acceptable","acceptable":["application/json","application/xml"]}`+"\n"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestWriteEntityNoAcceptWithProduces(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := Response{ResponseWriter: httpWriter, requestAccept: "", routeProduces: []string{"application/json"}, prettyPrint: true}
 	resp.WriteEntity("done")
 	if httpWriter.Code != http.StatusOK {
 		t.Errorf("got %d want %d", httpWriter.Code, http.StatusOK)
@@ -211,3 +226,49 @@ func TestWriteEntityNoAcceptMatchNoProduces(t *testing.T) {
 		t.Errorf("got %d want %d", httpWriter.Code, http.StatusNotAcceptable)
 	}
 }
+
+type unencodable struct {
+	Name    string
+	Updates chan int
+}
+
+// go test -v -test.run TestWriteEntityUnencodable ...restful
+func TestWriteEntityUnencodable(t *testing.T) {
+	for _, pretty := range []bool{true, false} {
+		httpWriter := httptest.NewRecorder()
+		resp := Response{ResponseWriter: httpWriter, requestAccept: MIME_JSON, routeProduces: []string{MIME_JSON}, prettyPrint: pretty}
+		if err := resp.WriteEntity(unencodable{Name: "apple", Updates: make(chan int)}); err == nil {
+			t.Errorf("pretty %v: expected error", pretty)
+		}
+		if got, want := httpWriter.Code, http.StatusInternalServerError; got != want {
+			t.Errorf("pretty %v: got status %d want %d", pretty, got, want)
+		}
+		if strings.Contains(httpWriter.Body.String(), "apple") {
+			t.Errorf("pretty %v: got partial body %q", pretty, httpWriter.Body.String())
+		}
+		if resp.Error() == nil {
+			t.Errorf("pretty %v: expected the error to be kept", pretty)
+		}
+	}
+}
+
+func TestWriteEnveloped(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := Response{ResponseWriter: httpWriter, requestAccept: MIME_JSON, routeProduces: []string{MIME_JSON}, prettyPrint: false}
+	if err := resp.WriteEnveloped(http.StatusCreated, food{Kind: "apple"}, map[string]int{"total": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := httpWriter.Code, http.StatusCreated; got != want {
+		t.Errorf("got status %d want %d", got, want)
+	}
+	if got, want := strings.TrimSpace(httpWriter.Body.String()), `{"data":{"Kind":"apple"},"meta":{"total":1}}`; got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+
+	httpWriter = httptest.NewRecorder()
+	resp = Response{ResponseWriter: httpWriter, requestAccept: MIME_JSON, routeProduces: []string{MIME_JSON}, prettyPrint: false}
+	resp.WriteEnveloped(http.StatusOK, "apple", nil)
+	if got, want := strings.TrimSpace(httpWriter.Body.String()), `{"data":"apple"}`; got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
@@ -5,8 +5,11 @@ package restful
 // that can be found in the LICENSE file.
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
 // RouteFunction declares the signature of a function that can be bound to a Route.
@@ -26,11 +29,24 @@ type Route struct {
 	Function RouteFunction
 	Filters  []FilterFunction
 	If       []RouteSelectionConditionFunction
+	// Conditions describes the If conditions for the documentation, see RouteBuilder.IfDoc
+	Conditions []string
+	// Timeout is the time the Function may take, zero if not limited ; see RouteBuilder.Timeout
+	Timeout time.Duration
+	// Host is the host pattern of requests this Route is restricted to, see RouteBuilder.Host
+	Host string
+	// Version is the version of the API this Route belongs to, see RouteBuilder.Version
+	Version string
+	// EntityReaderWriters by MIME type used by this Route only, see RouteBuilder.EntityCodec
+	EntityCodecs map[string]EntityReaderWriter
 
 	// cached values for dispatching
 	relativePath string
 	pathParts    []string
 	pathExpr     *pathExpression // cached compilation of relativePath as RegExp
+	successCode  int             // the only 2xx code documented in ResponseErrors, zero if none or ambiguous
+	hostPattern  *hostPattern    // compiled Host, nil if not restricted
+	isDefault    bool            // Version is the ApiVersion of the WebService
 
 	// documentation
 	Doc                     string
@@ -39,18 +55,46 @@ type Route struct {
 	ParameterDocs           []*Parameter
 	ResponseErrors          map[int]*ResponseError
 	ReadSample, WriteSample interface{} // structs that model an example request or response payload
+	WriteBinary             string      // the MIME type of a binary response payload, see RouteBuilder.WriteBinary
 
 	// Extra information used to store custom information about the route.
 	Metadata map[string]interface{}
+	// Vendor extensions of the operation, see RouteBuilder.Extension
+	Extensions map[string]interface{}
 
 	// marks a route as deprecated
 	Deprecated bool
+	// if not zero, the date after which a deprecated route will be removed
+	Sunset time.Time
+	// if not empty, refers to information about the sunset
+	SunsetLink string
 	Security   []map[string][]string
 }
 
 // Initialize for Route
 func (r *Route) postBuild() {
 	r.pathParts = tokenizePath(r.Path)
+	r.successCode = r.declaredSuccessCode()
+}
+
+// declaredSuccessCode returns the status code of the ResponseErrors if there is exactly one in the 2xx range.
+func (r *Route) declaredSuccessCode() int {
+	code := 0
+	for each := range r.ResponseErrors {
+		if each/100 != 2 {
+			continue
+		}
+		if code != 0 {
+			return 0
+		}
+		code = each
+	}
+	return code
+}
+
+// hasCatchAll returns whether the last path segment is a catch-all parameter, e.g. /files/{path:*}
+func (r *Route) hasCatchAll() bool {
+	return len(r.pathParts) > 0 && isCatchAllToken(r.pathParts[len(r.pathParts)-1])
 }
 
 // Create Request and Response from their http versions
@@ -58,9 +102,19 @@ func (r *Route) wrapRequestResponse(httpWriter http.ResponseWriter, httpRequest
 	wrappedRequest := NewRequest(httpRequest)
 	wrappedRequest.pathParameters = pathParams
 	wrappedRequest.selectedRoutePath = r.Path
+	wrappedRequest.routeMetadata = r.Metadata
+	wrappedRequest.routeSecurity = r.Security
+	wrappedRequest.routeParams = r.ParameterDocs
+	wrappedRequest.codecs = r.EntityCodecs
 	wrappedResponse := NewResponse(httpWriter)
 	wrappedResponse.requestAccept = httpRequest.Header.Get(HEADER_Accept)
 	wrappedResponse.routeProduces = r.Produces
+	wrappedResponse.codecs = r.EntityCodecs
+	wrappedResponse.ctx = httpRequest.Context()
+	wrappedResponse.errorType = r.negotiatedType(wrappedResponse.requestAccept)
+	if rootName, ok := r.Metadata[KeyXMLRootName].(string); ok {
+		wrappedResponse.xmlRootName = rootName
+	}
 	return wrappedRequest, wrappedResponse
 }
 
@@ -75,6 +129,39 @@ func (r *Route) dispatchWithFilters(wrappedRequest *Request, wrappedResponse *Re
 	}
 }
 
+// negotiatedType returns the first MIME type this Route can produce that is accepted, in order of quality ;
+// empty if there is none.
+func (r Route) negotiatedType(accept string) string {
+	if len(strings.TrimSpace(accept)) == 0 {
+		accept = "*/*"
+	}
+	for _, each := range sortedMimes(accept) {
+		for _, producibleType := range r.Produces {
+			if each.media == "*/*" || each.media == producibleType {
+				return producibleType
+			}
+		}
+	}
+	return ""
+}
+
+// matchesHost returns whether the Route is not restricted to a host or the host of the request matches it.
+func (r Route) matchesHost(httpRequest *http.Request) bool {
+	return r.hostPattern == nil || r.hostPattern.matches(httpRequest.Host)
+}
+
+// matchesVersion returns whether the Route has no Version or the version requested, see Container.VersionResolver ;
+// if no version was requested, whether it has the default version.
+func (r Route) matchesVersion(httpRequest *http.Request) bool {
+	if len(r.Version) == 0 {
+		return true
+	}
+	if requested, ok := httpRequest.Context().Value(requestedVersionKey{}).(string); ok {
+		return r.Version == requested
+	}
+	return r.isDefault
+}
+
 // Return whether the mimeType matches to what this Route can produce.
 func (r Route) matchesAccept(mimeTypesWithQuality string) bool {
 	parts := strings.Split(mimeTypesWithQuality, ",")
@@ -136,6 +223,69 @@ func (r Route) matchesContentType(mimeTypes string) bool {
 	return false
 }
 
+// URL returns the path of the Route with its parameters substituted by the values, followed by the encoded query if any.
+// The values are escaped ; each must match the expression of its path token, e.g. {id:[0-9]+}, and the Regex
+// of its documented path parameter. Missing or invalid values are reported as an error.
+func (r Route) URL(pathParams map[string]string, query url.Values) (string, error) {
+	var buffer strings.Builder
+	for _, each := range tokenizePath(r.Path) {
+		buffer.WriteString("/")
+		if !strings.HasPrefix(each, "{") {
+			buffer.WriteString(each)
+			continue
+		}
+		name, _ := parameterToRegularExpression(each)
+		value, ok := pathParams[name]
+		if !ok || len(value) == 0 {
+			return "", fmt.Errorf("missing value of path parameter %s for route %s", name, r.String())
+		}
+		if err := r.validatePathValue(name, value); err != nil {
+			return "", err
+		}
+		if isCatchAllToken(each) {
+			buffer.WriteString(escapeSegments(value))
+		} else {
+			buffer.WriteString(url.PathEscape(value))
+		}
+	}
+	path := buffer.String()
+	if len(path) == 0 {
+		path = "/"
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// validatePathValue returns an error if the value does not match the expression of the path token, if any,
+// or the regular expression of the documented path parameter with that name.
+func (r Route) validatePathValue(name, value string) error {
+	if r.pathExpr != nil {
+		if matcher, ok := r.pathExpr.valueMatchers[name]; ok && !matcher.MatchString(value) {
+			return fmt.Errorf("invalid value %q of path parameter %s for route %s", value, name, r.String())
+		}
+	}
+	for _, each := range r.ParameterDocs {
+		if each.Name != name || each.In != "path" || each.regex == nil {
+			continue
+		}
+		if !each.regex.MatchString(value) {
+			return fmt.Errorf("invalid value %q of path parameter %s for route %s: %v", value, name, r.String(), errBadPattern)
+		}
+	}
+	return nil
+}
+
+// escapeSegments escapes each segment of a slash-separated value.
+func escapeSegments(value string) string {
+	segments := strings.Split(value, "/")
+	for i, each := range segments {
+		segments[i] = url.PathEscape(each)
+	}
+	return strings.Join(segments, "/")
+}
+
 // Tokenize an URL path using the slash separator ; the result does not have empty tokens
 func tokenizePath(path string) []string {
 	if "/" == path {
@@ -6,11 +6,12 @@ package restful
 
 import (
 	"fmt"
-	"os"
+	"net/http"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/tangblue/goapi/restful/log"
 	"github.com/tangblue/goapi/spec"
@@ -26,6 +27,10 @@ type RouteBuilder struct {
 	function    RouteFunction // required
 	filters     []FilterFunction
 	conditions  []RouteSelectionConditionFunction
+	codecs      entityCodecs
+	host        string
+	version     string
+	timeout     time.Duration
 
 	typeNameHandleFunc TypeNameHandleFunction // required
 
@@ -34,11 +39,18 @@ type RouteBuilder struct {
 	notes                   string
 	operation               string
 	readSample, writeSample interface{}
+	writeBinary             string
 	parameters              []*Parameter
+	conditionDocs           []string // descriptions of the conditions, see IfDoc
 	errorMap                map[int]*ResponseError
 	metadata                map[string]interface{}
+	extensions              map[string]interface{}
 	deprecated              bool
+	sunset                  time.Time
+	sunsetLink              string
 	securities              []map[string][]string
+
+	err error // the first error of the builder, returned by Build
 }
 
 // Do evaluates each argument with the RouteBuilder itself.
@@ -63,11 +75,32 @@ func (b *RouteBuilder) Handler(function RouteFunction) *RouteBuilder {
 	return b
 }
 
+// Security adds a security requirement of the scheme with the scopes ; a request must satisfy one of the requirements
+// of the Route. Use SecurityAll for a requirement of several schemes.
 func (b *RouteBuilder) Security(name string, scopes []string) *RouteBuilder {
+	return b.SecurityAll(SecurityPair{Name: name, Scopes: scopes})
+}
+
+// SecurityPair is a security scheme with the scopes required by a security requirement, see RouteBuilder.SecurityAll.
+type SecurityPair struct {
+	Name   string
+	Scopes []string
+}
+
+// SecurityAll adds a security requirement of all the schemes with their scopes, e.g. a token and an API key.
+func (b *RouteBuilder) SecurityAll(pairs ...SecurityPair) *RouteBuilder {
 	if b.securities == nil {
 		b.securities = []map[string][]string{}
 	}
-	b.securities = append(b.securities, map[string][]string{name: scopes})
+	requirement := map[string][]string{}
+	for _, each := range pairs {
+		scopes := each.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		requirement[each.Name] = scopes
+	}
+	b.securities = append(b.securities, requirement)
 	return b
 }
 
@@ -90,6 +123,8 @@ func (b *RouteBuilder) Consumes(mimeTypes ...string) *RouteBuilder {
 }
 
 // Path specifies the relative (w.r.t WebService root path) URL path to match. Default is "/".
+// Duplicate slashes are collapsed ; the path of the Route ends with a slash if subPath does,
+// e.g. "" and "/" under /users give /users and /users/.
 func (b *RouteBuilder) Path(subPath string) *RouteBuilder {
 	b.currentPath = subPath
 	return b
@@ -140,11 +175,38 @@ func (b *RouteBuilder) Read(sample interface{}, optionalDescription ...string) *
 	b.readSample = sample
 	bodyParameter := BodyParameter("body", description)
 	bodyParameter.DataType(sample)
-	bodyParameter.Typed(typeAsName, "")
+	if !isCollectionSample(sample) {
+		bodyParameter.Typed(typeAsName, "")
+	}
+	// a collection keeps the object type of a body parameter ; its array schema is derived from the sample when documenting
 	b.Params(bodyParameter)
 	return b
 }
 
+// ReadPartial tells what resource type will be read from the request payload, like Read, for a request that only
+// has the fields to update, e.g. PATCH. The body is documented with a variant of the model, e.g. User.Patch,
+// whose fields are all optional and nullable ; the model itself keeps its required fields elsewhere.
+func (b *RouteBuilder) ReadPartial(sample interface{}, optionalDescription ...string) *RouteBuilder {
+	b.Read(sample, optionalDescription...)
+	b.parameters[len(b.parameters)-1].Partial()
+	return b
+}
+
+// isCollectionSample returns whether the sample is a slice or array, other than []byte, or a pointer to one.
+func isCollectionSample(sample interface{}) bool {
+	st := reflect.TypeOf(sample)
+	if st == nil {
+		return false
+	}
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Slice && st.Kind() != reflect.Array {
+		return false
+	}
+	return st.Elem().Kind() != reflect.Uint8
+}
+
 // ParameterNamed returns a Parameter already known to the RouteBuilder. Return nil if not.
 // Use this to modify or extend information for the Parameter (through its Data()).
 func (b RouteBuilder) ParameterNamed(name string) (p *Parameter) {
@@ -162,6 +224,16 @@ func (b *RouteBuilder) Write(sample interface{}) *RouteBuilder {
 	return b
 }
 
+// WriteBinary tells that the response payload is binary data of the MIME type, e.g. image/png, which is added to the Produces.
+// It is documented as a 200 response with a binary string schema unless Return documents another success response.
+func (b *RouteBuilder) WriteBinary(mimeType string) *RouteBuilder {
+	b.writeBinary = mimeType
+	if !containsString(b.produces, mimeType) {
+		b.produces = append(b.produces, mimeType)
+	}
+	return b
+}
+
 // Params allows you to document the parameters of the Route. It adds a new Parameter (does not check for duplicates).
 func (b *RouteBuilder) Params(parameters ...*Parameter) *RouteBuilder {
 	if b.parameters == nil {
@@ -180,15 +252,22 @@ func (b *RouteBuilder) Operation(name string) *RouteBuilder {
 
 // Return allows you to document what responses (errors or regular) can be expected.
 // The model parameter is optional ; either pass a struct instance or use nil if not applicable.
-func (b *RouteBuilder) Return(code int, message string, model interface{}) *RouteBuilder {
-	// lazy init because there is no NewRouteBuilder (yet)
-	if b.errorMap == nil {
-		b.errorMap = map[int]*ResponseError{}
-	}
-	b.errorMap[code] = NewResponseError(code, message, model)
+// The examples, if any, are the bodies of the response for their MIME types, e.g. one per type the Route produces.
+// A response documented before for the code is replaced, with a warning ; use ReturnE to get an error instead.
+func (b *RouteBuilder) Return(code int, message string, model interface{}, examples ...ResponseExample) *RouteBuilder {
+	b.setResponse(newResponseWithExamples(code, message, model, examples))
 	return b
 }
 
+// ReturnE is Return that returns an error, and leaves the response as is, if a response is documented for the code.
+func (b *RouteBuilder) ReturnE(code int, message string, model interface{}, examples ...ResponseExample) error {
+	if _, ok := b.errorMap[code]; ok {
+		return fmt.Errorf("response %d of route %s %s already documented", code, b.httpMethod, b.currentPath)
+	}
+	b.Return(code, message, model, examples...)
+	return nil
+}
+
 // DefaultReturn is a special Return call that sets the default of the response ; the code is zero.
 func (b *RouteBuilder) DefaultReturn(message string, model interface{}) *RouteBuilder {
 	b.Return(0, message, model)
@@ -198,14 +277,22 @@ func (b *RouteBuilder) DefaultReturn(message string, model interface{}) *RouteBu
 }
 
 func (b *RouteBuilder) ReturnResponses(errs ...*ResponseError) *RouteBuilder {
+	for _, e := range errs {
+		b.setResponse(e)
+	}
+	return b
+}
+
+// setResponse documents the response for its code ; a different response documented before is replaced, with a warning.
+func (b *RouteBuilder) setResponse(e *ResponseError) {
 	// lazy init because there is no NewRouteBuilder (yet)
 	if b.errorMap == nil {
 		b.errorMap = map[int]*ResponseError{}
 	}
-	for _, e := range errs {
-		b.errorMap[e.Code] = e
+	if previous, ok := b.errorMap[e.Code]; ok && previous != e {
+		log.Printf("warning: response %d of route %s %s documented twice ; the last one is kept", e.Code, b.httpMethod, b.currentPath)
 	}
-	return b
+	b.errorMap[e.Code] = e
 }
 
 // Metadata adds or updates a key=value pair to the metadata map.
@@ -217,12 +304,47 @@ func (b *RouteBuilder) Metadata(key string, value interface{}) *RouteBuilder {
 	return b
 }
 
+// Extension adds a vendor extension to the documentation of the operation, e.g. x-internal.
+// The key must start with "x-".
+func (b *RouteBuilder) Extension(key string, value interface{}) *RouteBuilder {
+	checkExtensionKey(key)
+	if b.extensions == nil {
+		b.extensions = map[string]interface{}{}
+	}
+	b.extensions[key] = value
+	return b
+}
+
 // Deprecate sets the value of deprecated to true.  Deprecated routes have a special UI treatment to warn against use
 func (b *RouteBuilder) Deprecate() *RouteBuilder {
 	b.deprecated = true
 	return b
 }
 
+// DeprecateWithSunset marks the route deprecated and documents the date after which it will be removed.
+// Responses of the route get the Deprecation and Sunset headers and, if link is not empty,
+// a Link header with rel="sunset" that refers to more information.
+func (b *RouteBuilder) DeprecateWithSunset(date time.Time, link string) *RouteBuilder {
+	b.deprecated = true
+	b.sunset = date
+	b.sunsetLink = link
+	b.filters = append(b.filters, sunsetFilter(date, link))
+	return b
+}
+
+// sunsetFilter returns a FilterFunction that adds the deprecation headers to the response.
+func sunsetFilter(date time.Time, link string) FilterFunction {
+	sunset := date.UTC().Format(http.TimeFormat)
+	return func(req *Request, resp *Response, next func(*Request, *Response)) {
+		resp.Header().Set(HEADER_Deprecation, "true")
+		resp.Header().Set(HEADER_Sunset, sunset)
+		if len(link) > 0 {
+			resp.Header().Add(HEADER_Link, "<"+link+">; rel=\"sunset\"")
+		}
+		next(req, resp)
+	}
+}
+
 // ResponseError represents a response; not necessarily an error.
 type ResponseError struct {
 	spec.Response
@@ -230,6 +352,14 @@ type ResponseError struct {
 	Model     interface{}
 	IsDefault bool
 	RefName   string
+	// MIME types of this response if they differ from the ones the Route produces, see Produces
+	ProducedTypes []string
+}
+
+// ResponseExample is an example of the body of a response for a MIME type, see RouteBuilder.Return.
+type ResponseExample struct {
+	MimeType string
+	Value    interface{}
 }
 
 func NewResponseError(code int, message string, model interface{}) *ResponseError {
@@ -243,6 +373,15 @@ func NewResponseError(code int, message string, model interface{}) *ResponseErro
 	return r
 }
 
+// newResponseWithExamples returns the response with the examples.
+func newResponseWithExamples(code int, message string, model interface{}, examples []ResponseExample) *ResponseError {
+	r := NewResponseError(code, message, model)
+	for _, each := range examples {
+		r.Example(each.MimeType, each.Value)
+	}
+	return r
+}
+
 func (r *ResponseError) SetRefName(refName string) *ResponseError {
 	r.RefName = refName
 	return r
@@ -255,17 +394,39 @@ func (r *ResponseError) Header(name, description string, v interface{}) *Respons
 	return r
 }
 
+// Example documents an example payload of the response for a MIME type, e.g. MIME_JSON or MIME_XML.
+// The value is written as is ; it should be encodable as the MIME type.
+func (r *ResponseError) Example(mime string, value interface{}) *ResponseError {
+	r.AddExample(mime, value)
+	return r
+}
+
+// Produces documents the MIME types of this response if they differ from the ones the Route produces,
+// e.g. MIME_OCTET for the 200 response of a download whose errors are MIME_JSON.
+func (r *ResponseError) Produces(mimeTypes ...string) *ResponseError {
+	r.ProducedTypes = mimeTypes
+	return r
+}
+
 func (b *RouteBuilder) servicePath(path string) *RouteBuilder {
 	b.rootPath = path
 	return b
 }
 
 // Filter appends a FilterFunction to the end of filters for this Route to build.
+// The filters of the Route run after the Container filters and the filters of its WebService.
 func (b *RouteBuilder) Filter(filter FilterFunction) *RouteBuilder {
 	b.filters = append(b.filters, filter)
 	return b
 }
 
+// FilterAt inserts a FilterFunction at the index in the filters for this Route to build.
+// An index below zero inserts at the start, an index beyond the end appends.
+func (b *RouteBuilder) FilterAt(index int, filter FilterFunction) *RouteBuilder {
+	b.filters = insertFilter(b.filters, index, filter)
+	return b
+}
+
 // If sets a condition function that controls matching the Route based on custom logic.
 // The condition function is provided the HTTP request and should return true if the route
 // should be considered.
@@ -281,6 +442,67 @@ func (b *RouteBuilder) If(condition RouteSelectionConditionFunction) *RouteBuild
 	return b
 }
 
+// CORS sets the CORS policy of the Route ; the CORS filter of the Container uses it instead of its own policy
+// for requests, and preflight requests, to the Route. See KeyCORS.
+func (b *RouteBuilder) CORS(policy CrossOriginResourceSharing) *RouteBuilder {
+	return b.Metadata(KeyCORS, &policy)
+}
+
+// RateLimit sets the rate of requests of a client to the Route allowed by the RateLimitFilter of the Container
+// instead of its own, e.g. a stricter one for a login Route. See KeyRateLimit.
+func (b *RouteBuilder) RateLimit(limit RateLimit) *RouteBuilder {
+	return b.Metadata(KeyRateLimit, limit)
+}
+
+// IfDoc is like If and describes the condition for the documentation, e.g. "header X-Feature is set",
+// such that the operation does not look always available.
+func (b *RouteBuilder) IfDoc(condition RouteSelectionConditionFunction, description string) *RouteBuilder {
+	b.conditionDocs = append(b.conditionDocs, description)
+	return b.If(condition)
+}
+
+// Host restricts the Route to requests for the host, e.g. "api.example.com". The port of the request is ignored.
+// The pattern can contain "*" wildcards that match a single label, e.g. "*.example.com",
+// or be a regular expression if it starts with "^", e.g. "^(api|admin)\.example\.com$".
+// Like an If condition, the host is checked before the method, produces and consumes criteria
+// such that other Routes for the same path can still match.
+// If not set then the host of the WebService applies, see WebService.Host.
+func (b *RouteBuilder) Host(pattern string) *RouteBuilder {
+	b.host = pattern
+	return b
+}
+
+// Version sets the version of the API the route belongs to, e.g. v2, such that routes with the same path
+// and method can serve different versions. If the Container has a VersionResolver then the route is selected
+// for requests of that version or, if no route has the version of a request, if it has the default version
+// of its WebService, see WebService.ApiVersion. Without a VersionResolver only routes of the default version are selected.
+// Routes without a version are selected for any version.
+func (b *RouteBuilder) Version(version string) *RouteBuilder {
+	b.version = version
+	return b
+}
+
+// Timeout limits the time the route function may take. Its Request has a context with this deadline.
+// If the function does not return in time then the context is canceled and the error
+// 503 Service Unavailable is written, using the ServiceErrorHandler of the Container if set.
+// The response of the function is buffered until it returns ; writes after the timeout are discarded
+// and fail with http.ErrHandlerTimeout. Filters are not subject to the timeout.
+func (b *RouteBuilder) Timeout(d time.Duration) *RouteBuilder {
+	b.timeout = d
+	return b
+}
+
+// EntityCodec sets the EntityReaderWriter for the MIME type that is used by this Route only.
+// It is consulted before the ones registered using RegisterEntityAccessor, both for reading (ReadEntity)
+// and writing (WriteEntity) entities. The documentation of the Route is not affected.
+func (b *RouteBuilder) EntityCodec(mime string, rw EntityReaderWriter) *RouteBuilder {
+	if b.codecs == nil {
+		b.codecs = entityCodecs{}
+	}
+	b.codecs[mime] = rw
+	return b
+}
+
 // If no specific Route path then set to rootPath
 // If no specific Produce then set to rootProduces
 // If no specific Consume then set to rootConsumes
@@ -300,16 +522,33 @@ func (b *RouteBuilder) typeNameHandler(handler TypeNameHandleFunction) *RouteBui
 	return b
 }
 
-// Build creates a new Route using the specification details collected by the RouteBuilder
-func (b *RouteBuilder) Build() Route {
+// addError keeps the first error of the builder, returned by Build.
+func (b *RouteBuilder) addError(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build creates a new Route using the specification details collected by the RouteBuilder.
+// It returns an error if the path or host is invalid, if no function is specified
+// or if a function of Do reported one, e.g. APIKeyAuth with invalid options.
+func (b *RouteBuilder) Build() (Route, error) {
+	path := concatPath(b.rootPath, b.currentPath)
+	if b.err != nil {
+		return Route{}, fmt.Errorf("invalid route %s %s: %v", b.httpMethod, path, b.err)
+	}
 	pathExpr, err := newPathExpression(b.currentPath)
 	if err != nil {
-		log.Printf("Invalid path:%s because:%v", b.currentPath, err)
-		os.Exit(1)
+		return Route{}, fmt.Errorf("invalid path %s: %v", path, err)
 	}
 	if b.function == nil {
-		log.Printf("No function specified for route:" + b.currentPath)
-		os.Exit(1)
+		return Route{}, fmt.Errorf("no function specified for route %s %s", b.httpMethod, path)
+	}
+	var host *hostPattern
+	if len(b.host) > 0 {
+		if host, err = newHostPattern(b.host); err != nil {
+			return Route{}, fmt.Errorf("invalid host %s of route %s %s: %v", b.host, b.httpMethod, path, err)
+		}
 	}
 	operationName := b.operation
 	if len(operationName) == 0 && b.function != nil {
@@ -318,12 +557,18 @@ func (b *RouteBuilder) Build() Route {
 	}
 	route := Route{
 		Method:         b.httpMethod,
-		Path:           concatPath(b.rootPath, b.currentPath),
+		Path:           path,
 		Produces:       b.produces,
 		Consumes:       b.consumes,
 		Function:       b.function,
+		Timeout:        b.timeout,
 		Filters:        b.filters,
 		If:             b.conditions,
+		Conditions:     b.conditionDocs,
+		Host:           b.host,
+		Version:        b.version,
+		hostPattern:    host,
+		EntityCodecs:   b.codecs,
 		relativePath:   b.currentPath,
 		pathExpr:       pathExpr,
 		Doc:            b.doc,
@@ -333,15 +578,61 @@ func (b *RouteBuilder) Build() Route {
 		ResponseErrors: b.errorMap,
 		ReadSample:     b.readSample,
 		WriteSample:    b.writeSample,
+		WriteBinary:    b.writeBinary,
 		Metadata:       b.metadata,
+		Extensions:     b.extensions,
 		Deprecated:     b.deprecated,
+		Sunset:         b.sunset,
+		SunsetLink:     b.sunsetLink,
 		Security:       b.securities}
+	if b.timeout > 0 {
+		route.Function = timeoutFunction(b.function, b.timeout)
+	}
 	route.postBuild()
+	if err := validateCatchAll(route.pathParts); err != nil {
+		return Route{}, fmt.Errorf("invalid path %s: %v", path, err)
+	}
+	return route, nil
+}
+
+// MustBuild is like Build but panics if the Route cannot be built.
+func (b *RouteBuilder) MustBuild() Route {
+	route, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
 	return route
 }
 
+// concatPath joins the paths with a single slash ; the result has a trailing slash if path2 has one
+// or if path2 is empty and path1 has one, e.g. /users and / give /users/. See cleanPath.
 func concatPath(path1, path2 string) string {
-	return strings.TrimRight(path1, "/") + "/" + strings.TrimLeft(path2, "/")
+	if len(path2) == 0 {
+		return cleanPath(path1)
+	}
+	return cleanPath(strings.TrimRight(path1, "/") + "/" + strings.TrimLeft(path2, "/"))
+}
+
+// cleanPath collapses the duplicate slashes of a path outside its parameter expressions, e.g. /users//{id}/ gives /users/{id}/.
+// Unlike path.Clean, it keeps a trailing slash and dot segments.
+func cleanPath(path string) string {
+	if !strings.Contains(path, "//") {
+		return path
+	}
+	var cleaned strings.Builder
+	depth := 0
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == '/' && depth == 0 && i > 0 && path[i-1] == '/':
+			continue
+		}
+		cleaned.WriteByte(path[i])
+	}
+	return cleaned.String()
 }
 
 var anonymousFuncCount int32
@@ -1,8 +1,13 @@
 package restful
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/tangblue/goapi/restful/log"
 )
 
 func TestRouteBuilder_PathParameter(t *testing.T) {
@@ -14,7 +19,7 @@ func TestRouteBuilder_PathParameter(t *testing.T) {
 	b := new(RouteBuilder)
 	b.function = dummy
 	b.Params(p)
-	r := b.Build()
+	r := b.MustBuild()
 	if r.ParameterDocs[0].CollectionFormat != "multi" {
 		t.Error("AllowMultiple invalid")
 	}
@@ -37,7 +42,7 @@ func TestRouteBuilder(t *testing.T) {
 	b := new(RouteBuilder)
 	b.Handler(dummy)
 	b.Path("/routes").Method("HEAD").Consumes(json).Produces(json).Metadata("test", "test-value").DefaultReturn("default", time.Now())
-	r := b.Build()
+	r := b.MustBuild()
 	if r.Path != "/routes" {
 		t.Error("path invalid")
 	}
@@ -58,6 +63,21 @@ func TestRouteBuilder(t *testing.T) {
 	}
 }
 
+func TestRouteBuilder_ReadCollection(t *testing.T) {
+	b := new(RouteBuilder)
+	b.Handler(dummy).Path("/foods").Method("POST").Read([]food{})
+	body := b.ParameterNamed("body")
+	if body.Type != "object" || body.Format != "" {
+		t.Errorf("unexpected type %q and format %q for collection body", body.Type, body.Format)
+	}
+
+	b = new(RouteBuilder)
+	b.Handler(dummy).Path("/foods").Method("POST").Read(food{})
+	if got, want := b.ParameterNamed("body").Type, "restful.food"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
 func TestAnonymousFuncNaming(t *testing.T) {
 	f1 := func() {}
 	f2 := func() {}
@@ -68,3 +88,152 @@ func TestAnonymousFuncNaming(t *testing.T) {
 		t.Errorf("got %v want %v", got, want)
 	}
 }
+
+func TestRouteBuilder_DeprecateWithSunset(t *testing.T) {
+	sunset := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	wc := NewContainer()
+	ws := new(WebService).Path("/old")
+	ws.Route(ws.GET("").Handler(dummy).DeprecateWithSunset(sunset, "https://example.com/sunset"))
+	wc.Add(ws)
+
+	r := ws.Routes()[0]
+	if !r.Deprecated || !r.Sunset.Equal(sunset) || r.SunsetLink != "https://example.com/sunset" {
+		t.Errorf("sunset not stored on route: %v %v %v", r.Deprecated, r.Sunset, r.SunsetLink)
+	}
+
+	httpRequest, _ := http.NewRequest("GET", "/old", nil)
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httpRequest)
+	for header, want := range map[string]string{
+		HEADER_Deprecation: "true",
+		HEADER_Sunset:      "Wed, 02 Jan 2030 03:04:05 GMT",
+		HEADER_Link:        `<https://example.com/sunset>; rel="sunset"`,
+	} {
+		if got := recorder.Header().Get(header); got != want {
+			t.Errorf("%s: got %q want %q", header, got, want)
+		}
+	}
+}
+
+func TestConcatPath(t *testing.T) {
+	for _, each := range []struct{ root, sub, want string }{
+		{"/users", "", "/users"},
+		{"/users/", "", "/users/"},
+		{"/users", "/", "/users/"},
+		{"/users/", "/{id}", "/users/{id}"},
+		{"/", "", "/"},
+		{"/", "/users", "/users"},
+		{"/", "/", "/"},
+		{"/users", "{id}/", "/users/{id}/"},
+		{"/users//", "//{id}//", "/users/{id}/"},
+		{"//users", "{id}//files", "/users/{id}/files"},
+		{"/users", "{id:a//b}", "/users/{id:a//b}"},
+		{"//users//", "", "/users/"},
+	} {
+		if got := concatPath(each.root, each.sub); got != each.want {
+			t.Errorf("concatPath(%q,%q): got %q want %q", each.root, each.sub, got, each.want)
+		}
+	}
+}
+
+func TestRouteBuilder_ExtensionKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a key without x- prefix")
+		}
+	}()
+	new(RouteBuilder).Extension("internal", true)
+}
+
+func TestRouteBuilder_BuildError(t *testing.T) {
+	for _, each := range []struct{ path, want string }{
+		{"/files/{path:*}/meta", "/users/files/{path:*}/meta"},
+		{"/{id:[0-9}", "/users/{id:[0-9}"},
+	} {
+		_, err := new(RouteBuilder).servicePath("/users").Method("GET").Path(each.path).Handler(dummy).Build()
+		if err == nil || !strings.Contains(err.Error(), each.want) {
+			t.Errorf("%s: expected error with the path, got %v", each.path, err)
+		}
+	}
+	if _, err := new(RouteBuilder).Method("GET").Path("/users").Build(); err == nil {
+		t.Error("expected error for route without function")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic from MustBuild")
+		}
+	}()
+	new(RouteBuilder).Path("/{id:[0-9}").Handler(dummy).MustBuild()
+}
+
+func TestRouteBuilder_IfDoc(t *testing.T) {
+	b := new(RouteBuilder)
+	b.Path("/feature").Handler(dummy).IfDoc(func(req *http.Request) bool { return false }, "never")
+	r := b.MustBuild()
+	if len(r.If) != 1 {
+		t.Fatalf("got %d conditions want 1", len(r.If))
+	}
+	if len(r.Conditions) != 1 || r.Conditions[0] != "never" {
+		t.Errorf("got condition docs %v want [never]", r.Conditions)
+	}
+}
+
+func TestRouteDuplicateSlashes(t *testing.T) {
+	ws := new(WebService).Path("/users//")
+	ws.Route(ws.GET("//{id}").Handler(writeUserId))
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.POST("/").Handler(dummy))
+	paths := []string{}
+	for _, each := range ws.Routes() {
+		paths = append(paths, each.Path)
+	}
+	if got, want := strings.Join(paths, ","), "/users/{id},/users/,/users/"; got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+
+	wc := NewContainer()
+	wc.Add(ws)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httptest.NewRequest("GET", "/users/1", nil))
+	if httpWriter.Code != http.StatusOK || httpWriter.Body.String() != "1" {
+		t.Errorf("got %d %q want 200 \"1\"", httpWriter.Code, httpWriter.Body.String())
+	}
+}
+
+func TestReturnTwice(t *testing.T) {
+	recorder := &entryRecorder{}
+	SetLogger(recorder)
+	defer SetLogger(log.Logger)
+
+	b := new(RouteBuilder).Method("GET").Path("/users").Handler(dummy).
+		Return(http.StatusOK, "OK", []string{}).
+		Return(http.StatusOK, "OK", map[string]string{})
+	if len(recorder.lines) != 1 || !strings.Contains(recorder.lines[0], "response 200 of route GET /users documented twice") {
+		t.Errorf("got %q want a warning", recorder.lines)
+	}
+	if err := b.ReturnE(http.StatusOK, "OK", nil); err == nil {
+		t.Error("got no error for the duplicate code")
+	}
+	if _, ok := b.errorMap[http.StatusOK].Model.(map[string]string); !ok {
+		t.Errorf("got model %T want the last one of Return", b.errorMap[http.StatusOK].Model)
+	}
+	if err := b.ReturnE(http.StatusNotFound, "Not Found", nil); err != nil {
+		t.Errorf("got %v for a new code", err)
+	}
+
+	// sharing a response is not a duplicate
+	b.ReturnResponses(ErrorUnauthorized).ReturnResponses(ErrorUnauthorized)
+	if len(recorder.lines) != 1 {
+		t.Errorf("got %q want no more warnings", recorder.lines)
+	}
+}
+
+func TestReturnExamples(t *testing.T) {
+	b := new(RouteBuilder).Return(http.StatusOK, "OK", nil,
+		ResponseExample{MimeType: MIME_JSON, Value: map[string]string{"id": "1"}},
+		ResponseExample{MimeType: MIME_XML, Value: "<user><id>1</id></user>"})
+	examples := b.errorMap[http.StatusOK].Examples
+	if len(examples) != 2 || examples[MIME_XML] != "<user><id>1</id></user>" {
+		t.Errorf("got examples %v want the JSON and XML ones", examples)
+	}
+}
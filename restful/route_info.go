@@ -0,0 +1,41 @@
+package restful
+
+// RouteInfo describes a Route of a WebService, e.g. for listing the routes on a debug endpoint.
+type RouteInfo struct {
+	Method     string
+	Path       string
+	Operation  string
+	Consumes   []string
+	Produces   []string
+	Tags       []string // from the Route Metadata with key KeyOpenAPITags, if any
+	Deprecated bool
+}
+
+// AllRoutes returns a RouteInfo for each Route of the WebServices registered in the Container,
+// in order of registration.
+func (c *Container) AllRoutes() []RouteInfo {
+	infos := []RouteInfo{}
+	for _, ws := range c.RegisteredWebServices() {
+		for _, each := range ws.Routes() {
+			infos = append(infos, newRouteInfo(each))
+		}
+	}
+	return infos
+}
+
+// newRouteInfo returns the RouteInfo of the Route ; slices are copied.
+func newRouteInfo(r Route) RouteInfo {
+	info := RouteInfo{
+		Method:     r.Method,
+		Path:       r.Path,
+		Operation:  r.Operation,
+		Consumes:   append([]string{}, r.Consumes...),
+		Produces:   append([]string{}, r.Produces...),
+		Tags:       []string{},
+		Deprecated: r.Deprecated,
+	}
+	if tags, ok := r.Metadata[KeyOpenAPITags].([]string); ok {
+		info.Tags = append(info.Tags, tags...)
+	}
+	return info
+}
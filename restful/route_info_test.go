@@ -0,0 +1,27 @@
+package restful
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContainer_AllRoutes(t *testing.T) {
+	wc := NewContainer()
+	users := new(WebService).Path("/users").Consumes(MIME_JSON).Produces(MIME_JSON, MIME_XML)
+	users.Route(users.GET("/{id}").Handler(dummy).Operation("getUser").
+		Metadata(KeyOpenAPITags, []string{"users"}))
+	wc.Add(users)
+	groups := new(WebService).Path("/groups")
+	groups.Route(groups.DELETE("/{id}").Handler(dummy).Operation("deleteGroup").Deprecate())
+	wc.Add(groups)
+
+	want := []RouteInfo{
+		{Method: "GET", Path: "/users/{id}", Operation: "getUser",
+			Consumes: []string{MIME_JSON}, Produces: []string{MIME_JSON, MIME_XML}, Tags: []string{"users"}},
+		{Method: "DELETE", Path: "/groups/{id}", Operation: "deleteGroup",
+			Consumes: []string{}, Produces: []string{}, Tags: []string{}, Deprecated: true},
+	}
+	if got := wc.AllRoutes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+}
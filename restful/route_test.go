@@ -1,6 +1,7 @@
 package restful
 
 import (
+	"net/url"
 	"testing"
 )
 
@@ -74,3 +75,56 @@ func TestTokenizePath(t *testing.T) {
 		t.Errorf("not empty path tokens")
 	}
 }
+
+func TestRouteURL(t *testing.T) {
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{user-id:[0-9]+}/files/{path:*}").Handler(dummy).Operation("getFile"))
+	route, ok := ws.RouteByOperation("getFile")
+	if !ok {
+		t.Fatal("route getFile not found")
+	}
+	got, err := route.URL(map[string]string{"user-id": "42", "path": "a b/c.txt"}, url.Values{"v": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/users/42/files/a%20b/c.txt?v=1"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if _, err := route.URL(map[string]string{"user-id": "x", "path": "a"}, nil); err == nil {
+		t.Error("expected error for value not matching the path expression")
+	}
+	if _, err := route.URL(map[string]string{"path": "a"}, nil); err == nil {
+		t.Error("expected error for missing value")
+	}
+	// the expression is matched as a whole
+	ws.Route(ws.GET("/{kind:admin|guest}").Handler(dummy).Operation("getKind"))
+	kind, _ := ws.RouteByOperation("getKind")
+	for value, valid := range map[string]bool{"admin": true, "guest": true, "admins": false, "xguest": false} {
+		if _, err := kind.URL(map[string]string{"kind": value}, nil); (err == nil) != valid {
+			t.Errorf("%s: got error %v want valid %v", value, err, valid)
+		}
+	}
+	if _, ok := ws.RouteByOperation("unknown"); ok {
+		t.Error("expected no route for unknown operation")
+	}
+}
+
+func TestRouteURLParameterRegex(t *testing.T) {
+	ws := new(WebService).Path("/")
+	ws.Route(ws.GET("/items/{%s}", ws.PathParameter("name", "").Regex("^[a-z]+$")).Handler(dummy))
+	route := ws.Routes()[0]
+	got, err := route.URL(map[string]string{"name": "abc"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/items/abc" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := route.URL(map[string]string{"name": "a/b"}, nil); err == nil {
+		t.Error("expected error for value not matching the parameter regex")
+	}
+	root := Route{Path: "/"}
+	if got, _ := root.URL(nil, nil); got != "/" {
+		t.Errorf("got %q want /", got)
+	}
+}
@@ -0,0 +1,67 @@
+package restful
+
+import (
+	"net/http"
+	"strings"
+)
+
+// EnableRouteTracing (default=false) sets whether the error response for a request that matches no Route explains
+// why each Route of the WebService for its path was rejected. The explanation is logged and written in the
+// X-Route-Trace header, e.g. "GET /users/{id}: method; PUT /users/{id}: content-type".
+// The reasons are path, host, version (not of the default version, see RouteBuilder.Version),
+// condition (an If function returned false), method, content-type and accept, in the order the checks are made. Tracing has no cost for requests that match a Route.
+// Do not enable it in production because the header reveals the Routes of the service.
+func (c *Container) EnableRouteTracing(enabled bool) {
+	c.routeTracing = enabled
+}
+
+// routeTrace returns the explanation why no Route of the WebService for the path of the request matches it.
+// The checks are those of the CurlyRouter.
+func routeTrace(webServices []*WebService, httpRequest *http.Request) string {
+	requestTokens := requestPathTokens(httpRequest)
+	ws := CurlyRouter{}.detectWebService(requestTokens, webServices)
+	if ws == nil {
+		return "no WebService matches path " + httpRequest.URL.Path
+	}
+	routes := ws.Routes()
+	if len(routes) == 0 {
+		return "WebService " + ws.RootPath() + " has no Routes"
+	}
+	reasons := make([]string, 0, len(routes))
+	for i := range routes {
+		reasons = append(reasons, routes[i].String()+": "+rejectionOf(&routes[i], ws, requestTokens, httpRequest))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// rejectionOf returns the first check of the Route that the request fails, "none" if it passes all.
+func rejectionOf(route *Route, ws *WebService, requestTokens []string, httpRequest *http.Request) string {
+	if matches, _, _ := (CurlyRouter{}).matchesRouteByPathTokens(route.pathParts, requestTokens, ws.caseInsensitivePaths); !matches {
+		return "path"
+	}
+	if !route.matchesHost(httpRequest) {
+		return "host"
+	}
+	if !route.matchesVersion(httpRequest) {
+		return "version"
+	}
+	for _, fn := range route.If {
+		if !fn(httpRequest) {
+			return "condition"
+		}
+	}
+	if route.Method != httpRequest.Method {
+		return "method"
+	}
+	if !route.matchesContentType(httpRequest.Header.Get(HEADER_ContentType)) {
+		return "content-type"
+	}
+	accept := httpRequest.Header.Get(HEADER_Accept)
+	if len(accept) == 0 {
+		accept = "*/*"
+	}
+	if !route.matchesAccept(accept) {
+		return "accept"
+	}
+	return "none"
+}
@@ -0,0 +1,62 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRouteTracingContainer() *Container {
+	wc := NewContainer()
+	wc.EnableRouteTracing(true)
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{id}").Produces(MIME_JSON).Handler(dummy))
+	ws.Route(ws.PUT("/{id}").Consumes(MIME_JSON).Handler(dummy))
+	ws.Route(ws.GET("/{id}/admin").Host("admin.example.com").Handler(dummy))
+	ws.Route(ws.GET("/{id}/beta").If(func(*http.Request) bool { return false }).Handler(dummy))
+	wc.Add(ws)
+	return wc
+}
+
+func TestRouteTracing(t *testing.T) {
+	for _, each := range []struct {
+		method, path, header, value string
+		want                        []string
+	}{
+		{"GET", "/users/1/other", "", "", []string{"GET /users/{id}: path", "GET /users/{id}/admin: path"}},
+		{"GET", "/users/1/admin", "", "", []string{"GET /users/{id}/admin: host"}},
+		{"GET", "/users/1/beta", "", "", []string{"GET /users/{id}/beta: condition"}},
+		{"DELETE", "/users/1", "", "", []string{"GET /users/{id}: method", "PUT /users/{id}: method"}},
+		{"PUT", "/users/1", HEADER_ContentType, MIME_XML, []string{"PUT /users/{id}: content-type"}},
+		{"GET", "/users/1", HEADER_Accept, MIME_XML, []string{"GET /users/{id}: accept"}},
+	} {
+		req := httptest.NewRequest(each.method, each.path, nil)
+		if len(each.header) > 0 {
+			req.Header.Set(each.header, each.value)
+		}
+		recorder := httptest.NewRecorder()
+		newRouteTracingContainer().ServeHTTP(recorder, req)
+		got := recorder.Header().Get(HEADER_XRouteTrace)
+		for _, want := range each.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s %s: expected %q in trace %q", each.method, each.path, want, got)
+			}
+		}
+	}
+}
+
+func TestRouteTracingOnlyForErrors(t *testing.T) {
+	wc := newRouteTracingContainer()
+	recorder := httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/1", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get(HEADER_XRouteTrace) != "" {
+		t.Errorf("unexpected trace for a matching request: %d %q", recorder.Code, recorder.Header().Get(HEADER_XRouteTrace))
+	}
+	wc.EnableRouteTracing(false)
+	recorder = httptest.NewRecorder()
+	wc.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/users/1", nil))
+	if got := recorder.Header().Get(HEADER_XRouteTrace); got != "" {
+		t.Errorf("unexpected trace when disabled: %q", got)
+	}
+}
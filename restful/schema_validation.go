@@ -0,0 +1,138 @@
+package restful
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+var errRequired = errors.New("required")
+
+// definitionsPrefix is the prefix of the references that ValidateAgainstSchema resolves.
+const definitionsPrefix = "#/definitions/"
+
+// ValidateAgainstSchema validates the JSON body, e.g. of RawBody, against the enums and required properties
+// of the schema, of its properties and items and of the schemas it is composed of (allOf). The enums can be loaded
+// at startup, e.g. the valid country codes of a data file, whereas GetParameter only validates those of parameters.
+// The body is validated rather than the entity read from it, such that a missing property is not taken
+// for its zero value. References to #/definitions/ are resolved against the definitions, which can be nil.
+// The error is a 400 Bad Request ServiceError naming the invalid property ; an unresolved reference is not.
+//
+//	body, err := req.RawBody()
+//	...
+//	if err := restful.ValidateAgainstSchema(body, userSchema, definitions); err != nil {
+//		resp.WriteError(http.StatusBadRequest, err)
+//		return
+//	}
+//	req.ReadEntity(&user)
+func ValidateAgainstSchema(body []byte, schema *spec.Schema, definitions spec.Definitions) error {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return NewError(http.StatusBadRequest, "400: Bad Request, "+err.Error())
+	}
+	if err := validateSchema("", decoded, schema, definitions); err != nil {
+		if _, unresolved := err.(unresolvedRefError); unresolved {
+			return err
+		}
+		return NewError(http.StatusBadRequest, "400: Bad Request, "+err.Error())
+	}
+	return nil
+}
+
+// unresolvedRefError is the error of a reference that is not one of the definitions.
+type unresolvedRefError string
+
+func (e unresolvedRefError) Error() string {
+	return "unresolved reference " + string(e)
+}
+
+// validateSchema validates the JSON value at the path against the schema.
+func validateSchema(path string, value interface{}, schema *spec.Schema, definitions spec.Definitions) error {
+	if schema == nil || value == nil {
+		return nil
+	}
+	if ref := schema.Ref.String(); len(ref) > 0 {
+		definition, ok := definitions[strings.TrimPrefix(ref, definitionsPrefix)]
+		if !ok || !strings.HasPrefix(ref, definitionsPrefix) {
+			return unresolvedRefError(ref)
+		}
+		return validateSchema(path, value, &definition, definitions)
+	}
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		return schemaError(path, errBadEnum)
+	}
+	for i := range schema.AllOf {
+		if err := validateSchema(path, value, &schema.AllOf[i], definitions); err != nil {
+			return err
+		}
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				return schemaError(propertyPath(path, name), errRequired)
+			}
+		}
+		// sorted such that the first invalid property is always reported
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop := schema.Properties[name]
+			if err := validateSchema(propertyPath(path, name), value[name], &prop, definitions); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if schema.Items == nil {
+			return nil
+		}
+		for i, each := range value {
+			items := schema.Items.Schema
+			if i < len(schema.Items.Schemas) {
+				items = &schema.Items.Schemas[i]
+			}
+			if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), each, items, definitions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// inEnum returns whether the JSON value is one of the enum values, compared as JSON, e.g. 1 and Status(1).
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, each := range enum {
+		data, err := json.Marshal(each)
+		if err != nil {
+			continue
+		}
+		var decoded interface{}
+		if json.Unmarshal(data, &decoded) == nil && reflect.DeepEqual(decoded, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func propertyPath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+func schemaError(path string, err error) error {
+	if len(path) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s: %v", path, err)
+}
@@ -0,0 +1,83 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+type address struct {
+	Street  string   `json:"street"`
+	Country string   `json:"country"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// countries are loaded from a data file at startup
+var countries = []interface{}{"FR", "JP", "NL"}
+
+var addressSchema = new(spec.Schema).
+	WithRequired("street", "country").
+	SetProperty("street", *spec.StringProperty()).
+	SetProperty("country", *spec.StringProperty().WithEnum(countries...)).
+	SetProperty("tags", *spec.ArrayProperty(spec.StringProperty().WithEnum("home", "work")))
+
+var addressDefinitions = spec.Definitions{"address": *addressSchema}
+
+func createAddress(req *Request, resp *Response) {
+	body, err := req.RawBody()
+	if err != nil {
+		resp.WriteError(http.StatusBadRequest, err)
+		return
+	}
+	if err := ValidateAgainstSchema(body, addressSchema, nil); err != nil {
+		resp.WriteError(http.StatusBadRequest, err)
+		return
+	}
+	var a address
+	if err := req.ReadEntity(&a); err != nil {
+		resp.WriteError(http.StatusBadRequest, err)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	wc := NewContainer()
+	ws := new(WebService).Path("/addresses").Consumes(MIME_JSON)
+	ws.Route(ws.POST("").Handler(createAddress))
+	wc.Add(ws)
+
+	for _, each := range []struct {
+		body  string
+		code  int
+		error string
+	}{
+		{`{"street":"Dam","country":"NL","tags":["home"]}`, http.StatusCreated, ""},
+		{`{"street":"Dam","country":"XX"}`, http.StatusBadRequest, "country: bad enum"},
+		{`{"street":"Dam","country":"NL","tags":["home","gym"]}`, http.StatusBadRequest, "tags[1]: bad enum"},
+		// not the zero value of the field
+		{`{"street":"Dam"}`, http.StatusBadRequest, "country: required"},
+	} {
+		httpRequest := httptest.NewRequest("POST", "/addresses", strings.NewReader(each.body))
+		httpRequest.Header.Set(HEADER_ContentType, MIME_JSON)
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if httpWriter.Code != each.code || !strings.Contains(httpWriter.Body.String(), each.error) {
+			t.Errorf("%s: got %d %q want %d %q", each.body, httpWriter.Code, httpWriter.Body.String(), each.code, each.error)
+		}
+	}
+
+	// the references are resolved against the definitions
+	people := spec.ArrayProperty(spec.RefProperty("#/definitions/address"))
+	err := ValidateAgainstSchema([]byte(`[{"street":"Dam","country":"NL"},{"street":"Dam"}]`), people, addressDefinitions)
+	if ser, ok := err.(ServiceError); !ok || ser.Code != http.StatusBadRequest || !strings.Contains(ser.Message, "[1].country: required") {
+		t.Errorf("got %v want a missing country", err)
+	}
+	err = ValidateAgainstSchema([]byte(`[{"street":"Dam"}]`), people, nil)
+	if _, ok := err.(ServiceError); ok || err == nil || !strings.Contains(err.Error(), "#/definitions/address") {
+		t.Errorf("got %v want an unresolved reference", err)
+	}
+}
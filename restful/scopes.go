@@ -0,0 +1,77 @@
+package restful
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScopesFunction returns the scopes granted to the request, e.g. by its OAuth2 token ;
+// an error if the request is not authenticated.
+type ScopesFunction func(req *Request) ([]string, error)
+
+// ScopesFilter returns a FilterFunction that enforces the scopes of the security requirements of the selected Route,
+// see RouteBuilder.Security. The request is allowed if one of the requirements has all its scopes granted.
+// Otherwise the chain is aborted with 401 Unauthorized if the scopes function returns an error, or with 403 Forbidden
+// listing the scopes missing from the first closest requirement, see AbortWith. Routes without scopes are not checked.
+// Install it after the filter that authenticates the requests, e.g. as a Route filter like jwtauth.RequireScopes does.
+func ScopesFilter(scopes ScopesFunction) FilterFunction {
+	return scopes.filter
+}
+
+func (f ScopesFunction) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	if !requiresScopes(req.routeSecurity) {
+		next(req, resp)
+		return
+	}
+	scopes, err := f(req)
+	if err != nil {
+		AbortWith(resp, NewError(http.StatusUnauthorized, "401: Unauthorized"))
+		return
+	}
+	granted := map[string]bool{}
+	for _, each := range scopes {
+		granted[each] = true
+	}
+	var missing []string
+	for _, requirement := range req.routeSecurity {
+		notGranted := missingScopes(requirement, granted)
+		if len(notGranted) == 0 {
+			next(req, resp)
+			return
+		}
+		if missing == nil || len(notGranted) < len(missing) {
+			missing = notGranted
+		}
+	}
+	scope := strings.Join(missing, " ")
+	resp.Header().Set(HEADER_WWWAuthenticate, `Bearer error="insufficient_scope", scope=`+strconv.Quote(scope))
+	AbortWith(resp, NewError(http.StatusForbidden, "403: Forbidden, missing scopes "+strings.Join(missing, ", ")))
+}
+
+// requiresScopes returns whether one of the security requirements has scopes.
+func requiresScopes(security []map[string][]string) bool {
+	for _, requirement := range security {
+		for _, scopes := range requirement {
+			if len(scopes) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// missingScopes returns the sorted scopes of the requirement that are not granted.
+func missingScopes(requirement map[string][]string, granted map[string]bool) []string {
+	missing := []string{}
+	for _, scopes := range requirement {
+		for _, each := range scopes {
+			if !granted[each] {
+				missing = append(missing, each)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,76 @@
+package restful
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// headerScopes returns the scopes of the X-Scopes header ; an error if the request has none
+func headerScopes(req *Request) ([]string, error) {
+	header, ok := req.Request.Header["X-Scopes"]
+	if !ok {
+		return nil, errors.New("no token")
+	}
+	return strings.Fields(strings.Join(header, " ")), nil
+}
+
+func newScopesContainer() *Container {
+	wc := NewContainer()
+	wc.Filter(ScopesFilter(headerScopes))
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.GET("/{id}").Handler(writeUserId).
+		Security("oauth2", []string{"users.read"}).
+		Security("admin_oauth2", []string{"admin", "audit"}))
+	ws.Route(ws.PUT("/{id}").Handler(writeUserId).Security("oauth2", []string{"users.read", "users.write"}))
+	ws.Route(ws.DELETE("/{id}").Handler(writeUserId).SecurityAll(
+		SecurityPair{Name: "oauth2", Scopes: []string{"users.write"}},
+		SecurityPair{Name: "admin_oauth2", Scopes: []string{"admin"}}))
+	wc.Add(ws)
+	return wc
+}
+
+func TestScopesFilter(t *testing.T) {
+	wc := newScopesContainer()
+	for _, each := range []struct {
+		method  string
+		path    string
+		scopes  string // none if empty
+		want    int
+		missing string
+	}{
+		{"GET", "/users", "", http.StatusOK, ""},
+		{"GET", "/users/1", "users.read", http.StatusOK, ""},
+		{"GET", "/users/1", "admin audit", http.StatusOK, ""},
+		{"GET", "/users/1", "admin", http.StatusForbidden, "users.read"}, // the first of the closest requirements
+		{"GET", "/users/1", "-", http.StatusForbidden, "users.read"},
+		{"GET", "/users/1", "", http.StatusUnauthorized, ""},
+		{"PUT", "/users/1", "users.read", http.StatusForbidden, "users.write"},
+		{"PUT", "/users/1", "users.write users.read", http.StatusOK, ""},
+		{"DELETE", "/users/1", "users.write", http.StatusForbidden, "admin"},
+		{"DELETE", "/users/1", "admin users.write", http.StatusOK, ""},
+	} {
+		httpRequest := httptest.NewRequest(each.method, each.path, nil)
+		if len(each.scopes) > 0 {
+			httpRequest.Header.Set("X-Scopes", each.scopes)
+		}
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if httpWriter.Code != each.want {
+			t.Errorf("%s %s %q: got status %d want %d", each.method, each.path, each.scopes, httpWriter.Code, each.want)
+			continue
+		}
+		if each.want != http.StatusForbidden {
+			continue
+		}
+		if got, want := httpWriter.Body.String(), "403: Forbidden, missing scopes "+each.missing; got != want {
+			t.Errorf("%s %s %q: got %q want %q", each.method, each.path, each.scopes, got, want)
+		}
+		if got, want := httpWriter.Header().Get(HEADER_WWWAuthenticate), `Bearer error="insufficient_scope", scope="`+each.missing+`"`; got != want {
+			t.Errorf("%s %s %q: got challenge %q want %q", each.method, each.path, each.scopes, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package restful
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/tangblue/goapi/restful/log"
+)
+
+// ShutdownTimeout is the time RunUntilSignal waits for requests in flight to complete.
+var ShutdownTimeout = 30 * time.Second
+
+// ServeOption changes the http.Server created by Container.Serve, e.g. its timeouts.
+type ServeOption func(server *http.Server)
+
+// WithReadTimeout sets the maximum duration for reading an entire request, including the body.
+func WithReadTimeout(d time.Duration) ServeOption {
+	return func(server *http.Server) { server.ReadTimeout = d }
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the response.
+func WithWriteTimeout(d time.Duration) ServeOption {
+	return func(server *http.Server) { server.WriteTimeout = d }
+}
+
+// WithIdleTimeout sets the maximum amount of time to wait for the next request when keep-alives are enabled.
+func WithIdleTimeout(d time.Duration) ServeOption {
+	return func(server *http.Server) { server.IdleTimeout = d }
+}
+
+// Serve listens on the TCP network address and serves the requests using the Container until it is shut down,
+// see Shutdown. The http.Server has timeouts that can be changed using the options.
+// It returns nil after Shutdown is called, also if Shutdown is called before it.
+func (c *Container) Serve(addr string, opts ...ServeOption) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return c.ServeListener(listener, opts...)
+}
+
+// ServeListener is like Serve but accepts the connections of the listener.
+func (c *Container) ServeListener(listener net.Listener, opts ...ServeOption) error {
+	server := &http.Server{
+		Handler:           c,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+	for _, each := range opts {
+		each(server)
+	}
+	c.serverLock.Lock()
+	if c.shuttingDown {
+		c.serverLock.Unlock()
+		listener.Close()
+		return nil
+	}
+	if c.server != nil {
+		c.serverLock.Unlock()
+		listener.Close()
+		return errors.New("container is already serving")
+	}
+	c.server = server
+	c.serverLock.Unlock()
+
+	err := server.Serve(listener)
+	c.serverLock.Lock()
+	c.server = nil
+	c.serverLock.Unlock()
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// OnShutdown registers a function that is called by Shutdown after the requests in flight have completed,
+// e.g. to close the connections of a database. The functions are called in order of registration.
+func (c *Container) OnShutdown(hook func(ctx context.Context) error) {
+	c.serverLock.Lock()
+	defer c.serverLock.Unlock()
+	c.shutdownHooks = append(c.shutdownHooks, hook)
+}
+
+// InFlight returns the number of requests that the Container is dispatching, i.e. that have not completed.
+func (c *Container) InFlight() int64 {
+	return atomic.LoadInt64(&c.inFlight)
+}
+
+// Shutdown stops the server of Serve from accepting new requests and waits for the requests in flight to complete
+// or the context to be done. Then it calls the OnShutdown functions with the context, also if the context is done.
+// It returns the first error of the server or the functions. Serve does not start once Shutdown is called.
+func (c *Container) Shutdown(ctx context.Context) error {
+	c.serverLock.Lock()
+	c.shuttingDown = true
+	server, hooks := c.server, append([]func(context.Context) error{}, c.shutdownHooks...)
+	c.serverLock.Unlock()
+
+	var err error
+	if server != nil {
+		log.Printf("shutting down, waiting for %d requests in flight", c.InFlight())
+		if err = server.Shutdown(ctx); err != nil {
+			log.Printf("shutdown did not complete, %d requests in flight: %v", c.InFlight(), err)
+		}
+	}
+	for _, each := range hooks {
+		if hookErr := each(ctx); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	return err
+}
+
+// RunUntilSignal serves the requests using the Container, see Serve, until the process receives
+// an interrupt or terminate signal. Then it shuts the Container down, waiting at most ShutdownTimeout.
+func RunUntilSignal(container *Container, addr string, opts ...ServeOption) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- container.Serve(addr, opts...)
+	}()
+	select {
+	case err := <-served:
+		return err
+	case sig := <-signals:
+		log.Printf("received signal %v", sig)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	err := container.Shutdown(ctx)
+	if serveErr := <-served; err == nil {
+		err = serveErr
+	}
+	return err
+}
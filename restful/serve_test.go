@@ -0,0 +1,125 @@
+package restful
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestContainerShutdown(t *testing.T) {
+	wc := NewContainer()
+	started, release := make(chan bool), make(chan bool)
+	slow := new(WebService).Path("/slow")
+	slow.Route(slow.GET("").Handler(blockingHandler{started, release}.serve))
+	wc.Add(slow)
+	hooks := 0
+	wc.OnShutdown(func(ctx context.Context) error {
+		hooks++
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- wc.ServeListener(listener) }()
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	responded := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			responded <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		responded <- result{code: resp.StatusCode, body: string(body)}
+	}()
+	<-started
+	if got, want := wc.InFlight(), int64(1); got != want {
+		t.Errorf("got %d requests in flight want %d", got, want)
+	}
+
+	// the slow request does not complete within the context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := wc.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v want %v", err, context.DeadlineExceeded)
+	}
+	if hooks != 1 {
+		t.Errorf("got %d hook calls want 1", hooks)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("ServeListener: %v", err)
+	}
+
+	// the request in flight completes after the server stopped accepting requests
+	close(release)
+	res := <-responded
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if res.code != http.StatusOK || res.body != "done" {
+		t.Errorf("got %d %q want 200 \"done\"", res.code, res.body)
+	}
+	if err := wc.Shutdown(context.Background()); err != nil {
+		t.Errorf("got %v want nil", err)
+	}
+	if got := wc.InFlight(); got != 0 {
+		t.Errorf("got %d requests in flight want 0", got)
+	}
+}
+
+func TestContainerShutdownHookError(t *testing.T) {
+	wc := NewContainer()
+	closed := errors.New("already closed")
+	calls := 0
+	wc.OnShutdown(func(ctx context.Context) error {
+		calls++
+		return closed
+	})
+	wc.OnShutdown(func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	if err := wc.Shutdown(context.Background()); err != closed {
+		t.Errorf("got %v want %v", err, closed)
+	}
+	if calls != 2 {
+		t.Errorf("got %d hook calls want 2", calls)
+	}
+}
+
+func TestContainerShutdownBeforeServe(t *testing.T) {
+	wc := NewContainer()
+	if err := wc.Shutdown(context.Background()); err != nil {
+		t.Errorf("got %v want nil", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- wc.ServeListener(listener) }()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("got %v want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ServeListener did not return after Shutdown")
+	}
+	if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Error("expected the listener to be closed")
+	}
+}
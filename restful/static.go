@@ -0,0 +1,46 @@
+package restful
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// StaticService returns a WebService that serves the files of the file system under the root path, e.g. the
+// swagger-ui distribution embedded using go:embed or os.DirFS. Unlike a http.FileServer registered on the ServeMux, its requests
+// are processed by the Container filters.
+//
+// The Content-Type is detected from the file extension or the content. A directory is served by its index.html,
+// missing files get 404 Not Found and paths with ".." segments are rejected. The Routes are hidden from the OpenAPI
+// documentation, see KeyOpenAPIHidden.
+func StaticService(rootPath string, fsys fs.FS) *WebService {
+	files := staticFiles{server: http.FileServer(http.FS(fsys))}
+	ws := new(WebService).Path(rootPath).Produces("*/*")
+	ws.Route(ws.GET("").Handler(files.serve).
+		Metadata(KeyOpenAPIHidden, true).
+		Doc("index of the static files"))
+	ws.Route(ws.GET("/{file:*}").Handler(files.serve).
+		Metadata(KeyOpenAPIHidden, true).
+		Doc("static file"))
+	return ws
+}
+
+// staticFiles is the resource of the WebService returned by StaticService.
+type staticFiles struct {
+	server http.Handler
+}
+
+// serve lets the file server handle the request with the path of the file relative to the root path.
+func (s staticFiles) serve(req *Request, resp *Response) {
+	fileRequest := new(http.Request)
+	*fileRequest = *req.Request
+	url := *req.Request.URL
+	url.Path = "/" + req.pathParameters["file"]
+	if strings.HasSuffix(req.Request.URL.Path, "/") && !strings.HasSuffix(url.Path, "/") {
+		// a directory, served by its index.html instead of a redirect
+		url.Path += "/"
+	}
+	url.RawPath = ""
+	fileRequest.URL = &url
+	s.server.ServeHTTP(resp, fileRequest)
+}
@@ -0,0 +1,91 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newStaticContainer() *Container {
+	files := fstest.MapFS{
+		"index.html":          {Data: []byte("<html>index</html>")},
+		"css/site.css":        {Data: []byte("body {}")},
+		"js/lib/app.js":       {Data: []byte("var app;")},
+		"docs/index.html":     {Data: []byte("<html>docs</html>")},
+		"docs/swagger.json":   {Data: []byte(`{"swagger":"2.0"}`)},
+		"docs/deep/notes.txt": {Data: []byte("notes")},
+	}
+	wc := NewContainer()
+	wc.Add(StaticService("/static", files))
+	return wc
+}
+
+func TestStaticService(t *testing.T) {
+	wc := newStaticContainer()
+	for _, each := range []struct {
+		path, contentType, body string
+	}{
+		{"/static/", "text/html; charset=utf-8", "<html>index</html>"},
+		{"/static/css/site.css", "text/css; charset=utf-8", "body {}"},
+		{"/static/js/lib/app.js", "text/javascript; charset=utf-8", "var app;"},
+		{"/static/docs/", "text/html; charset=utf-8", "<html>docs</html>"},
+		{"/static/docs/swagger.json", "application/json", `{"swagger":"2.0"}`},
+		{"/static/docs/deep/notes.txt", "text/plain; charset=utf-8", "notes"},
+	} {
+		req := httptest.NewRequest("GET", each.path, nil)
+		req.Header.Set(HEADER_Accept, "text/html,*/*;q=0.8")
+		rec := httptest.NewRecorder()
+		wc.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: got status %d want 200", each.path, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get(HEADER_ContentType); got != each.contentType {
+			t.Errorf("%s: got Content-Type %q want %q", each.path, got, each.contentType)
+		}
+		if got := rec.Body.String(); got != each.body {
+			t.Errorf("%s: got body %q want %q", each.path, got, each.body)
+		}
+	}
+}
+
+func TestStaticServiceNotFound(t *testing.T) {
+	wc := newStaticContainer()
+	for _, each := range []string{
+		"/static/missing.html",
+		"/static/docs/deep/missing.txt",
+		"/static/css/site.css/more",
+	} {
+		rec := httptest.NewRecorder()
+		wc.ServeHTTP(rec, httptest.NewRequest("GET", each, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: got status %d want 404", each, rec.Code)
+		}
+	}
+}
+
+func TestStaticServiceTraversal(t *testing.T) {
+	wc := newStaticContainer()
+	for _, each := range []string{
+		"/static/../container.go",
+		"/static/docs/../../static.go",
+		"/static/%2e%2e/static.go",
+		"/static/..%2fstatic.go",
+	} {
+		rec := httptest.NewRecorder()
+		wc.ServeHTTP(rec, httptest.NewRequest("GET", each, nil))
+		if rec.Code == http.StatusOK || strings.Contains(rec.Body.String(), "package restful") {
+			t.Errorf("%s: got status %d, body %q", each, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestStaticServiceHidden(t *testing.T) {
+	for _, each := range StaticService("/static", fstest.MapFS{}).Routes() {
+		if hidden, _ := each.Metadata[KeyOpenAPIHidden].(bool); !hidden {
+			t.Errorf("%s: expected hidden from the OpenAPI documentation", each.String())
+		}
+	}
+}
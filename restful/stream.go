@@ -0,0 +1,58 @@
+package restful
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// StreamFlushInterval is the default number of entities written by WriteJSONStream or an NDJSONWriter
+// before the response is flushed to the client.
+var StreamFlushInterval = 100
+
+// WriteJSONStream writes the values received from the channel as the elements of a JSON array,
+// one by one, flushing the response every StreamFlushInterval elements. It returns when the channel
+// is closed or the request is canceled. If the underlying ResponseWriter does not implement
+// http.Flusher then the elements are written without flushing.
+// An element that cannot be encoded aborts the connection because the array is already incomplete.
+func (r *Response) WriteJSONStream(items <-chan interface{}) error {
+	var done <-chan struct{}
+	if r.ctx != nil {
+		done = r.ctx.Done()
+	}
+	r.Header().Set(HEADER_ContentType, MIME_JSON)
+	r.WriteHeader(http.StatusOK)
+	if _, err := r.Write([]byte{'['}); err != nil {
+		return err
+	}
+	count := 0
+	for {
+		select {
+		case each, ok := <-items:
+			if !ok {
+				_, err := r.Write([]byte{']', '\n'})
+				r.Flush()
+				return err
+			}
+			var buffer bytes.Buffer
+			if count > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := NewEncoder(&buffer).Encode(each); err != nil {
+				if trace {
+					traceLogger.Printf("aborting json stream after %d elements: %v", count, err)
+				}
+				panic(http.ErrAbortHandler)
+			}
+			// Encode appends a newline which separates the elements
+			if _, err := r.Write(buffer.Bytes()); err != nil {
+				return err
+			}
+			count++
+			if StreamFlushInterval <= 0 || count%StreamFlushInterval == 0 {
+				r.Flush()
+			}
+		case <-done:
+			return r.ctx.Err()
+		}
+	}
+}
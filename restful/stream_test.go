@@ -0,0 +1,54 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// flushRecorder remembers the body content at each Flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushed = append(f.flushed, f.Body.String())
+}
+
+func TestWriteJSONStream(t *testing.T) {
+	defer func(interval int) { StreamFlushInterval = interval }(StreamFlushInterval)
+	StreamFlushInterval = 1
+
+	httpWriter := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	resp := NewResponse(httpWriter)
+	items := make(chan interface{}, 2)
+	items <- food{"apple"}
+	items <- food{"pear"}
+	close(items)
+	if err := resp.WriteJSONStream(items); err != nil {
+		t.Fatalf("unexpected error:%v", err)
+	}
+	if got, want := httpWriter.Body.String(), "[{\"Kind\":\"apple\"}\n,{\"Kind\":\"pear\"}\n]\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if len(httpWriter.flushed) != 3 {
+		t.Fatalf("got %d flushes want 3", len(httpWriter.flushed))
+	}
+	if got, want := httpWriter.flushed[0], "[{\"Kind\":\"apple\"}\n"; got != want {
+		t.Errorf("first flush got %q want %q", got, want)
+	}
+}
+
+func TestWriteJSONStreamWithoutFlusher(t *testing.T) {
+	httpWriter := httptest.NewRecorder()
+	resp := NewResponse(struct{ http.ResponseWriter }{httpWriter})
+	items := make(chan interface{})
+	close(items)
+	if err := resp.WriteJSONStream(items); err != nil {
+		t.Fatalf("unexpected error:%v", err)
+	}
+	if got, want := httpWriter.Body.String(), "[]\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package restful
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutFunction returns a RouteFunction that calls the function with a Request whose context
+// has the timeout as deadline. The function writes to a buffer that is copied to the Response
+// when it returns in time. Otherwise the context is canceled and a 503 Service Unavailable error is written ;
+// the function is abandoned and its later writes are discarded, like http.TimeoutHandler does.
+// The function sets the attributes of its own copy of the Request ; they are copied back when it returns in time.
+func timeoutFunction(function RouteFunction, timeout time.Duration) RouteFunction {
+	return func(req *Request, resp *Response) {
+		ctx, cancel := context.WithTimeout(req.Request.Context(), timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{buffered: newBufferedWriter(resp)}
+		timeoutReq := *req
+		timeoutReq.Request = req.Request.WithContext(ctx)
+		timeoutReq.attributes = cloneAttributes(req.attributes)
+		timeoutResp := *resp
+		timeoutResp.ResponseWriter, timeoutResp.hijacker = tw, nil
+		timeoutResp.request, timeoutResp.ctx = &timeoutReq, ctx
+		timeoutResp.writeHooks = nil
+
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			function(&timeoutReq, &timeoutResp)
+			close(done)
+		}()
+		select {
+		case p := <-panicChan:
+			// let the Container recover from it
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.buffered.copyTo(resp)
+			req.attributes = timeoutReq.attributes
+			resp.err = timeoutResp.err
+			resp.writeHooks = append(resp.writeHooks, timeoutResp.writeHooks...)
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			if trace {
+				traceLogger.Printf("route function of %s did not finish within %v", req.SelectedRoutePath(), timeout)
+			}
+			resp.err = http.ErrHandlerTimeout
+			resp.WriteErrorString(http.StatusServiceUnavailable, "503: Service Unavailable")
+		}
+	}
+}
+
+// timeoutWriter is the http.ResponseWriter given to a route function with a timeout.
+// It buffers the response until the function returns ; writes after the timeout fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	buffered *bufferedWriter
+	timedOut bool
+}
+
+// Header is part of http.ResponseWriter interface
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.buffered.Header()
+}
+
+// WriteHeader is part of http.ResponseWriter interface
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.buffered.WriteHeader(code)
+}
+
+// Write is part of http.ResponseWriter interface
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.buffered.Write(data)
+}
+
+// cloneAttributes returns a copy of the attributes of a Request.
+func cloneAttributes(attributes map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		clone[name] = value
+	}
+	return clone
+}
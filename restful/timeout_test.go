@@ -0,0 +1,163 @@
+package restful
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowHandler waits for its context to be done and for release, and then tries to write
+type slowHandler struct {
+	release  chan bool
+	canceled chan error // receives the error of the context
+	written  chan error // receives the error of the late write
+}
+
+func (s slowHandler) serve(req *Request, resp *Response) {
+	<-req.Request.Context().Done()
+	s.canceled <- req.Request.Context().Err()
+	<-s.release
+	_, err := io.WriteString(resp, "late")
+	s.written <- err
+}
+
+func writeInTime(req *Request, resp *Response) {
+	resp.AddHeader("X-Handler", "done")
+	resp.WriteHeader(http.StatusCreated)
+	io.WriteString(resp, "in time")
+}
+
+func TestRouteTimeoutInTime(t *testing.T) {
+	ws := new(WebService).Path("/fast")
+	ws.Route(ws.POST("").Timeout(time.Second).Handler(writeInTime))
+	wc := NewContainer()
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("POST", "/fast", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Code, http.StatusCreated; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := httpWriter.Body.String(), "in time"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if got, want := httpWriter.Header().Get("X-Handler"), "done"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestRouteTimeoutExceeded(t *testing.T) {
+	slow := slowHandler{make(chan bool), make(chan error, 1), make(chan error, 1)}
+	ws := new(WebService).Path("/slow")
+	ws.Route(ws.GET("").Timeout(10 * time.Millisecond).Handler(slow.serve))
+	wc := NewContainer()
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/slow", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Code, http.StatusServiceUnavailable; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := httpWriter.Body.String(), "503: Service Unavailable"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if err := <-slow.canceled; err == nil {
+		t.Error("expected canceled context")
+	}
+	close(slow.release)
+	if err := <-slow.written; err != http.ErrHandlerTimeout {
+		t.Errorf("got %v want %v", err, http.ErrHandlerTimeout)
+	}
+	if got, want := httpWriter.Body.String(), "503: Service Unavailable"; got != want {
+		t.Errorf("late write changed the response: %q", got)
+	}
+}
+
+func TestRouteTimeoutServiceErrorHandler(t *testing.T) {
+	slow := slowHandler{make(chan bool), make(chan error, 1), make(chan error, 1)}
+	ws := new(WebService).Path("/slow")
+	ws.Route(ws.GET("").Timeout(10 * time.Millisecond).Handler(slow.serve))
+	wc := NewContainer()
+	wc.ServiceErrorHandler(writeServiceErrorCode)
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/slow", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "service error 503"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	close(slow.release)
+	<-slow.written
+}
+
+// attributeRecorder records the attribute set by a route function
+type attributeRecorder struct {
+	attribute interface{}
+	slow      slowHandler
+}
+
+func (r *attributeRecorder) filter(req *Request, resp *Response, next func(*Request, *Response)) {
+	req.SetAttribute("filter", "before")
+	next(req, resp)
+	r.attribute = req.Attribute("handler")
+}
+
+func (r *attributeRecorder) fast(req *Request, resp *Response) {
+	req.SetAttribute("handler", req.Attribute("filter"))
+}
+
+func (r *attributeRecorder) serveSlow(req *Request, resp *Response) {
+	req.SetAttribute("handler", req.Attribute("filter"))
+	r.slow.serve(req, resp)
+	req.SetAttribute("handler", "late")
+}
+
+func TestRouteTimeoutAttributes(t *testing.T) {
+	recorder := &attributeRecorder{slow: slowHandler{make(chan bool), make(chan error, 1), make(chan error, 1)}}
+	ws := new(WebService).Path("/")
+	ws.Route(ws.GET("fast").Timeout(time.Second).Filter(recorder.filter).Handler(recorder.fast))
+	ws.Route(ws.GET("slow").Timeout(10 * time.Millisecond).Filter(recorder.filter).Handler(recorder.serveSlow))
+	wc := NewContainer()
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/fast", nil)
+	wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	if got, want := recorder.attribute, "before"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+
+	// the attributes set by an abandoned function are discarded
+	httpRequest, _ = http.NewRequest("GET", "/slow", nil)
+	wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	if recorder.attribute != nil {
+		t.Errorf("got %v want nil", recorder.attribute)
+	}
+	close(recorder.slow.release)
+	<-recorder.slow.written
+}
+
+func writeServiceErrorCode(err ServiceError, req *Request, resp *Response) {
+	resp.WriteHeader(err.Code)
+	fmt.Fprintf(resp, "service error %d", err.Code)
+}
+
+func TestRouteTimeoutPanic(t *testing.T) {
+	ws := new(WebService).Path("/fire")
+	ws.Route(ws.GET("").Timeout(time.Second).Handler(doPanic))
+	wc := NewContainer()
+	wc.DoNotRecover(false)
+	wc.Add(ws)
+
+	httpRequest, _ := http.NewRequest("GET", "/fire", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package tracing_test
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restful/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func hello(req *restful.Request, resp *restful.Response) {
+	// spans started with req.Request.Context() are children of the span of the request
+	_, span := otel.Tracer("hello").Start(req.Request.Context(), "greet")
+	defer span.End()
+	io.WriteString(resp, "world")
+}
+
+func ExampleNewFilter() {
+	// register an exporter with the provider to send the spans somewhere
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// spans are named e.g. GET /hello/{name}
+	restful.DefaultContainer.Filter(tracing.NewFilter(tracing.Options{Skip: []string{"/healthz"}}))
+	ws := new(restful.WebService).Path("/hello")
+	ws.Route(ws.GET("/{name}").Handler(hello))
+	if err := restful.DefaultContainer.Add(ws); err != nil {
+		log.Fatal(err)
+	}
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
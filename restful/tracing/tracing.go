@@ -0,0 +1,165 @@
+// Package tracing provides a restful.FilterFunction that traces the requests with OpenTelemetry.
+//
+// Each request gets a server span named by its method and the path of the selected Route, e.g. GET /users/{userID},
+// that is a child of the span of the traceparent header, if any. Requests for which no Route was selected
+// get a span named by the method only, e.g. GET, such that the number of span names does not grow with the paths.
+//
+//	restful.DefaultContainer.Filter(tracing.NewFilter(tracing.Options{}))
+//
+// The context of the http.Request passed to the next filters and the Route function has the span ;
+// use it to create child spans.
+//
+//	ctx, span := tracer.Start(req.Request.Context(), "query")
+//	defer span.End()
+package tracing
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/tangblue/goapi/restful"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the Tracer of the spans.
+const InstrumentationName = "github.com/tangblue/goapi/restful/tracing"
+
+// SpanAttribute is the name of the request attribute with the trace.SpanContext of the server span.
+const SpanAttribute = "otel.span_context"
+
+// the attributes of the HTTP semantic conventions
+const (
+	attrRequestMethod   = attribute.Key("http.request.method")
+	attrRoute           = attribute.Key("http.route")
+	attrStatusCode      = attribute.Key("http.response.status_code")
+	attrRequestSize     = attribute.Key("http.request.body.size")
+	attrResponseSize    = attribute.Key("http.response.body.size")
+	attrURLPath         = attribute.Key("url.path")
+	attrURLScheme       = attribute.Key("url.scheme")
+	attrServerAddress   = attribute.Key("server.address")
+	attrClientAddress   = attribute.Key("client.address")
+	attrUserAgent       = attribute.Key("user_agent.original")
+	attrProtocolVersion = attribute.Key("network.protocol.version")
+)
+
+// Options configures the FilterFunction created by NewFilter.
+type Options struct {
+	// TracerProvider creates the Tracer ; default is the global one, see otel.SetTracerProvider.
+	TracerProvider trace.TracerProvider
+	// Propagator extracts the parent span from the request headers ; default is the global one, see otel.SetTextMapPropagator.
+	Propagator propagation.TextMapPropagator
+	// Skip lists the request paths that are not traced, e.g. /healthz.
+	Skip []string
+}
+
+type filter struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	skip       map[string]bool
+}
+
+// NewFilter returns a FilterFunction that starts a server span for each request and ends it once the request
+// has been responded to, with an error status for a 5xx response. Install it as the first Container filter.
+func NewFilter(opts Options) restful.FilterFunction {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+	if opts.Propagator == nil {
+		opts.Propagator = otel.GetTextMapPropagator()
+	}
+	f := &filter{
+		tracer:     opts.TracerProvider.Tracer(InstrumentationName),
+		propagator: opts.Propagator,
+		skip:       map[string]bool{},
+	}
+	for _, each := range opts.Skip {
+		f.skip[each] = true
+	}
+	return f.filter
+}
+
+func (f *filter) filter(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
+	httpRequest := req.Request
+	if f.skip[httpRequest.URL.Path] {
+		next(req, resp)
+		return
+	}
+	ctx := f.propagator.Extract(httpRequest.Context(), propagation.HeaderCarrier(httpRequest.Header))
+	route := req.SelectedRoutePath()
+	ctx, span := f.tracer.Start(ctx, spanName(httpRequest.Method, route),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(requestAttributes(httpRequest, route)...))
+	req.Request = httpRequest.WithContext(ctx)
+	req.SetAttribute(SpanAttribute, span.SpanContext())
+
+	// stays true if next panics ; the write hooks are called nevertheless
+	panicked := true
+	resp.OnWrite(func(stats restful.ResponseStats) {
+		status := stats.StatusCode
+		if panicked {
+			status = http.StatusInternalServerError
+		}
+		span.SetAttributes(attrStatusCode.Int(status), attrResponseSize.Int(stats.ContentLength))
+		if stats.Err != nil && status >= 500 {
+			span.RecordError(stats.Err)
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	})
+	next(req, resp)
+	panicked = false
+}
+
+// spanName returns e.g. GET /users/{userID}, or GET if no Route was selected.
+// A non-standard method is named HTTP.
+func spanName(method, route string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+	default:
+		method = "HTTP"
+	}
+	if len(route) == 0 {
+		return method
+	}
+	return method + " " + route
+}
+
+// requestAttributes returns the attributes of the span known before the request is handled.
+func requestAttributes(r *http.Request, route string) []attribute.KeyValue {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	attrs := []attribute.KeyValue{
+		attrRequestMethod.String(r.Method),
+		attrURLPath.String(r.URL.Path),
+		attrURLScheme.String(scheme),
+		attrProtocolVersion.String(strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)),
+	}
+	if len(route) > 0 {
+		attrs = append(attrs, attrRoute.String(route))
+	}
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		attrs = append(attrs, attrServerAddress.String(host))
+	} else if len(r.Host) > 0 {
+		attrs = append(attrs, attrServerAddress.String(r.Host))
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		attrs = append(attrs, attrClientAddress.String(host))
+	}
+	if agent := r.UserAgent(); len(agent) > 0 {
+		attrs = append(attrs, attrUserAgent.String(agent))
+	}
+	if r.ContentLength > 0 {
+		attrs = append(attrs, attrRequestSize.Int64(r.ContentLength))
+	}
+	return attrs
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tangblue/goapi/restful"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var paramUserID = restful.PathParameter("userID", "identifier of the user")
+
+func findUser(req *restful.Request, resp *restful.Response) {
+	// a child span of the server span
+	_, span := trace.SpanFromContext(req.Request.Context()).TracerProvider().Tracer("test").Start(req.Request.Context(), "query")
+	span.End()
+	var userID string
+	req.GetParameter(paramUserID, &userID)
+	io.WriteString(resp, userID)
+}
+
+func failing(req *restful.Request, resp *restful.Response) {
+	resp.WriteErrorString(http.StatusServiceUnavailable, "down")
+}
+
+func newTracedContainer(t *testing.T, exporter *tracetest.InMemoryExporter) *restful.Container {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	wc := restful.NewContainer()
+	wc.Filter(NewFilter(Options{TracerProvider: provider, Propagator: propagation.TraceContext{}, Skip: []string{"/users/healthz"}}))
+	ws := new(restful.WebService).Path("/users")
+	ws.Route(ws.GET("/{userID}").Handler(findUser).Params(paramUserID))
+	ws.Route(ws.POST("/{userID}/fail").Handler(failing))
+	if err := wc.Add(ws); err != nil {
+		t.Fatal(err)
+	}
+	return wc
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, each := range span.Attributes {
+		if each.Key == key {
+			return each.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestFilterSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	wc := newTracedContainer(t, exporter)
+	httpRequest := httptest.NewRequest("GET", "/users/42", nil)
+	httpRequest.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	wc.ServeHTTP(httptest.NewRecorder(), httpRequest)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if got, want := server.Name, "GET /users/{userID}"; got != want {
+		t.Errorf("got name %q want %q", got, want)
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("got kind %v want server", server.SpanKind)
+	}
+	if got, want := server.Parent.TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("got parent trace %s want %s", got, want)
+	}
+	if got, want := server.Parent.SpanID().String(), "00f067aa0ba902b7"; got != want {
+		t.Errorf("got parent span %s want %s", got, want)
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("got child of %s want %s", child.Parent.SpanID(), server.SpanContext.SpanID())
+	}
+	if value, _ := attributeValue(server, attrRoute); value.AsString() != "/users/{userID}" {
+		t.Errorf("got route %q", value.AsString())
+	}
+	if value, _ := attributeValue(server, attrStatusCode); value.AsInt64() != 200 {
+		t.Errorf("got status %d want 200", value.AsInt64())
+	}
+	if server.Status.Code == codes.Error {
+		t.Errorf("got error status for a 200")
+	}
+}
+
+func TestFilterErrorStatus(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	wc := newTracedContainer(t, exporter)
+	wc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users/42/fail", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("got status %v want error", spans[0].Status.Code)
+	}
+	if value, _ := attributeValue(spans[0], attrStatusCode); value.AsInt64() != 503 {
+		t.Errorf("got status %d want 503", value.AsInt64())
+	}
+}
+
+func TestFilterUnmatched(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	wc := newTracedContainer(t, exporter)
+	wc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42/unknown", nil))
+	wc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/users/42", nil))
+	wc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/healthz", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans want 2", len(spans))
+	}
+	for i, want := range []string{"GET", "HTTP"} {
+		if got := spans[i].Name; got != want {
+			t.Errorf("got name %q want %q", got, want)
+		}
+		if _, ok := attributeValue(spans[i], attrRoute); ok {
+			t.Errorf("%s: unexpected route attribute", spans[i].Name)
+		}
+	}
+}
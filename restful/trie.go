@@ -0,0 +1,193 @@
+package restful
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TrieRouter selects Routes like the CurlyRouter but finds the candidate Routes of a WebService
+// using a tree of path tokens instead of matching the path of every Route.
+// The tree of a WebService is built when first needed and rebuilt after its Routes change.
+// It is faster than the CurlyRouter for WebServices with many Routes.
+type TrieRouter struct{}
+
+// SelectRoute is part of the Router interface and returns the best match
+// for the WebService and its Route for the given Request.
+func (t TrieRouter) SelectRoute(
+	webServices []*WebService,
+	httpRequest *http.Request) (selectedService *WebService, selected *Route, err error) {
+
+	requestTokens := requestPathTokens(httpRequest)
+
+	detectedService := CurlyRouter{}.detectWebService(requestTokens, webServices)
+	if detectedService == nil {
+		if trace {
+			traceLogger.Printf("no WebService was found to match URL path:%s\n", httpRequest.URL.Path)
+		}
+		return nil, nil, NewError(http.StatusNotFound, "404: Page Not Found")
+	}
+	candidateRoutes := detectedService.routeTrie().selectRoutes(requestTokens)
+	if len(candidateRoutes) == 0 {
+		if trace {
+			traceLogger.Printf("no Route in WebService with path %s was found to match URL path:%s\n", detectedService.rootPath, httpRequest.URL.Path)
+		}
+		return detectedService, nil, NewError(http.StatusNotFound, "404: Page Not Found")
+	}
+	selectedRoute, err := CurlyRouter{}.detectRoute(candidateRoutes, httpRequest)
+	if selectedRoute == nil {
+		return detectedService, nil, err
+	}
+	return detectedService, selectedRoute, nil
+}
+
+// routeTrie is a tree of the path tokens of the Routes of a WebService.
+type routeTrie struct {
+	root       *trieNode
+	ignoreCase bool // static path tokens are compared regardless of case
+}
+
+// trieNode has a child for each static token and each parameter token that follows the tokens of its parent.
+type trieNode struct {
+	static map[string]*trieNode
+	params []*trieParam
+	leaves []trieLeaf // the Routes whose path ends with the tokens of this node
+}
+
+// trieParam is a parameter token, e.g. {id} or {id:[0-9]+}, and the node of the tokens that follow it.
+type trieParam struct {
+	token    string
+	regex    *regexp.Regexp // nil if the parameter has no expression
+	invalid  bool           // the expression does not compile ; the parameter matches nothing
+	catchAll bool           // the expression is "*" ; the parameter matches the remaining tokens
+	node     *trieNode
+}
+
+// trieLeaf is a Route with its position in the WebService.
+type trieLeaf struct {
+	index int
+	route curlyRoute
+	// the last token ends with "*}" ; the Route also matches paths with more tokens
+	anyRemainder bool
+}
+
+// newRouteTrie returns the tree of the routes. Parameters and leaves are kept in order of the routes.
+func newRouteTrie(routes []Route, ignoreCase bool) *routeTrie {
+	t := &routeTrie{root: newTrieNode(), ignoreCase: ignoreCase}
+	for i, each := range routes {
+		t.add(i, each)
+	}
+	return t
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{static: map[string]*trieNode{}}
+}
+
+// add inserts the route with the same counts of parameters and static tokens as CurlyRouter.matchesRouteByPathTokens.
+func (t *routeTrie) add(index int, route Route) {
+	node := t.root
+	leaf := trieLeaf{index: index, route: curlyRoute{route: route}}
+	for _, each := range route.pathParts {
+		if !strings.HasPrefix(each, "{") {
+			leaf.route.staticCount++
+			child, ok := node.static[each]
+			if !ok {
+				child = newTrieNode()
+				node.static[each] = child
+			}
+			node = child
+			continue
+		}
+		leaf.route.paramCount++
+		param := node.param(each)
+		if param.catchAll {
+			// the remaining tokens of the route are never compared
+			param.node.leaves = append(param.node.leaves, leaf)
+			return
+		}
+		node = param.node
+	}
+	if count := len(route.pathParts); count > 0 {
+		leaf.anyRemainder = strings.HasSuffix(route.pathParts[count-1], "*}")
+	}
+	node.leaves = append(node.leaves, leaf)
+}
+
+// param returns the child for the parameter token, adding it if needed.
+func (n *trieNode) param(token string) *trieParam {
+	for _, each := range n.params {
+		if each.token == token {
+			return each
+		}
+	}
+	param := &trieParam{token: token, node: newTrieNode()}
+	if colon := strings.Index(token, ":"); colon != -1 {
+		expression := token[colon+1 : len(token)-1]
+		if expression == "*" {
+			param.catchAll = true
+		} else if regex, err := regexp.Compile(expression); err == nil {
+			param.regex = regex
+		} else {
+			param.invalid = true
+		}
+	}
+	n.params = append(n.params, param)
+	return param
+}
+
+// matches returns whether the request token matches the parameter ; like CurlyRouter.regularMatchesPathToken
+// the expression is not anchored.
+func (p *trieParam) matches(requestToken string) bool {
+	if p.invalid {
+		return false
+	}
+	return p.regex == nil || p.regex.MatchString(requestToken)
+}
+
+// selectRoutes returns the Routes that match the request tokens, sorted like CurlyRouter.selectRoutes.
+func (t *routeTrie) selectRoutes(requestTokens []string) sortableCurlyRoutes {
+	leaves := []trieLeaf{}
+	t.collect(t.root, requestTokens, 0, &leaves)
+	// sort the candidates in the order of the WebService, as collected by the CurlyRouter
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].index < leaves[j].index })
+	candidates := make(sortableCurlyRoutes, len(leaves))
+	for i, each := range leaves {
+		candidates[i] = each.route
+	}
+	sort.Sort(sort.Reverse(candidates))
+	return candidates
+}
+
+// collect appends the leaves of the node and its descendants that match the request tokens from depth onwards.
+func (t *routeTrie) collect(node *trieNode, requestTokens []string, depth int, leaves *[]trieLeaf) {
+	if depth == len(requestTokens) {
+		*leaves = append(*leaves, node.leaves...)
+		return
+	}
+	for _, each := range node.leaves {
+		if each.anyRemainder {
+			*leaves = append(*leaves, each)
+		}
+	}
+	requestToken := requestTokens[depth]
+	if t.ignoreCase {
+		for token, child := range node.static {
+			if strings.EqualFold(requestToken, token) {
+				t.collect(child, requestTokens, depth+1, leaves)
+			}
+		}
+	} else if child, ok := node.static[requestToken]; ok {
+		t.collect(child, requestTokens, depth+1, leaves)
+	}
+	for _, each := range node.params {
+		if each.catchAll {
+			*leaves = append(*leaves, each.node.leaves...)
+			continue
+		}
+		if each.matches(requestToken) {
+			t.collect(each.node, requestTokens, depth+1, leaves)
+		}
+	}
+}
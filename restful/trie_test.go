@@ -0,0 +1,139 @@
+package restful
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// newRouterTestServices returns WebServices with static, parameter, expression and catch-all path tokens.
+func newRouterTestServices() []*WebService {
+	users := new(WebService).Path("/users")
+	users.Route(users.GET("").Handler(curlyDummy))
+	users.Route(users.POST("").Consumes(MIME_JSON).Handler(curlyDummy))
+	users.Route(users.GET("/me").Handler(curlyDummy))
+	users.Route(users.GET("/{id}").Handler(curlyDummy))
+	users.Route(users.PUT("/{id:[0-9]+}").Handler(curlyDummy))
+	users.Route(users.GET("/{id}/groups/{group}").Produces(MIME_XML).Handler(curlyDummy))
+	users.Route(users.GET("/{id}/groups/{name:[a-z]*}").Handler(curlyDummy))
+	users.Route(users.GET("/{id}/files/{path:*}").Handler(curlyDummy))
+	users.Route(users.GET("/{id}/files/readme").Handler(curlyDummy))
+
+	groups := new(WebService).Path("/{tenant}/groups").CaseInsensitivePaths(true)
+	groups.Route(groups.GET("/{id}").Handler(curlyDummy))
+	groups.Route(groups.GET("/Admins").Handler(curlyDummy))
+	groups.Route(groups.DELETE("/{id}/Members/{member}").Handler(curlyDummy))
+
+	files := new(WebService).Path("/")
+	files.Route(files.GET("/{path:*}").Handler(curlyDummy))
+	files.Route(files.GET("/").Handler(curlyDummy))
+	return []*WebService{users, groups, files}
+}
+
+var routerTestPaths = []string{
+	"/", "/users", "/users/", "/users/me", "/users/12", "/users/abc", "/users/12/groups/7",
+	"/users/12/groups/abc", "/users/12/groups/abc/def", "/users/12/files/readme", "/users/12/files/a/b/c",
+	"/users/12/files", "/acme/groups/admins", "/acme/GROUPS/7", "/acme/groups/7/members/8",
+	"/acme/groups/7/MEMBERS/8/extra", "/static/css/site.css", "/users//12", "/a//b",
+}
+
+// sameSelection returns whether both routers select the same WebService and Route, or fail with the same status.
+func sameSelection(t *testing.T, services []*WebService, method, path string) {
+	httpRequest := &http.Request{Method: method, URL: &url.URL{Path: path}, Header: http.Header{}}
+	curlyService, curlyRoute, curlyErr := CurlyRouter{}.SelectRoute(services, httpRequest)
+	trieService, trieRoute, trieErr := TrieRouter{}.SelectRoute(services, httpRequest)
+	if curlyService != trieService {
+		t.Errorf("%s %s: got service %v want %v", method, path, trieService, curlyService)
+	}
+	if (curlyRoute == nil) != (trieRoute == nil) || curlyRoute != nil && curlyRoute.String() != trieRoute.String() {
+		t.Errorf("%s %s: got route %v want %v", method, path, trieRoute, curlyRoute)
+	}
+	if (curlyErr == nil) != (trieErr == nil) || curlyErr != nil && curlyErr.(ServiceError).Code != trieErr.(ServiceError).Code {
+		t.Errorf("%s %s: got error %v want %v", method, path, trieErr, curlyErr)
+	}
+}
+
+func TestTrieRouter_SameSelectionAsCurly(t *testing.T) {
+	services := newRouterTestServices()
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		for _, path := range routerTestPaths {
+			sameSelection(t, services, method, path)
+		}
+	}
+}
+
+func TestTrieRouter_SelectRoute(t *testing.T) {
+	services := newRouterTestServices()
+	for _, each := range []struct {
+		method, path, route string
+	}{
+		{"GET", "/users/me", "GET /users/me"},
+		{"GET", "/users/12", "GET /users/{id}"},
+		{"PUT", "/users/12", "PUT /users/{id:[0-9]+}"},
+		{"GET", "/users/12/files/readme", "GET /users/{id}/files/readme"},
+		{"GET", "/users/12/files/a/b", "GET /users/{id}/files/{path:*}"},
+		{"GET", "/acme/GROUPS/admins", "GET /{tenant}/groups/Admins"},
+		{"GET", "/", "GET /"},
+	} {
+		httpRequest, _ := http.NewRequest(each.method, each.path, nil)
+		_, route, err := TrieRouter{}.SelectRoute(services, httpRequest)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %v", each.method, each.path, err)
+			continue
+		}
+		if got, want := route.String(), each.route; got != want {
+			t.Errorf("%s %s: got %v want %v", each.method, each.path, got, want)
+		}
+	}
+}
+
+func TestTrieRouter_DynamicRoutes(t *testing.T) {
+	ws := new(WebService).Path("/users")
+	ws.SetDynamicRoutes(true)
+	ws.Route(ws.GET("/{id}").Handler(curlyDummy))
+	services := []*WebService{ws}
+
+	httpRequest, _ := http.NewRequest("GET", "/users/me", nil)
+	if _, route, _ := (TrieRouter{}).SelectRoute(services, httpRequest); route == nil || route.Path != "/users/{id}" {
+		t.Fatalf("unexpected route %v", route)
+	}
+	ws.Route(ws.GET("/me").Handler(curlyDummy))
+	if _, route, _ := (TrieRouter{}).SelectRoute(services, httpRequest); route == nil || route.Path != "/users/me" {
+		t.Errorf("expected added route, got %v", route)
+	}
+	ws.RemoveRoute("/users/me", "GET")
+	if _, route, _ := (TrieRouter{}).SelectRoute(services, httpRequest); route == nil || route.Path != "/users/{id}" {
+		t.Errorf("expected removed route, got %v", route)
+	}
+}
+
+func TestTrieRouter_ApiVersion(t *testing.T) {
+	ws := new(WebService).Path("/users").ApiVersion("v1")
+	ws.Route(ws.GET("").Version("v1").Handler(curlyDummy))
+	ws.Route(ws.GET("").Version("v2").Handler(curlyDummy))
+	services := []*WebService{ws}
+
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	if _, route, _ := (TrieRouter{}).SelectRoute(services, httpRequest); route == nil || route.Version != "v1" {
+		t.Fatalf("unexpected route %v", route)
+	}
+	ws.ApiVersion("v2")
+	if _, route, _ := (TrieRouter{}).SelectRoute(services, httpRequest); route == nil || route.Version != "v2" {
+		t.Errorf("expected route of the new default version, got %v", route)
+	}
+}
+
+// go test -run=^$ -fuzz=FuzzTrieRouter ...restful
+func FuzzTrieRouter(f *testing.F) {
+	for _, each := range routerTestPaths {
+		f.Add("GET", each)
+	}
+	f.Add("PUT", "/users/12")
+	f.Add("DELETE", "/acme/groups/7/members/8")
+	services := newRouterTestServices()
+	// the routers trace to the logger of a test that may have completed
+	EnableTracing(false)
+	f.Fuzz(func(t *testing.T, method, path string) {
+		sameSelection(t, services, method, path)
+	})
+}
@@ -3,11 +3,9 @@ package restful
 import (
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
+	"strings"
 	"sync"
-
-	"github.com/tangblue/goapi/restful/log"
 )
 
 // Copyright 2013 Ernest Micklei. All rights reserved.
@@ -23,21 +21,57 @@ type WebService struct {
 	consumes       []string
 	pathParameters []*Parameter
 	filters        []FilterFunction
+	host           string
 	documentation  string
 	apiVersion     string
+	deprecated     bool
+	metadata       map[string]interface{} // entries of the Routes that do not set them, see Metadata
+	err            error                  // the first error of compiling the path or building a Route, see Err
 
 	typeNameHandleFunc TypeNameHandleFunction
 
 	dynamicRoutes bool
 
+	caseInsensitivePaths    bool // static path parts match regardless of case
+	caseInsensitivePathsSet bool // if false then the Container default applies
+
 	// protects 'routes' if dynamic routes are enabled
 	routesLock sync.RWMutex
+
+	trie     *routeTrie // tree of the routes used by the TrieRouter, nil if not built or invalid
+	trieLock sync.RWMutex
 }
 
 func (w *WebService) SetDynamicRoutes(enable bool) {
 	w.dynamicRoutes = enable
 }
 
+// CaseInsensitivePaths sets whether the static parts of the root path and the Route paths match
+// the request path regardless of case, e.g. a request for /Users/5 matches the Route /users/{id}.
+// Path parameter values are left untouched. The documentation keeps the paths as declared.
+// If not set then the default of the Container applies, see Container.EnableCaseInsensitivePaths.
+func (w *WebService) CaseInsensitivePaths(enabled bool) *WebService {
+	w.caseInsensitivePaths = enabled
+	w.caseInsensitivePathsSet = true
+	return w
+}
+
+// matchesRootPath returns whether the root path of the WebService is the same as the other
+// taking case-insensitive paths into account.
+func (w *WebService) matchesRootPath(other *WebService) bool {
+	if w.caseInsensitivePaths || other.caseInsensitivePaths {
+		return strings.EqualFold(w.RootPath(), other.RootPath())
+	}
+	return w.RootPath() == other.RootPath()
+}
+
+// Host sets the host pattern of the Routes that do not set one, see RouteBuilder.Host.
+// It applies to the Routes added after calling it.
+func (w *WebService) Host(pattern string) *WebService {
+	w.host = pattern
+	return w
+}
+
 // TypeNameHandleFunction declares functions that can handle translating the name of a sample object
 // into the restful documentation for the service.
 type TypeNameHandleFunction func(sample interface{}) string
@@ -61,15 +95,81 @@ func reflectTypeName(sample interface{}) string {
 func (w *WebService) compilePathExpression() {
 	compiled, err := newPathExpression(w.rootPath)
 	if err != nil {
-		log.Printf("invalid path:%s because:%v", w.rootPath, err)
-		os.Exit(1)
+		w.addError(fmt.Errorf("invalid path %s: %v", w.rootPath, err))
+		return
 	}
 	w.pathExpr = compiled
 }
 
+// addError keeps the error unless an earlier one was kept.
+func (w *WebService) addError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Err returns the first error of compiling the root path or building a Route, nil if there was none.
+// A Route that could not be built is not added. Container.Add refuses a WebService with an error.
+func (w *WebService) Err() error {
+	return w.err
+}
+
+// Deprecate marks all Routes of the WebService deprecated, see RouteBuilder.Deprecate.
+// It applies to the Routes added before and after calling it, including mounted Routes.
+func (w *WebService) Deprecate() *WebService {
+	w.routesLock.Lock()
+	defer w.routesLock.Unlock()
+	w.deprecated = true
+	for i := range w.routes {
+		w.routes[i].Deprecated = true
+	}
+	return w
+}
+
+// Metadata adds or updates a key=value pair of the metadata of the WebService. The entry is added to the metadata
+// of the Routes added or mounted after calling it, unless a Route sets the key itself, see RouteBuilder.Metadata.
+func (w *WebService) Metadata(key string, value interface{}) *WebService {
+	w.routesLock.Lock()
+	defer w.routesLock.Unlock()
+	if w.metadata == nil {
+		w.metadata = map[string]interface{}{}
+	}
+	w.metadata[key] = value
+	return w
+}
+
+// MetadataMap returns a copy of the metadata of the WebService, see Metadata.
+func (w *WebService) MetadataMap() map[string]interface{} {
+	w.routesLock.RLock()
+	defer w.routesLock.RUnlock()
+	return mergeMetadata(nil, w.metadata)
+}
+
+// mergeMetadata returns the metadata of a Route with the entries of the defaults whose keys it does not have.
+func mergeMetadata(metadata, defaults map[string]interface{}) map[string]interface{} {
+	if len(defaults) == 0 {
+		return metadata
+	}
+	merged := make(map[string]interface{}, len(metadata)+len(defaults))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	return merged
+}
+
 // ApiVersion sets the API version for documentation purposes.
+// It is also the default version of its Routes, see RouteBuilder.Version.
 func (w *WebService) ApiVersion(apiVersion string) *WebService {
+	w.routesLock.Lock()
+	defer w.routesLock.Unlock()
 	w.apiVersion = apiVersion
+	for i := range w.routes {
+		w.routes[i].isDefault = len(w.routes[i].Version) > 0 && w.routes[i].Version == apiVersion
+	}
+	w.invalidateRouteTrie()
 	return w
 }
 
@@ -77,9 +177,9 @@ func (w *WebService) ApiVersion(apiVersion string) *WebService {
 func (w *WebService) Version() string { return w.apiVersion }
 
 // Path specifies the root URL template path of the WebService.
-// All Routes will be relative to this path.
+// All Routes will be relative to this path. An invalid path is reported by Err.
 func (w *WebService) Path(root string) *WebService {
-	w.rootPath = root
+	w.rootPath = cleanPath(root)
 	if len(w.rootPath) == 0 {
 		w.rootPath = "/"
 	}
@@ -148,14 +248,110 @@ func (w *WebService) FormParameter(name, description string) *Parameter {
 }
 
 // Route creates a new Route using the RouteBuilder and add to the ordered list of Routes.
+// If the Route cannot be built then it is not added and the error is reported by Err.
 func (w *WebService) Route(builder *RouteBuilder) *WebService {
 	w.routesLock.Lock()
 	defer w.routesLock.Unlock()
 	builder.copyDefaults(w.produces, w.consumes)
-	w.routes = append(w.routes, builder.Build())
+	if len(builder.host) == 0 {
+		builder.host = w.host
+	}
+	route, err := builder.Build()
+	if err != nil {
+		w.addError(err)
+		return w
+	}
+	route.Deprecated = route.Deprecated || w.deprecated
+	route.isDefault = len(route.Version) > 0 && route.Version == w.apiVersion
+	route.Metadata = mergeMetadata(route.Metadata, w.metadata)
+	w.routes = append(w.routes, route)
+	w.invalidateRouteTrie()
+	return w
+}
+
+// Mount adds the Routes of the child WebService under the prefix, relative to the root path of this WebService,
+// e.g. a WebService with root path /api mounts the Route /users/{id} of a child under /v1 as /api/v1/users/{id}.
+// The filters of this WebService run before the filters of the child and its Routes.
+// Routes that do not produce or consume MIME types or are not restricted to a host take the ones of this WebService.
+// The path parameters of both WebServices are documented as parameters of each mounted Route.
+// The child is not changed and can still be used on its own ; Routes added to it later are not mounted.
+// An error of the child or of a mounted Route is reported by Err.
+func (w *WebService) Mount(prefix string, child *WebService) *WebService {
+	return w.mount(prefix, child, false)
+}
+
+// Subroute adds the Routes of the sub WebService under the prefix like Mount, except that the prefix replaces the root
+// path of the sub, e.g. a reusable WebService with root path /comments mounts its Route /{comment-id} under
+// /posts/{post-id} as /posts/{post-id}/{comment-id}. The path parameters of the sub are only documented
+// as parameters of the mounted Routes whose path has them.
+func (w *WebService) Subroute(prefix string, sub *WebService) *WebService {
+	return w.mount(prefix, sub, true)
+}
+
+// mount adds the Routes of the child under the prefix ; relative replaces the root path of the child by the prefix.
+func (w *WebService) mount(prefix string, child *WebService, relative bool) *WebService {
+	routes := child.Routes()
+	w.routesLock.Lock()
+	defer w.routesLock.Unlock()
+	w.addError(child.Err())
+	for _, each := range routes {
+		route, err := w.mountedRoute(prefix, child, each, relative)
+		if err != nil {
+			w.addError(err)
+			continue
+		}
+		w.routes = append(w.routes, route)
+	}
+	w.invalidateRouteTrie()
 	return w
 }
 
+// mountedRoute returns a copy of the Route of the child rebased under the prefix ; relative rebases its path relative
+// to the root path of the child and leaves out the path parameters of the child that are not in the rebased path.
+func (w *WebService) mountedRoute(prefix string, child *WebService, route Route, relative bool) (Route, error) {
+	childPath := route.Path
+	if relative {
+		childPath = route.relativePath
+	}
+	if childPath == "/" {
+		childPath = ""
+	}
+	route.relativePath = concatPath(prefix, childPath)
+	route.Path = concatPath(w.rootPath, route.relativePath)
+	pathExpr, err := newPathExpression(route.relativePath)
+	if err != nil {
+		return Route{}, fmt.Errorf("invalid path %s: %v", route.Path, err)
+	}
+	route.pathExpr = pathExpr
+	route.Filters = append(append([]FilterFunction{}, child.filters...), route.Filters...)
+	route.Deprecated = route.Deprecated || w.deprecated
+	route.Metadata = mergeMetadata(route.Metadata, w.metadata)
+	if len(route.Produces) == 0 {
+		route.Produces = w.produces
+	}
+	if len(route.Consumes) == 0 {
+		route.Consumes = w.consumes
+	}
+	if len(route.Host) == 0 && len(w.host) > 0 {
+		route.Host = w.host
+		if route.hostPattern, err = newHostPattern(w.host); err != nil {
+			return Route{}, fmt.Errorf("invalid host %s of route %s: %v", w.host, route.String(), err)
+		}
+	}
+	params := append([]*Parameter{}, w.pathParameters...)
+	for _, each := range child.pathParameters {
+		if !relative || containsString(pathExpr.VarNames, each.Name) {
+			params = append(params, each)
+		}
+	}
+	route.ParameterDocs = append(params, route.ParameterDocs...)
+	route.postBuild()
+	if err := validateCatchAll(route.pathParts); err != nil {
+		return Route{}, fmt.Errorf("invalid path %s: %v", route.Path, err)
+	}
+	return route, nil
+}
+
 // RemoveRoute removes the specified route, looks for something that matches 'path' and 'method'
 func (w *WebService) RemoveRoute(path, method string) error {
 	if !w.dynamicRoutes {
@@ -173,9 +369,35 @@ func (w *WebService) RemoveRoute(path, method string) error {
 		current = current + 1
 	}
 	w.routes = newRoutes
+	w.invalidateRouteTrie()
 	return nil
 }
 
+// routeTrie returns the tree of the routes, building it if needed.
+func (w *WebService) routeTrie() *routeTrie {
+	w.trieLock.RLock()
+	t := w.trie
+	w.trieLock.RUnlock()
+	if t != nil && t.ignoreCase == w.caseInsensitivePaths {
+		return t
+	}
+	// routes cannot change while building ; see invalidateRouteTrie
+	w.routesLock.RLock()
+	defer w.routesLock.RUnlock()
+	t = newRouteTrie(w.routes, w.caseInsensitivePaths)
+	w.trieLock.Lock()
+	w.trie = t
+	w.trieLock.Unlock()
+	return t
+}
+
+// invalidateRouteTrie discards the tree of the routes ; the routesLock must be held.
+func (w *WebService) invalidateRouteTrie() {
+	w.trieLock.Lock()
+	w.trie = nil
+	w.trieLock.Unlock()
+}
+
 // Method creates a new RouteBuilder and initialize its http method
 func (w *WebService) Method(httpMethod string) *RouteBuilder {
 	return new(RouteBuilder).typeNameHandler(w.typeNameHandleFunc).servicePath(w.rootPath).Method(httpMethod)
@@ -195,6 +417,31 @@ func (w *WebService) Consumes(accepts ...string) *WebService {
 	return w
 }
 
+// inheritDefaults sets the MIME types of the Container if the WebService does not specify them,
+// also for its Routes that do not specify them.
+func (w *WebService) inheritDefaults(produces, consumes []string) {
+	w.routesLock.Lock()
+	defer w.routesLock.Unlock()
+	if len(w.produces) == 0 && len(produces) > 0 {
+		w.produces = produces
+		for i := range w.routes {
+			if len(w.routes[i].Produces) == 0 {
+				w.routes[i].Produces = produces
+			}
+		}
+	}
+	if len(w.consumes) == 0 && len(consumes) > 0 {
+		w.consumes = consumes
+		for i := range w.routes {
+			if len(w.routes[i].Consumes) == 0 {
+				w.routes[i].Consumes = consumes
+			}
+		}
+	}
+	// the tree has copies of the Routes
+	w.invalidateRouteTrie()
+}
+
 // Routes returns the Routes associated with this WebService
 func (w *WebService) Routes() []Route {
 	if !w.dynamicRoutes {
@@ -210,6 +457,17 @@ func (w *WebService) Routes() []Route {
 	return result
 }
 
+// RouteByOperation returns the Route with the operation name, see RouteBuilder.Operation, e.g. to build its URL.
+func (w *WebService) RouteByOperation(name string) (*Route, bool) {
+	routes := w.Routes()
+	for i := range routes {
+		if routes[i].Operation == name {
+			return &routes[i], true
+		}
+	}
+	return nil, false
+}
+
 // RootPath returns the RootPath associated with this WebService. Default "/"
 func (w *WebService) RootPath() string {
 	return w.rootPath
@@ -220,12 +478,21 @@ func (w *WebService) PathParameters() []*Parameter {
 	return w.pathParameters
 }
 
-// Filter adds a filter function to the chain of filters applicable to all its Routes
+// Filter adds a filter function to the chain of filters applicable to all its Routes.
+// The filters of the WebService run after the Container filters and before the filters of the Route.
 func (w *WebService) Filter(filter FilterFunction) *WebService {
 	w.filters = append(w.filters, filter)
 	return w
 }
 
+// FilterAt inserts a filter function at the index in the chain of filters applicable to all its Routes,
+// e.g. at 0 to run an authentication filter before a logging filter that was added earlier.
+// An index below zero inserts at the start, an index beyond the end appends.
+func (w *WebService) FilterAt(index int, filter FilterFunction) *WebService {
+	w.filters = insertFilter(w.filters, index, filter)
+	return w
+}
+
 // Doc is used to set the documentation of this service.
 func (w *WebService) Doc(plainText string) *WebService {
 	w.documentation = plainText
@@ -22,9 +22,9 @@ func init() {
 // OBSOLETE ; use restful.DefaultContainer.DoNotRecover(true)
 var DoNotRecover = false
 
-// Add registers a new WebService add it to the DefaultContainer.
-func Add(service *WebService) {
-	DefaultContainer.Add(service)
+// Add registers a new WebService add it to the DefaultContainer, see Container.Add.
+func Add(service *WebService) error {
+	return DefaultContainer.Add(service)
 }
 
 // Filter appends a container FilterFunction from the DefaultContainer.
@@ -37,3 +37,8 @@ func Filter(filter FilterFunction) {
 func RegisteredWebServices() []*WebService {
 	return DefaultContainer.RegisteredWebServices()
 }
+
+// AllRoutes returns a RouteInfo for each Route of the WebServices from the DefaultContainer
+func AllRoutes() []RouteInfo {
+	return DefaultContainer.AllRoutes()
+}
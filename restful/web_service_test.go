@@ -3,6 +3,7 @@ package restful
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -261,6 +262,74 @@ func TestParameterDataTypeCustomization(t *testing.T) {
 	}
 }
 
+func TestCaseInsensitivePaths(t *testing.T) {
+	for _, router := range []RouteSelector{CurlyRouter{}, RouterJSR311{}} {
+		wc := NewContainer()
+		wc.Router(router)
+		ws := new(WebService).Path("/users").CaseInsensitivePaths(true)
+		ws.Route(ws.GET("/{id}/Friends").Handler(writeUserId))
+		ws.Route(ws.GET("/code/{code:[a-z]+}").Handler(writeUserId))
+		wc.Add(ws)
+		for _, each := range []struct {
+			path  string
+			code  int
+			value string
+		}{
+			{"/users/AbC/friends", http.StatusOK, "AbC"},
+			{"/Users/AbC/FRIENDS", http.StatusOK, "AbC"},
+			{"/USERS/code/abc", http.StatusOK, "abc"},
+			{"/users/code/ABC", http.StatusNotFound, ""},
+		} {
+			httpRequest, _ := http.NewRequest("GET", each.path, nil)
+			httpWriter := httptest.NewRecorder()
+			wc.ServeHTTP(httpWriter, httpRequest)
+			if httpWriter.Code != each.code {
+				t.Errorf("%T %s: got %d want %d", router, each.path, httpWriter.Code, each.code)
+			}
+			if each.code == http.StatusOK && httpWriter.Body.String() != each.value {
+				t.Errorf("%T %s: unexpected parameter value %q", router, each.path, httpWriter.Body.String())
+			}
+		}
+	}
+}
+
+func TestCaseInsensitivePathsContainerDefault(t *testing.T) {
+	wc := NewContainer()
+	wc.EnableCaseInsensitivePaths(true)
+	ws1 := new(WebService).Path("/users")
+	ws1.Route(ws1.GET("").Handler(doNothing))
+	ws2 := new(WebService).Path("/groups").CaseInsensitivePaths(false)
+	ws2.Route(ws2.GET("").Handler(doNothing))
+	wc.Add(ws1)
+	wc.Add(ws2)
+	for path, want := range map[string]int{
+		"/USERS":  http.StatusOK,
+		"/groups": http.StatusOK,
+		"/GROUPS": http.StatusNotFound,
+	} {
+		httpRequest, _ := http.NewRequest("GET", path, nil)
+		httpWriter := httptest.NewRecorder()
+		wc.ServeHTTP(httpWriter, httpRequest)
+		if httpWriter.Code != want {
+			t.Errorf("%s: got %d want %d", path, httpWriter.Code, want)
+		}
+	}
+	if !ws1.matchesRootPath(new(WebService).Path("/Users")) {
+		t.Error("expected same root path regardless of case")
+	}
+	if ws2.matchesRootPath(new(WebService).Path("/Groups")) {
+		t.Error("expected different root paths")
+	}
+}
+
+func writeUserId(req *Request, resp *Response) {
+	for _, each := range []string{"id", "code"} {
+		if value, ok := req.pathParameters[each]; ok {
+			resp.Write([]byte(value))
+		}
+	}
+}
+
 func newPanicingService() *WebService {
 	ws := new(WebService).Path("")
 	ws.Route(ws.GET("/fire").Handler(doPanic))
@@ -338,3 +407,203 @@ func doNothing(req *Request, resp *Response) {
 func return204(req *Request, resp *Response) {
 	resp.WriteHeader(204)
 }
+
+func TestMount(t *testing.T) {
+	child := new(WebService).Path("/users").Filter(serviceFilter)
+	child.Params(child.PathParameter("group", "group of the user"))
+	child.Route(child.GET("/{group}/{id}").Filter(routeFilter).Handler(writeUserId).
+		Params(child.PathParameter("id", "identifier of the user")))
+	parent := new(WebService).Path("/api/{version}").Filter(globalFilter).Produces(MIME_JSON)
+	parent.Params(parent.PathParameter("version", "version of the api"))
+	parent.Mount("/v1", child)
+
+	wc := NewContainer()
+	wc.Add(parent)
+	httpRequest, _ := http.NewRequest("GET", "/api/2/v1/users/admins/42", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "global-service-route-42"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+
+	route := parent.Routes()[0]
+	if got, want := route.Path, "/api/{version}/v1/users/{group}/{id}"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := route.Produces, []string{MIME_JSON}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %v want %v", got, want)
+	}
+	names := []string{}
+	for _, each := range route.ParameterDocs {
+		names = append(names, each.Name)
+	}
+	if got, want := strings.Join(names, ","), "version,group,id"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+
+	// the child is not changed
+	if got, want := child.Routes()[0].Path, "/users/{group}/{id}"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := len(child.Routes()[0].Filters), 1; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	wc.Add(child)
+	httpRequest, _ = http.NewRequest("GET", "/users/admins/7", nil)
+	httpWriter = httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "service-route-7"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestSubroute(t *testing.T) {
+	comments := new(WebService).Path("/comments/{tenant}").Consumes(MIME_XML)
+	comments.Params(comments.PathParameter("tenant", "tenant of the comments"))
+	comments.Route(comments.GET("").Handler(dummy))
+	comments.Route(comments.GET("/{code}").Handler(writeUserId).
+		Params(comments.PathParameter("code", "identifier of the comment")))
+	posts := new(WebService).Path("/posts").Produces(MIME_JSON).Consumes(MIME_JSON)
+	posts.Subroute("/{id}/comments", comments)
+
+	wc := NewContainer()
+	wc.Add(posts)
+	httpRequest, _ := http.NewRequest("GET", "/posts/42/comments/7", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Body.String(), "427"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+
+	routes := posts.Routes()
+	if got, want := routes[0].Path+" "+routes[1].Path, "/posts/{id}/comments /posts/{id}/comments/{code}"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	for _, each := range routes {
+		// the produced types are inherited, the consumed ones of the sub are kept
+		if got := strings.Join(each.Produces, ",") + " " + strings.Join(each.Consumes, ","); got != MIME_JSON+" "+MIME_XML {
+			t.Errorf("%s: got content types %v", each, got)
+		}
+	}
+	names := []string{}
+	for _, each := range routes[1].ParameterDocs {
+		names = append(names, each.Name)
+	}
+	if got, want := strings.Join(names, ","), "code"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestMountRoot(t *testing.T) {
+	child := new(WebService).Path("/")
+	child.Route(child.GET("").Handler(dummy))
+	parent := new(WebService).Path("/api")
+	parent.Mount("/v1", child)
+
+	if got, want := parent.Routes()[0].Path, "/api/v1"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	wc := NewContainer()
+	wc.Add(parent)
+	httpRequest, _ := http.NewRequest("GET", "/api/v1", nil)
+	httpWriter := httptest.NewRecorder()
+	wc.ServeHTTP(httpWriter, httpRequest)
+	if got, want := httpWriter.Code, http.StatusOK; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestWebServiceError(t *testing.T) {
+	ws := new(WebService).Path("/users")
+	ws.Route(ws.GET("/{id:[0-9}").Handler(dummy))
+	ws.Route(ws.GET("").Handler(dummy))
+	if err := ws.Err(); err == nil || !strings.Contains(err.Error(), "/users/{id:[0-9}") {
+		t.Fatalf("expected error with the path, got %v", err)
+	}
+	if len(ws.Routes()) != 1 {
+		t.Errorf("expected only the valid route, got %v", ws.Routes())
+	}
+
+	wc := NewContainer()
+	if err := wc.Add(ws); err == nil {
+		t.Error("expected error adding a WebService with an error")
+	}
+	if len(wc.RegisteredWebServices()) != 0 {
+		t.Error("WebService with an error should not be registered")
+	}
+	other := new(WebService).Path("/{id:*}/other")
+	if err := other.Err(); err == nil || !strings.Contains(err.Error(), "/{id:*}/other") {
+		t.Errorf("expected error with the root path, got %v", err)
+	}
+}
+
+func TestContainerAddError(t *testing.T) {
+	wc := NewContainer()
+	users := new(WebService).Path("/users")
+	users.Route(users.GET("").Handler(dummy))
+	if err := wc.Add(users); err != nil {
+		t.Fatal(err)
+	}
+	if err := wc.Add(new(WebService).Path("/users")); err == nil {
+		t.Error("expected error for duplicate root path")
+	}
+	wc.PanicOnAddError(true)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate root path")
+		}
+	}()
+	wc.Add(new(WebService).Path("/users"))
+}
+
+func TestWebServiceDeprecate(t *testing.T) {
+	ws := new(WebService).Path("/v1")
+	ws.Route(ws.GET("/users").Handler(dummy))
+	ws.Deprecate()
+	ws.Route(ws.POST("/users").Handler(dummy))
+	for _, each := range ws.Routes() {
+		if !each.Deprecated {
+			t.Errorf("expected %s to be deprecated", each)
+		}
+	}
+}
+
+func TestWebServiceMetadata(t *testing.T) {
+	ws := new(WebService).Path("/users").Metadata(KeyOpenAPITags, []string{"users"}).Metadata("owner", "team-a")
+	ws.Route(ws.GET("/").Handler(dummy))
+	ws.Route(ws.GET("/{id}").Handler(dummy).Metadata(KeyOpenAPITags, []string{"admin"}))
+
+	routes := ws.Routes()
+	if got, _ := routes[0].Metadata[KeyOpenAPITags].([]string); len(got) != 1 || got[0] != "users" {
+		t.Errorf("got tags %v want [users]", got)
+	}
+	if got, _ := routes[1].Metadata[KeyOpenAPITags].([]string); len(got) != 1 || got[0] != "admin" {
+		t.Errorf("got tags %v want [admin]", got)
+	}
+	for _, each := range routes {
+		if got := each.Metadata["owner"]; got != "team-a" {
+			t.Errorf("%s: got owner %v want team-a", each, got)
+		}
+	}
+	if got := ws.MetadataMap(); len(got) != 2 || got["owner"] != "team-a" {
+		t.Errorf("got metadata %v", got)
+	}
+}
+
+func TestMountWebServiceMetadata(t *testing.T) {
+	users := new(WebService).Path("/users").Metadata("owner", "team-a")
+	users.Route(users.GET("/").Handler(dummy))
+	api := new(WebService).Path("/api").Metadata("owner", "team-b").Metadata("version", "v1")
+	api.Mount("/v1", users)
+
+	routes := api.Routes()
+	if len(routes) != 1 {
+		t.Fatalf("got %d routes want 1", len(routes))
+	}
+	if got := routes[0].Metadata["owner"]; got != "team-a" {
+		t.Errorf("got owner %v want team-a", got)
+	}
+	if got := routes[0].Metadata["version"]; got != "v1" {
+		t.Errorf("got version %v want v1", got)
+	}
+}
@@ -4,20 +4,69 @@ import (
 	"net/http"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tangblue/goapi/restful"
 	"github.com/tangblue/goapi/spec"
 )
 
 // KeyOpenAPITags is a Metadata key for a restful Route
-const KeyOpenAPITags = "openapi.tags"
+const KeyOpenAPITags = restful.KeyOpenAPITags
+
+// KeyOpenAPIHidden is a Metadata key for a restful Route ; its bool value true leaves the Route out of the documentation
+const KeyOpenAPIHidden = restful.KeyOpenAPIHidden
+
+// ExtensionStreaming is the vendor extension set on operations that produce a stream of entities
+const ExtensionStreaming = "x-streaming"
+
+// ExtensionSunset is the vendor extension with the sunset date (RFC 3339) of a deprecated operation
+const ExtensionSunset = "x-sunset"
+
+// ExtensionHost is the vendor extension with the host pattern an operation is restricted to, see restful.RouteBuilder.Host
+const ExtensionHost = "x-host"
+
+// ExtensionVersion is the vendor extension with the version of the API an operation belongs to, see restful.RouteBuilder.Version
+const ExtensionVersion = "x-version"
+
+// ExtensionProduces is the vendor extension with the MIME types of a response that differ from the ones of its operation,
+// see restful.ResponseError.Produces
+const ExtensionProduces = "x-produces"
+
+// ExtensionCondition is the vendor extension describing the conditions an operation is selected on,
+// see restful.RouteBuilder.IfDoc
+const ExtensionCondition = "x-condition"
+
+// ExtensionRequiredIf is the vendor extension of a definition with the properties that are only required
+// if another property has a value, e.g. {"reason": "status=rejected"} for the tag `requiredIf:"status=rejected"`
+const ExtensionRequiredIf = "x-required-if"
+
+// ExtensionDeprecated is the vendor extension set on a property that is being phased out,
+// for the tag `deprecated:"true"` ; OpenAPI 2.0 has no deprecated schemas
+const ExtensionDeprecated = "x-deprecated"
+
+// ExtensionNullable is the vendor extension set on the properties of the partial variant of a model, e.g. User.Patch,
+// whose values can be null, see restful.RouteBuilder.ReadPartial ; OpenAPI 2.0 has no nullable schemas
+const ExtensionNullable = "x-nullable"
+
+// ExtensionSensitive is the vendor extension set on a parameter holding a secret, e.g. a password,
+// whose values must not be logged, see restful.Parameter.Sensitive
+const ExtensionSensitive = "x-sensitive"
+
+// ExtensionCatchAll is the vendor extension set on a path parameter that matches the remainder of the path,
+// including slashes, e.g. {path:*}
+const ExtensionCatchAll = "x-catch-all"
 
 func buildPaths(ws *restful.WebService, cfg Config, sb *swaggerBuilder) spec.Paths {
 	p := spec.Paths{Paths: map[string]spec.PathItem{}}
 	for _, each := range ws.Routes() {
+		if isHidden(each) || !documentsVersion(ws, each, cfg) {
+			continue
+		}
 		path, patterns := sanitizePath(each.Path)
+		path = relativePath(cfg.BasePath, path)
 		existingPathItem, ok := p.Paths[path]
 		if !ok {
 			existingPathItem = spec.PathItem{}
@@ -29,7 +78,7 @@ func buildPaths(ws *restful.WebService, cfg Config, sb *swaggerBuilder) spec.Pat
 
 // sanitizePath removes regex expressions from named path params,
 // since openapi only supports setting the pattern as a a property named "pattern".
-// Expressions like "/api/v1/{name:[a-z]/" are converted to "/api/v1/{name}/".
+// Expressions like "/api/v1/{name:[a-z]}/" are converted to "/api/v1/{name}/".
 // The second return value is a map which contains the mapping from the path parameter
 // name to the extracted pattern
 func sanitizePath(restfulPath string) (string, map[string]string) {
@@ -48,9 +97,41 @@ func sanitizePath(restfulPath string) (string, map[string]string) {
 		}
 		openapiPath += "/" + fragment
 	}
+	// the same path as the route matches, see restful.Container.TrailingSlash
+	if openapiPath == "" || strings.HasSuffix(restfulPath, "/") {
+		openapiPath += "/"
+	}
 	return openapiPath, patterns
 }
 
+// buildPatternParameter builds the parameter with the pattern extracted from the path.
+// A catch-all pattern is not a regular expression ; it is documented using ExtensionCatchAll instead.
+func buildPatternParameter(sb *swaggerBuilder, param *restful.Parameter, pattern string) spec.Parameter {
+	if pattern != "*" {
+		return sb.buildParameter(param, pattern)
+	}
+	p := sb.buildParameter(param, "")
+	if p.Ref.String() == "" {
+		p.AddExtension(ExtensionCatchAll, true)
+	}
+	return p
+}
+
+// relativePath returns the path relative to the basePath ; a path not under the basePath is returned as is.
+func relativePath(basePath, path string) string {
+	base := strings.TrimRight(basePath, "/")
+	if base == "" {
+		return path
+	}
+	if path == base {
+		return "/"
+	}
+	if strings.HasPrefix(path, base+"/") {
+		return path[len(base):]
+	}
+	return path
+}
+
 func buildPathItem(ws *restful.WebService, r restful.Route, existingPathItem spec.PathItem, patterns map[string]string, cfg Config, sb *swaggerBuilder) spec.PathItem {
 	op := buildOperation(ws, r, patterns, cfg, sb)
 	switch r.Method {
@@ -76,10 +157,31 @@ func buildOperation(ws *restful.WebService, r restful.Route, patterns map[string
 	o := spec.NewOperation(r.Operation)
 	o.Description = r.Notes
 	o.Summary = stripTags(r.Doc)
-	o.Consumes = r.Consumes
-	o.Produces = r.Produces
+	o.Consumes = operationContentTypes(r.Consumes, cfg.Consumes)
+	o.Produces = operationContentTypes(r.Produces, cfg.Produces)
 	o.Deprecated = r.Deprecated
+	if !r.Sunset.IsZero() {
+		o.AddExtension(ExtensionSunset, r.Sunset.UTC().Format(time.RFC3339))
+	}
 	o.Security = r.Security
+	if len(o.Security) == 0 {
+		o.Security = cfg.Security
+	}
+	if isStreaming(r.Produces) {
+		o.AddExtension(ExtensionStreaming, true)
+	}
+	if len(r.Host) > 0 {
+		o.AddExtension(ExtensionHost, r.Host)
+	}
+	if len(r.Version) > 0 {
+		o.AddExtension(ExtensionVersion, r.Version)
+	}
+	if len(r.Conditions) > 0 {
+		o.AddExtension(ExtensionCondition, strings.Join(r.Conditions, " and "))
+	}
+	for key, value := range r.Extensions {
+		o.AddExtension(key, value)
+	}
 	if r.Metadata != nil {
 		if tags, ok := r.Metadata[KeyOpenAPITags]; ok {
 			if tagList, ok := tags.([]string); ok {
@@ -87,30 +189,158 @@ func buildOperation(ws *restful.WebService, r restful.Route, patterns map[string
 			}
 		}
 	}
-	// collect any path parameters
+	// collect any path parameters ; a mounted route documents them itself, see WebService.Mount
 	for _, param := range ws.PathParameters() {
-		o.Parameters = append(o.Parameters, sb.buildParameter(param, patterns[param.Name]))
+		if !hasParameter(r.ParameterDocs, param) {
+			o.Parameters = append(o.Parameters, buildPatternParameter(sb, param, patterns[param.Name]))
+		}
 	}
 	// route specific params
 	for _, each := range r.ParameterDocs {
-		o.Parameters = append(o.Parameters, sb.buildParameter(each, patterns[each.Name]))
+		o.Parameters = append(o.Parameters, buildPatternParameter(sb, each, patterns[each.Name]))
+	}
+	// a path parameter with a pattern is documented even if not declared
+	for _, name := range undeclaredPatternParameters(ws, r, patterns) {
+		o.Parameters = append(o.Parameters, buildUndeclaredParameter(name, patterns[name]))
 	}
 	o.Responses = new(spec.Responses)
 	props := &o.Responses.ResponsesProps
 	props.StatusCodeResponses = map[int]spec.Response{}
 	for k, v := range r.ResponseErrors {
 		r := sb.buildResponse(v)
-		props.StatusCodeResponses[k] = r
 		if v.IsDefault {
+			// documented under default only, not as a "0" status code
 			o.Responses.Default = &r
+			continue
 		}
+		props.StatusCodeResponses[k] = r
+	}
+	if len(r.WriteBinary) > 0 {
+		setBinaryResponses(props)
 	}
-	if len(o.Responses.StatusCodeResponses) == 0 {
+	if len(o.Responses.StatusCodeResponses) == 0 && o.Responses.Default == nil {
 		o.Responses.StatusCodeResponses[200] = spec.Response{ResponseProps: spec.ResponseProps{Description: http.StatusText(http.StatusOK)}}
 	}
 	return o
 }
 
+// setBinaryResponses sets a binary string schema on the success responses without schema, see restful.RouteBuilder.WriteBinary ;
+// adds a 200 response if there is no success response.
+func setBinaryResponses(props *spec.ResponsesProps) {
+	found := false
+	for code, each := range props.StatusCodeResponses {
+		if code < 200 || code > 299 {
+			continue
+		}
+		found = true
+		if each.Schema == nil {
+			each.Schema = spec.StrFmtProperty("binary")
+			props.StatusCodeResponses[code] = each
+		}
+	}
+	if !found {
+		props.StatusCodeResponses[200] = spec.Response{ResponseProps: spec.ResponseProps{
+			Description: http.StatusText(http.StatusOK),
+			Schema:      spec.StrFmtProperty("binary")}}
+	}
+}
+
+// undeclaredPatternParameters returns the names, in path order, of the path parameters with a pattern
+// that are not declared by the route or its WebService.
+func undeclaredPatternParameters(ws *restful.WebService, r restful.Route, patterns map[string]string) (names []string) {
+	for _, fragment := range strings.Split(r.Path, "/") {
+		if !strings.HasPrefix(fragment, "{") || !strings.Contains(fragment, ":") {
+			continue
+		}
+		name := fragment[1:strings.Index(fragment, ":")]
+		if _, ok := patterns[name]; !ok {
+			continue
+		}
+		param := restful.PathParameter(name, "")
+		if hasParameter(r.ParameterDocs, param) || hasParameter(ws.PathParameters(), param) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// buildUndeclaredParameter builds a required string path parameter with the pattern extracted from the path.
+func buildUndeclaredParameter(name, pattern string) spec.Parameter {
+	p := *spec.PathParam(name)
+	p.Type = "string"
+	if pattern == "*" {
+		p.AddExtension(ExtensionCatchAll, true)
+		return p
+	}
+	p.Pattern = pattern
+	return p
+}
+
+// isHidden returns whether the route is left out of the documentation, see KeyOpenAPIHidden.
+func isHidden(r restful.Route) bool {
+	hidden, _ := r.Metadata[KeyOpenAPIHidden].(bool)
+	return hidden
+}
+
+// documentsVersion returns whether the route has no version or the version documented by the Config ;
+// if the Config has no Version then the default version of the WebService, see restful.WebService.ApiVersion.
+func documentsVersion(ws *restful.WebService, r restful.Route, cfg Config) bool {
+	if len(r.Version) == 0 {
+		return true
+	}
+	if len(cfg.Version) > 0 {
+		return r.Version == cfg.Version
+	}
+	return r.Version == ws.Version()
+}
+
+// hasParameter returns whether the list has a parameter with the name and location of param.
+func hasParameter(list []*restful.Parameter, param *restful.Parameter) bool {
+	for _, each := range list {
+		if each.Name == param.Name && each.In == param.In {
+			return true
+		}
+	}
+	return false
+}
+
+// contentTypes returns the MIME types sorted and without duplicates, nil if there are none.
+func contentTypes(types []string) []string {
+	if len(types) == 0 {
+		return nil
+	}
+	sorted := append([]string{}, types...)
+	sort.Strings(sorted)
+	unique := sorted[:1]
+	for _, each := range sorted[1:] {
+		if each != unique[len(unique)-1] {
+			unique = append(unique, each)
+		}
+	}
+	return unique
+}
+
+// operationContentTypes returns the MIME types of a route as listed by its operation ;
+// nil if they are the same as the global ones of the API.
+func operationContentTypes(types, global []string) []string {
+	listed := contentTypes(types)
+	if len(global) > 0 && reflect.DeepEqual(listed, contentTypes(global)) {
+		return nil
+	}
+	return listed
+}
+
+// isStreaming returns whether any of the mime-types is a streaming format (e.g. NDJSON)
+func isStreaming(produces []string) bool {
+	for _, each := range produces {
+		if each == restful.MIME_NDJSON {
+			return true
+		}
+	}
+	return false
+}
+
 // stringAutoType automatically picks the correct type from an ambiguously typed
 // string. Ex. numbers become int, true/false become bool, etc.
 func stringAutoType(dataType, ambiguous string) interface{} {
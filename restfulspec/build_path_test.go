@@ -1,9 +1,16 @@
 package restfulspec
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restful/log"
 	"github.com/tangblue/goapi/spec"
 )
 
@@ -231,3 +238,557 @@ func TestWritesPrimitive(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamingOperation(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/stream")
+	ws.Route(ws.GET("/users").Handler(dummy).
+		Produces(restful.MIME_NDJSON).
+		Return(200, "stream of users", Sample{}))
+	ws.Route(ws.GET("/user").Handler(dummy).
+		Produces(restful.MIME_JSON).
+		Return(200, "a user", Sample{}))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	if streaming, _ := p.Paths["/tests/stream/users"].Get.Extensions.GetBool(ExtensionStreaming); !streaming {
+		t.Errorf("expected %s extension on streaming operation", ExtensionStreaming)
+	}
+	if _, ok := p.Paths["/tests/stream/user"].Get.Extensions[ExtensionStreaming]; ok {
+		t.Errorf("unexpected %s extension", ExtensionStreaming)
+	}
+}
+
+func TestBinaryOperation(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/binary").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/qr").Handler(dummy).
+		WriteBinary("image/png").
+		Return(http.StatusBadRequest, "no data", nil))
+	ws.Route(ws.GET("/avatar").Handler(dummy).
+		WriteBinary("image/jpeg").
+		Return(http.StatusCreated, "created", nil))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	op := p.Paths["/tests/binary/qr"].Get
+	if got, want := op.Produces, []string{"image/png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got produces %v want %v", got, want)
+	}
+	schema := op.Responses.StatusCodeResponses[200].Schema
+	if schema == nil || !schema.Type.Contains("string") || schema.Format != "binary" {
+		t.Errorf("got schema %v want a binary string", schema)
+	}
+	if op.Responses.StatusCodeResponses[400].Schema != nil {
+		t.Error("unexpected schema of the 400 response")
+	}
+	op = p.Paths["/tests/binary/avatar"].Get
+	if _, ok := op.Responses.StatusCodeResponses[200]; ok {
+		t.Error("unexpected 200 response")
+	}
+	if schema := op.Responses.StatusCodeResponses[201].Schema; schema == nil || schema.Format != "binary" {
+		t.Errorf("got schema %v of the 201 response want a binary string", schema)
+	}
+}
+
+func TestDefaultOnlyResponse(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/default")
+	ws.Route(ws.GET("/only").Handler(dummy).DefaultReturn("error", restful.ServiceError{}))
+	ws.Route(ws.GET("/both").Handler(dummy).DefaultReturn("error", nil).Return(http.StatusOK, "OK", nil))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	op := p.Paths["/tests/default/only"].Get
+	if op.Responses.Default == nil || op.Responses.Default.Description != "error" {
+		t.Errorf("got default %v want the error", op.Responses.Default)
+	}
+	data, _ := json.Marshal(op.Responses)
+	if got, want := string(data), `{"default":{"description":"error","schema":{"$ref":"#/definitions/restful.ServiceError"}}}`; got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+	op = p.Paths["/tests/default/both"].Get
+	if _, ok := op.Responses.StatusCodeResponses[0]; ok || len(op.Responses.StatusCodeResponses) != 1 || op.Responses.Default == nil {
+		t.Errorf("got responses %v want 200 and default", op.Responses.StatusCodeResponses)
+	}
+}
+
+func TestSunsetOperation(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/sunset")
+	ws.Route(ws.GET("/old").Handler(dummy).
+		DeprecateWithSunset(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), "https://example.com/sunset"))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	op := p.Paths["/tests/sunset/old"].Get
+	if !op.Deprecated {
+		t.Error("expected deprecated operation")
+	}
+	if sunset, _ := op.Extensions.GetString(ExtensionSunset); sunset != "2030-01-02T03:04:05Z" {
+		t.Errorf("unexpected %s extension: %q", ExtensionSunset, sunset)
+	}
+}
+
+func TestParameterExampleAndDefault(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/params")
+	ws.Route(ws.GET("/{id}").Handler(dummy).
+		Params(ws.PathParameter("id", "identifier").DataType("abc")).
+		Params(ws.QueryParameter("limit", "page size").DataType(10).WithExampleValue(25)))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	params := p.Paths["/tests/params/{id}"].Get.Parameters
+	if got := params[0].Example; got != "abc" {
+		t.Errorf("expected model as example of required parameter, got %v", got)
+	}
+	if got := params[1].Default; got != 10 {
+		t.Errorf("expected default 10, got %v", got)
+	}
+	if got := params[1].Example; got != 25 {
+		t.Errorf("expected example 25, got %v", got)
+	}
+}
+
+func TestCatchAllPathParameter(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/files")
+	ws.Route(ws.GET("/{path:*}").Handler(dummy).
+		Params(ws.PathParameter("path", "remainder of the path")))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	item, ok := p.Paths["/tests/files/{path}"]
+	if !ok {
+		t.Fatalf("expected sanitized path in %v", p.Paths)
+	}
+	param := item.Get.Parameters[0]
+	if catchAll, _ := param.Extensions.GetBool(ExtensionCatchAll); !catchAll {
+		t.Errorf("expected %s extension on parameter", ExtensionCatchAll)
+	}
+	if param.Pattern != "" {
+		t.Errorf("unexpected pattern %q", param.Pattern)
+	}
+}
+
+func TestResponseExamples(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/examples")
+	ws.Route(ws.GET("/sample").Handler(dummy).
+		Produces(restful.MIME_JSON, restful.MIME_XML).
+		ReturnResponses(restful.NewResponseError(200, "a sample", Sample{}).
+			Example(restful.MIME_JSON, map[string]string{"id": "42"}).
+			Example(restful.MIME_XML, "<Sample><id>42</id></Sample>")))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	examples := p.Paths["/tests/examples/sample"].Get.Responses.StatusCodeResponses[200].Examples
+	if got, ok := examples[restful.MIME_JSON].(map[string]string); !ok || got["id"] != "42" {
+		t.Errorf("unexpected JSON example %v", examples[restful.MIME_JSON])
+	}
+	if got := examples[restful.MIME_XML]; got != "<Sample><id>42</id></Sample>" {
+		t.Errorf("unexpected XML example %v", got)
+	}
+}
+
+func TestMountedRouteParameters(t *testing.T) {
+	child := new(restful.WebService)
+	child.Path("/users")
+	child.Route(child.GET("/{id}").Handler(dummy).
+		Params(child.PathParameter("id", "identifier of the user")))
+	ws := new(restful.WebService)
+	ws.Path("/api/{version}")
+	ws.Params(ws.PathParameter("version", "version of the api"))
+	ws.Mount("", child)
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	item, ok := p.Paths["/api/{version}/users/{id}"]
+	if !ok {
+		t.Fatalf("expected mounted path in %v", p.Paths)
+	}
+	names := []string{}
+	for _, each := range item.Get.Parameters {
+		names = append(names, each.Name)
+	}
+	if got, want := len(names), 2; got != want || names[0] != "version" || names[1] != "id" {
+		t.Errorf("got %v want [version id]", names)
+	}
+}
+
+func TestHostOperation(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/host")
+	ws.Route(ws.GET("").Host("*.example.com").Handler(dummy))
+	ws.Route(ws.POST("").Handler(dummy))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	item := p.Paths["/tests/host"]
+	if host, _ := item.Get.Extensions.GetString(ExtensionHost); host != "*.example.com" {
+		t.Errorf("unexpected %s extension: %q", ExtensionHost, host)
+	}
+	if _, ok := item.Post.Extensions[ExtensionHost]; ok {
+		t.Errorf("unexpected %s extension", ExtensionHost)
+	}
+}
+
+func hasFeatureHeader(httpRequest *http.Request) bool {
+	return httpRequest.Header.Get("X-Feature") != ""
+}
+
+func TestConditionOperation(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/condition")
+	ws.Route(ws.GET("").IfDoc(hasFeatureHeader, "header X-Feature is set").Handler(dummy))
+	ws.Route(ws.POST("").If(hasFeatureHeader).Handler(dummy))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	item := p.Paths["/tests/condition"]
+	if got, _ := item.Get.Extensions.GetString(ExtensionCondition); got != "header X-Feature is set" {
+		t.Errorf("unexpected %s extension: %q", ExtensionCondition, got)
+	}
+	if _, ok := item.Post.Extensions[ExtensionCondition]; ok {
+		t.Errorf("unexpected %s extension", ExtensionCondition)
+	}
+}
+
+func TestVendorExtensions(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/internal")
+	ws.Route(ws.GET("").Handler(dummy).Extension("x-internal", true).
+		Params(ws.QueryParameter("debug", "debug output").Extension("x-internal", true)))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	op := p.Paths["/tests/internal"].Get
+	if internal, _ := op.Extensions.GetBool("x-internal"); !internal {
+		t.Errorf("expected x-internal extension on operation, got %v", op.Extensions)
+	}
+	if internal, _ := op.Parameters[0].Extensions.GetBool("x-internal"); !internal {
+		t.Errorf("expected x-internal extension on parameter, got %v", op.Parameters[0].Extensions)
+	}
+}
+
+func TestOperationContentTypes(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/types")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_XML, restful.MIME_JSON, restful.MIME_XML)
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.POST("").Handler(dummy).Consumes(restful.MIME_XML, restful.MIME_JSON, restful.MIME_XML).Produces(restful.MIME_XML))
+
+	config := Config{
+		WebServices: []*restful.WebService{ws},
+		Consumes:    []string{restful.MIME_JSON},
+		Produces:    []string{restful.MIME_JSON, restful.MIME_XML},
+	}
+	swagger := BuildSwagger(config)
+	if got, want := swagger.Produces, []string{restful.MIME_JSON, restful.MIME_XML}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got produces %v want %v", got, want)
+	}
+
+	item := swagger.Paths.Paths["/tests/types"]
+	if item.Get.Consumes != nil || item.Get.Produces != nil {
+		t.Errorf("inherited types same as the global ones should not be listed, got %v and %v", item.Get.Consumes, item.Get.Produces)
+	}
+	if got, want := item.Post.Consumes, []string{restful.MIME_JSON, restful.MIME_XML}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got consumes %v want %v", got, want)
+	}
+	if got, want := item.Post.Produces, []string{restful.MIME_XML}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got produces %v want %v", got, want)
+	}
+}
+
+func TestDeprecatedWebService(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/v1/users")
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.PUT("/{id}").Handler(dummy))
+	ws.Deprecate()
+	ws.Route(ws.DELETE("/{id}").Handler(dummy))
+
+	swagger := BuildSwagger(Config{WebServices: []*restful.WebService{ws}})
+	for path, item := range swagger.Paths.Paths {
+		for _, op := range []*spec.Operation{item.Get, item.Put, item.Delete} {
+			if op != nil && !op.Deprecated {
+				t.Errorf("expected deprecated operation %s of %s", op.ID, path)
+			}
+		}
+	}
+	if item := swagger.Paths.Paths["/v1/users/{id}"]; item.Put == nil || item.Delete == nil {
+		t.Errorf("missing operations: %v", swagger.Paths.Paths)
+	}
+}
+
+func TestVersionedOperations(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/users").ApiVersion("v1")
+	ws.Route(ws.GET("").Version("v1").Operation("listUsersV1").Handler(dummy))
+	ws.Route(ws.GET("").Version("v2").Operation("listUsersV2").Handler(dummy))
+	ws.Route(ws.DELETE("").Handler(dummy))
+
+	for version, want := range map[string]string{"": "listUsersV1", "v1": "listUsersV1", "v2": "listUsersV2"} {
+		swagger := BuildSwagger(Config{WebServices: []*restful.WebService{ws}, Version: version})
+		item := swagger.Paths.Paths["/users"]
+		if item.Get == nil || item.Get.ID != want {
+			t.Errorf("version %q: got %v want operation %s", version, item.Get, want)
+			continue
+		}
+		if got, _ := item.Get.Extensions.GetString(ExtensionVersion); "listUsers"+strings.ToUpper(got) != want {
+			t.Errorf("version %q: got %s %q", version, ExtensionVersion, got)
+		}
+		if item.Delete == nil {
+			t.Errorf("version %q: expected the unversioned operation", version)
+		}
+	}
+}
+
+func TestUndeclaredPatternParameter(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/items")
+	ws.Route(ws.GET("/{id:[0-9]+}").Handler(dummy))
+	ws.Route(ws.GET("/{id:[0-9]+}/files/{name:*}").Handler(dummy).
+		Params(ws.PathParameter("id", "identifier").DataType("")))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	path := p.Paths["/items/{id}"]
+	if got := len(path.Get.Parameters); got != 1 {
+		t.Fatalf("got %d parameters want 1", got)
+	}
+	id := path.Get.Parameters[0]
+	if id.Name != "id" || id.In != "path" || !id.Required || id.Type != "string" || id.Pattern != "[0-9]+" {
+		t.Errorf("unexpected parameter %s", asJSON(id))
+	}
+
+	files := p.Paths["/items/{id}/files/{name}"]
+	if got := len(files.Get.Parameters); got != 2 {
+		t.Fatalf("got %d parameters want 2", got)
+	}
+	if got := files.Get.Parameters[0].Description; got != "identifier" {
+		t.Errorf("expected the declared id parameter, got description %q", got)
+	}
+	name := files.Get.Parameters[1]
+	if name.Name != "name" || name.Pattern != "" || name.Extensions[ExtensionCatchAll] != true {
+		t.Errorf("unexpected parameter %s", asJSON(name))
+	}
+}
+
+func TestResponseProduces(t *testing.T) {
+	ws := new(restful.WebService).Path("/files").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/{name}").Handler(dummy).
+		ReturnResponses(restful.NewResponseError(200, "OK", nil).Produces(restful.MIME_OCTET)).
+		Return(404, "Not Found", restful.ServiceError{}))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+	o := p.Paths["/files/{name}"].Get
+
+	if got, want := o.Produces, []string{restful.MIME_JSON}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got produces %v want %v", got, want)
+	}
+	ok := o.Responses.StatusCodeResponses[200]
+	if got, want := ok.Extensions[ExtensionProduces], []string{restful.MIME_OCTET}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s %v want %v", ExtensionProduces, got, want)
+	}
+	notFound := o.Responses.StatusCodeResponses[404]
+	if _, found := notFound.Extensions[ExtensionProduces]; found {
+		t.Errorf("unexpected %s on 404 response", ExtensionProduces)
+	}
+}
+
+func TestAnonymousResponseModels(t *testing.T) {
+	ws := new(restful.WebService).Path("/items")
+	ws.Route(ws.GET("").Handler(dummy).
+		Return(200, "OK", struct{ A int }{}).
+		Return(400, "Bad Request", struct{ B string }{}))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	o := buildPaths(ws, Config{}, sb).Paths["/items"].Get
+	ok, bad := o.Responses.StatusCodeResponses[200].Schema, o.Responses.StatusCodeResponses[400].Schema
+	if ok.Ref.String() == bad.Ref.String() {
+		t.Fatalf("got the same reference %s for both models", ok.Ref.String())
+	}
+	for property, each := range map[string]interface{}{"A": struct{ A int }{}, "B": struct{ B string }{}} {
+		name := reflect.TypeOf(each).String()
+		if _, found := sb.def.Definitions[name].Properties[property]; !found {
+			t.Errorf("got definition %s %v want property %s", name, asJSON(sb.def.Definitions[name]), property)
+		}
+	}
+}
+
+func TestArrayOfEnumParameter(t *testing.T) {
+	ws := new(restful.WebService).Path("/items")
+	colors := ws.QueryParameter("colors", "colors of the item").
+		ArrayOf("", restful.CollectionFormatCSV).
+		EnumFromValues("red", "green")
+	ws.Route(ws.GET("").Handler(dummy).Params(colors))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb).Paths["/items"].Get.Parameters[0]
+
+	if p.Type != "array" || p.CollectionFormat != "csv" || p.Default != nil || p.Enum != nil {
+		t.Errorf("unexpected parameter %s", asJSON(p))
+	}
+	if p.Items == nil || p.Items.Type != "string" || !reflect.DeepEqual(p.Items.Enum, []interface{}{"red", "green"}) {
+		t.Errorf("unexpected items %s", asJSON(p.Items))
+	}
+	// the enum still validates the items of a request
+	if len(colors.Enum) != 2 {
+		t.Errorf("got enum %v", colors.Enum)
+	}
+}
+
+func TestDocumentedPathMatchesRoute(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests//paths")
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.POST("/").Handler(dummy))
+	ws.Route(ws.GET("//{id}//").Handler(dummy))
+	root := new(restful.WebService)
+	root.Route(root.GET("/").Handler(dummy))
+
+	paths := BuildSwagger(Config{WebServices: []*restful.WebService{ws, root}}).Paths.Paths
+	for path, method := range map[string]string{"/tests/paths": "GET", "/tests/paths/": "POST", "/tests/paths/{id}/": "GET", "/": "GET"} {
+		item, ok := paths[path]
+		if !ok {
+			t.Errorf("missing path %s", path)
+			continue
+		}
+		if (method == "GET" && item.Get == nil) || (method == "POST" && item.Post == nil) {
+			t.Errorf("missing %s %s", method, path)
+		}
+	}
+	if got, want := len(paths), 4; got != want {
+		t.Errorf("got %d paths want %d", got, want)
+	}
+}
+
+func TestSensitiveParameter(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/sensitive")
+	password := ws.QueryParameter("password", "the password").Sensitive()
+	ws.Route(ws.GET("/login").Handler(dummy).
+		Params(ws.QueryParameter("name", "the user name"), password))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	params := p.Paths["/tests/sensitive/login"].Get.Parameters
+	if sensitive, _ := params[1].Extensions.GetBool(ExtensionSensitive); !sensitive {
+		t.Errorf("expected %s extension on parameter %s", ExtensionSensitive, asJSON(params[1]))
+	}
+	if _, ok := params[0].Extensions[ExtensionSensitive]; ok {
+		t.Errorf("unexpected %s extension on parameter %s", ExtensionSensitive, asJSON(params[0]))
+	}
+	if len(password.Extensions) > 0 {
+		t.Errorf("unexpected extensions of the restful.Parameter %v", password.Extensions)
+	}
+}
+
+func TestPartialBodyParameter(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests/partial")
+	ws.Route(ws.PUT("/{id}").Handler(dummy).Read(Sample{}))
+	ws.Route(ws.PATCH("/{id}").Handler(dummy).ReadPartial(Sample{}))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	item := p.Paths["/tests/partial/{id}"]
+	if got := item.Put.Parameters[0].Schema.Ref.String(); got != "#/definitions/restfulspec.Sample" {
+		t.Errorf("got PUT body %s want the model", got)
+	}
+	if got := item.Patch.Parameters[0].Schema.Ref.String(); got != "#/definitions/restfulspec.Sample.Patch" {
+		t.Errorf("got PATCH body %s want the partial model", got)
+	}
+	full, partial := sb.def.Definitions["restfulspec.Sample"], sb.def.Definitions["restfulspec.Sample.Patch"]
+	if want := []string{"ID", "root", "Items"}; !reflect.DeepEqual(full.Required, want) {
+		t.Errorf("got required %v of the model want %v", full.Required, want)
+	}
+	if len(partial.Required) > 0 {
+		t.Errorf("got required %v of the partial model want none", partial.Required)
+	}
+	if len(partial.Properties) != len(full.Properties) {
+		t.Fatalf("got properties %s of the partial model", asJSON(partial.Properties))
+	}
+	for name, prop := range partial.Properties {
+		if nullable, _ := prop.Extensions.GetBool(ExtensionNullable); !nullable {
+			t.Errorf("expected %s extension on property %s", ExtensionNullable, name)
+		}
+		if _, ok := full.Properties[name].Extensions[ExtensionNullable]; ok {
+			t.Errorf("unexpected %s extension on property %s of the model", ExtensionNullable, name)
+		}
+	}
+}
+
+// lineRecorder is a log.StdLogger that records the warnings
+type lineRecorder struct {
+	lines []string
+}
+
+func (r *lineRecorder) Print(v ...interface{}) {
+	r.lines = append(r.lines, fmt.Sprint(v...))
+}
+
+func (r *lineRecorder) Printf(format string, v ...interface{}) {
+	r.lines = append(r.lines, fmt.Sprintf(format, v...))
+}
+
+func TestOptionalPathParameter(t *testing.T) {
+	recorder := &lineRecorder{}
+	restful.SetLogger(recorder)
+	defer restful.SetLogger(log.Logger)
+
+	ws := new(restful.WebService)
+	ws.Path("/tests/optional")
+	id := ws.PathParameter("id", "identifier")
+	id.Required = false
+	ws.Route(ws.GET("/{id}").Handler(dummy).Params(id))
+
+	sb := &swaggerBuilder{}
+	sb.def.Definitions = spec.Definitions{}
+	p := buildPaths(ws, Config{}, sb)
+
+	if param := p.Paths["/tests/optional/{id}"].Get.Parameters[0]; !param.Required {
+		t.Errorf("got optional path parameter %s", asJSON(param))
+	}
+	if len(recorder.lines) != 1 || !strings.Contains(recorder.lines[0], "path parameter id is not required") {
+		t.Errorf("got %q want a warning", recorder.lines)
+	}
+	if id.Required {
+		t.Error("got the parameter of the route changed to required")
+	}
+}
@@ -20,6 +20,10 @@ type MapModelTypeNameFunc func(t reflect.Type) (string, bool)
 // before serving it. To use it set the PostBuildSwaggerObjectHandler in the config.
 type PostBuildSwaggerObjectFunc func(s *spec.Swagger)
 
+// ResponseEnvelopeFunc returns the schema of a success response that wraps the payload with the schema,
+// e.g. an object with the payload as data property. To use it set the ResponseEnvelope in the config.
+type ResponseEnvelopeFunc func(schema *spec.Schema) *spec.Schema
+
 // Config holds service api metadata.
 type Config struct {
 	// WebServicesURL is a DEPRECATED field; it never had any effect in this package.
@@ -30,12 +34,50 @@ type Config struct {
 	WebServices []*restful.WebService
 	// [optional] on default CORS (Cross-Origin-Resource-Sharing) is enabled.
 	DisableCORS bool
+	// [optional] If set, the served JSON is indented for readability ; it is compact by default.
+	IndentJSON bool
 	// Top-level API version. Is reflected in the resource listing.
 	APIVersion string
+	// [optional] The version of the routes to document, see restful.RouteBuilder.Version ; routes without a version
+	// are always documented. If empty then the routes of the default version of each WebService are documented.
+	// Build a spec per version to document all versions.
+	Version string
+	// [optional] The path prefix under which the API is served, e.g. /api. Is reflected as basePath ;
+	// the paths of routes under this prefix are listed relative to it.
+	BasePath string
+	// [optional] The host (name or ip) and optional port serving the API, e.g. api.example.com:8080. Is reflected as host.
+	Host string
+	// [optional] The transfer protocols of the API ; values must be "http", "https", "ws" or "wss". Is reflected as schemes.
+	Schemes []string
+	// [optional] The MIME types the API consumes. Is reflected as consumes ; operations that consume the same types do not list them.
+	Consumes []string
+	// [optional] The MIME types the API produces. Is reflected as produces ; operations that produce the same types do not list them.
+	Produces []string
+	// [optional] The security schemes available to the API, by name. Is reflected as securityDefinitions.
+	SecurityDefinitions spec.SecurityDefinitions
+	// [optional] The security requirements of operations whose route has no security of its own. Is reflected as security.
+	Security []map[string][]string
+	// [optional] If set, BuildSwagger panics if a security requirement refers to a scheme that is not
+	// in the securityDefinitions after calling the PostBuildSwaggerObjectHandler. See ValidateSecurity.
+	ValidateSecurity bool
 	// [optional] If set, model builder should call this handler to get addition typename-to-swagger-format-field conversion.
+	// The format tag of a field takes precedence over it.
 	SchemaFormatHandler MapSchemaFormatFunc
+	// [optional] The schemas of types that replace the ones built from the types, e.g. {type: string, format: uuid} for a UUID struct.
+	// The schema is used inline for the properties, items and responses of the type ; no definition is added for it.
+	TypeSchemaOverrides map[reflect.Type]spec.Schema
 	// [optional] If set, model builder should call this handler to retrieve the name for a given type.
 	ModelTypeNameHandler MapModelTypeNameFunc
+	// [optional] If set, definitions are named after their type without the package, e.g. User instead of v1.User.
+	// If types of different packages have the same name then the first one keeps the short name and the others
+	// keep the qualified name. Names returned by the ModelTypeNameHandler are not changed.
+	ShortDefinitionNames bool
+	// [optional] If set, the definition of a struct with embedded structs is an allOf of references to their
+	// definitions and an inline schema with its other properties. By default, the embedded fields are flattened.
+	EmbedAsAllOf bool
+	// [optional] If set, the schema of each success (2xx) response is replaced by the one returned by this function,
+	// e.g. to document the payloads written using restful.Response.WriteEnveloped.
+	ResponseEnvelope ResponseEnvelopeFunc
 	// [optional] If set then call this function with the generated Swagger Object
 	PostBuildSwaggerObjectHandler PostBuildSwaggerObjectFunc
 }
@@ -11,6 +11,8 @@ import (
 type definitionBuilder struct {
 	Definitions spec.Definitions
 	Config      Config
+	// shortNames maps a short definition name to the type that uses it, see Config.ShortDefinitionNames
+	shortNames map[string]reflect.Type
 }
 
 // Documented is
@@ -43,13 +45,25 @@ func (b *definitionBuilder) SchemaFromModel(model reflect.Type, modelName, jsonN
 	if model.Kind() == reflect.Ptr {
 		model = model.Elem()
 	}
+	if override, ok := b.typeSchemaOverride(model); ok {
+		*s = override
+		return ret
+	}
+	if isFreeForm(model) {
+		return ret
+	}
 
 	name := model.Kind().String()
 	if isPrimitiveType(name) {
-		s.AddType(jsonSchemaType(name), jsonSchemaFormat(name))
+		s.AddType(jsonSchemaType(name), b.primitiveFormat(model))
 	} else {
 		name = model.String()
-		if name == "" {
+		if len(model.Name()) > 0 {
+			// the same name as addModel gives it, such that a self-reference refers to the model being built
+			name = b.keyFrom(model)
+		} else if model.Kind() == reflect.Struct && len(modelName) > 0 {
+			// anonymous, named after the enclosing model like buildStructTypeProperty does ;
+			// otherwise, e.g. a response model, after its type
 			name = modelName + "." + jsonName
 		}
 		s.Ref = b.createRef(model, name)
@@ -70,6 +84,10 @@ func (b *definitionBuilder) addModel(st reflect.Type, nameOverride string) *spec
 	if st.Kind() == reflect.Ptr {
 		st = st.Elem()
 	}
+	// no models needed for types with a schema of their own
+	if _, ok := b.Config.TypeSchemaOverrides[st]; ok {
+		return nil
+	}
 
 	modelName := b.keyFrom(st)
 	if nameOverride != "" {
@@ -112,9 +130,14 @@ func (b *definitionBuilder) addModel(st reflect.Type, nameOverride string) *spec
 
 	fullDoc := getDocFromMethodSwaggerDoc2(st)
 	modelDescriptions := []string{}
+	requiredIf := map[string]string{}
 
 	for i := 0; i < st.NumField(); i++ {
 		field := st.Field(i)
+		if field.Name == "XMLName" && field.Type.String() == "xml.Name" {
+			// the name of the element for the model
+			setXML(&sm, field)
+		}
 		jsonName, modelDescription, prop := b.buildProperty(field, &sm, modelName)
 		if len(modelDescription) > 0 {
 			modelDescriptions = append(modelDescriptions, modelDescription)
@@ -130,6 +153,9 @@ func (b *definitionBuilder) addModel(st reflect.Type, nameOverride string) *spec
 			if b.isPropertyRequired(field) {
 				sm.Required = append(sm.Required, jsonName)
 			}
+			if condition := field.Tag.Get("requiredIf"); condition != "" {
+				requiredIf[jsonName] = condition
+			}
 			sm.Properties[jsonName] = prop
 		}
 	}
@@ -141,6 +167,12 @@ func (b *definitionBuilder) addModel(st reflect.Type, nameOverride string) *spec
 	} else if len(modelDescriptions) != 0 {
 		sm.Description = strings.Join(modelDescriptions, "\n")
 	}
+	if len(requiredIf) > 0 {
+		sm.AddExtension(ExtensionRequiredIf, requiredIf)
+	}
+	if len(sm.AllOf) > 0 {
+		sm = composeAllOf(sm)
+	}
 	// Needed to pass openapi validation. This field exists for json-schema compatibility,
 	// but it conflicts with the openapi specification.
 	// See https://github.com/go-openapi/spec/issues/23 for more context
@@ -152,11 +184,79 @@ func (b *definitionBuilder) addModel(st reflect.Type, nameOverride string) *spec
 	return &sm
 }
 
+// PartialSchemaFromModel returns the schema of the model like SchemaFromModel, referring to the partial variant
+// of its definition, e.g. User.Patch, whose properties are all optional and nullable, see restful.RouteBuilder.ReadPartial.
+func (b *definitionBuilder) PartialSchemaFromModel(model reflect.Type) *spec.Schema {
+	ret := b.SchemaFromModel(model, "", "")
+	s := ret
+	if ret.Items != nil {
+		s = ret.Items.Schema
+	}
+	if name := strings.TrimPrefix(s.Ref.String(), "#/definitions/"); name != s.Ref.String() {
+		s.Ref = spec.MustCreateRef("#/definitions/" + b.addPartialModel(name))
+	}
+	return ret
+}
+
+// addPartialModel adds the partial variant of the definition, if not yet added, and returns its name.
+// The properties of the model itself are optional and nullable ; embedded models of an allOf composition
+// refer to their partial variants, other referenced models are unchanged.
+func (b *definitionBuilder) addPartialModel(name string) string {
+	partialName := name + ".Patch"
+	if _, ok := b.Definitions[partialName]; ok {
+		return partialName
+	}
+	sm := partialSchema(b.Definitions[name])
+	// reference the model before composing it (enables recursive structs)
+	b.Definitions[partialName] = sm
+	for i, each := range sm.AllOf {
+		if ref := strings.TrimPrefix(each.Ref.String(), "#/definitions/"); ref != each.Ref.String() {
+			sm.AllOf[i] = spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/definitions/" + b.addPartialModel(ref))}}
+		} else {
+			sm.AllOf[i] = partialSchema(each)
+		}
+	}
+	b.Definitions[partialName] = sm
+	return partialName
+}
+
+// partialSchema returns a copy of the schema without required properties, each property being nullable.
+func partialSchema(model spec.Schema) spec.Schema {
+	partial := model
+	partial.Required = nil
+	partial.Extensions = nil
+	for key, value := range model.Extensions {
+		if key != ExtensionRequiredIf {
+			partial.AddExtension(key, value)
+		}
+	}
+	if model.AllOf != nil {
+		partial.AllOf = append([]spec.Schema{}, model.AllOf...)
+	}
+	if model.Properties != nil {
+		partial.Properties = map[string]spec.Schema{}
+		for name, prop := range model.Properties {
+			nullable := prop
+			nullable.Extensions = spec.Extensions{}
+			for key, value := range prop.Extensions {
+				nullable.Extensions[key] = value
+			}
+			nullable.AddExtension(ExtensionNullable, true)
+			partial.Properties[name] = nullable
+		}
+	}
+	return partial
+}
+
 func (b *definitionBuilder) isPropertyRequired(field reflect.StructField) bool {
 	required := true
 	if optionalTag := field.Tag.Get("optional"); optionalTag == "true" {
 		return false
 	}
+	// only required if another field has a value, see ExtensionRequiredIf
+	if field.Tag.Get("requiredIf") != "" {
+		return false
+	}
 	if jsonTag := field.Tag.Get("json"); jsonTag != "" {
 		s := strings.Split(jsonTag, ",")
 		if len(s) > 1 && s[1] == "omitempty" {
@@ -188,6 +288,17 @@ func (b *definitionBuilder) buildProperty(field reflect.StructField, model *spec
 	}
 	fieldType := field.Type
 
+	if override, ok := b.typeSchemaOverride(fieldType); ok {
+		prop = override
+		setPropertyMetadata(&prop, field)
+		return jsonName, modelDescription, prop
+	}
+
+	// any JSON value, e.g. of a json.RawMessage or an interface{}
+	if isFreeForm(fieldType) {
+		return jsonName, modelDescription, prop
+	}
+
 	// check if type is doing its own marshalling
 	marshalerType := reflect.TypeOf((*json.Marshaler)(nil)).Elem()
 	if fieldType.Implements(marshalerType) {
@@ -214,7 +325,7 @@ func (b *definitionBuilder) buildProperty(field reflect.StructField, model *spec
 	fieldKind := fieldType.Kind()
 	switch {
 	case fieldKind == reflect.Struct:
-		jsonName, prop := b.buildStructTypeProperty(field, jsonName, model)
+		jsonName, prop := b.buildStructTypeProperty(field, jsonName, model, modelName)
 		return jsonName, modelDescription, prop
 	case fieldKind == reflect.Slice || fieldKind == reflect.Array:
 		jsonName, prop := b.buildArrayTypeProperty(field, jsonName, modelName)
@@ -236,6 +347,31 @@ func (b *definitionBuilder) buildProperty(field reflect.StructField, model *spec
 	return jsonName, modelDescription, prop
 }
 
+// typeSchemaOverride returns a copy of the schema of the type in the TypeSchemaOverrides, if any.
+func (b *definitionBuilder) typeSchemaOverride(st reflect.Type) (spec.Schema, bool) {
+	override, ok := b.Config.TypeSchemaOverrides[st]
+	if !ok {
+		return override, false
+	}
+	// the schema is shared by the models and properties of the type ; copy its maps and slices
+	var copied spec.Schema
+	data, err := json.Marshal(override)
+	if err != nil || json.Unmarshal(data, &copied) != nil {
+		return override, true
+	}
+	return copied, true
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// isFreeForm returns whether values of the type can be any JSON value ; their schema is empty.
+func isFreeForm(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == rawMessageType || t.Kind() == reflect.Interface
+}
+
 func (b *definitionBuilder) createRef(st reflect.Type, name string) spec.Ref {
 	b.addModel(st, name)
 	return spec.MustCreateRef("#/definitions/" + name)
@@ -254,22 +390,34 @@ func hasNamedJSONTag(field reflect.StructField) bool {
 	return len(parts[0]) > 0
 }
 
-func (b *definitionBuilder) buildStructTypeProperty(field reflect.StructField, jsonName string, model *spec.Schema) (nameJson string, prop spec.Schema) {
+func (b *definitionBuilder) buildStructTypeProperty(field reflect.StructField, jsonName string, model *spec.Schema, modelName string) (nameJson string, prop spec.Schema) {
 	setPropertyMetadata(&prop, field)
 	fieldType := field.Type
 	// check for anonymous
 	if len(fieldType.Name()) == 0 {
-		// anonymous
-		anonType := model.ID + "." + jsonName
+		// anonymous, named after the enclosing model, e.g. User.address
+		anonType := modelName + "." + jsonName
 		prop.Ref = b.createRef(fieldType, anonType)
 		return jsonName, prop
 	}
 
 	if field.Name == fieldType.Name() && field.Anonymous && !hasNamedJSONTag(field) {
 		// embedded struct
-		sub := definitionBuilder{make(spec.Definitions), b.Config}
+		if b.Config.EmbedAsAllOf {
+			// referenced from the model, see composeAllOf
+			model.AllOf = append(model.AllOf, spec.Schema{SchemaProps: spec.SchemaProps{Ref: b.createRef(fieldType, b.keyFrom(fieldType))}})
+			return "", prop
+		}
+		subKey := b.keyFrom(fieldType)
+		// the sub builder knows the models that are (being) built, except the embedded one,
+		// such that references back to them, e.g. from a field of the embedded struct, terminate
+		sub := definitionBuilder{make(spec.Definitions), b.Config, b.shortNames}
+		for key, each := range b.Definitions {
+			if key != subKey {
+				sub.Definitions[key] = each
+			}
+		}
 		sub.addModel(fieldType, "")
-		subKey := sub.keyFrom(fieldType)
 		// merge properties from sub
 		subModel, _ := sub.Definitions[subKey]
 		for k, v := range subModel.Properties {
@@ -303,6 +451,17 @@ func (b *definitionBuilder) buildStructTypeProperty(field reflect.StructField, j
 	return jsonName, prop
 }
 
+// composeAllOf returns the model as the composition of the embedded models it references in AllOf
+// and an inline schema with its own properties, see Config.EmbedAsAllOf.
+func composeAllOf(model spec.Schema) spec.Schema {
+	composed := spec.Schema{SchemaProps: spec.SchemaProps{Description: model.Description, AllOf: model.AllOf}, SwaggerSchemaProps: model.SwaggerSchemaProps, VendorExtensible: model.VendorExtensible}
+	if len(model.Properties) > 0 {
+		own := spec.Schema{SchemaProps: spec.SchemaProps{Required: model.Required, Properties: model.Properties}}
+		composed.AllOf = append(composed.AllOf, own)
+	}
+	return composed
+}
+
 func (b *definitionBuilder) buildArrayTypeProperty(field reflect.StructField, jsonName, modelName string) (nameJson string, prop spec.Schema) {
 	setPropertyMetadata(&prop, field)
 	fieldType := field.Type
@@ -316,6 +475,7 @@ func (b *definitionBuilder) buildArrayTypeProperty(field reflect.StructField, js
 	prop.Items = &spec.SchemaOrArray{
 		Schema: b.SchemaFromModel(fieldType.Elem(), modelName, jsonName),
 	}
+	setXMLItems(&prop, field)
 	return jsonName, prop
 }
 
@@ -356,11 +516,15 @@ func (b *definitionBuilder) getElementTypeName(modelName, jsonName string, t ref
 
 func (b *definitionBuilder) keyFrom(st reflect.Type) string {
 	key := st.String()
+	handled := false
 	if b.Config.ModelTypeNameHandler != nil {
 		if name, ok := b.Config.ModelTypeNameHandler(st); ok {
-			key = name
+			key, handled = name, true
 		}
 	}
+	if !handled && b.Config.ShortDefinitionNames {
+		key = b.shortKeyFrom(st, key)
+	}
 	if len(st.Name()) == 0 { // unnamed type
 		// If it is an array, remove the leading []
 		key = strings.TrimPrefix(key, "[]")
@@ -370,6 +534,33 @@ func (b *definitionBuilder) keyFrom(st reflect.Type) string {
 	return key
 }
 
+// shortKeyFrom returns the key without the package qualifier of the named type, e.g. []User for []v1.User.
+// It returns the qualified key if the short name is used by another type or is the name of a primitive type.
+func (b *definitionBuilder) shortKeyFrom(st reflect.Type, key string) string {
+	prefix := ""
+	for len(st.Name()) == 0 && (st.Kind() == reflect.Slice || st.Kind() == reflect.Ptr) {
+		if st.Kind() == reflect.Slice {
+			prefix += "[]"
+		} else {
+			prefix += "*"
+		}
+		st = st.Elem()
+	}
+	short := st.Name()
+	if len(short) == 0 || b.isPrimitiveType(st.String()) || b.isPrimitiveType(short) {
+		return key
+	}
+	if b.shortNames == nil {
+		b.shortNames = map[string]reflect.Type{}
+	}
+	if other, ok := b.shortNames[short]; ok && other != st {
+		// the first type keeps the short name
+		return key
+	}
+	b.shortNames[short] = st
+	return prefix + short
+}
+
 // see also https://golang.org/ref/spec#Numeric_types
 func (b *definitionBuilder) isPrimitiveType(modelName string) bool {
 	if len(modelName) == 0 {
@@ -398,6 +589,18 @@ func (b *definitionBuilder) jsonSchemaType(modelName string) string {
 	return jsonSchemaType(modelName)
 }
 
+// primitiveFormat returns the format of a primitive type: the one of the SchemaFormatHandler for its name,
+// e.g. main.Amount for a named float64, else the built-in one of its kind.
+// The format tag of a field takes precedence over both, see setFormat.
+func (b *definitionBuilder) primitiveFormat(st reflect.Type) string {
+	if b.Config.SchemaFormatHandler != nil {
+		if mapped := b.Config.SchemaFormatHandler(b.keyFrom(st)); mapped != "" {
+			return mapped
+		}
+	}
+	return jsonSchemaFormat(st.Kind().String())
+}
+
 func (b *definitionBuilder) jsonSchemaFormat(modelName string) string {
 	if b.Config.SchemaFormatHandler != nil {
 		if mapped := b.Config.SchemaFormatHandler(modelName); mapped != "" {
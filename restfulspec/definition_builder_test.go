@@ -1,6 +1,8 @@
 package restfulspec
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/tangblue/goapi/spec"
@@ -185,3 +187,326 @@ func TestRecursiveDictionarySupport(t *testing.T) {
 		}
 	}
 }
+
+type Node struct {
+	Name     string  `json:"name"`
+	Parent   *Node   `json:"parent,omitempty"`
+	Children []*Node `json:"children"`
+	Siblings *[]Node `json:"siblings,omitempty"`
+}
+
+type NodeBase struct {
+	Owner *TreeNode `json:"owner,omitempty"`
+}
+
+type TreeNode struct {
+	NodeBase
+	Children []TreeNode `json:"children"`
+}
+
+func TestRecursivePointerSupport(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(Node{})
+
+	schema, ok := db.Definitions["restfulspec.Node"]
+	if !ok {
+		t.Fatalf("could not find schema in %v", db.Definitions)
+	}
+	if got, want := refOf(schema.Properties["parent"]), "#/definitions/restfulspec.Node"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := schema.Properties["children"].Items.Schema.Ref.String(), "#/definitions/restfulspec.Node"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := schema.Properties["siblings"].Items.Schema.Ref.String(), "#/definitions/restfulspec.Node"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := len(db.Definitions), 1; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestRecursiveEmbeddedSupport(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(TreeNode{})
+
+	schema := db.Definitions["restfulspec.TreeNode"]
+	if got, want := refOf(schema.Properties["owner"]), "#/definitions/restfulspec.TreeNode"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := schema.Properties["children"].Items.Schema.Ref.String(), "#/definitions/restfulspec.TreeNode"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func refOf(schema spec.Schema) string {
+	return schema.Ref.String()
+}
+
+func TestRecursivePointerSupportWithTypeNameHandler(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{
+		ModelTypeNameHandler: func(t reflect.Type) (string, bool) {
+			return t.Name(), t.Name() != ""
+		}}}
+	db.addModelFrom(Node{})
+
+	schema := db.Definitions["Node"]
+	if got, want := schema.Properties["children"].Items.Schema.Ref.String(), "#/definitions/Node"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := len(db.Definitions), 1; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+type License struct {
+	Name string `json:"name"`
+}
+
+type Product struct {
+	License   License         `json:"license"`
+	Upstream  spec.License    `json:"upstream"`
+	Licenses  []License       `json:"licenses"`
+	Upstreams []*spec.License `json:"upstreams"`
+}
+
+func TestShortDefinitionNames(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{ShortDefinitionNames: true}}
+	db.addModelFrom(Product{})
+
+	for _, each := range []string{"Product", "License", "spec.License"} {
+		if _, ok := db.Definitions[each]; !ok {
+			t.Errorf("missing definition %q", each)
+		}
+	}
+	if got, want := len(db.Definitions), 3; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	schema := db.Definitions["Product"]
+	for name, want := range map[string]string{
+		"license":   "#/definitions/License",
+		"upstream":  "#/definitions/spec.License",
+		"licenses":  "#/definitions/License",
+		"upstreams": "#/definitions/spec.License",
+	} {
+		prop := schema.Properties[name]
+		if prop.Items != nil {
+			prop = *prop.Items.Schema
+		}
+		if got := refOf(prop); got != want {
+			t.Errorf("%s: got %v want %v", name, got, want)
+		}
+	}
+}
+
+func TestShortDefinitionNamesKeepsTypeNameHandler(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{
+		ShortDefinitionNames: true,
+		ModelTypeNameHandler: func(t reflect.Type) (string, bool) {
+			return "v1." + t.Name(), t.Name() == "Apple"
+		}}}
+	db.addModelFrom(Apple{})
+	db.addModelFrom(Item{})
+
+	for _, each := range []string{"v1.Apple", "Item"} {
+		if _, ok := db.Definitions[each]; !ok {
+			t.Errorf("missing definition %q", each)
+		}
+	}
+}
+
+type Base struct {
+	ID      string `json:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+type Article struct {
+	Base
+	Title string `json:"title"`
+}
+
+func TestEmbeddedStructFlattened(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(Article{})
+
+	if _, ok := db.Definitions["restfulspec.Base"]; ok {
+		t.Error("unexpected definition restfulspec.Base")
+	}
+	schema := db.Definitions["restfulspec.Article"]
+	if got, want := len(schema.AllOf), 0; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	for _, each := range []string{"id", "created", "title"} {
+		if _, ok := schema.Properties[each]; !ok {
+			t.Errorf("missing property %q", each)
+		}
+	}
+	if got, want := schema.Required, []string{"id", "title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestEmbeddedStructAsAllOf(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{EmbedAsAllOf: true}}
+	db.addModelFrom(Article{})
+
+	base, ok := db.Definitions["restfulspec.Base"]
+	if !ok {
+		t.Fatal("missing definition restfulspec.Base")
+	}
+	if got, want := base.Required, []string{"id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+	schema := db.Definitions["restfulspec.Article"]
+	if len(schema.Properties) != 0 {
+		t.Errorf("got properties %v want none", schema.Properties)
+	}
+	if got, want := len(schema.AllOf), 2; got != want {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if got, want := refOf(schema.AllOf[0]), "#/definitions/restfulspec.Base"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	own := schema.AllOf[1]
+	if _, ok := own.Properties["title"]; !ok || len(own.Properties) != 1 {
+		t.Errorf("got properties %v want [title]", own.Properties)
+	}
+	if got, want := own.Required, []string{"title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+type Review struct {
+	Status string `json:"status"`
+	Reason string `json:"reason" requiredIf:"status=rejected"`
+}
+
+func TestRequiredIfExtension(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(Review{})
+
+	schema := db.Definitions["restfulspec.Review"]
+	if got, want := schema.Required, []string{"status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := schema.Extensions[ExtensionRequiredIf], map[string]string{"reason": "status=rejected"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+type Event struct {
+	Payload  json.RawMessage        `json:"payload"`
+	Previous *json.RawMessage       `json:"previous,omitempty"`
+	Data     interface{}            `json:"data"`
+	Items    []interface{}          `json:"items"`
+	Labels   map[string]interface{} `json:"labels"`
+}
+
+func TestFreeFormProperties(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(Event{})
+
+	if got, want := len(db.Definitions), 1; got != want {
+		t.Errorf("got %d definitions want %d: %v", got, want, db.Definitions)
+	}
+	schema := db.Definitions["restfulspec.Event"]
+	for _, each := range []string{"payload", "previous", "data"} {
+		prop := schema.Properties[each]
+		if len(prop.Type) != 0 || prop.Ref.String() != "" || prop.Items != nil {
+			t.Errorf("%s: got %v want a free-form schema", each, prop)
+		}
+	}
+	items := schema.Properties["items"].Items.Schema
+	if len(items.Type) != 0 || items.Ref.String() != "" {
+		t.Errorf("items: got %v want a free-form schema", items)
+	}
+	if got, want := schema.Properties["labels"].Type, (spec.StringOrArray{"object"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("labels: got %v want %v", got, want)
+	}
+}
+
+type Contact struct {
+	Address struct {
+		Street string `json:"street"`
+		Geo    struct {
+			Lat float64 `json:"lat"`
+		} `json:"geo"`
+	} `json:"address"`
+	Billing *struct {
+		Street string `json:"street"`
+	} `json:"billing,omitempty"`
+	Phones []struct {
+		Number string `json:"number"`
+	} `json:"phones"`
+}
+
+func TestAnonymousStructNames(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(Contact{})
+
+	for _, each := range []string{
+		"restfulspec.Contact",
+		"restfulspec.Contact.address",
+		"restfulspec.Contact.address.geo",
+		"restfulspec.Contact.billing",
+		"restfulspec.Contact.phones",
+	} {
+		if _, ok := db.Definitions[each]; !ok {
+			t.Errorf("missing definition %s", each)
+		}
+	}
+	if got, want := len(db.Definitions), 5; got != want {
+		t.Errorf("got %d definitions want %d: %v", got, want, db.Definitions)
+	}
+	props := db.Definitions["restfulspec.Contact"].Properties
+	address := props["address"]
+	if got, want := address.Ref.String(), "#/definitions/restfulspec.Contact.address"; got != want {
+		t.Errorf("got ref %s want %s", got, want)
+	}
+	billing := props["billing"]
+	if got, want := billing.Ref.String(), "#/definitions/restfulspec.Contact.billing"; got != want {
+		t.Errorf("got ref %s want %s", got, want)
+	}
+}
+
+type UUID struct {
+	High, Low uint64
+}
+
+type Account struct {
+	ID      UUID            `json:"id" description:"identifier"`
+	Parent  *UUID           `json:"parent"`
+	Members []UUID          `json:"members"`
+	Roles   map[string]UUID `json:"roles"`
+}
+
+func TestTypeSchemaOverrides(t *testing.T) {
+	uuid := *spec.StrFmtProperty("uuid")
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{
+		TypeSchemaOverrides: map[reflect.Type]spec.Schema{reflect.TypeOf(UUID{}): uuid},
+	}}
+	db.addModelFrom(Account{})
+
+	if _, ok := db.Definitions["restfulspec.UUID"]; ok || len(db.Definitions) != 1 {
+		t.Errorf("got definitions %v want restfulspec.Account only", db.Definitions)
+	}
+	props := db.Definitions["restfulspec.Account"].Properties
+	for name, schema := range map[string]*spec.Schema{
+		"id":      &spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}, Format: "uuid", Description: "identifier"}},
+		"parent":  &uuid,
+		"members": spec.ArrayProperty(&uuid),
+		"roles":   spec.MapProperty(&uuid),
+	} {
+		got, _ := json.Marshal(props[name])
+		want, _ := json.Marshal(schema)
+		if string(got) != string(want) {
+			t.Errorf("%s: got %s want %s", name, got, want)
+		}
+	}
+	if got := db.SchemaFromModel(reflect.TypeOf([]UUID{}), "", ""); got.Items.Schema.Format != "uuid" {
+		t.Errorf("got %v want an array of uuid", got)
+	}
+	if uuid.Description != "" {
+		t.Errorf("got description %q of the override want it unchanged", uuid.Description)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"reflect"
 
 	"github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/restful/log"
 	"github.com/tangblue/goapi/spec"
 )
 
@@ -48,17 +49,29 @@ func (b *parameterBuilder) build(param *restful.Parameter, pattern string, defBu
 }
 
 func (b *parameterBuilder) createParameter(param *restful.Parameter, defBuilder *definitionBuilder) spec.Parameter {
+	required := param.Required
+	if param.In == "path" && !required {
+		// OpenAPI 2.0 requires path parameters ; the parameter itself is left unchanged
+		log.Printf("warning: path parameter %s is not required ; it is documented as required", param.Name)
+		required = true
+	}
 	if param.Model == nil {
-		return param.Parameter
+		p := param.Parameter
+		p.Required = required
+		return withSensitive(param, p)
 	}
 
-	if param.Required {
-		param.Example = param.Model
-	} else {
+	if required {
+		// a default has no meaning for a required parameter ; the model serves as example unless one is given
+		if param.Example == nil {
+			param.Example = param.Model
+		}
+	} else if param.CollectionFormat == "" {
+		// the model of an array is the type of its items, not a default
 		param.Default = param.Model
 	}
 
-	if param.TypeName() == "" {
+	if param.TypeName() == "" && param.In != "body" {
 		typeName := reflect.TypeOf(param.Model).Kind().String()
 		if !isPrimitiveType(typeName) {
 			panic("parameter type is not primitive.")
@@ -67,6 +80,8 @@ func (b *parameterBuilder) createParameter(param *restful.Parameter, defBuilder
 			param.Type = "array"
 			param.Items = spec.NewItems()
 			param.Items.Typed(jsonSchemaType(typeName), jsonSchemaFormat(typeName))
+			// the enum restricts each item
+			param.Items.Enum = param.Enum
 		} else {
 			param.Typed(jsonSchemaType(typeName), jsonSchemaFormat(typeName))
 		}
@@ -75,8 +90,33 @@ func (b *parameterBuilder) createParameter(param *restful.Parameter, defBuilder
 	if param.In == "body" && param.Schema == nil {
 		st := reflect.TypeOf(param.Model)
 		param.SimpleSchema = spec.SimpleSchema{}
-		param.Schema = defBuilder.SchemaFromModel(st, "", "")
+		if param.IsPartial() {
+			param.Schema = defBuilder.PartialSchemaFromModel(st)
+		} else {
+			param.Schema = defBuilder.SchemaFromModel(st, "", "")
+		}
 	}
 
-	return param.Parameter
+	p := param.Parameter
+	p.Required = required
+	if p.Items != nil && len(p.Items.Enum) > 0 {
+		// documented on the items only ; GetParameter validates each item against the enum of the parameter
+		p.Enum = nil
+	}
+	return withSensitive(param, p)
+}
+
+// withSensitive returns the documentation of the parameter with ExtensionSensitive if it is Sensitive ;
+// the extensions of the restful.Parameter are not modified.
+func withSensitive(param *restful.Parameter, p spec.Parameter) spec.Parameter {
+	if !param.IsSensitive() {
+		return p
+	}
+	extensions := spec.Extensions{}
+	for key, value := range p.Extensions {
+		extensions[key] = value
+	}
+	p.Extensions = extensions
+	p.AddExtension(ExtensionSensitive, true)
+	return p
 }
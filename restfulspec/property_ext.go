@@ -2,6 +2,7 @@ package restfulspec
 
 import (
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/tangblue/goapi/spec"
@@ -31,6 +32,14 @@ func setEnumValues(prop *spec.Schema, field reflect.StructField) {
 	}
 }
 
+// setFormat documents the format of the field, e.g. `format:"decimal"` ; it takes precedence
+// over the SchemaFormatHandler and the built-in format of the type.
+func setFormat(prop *spec.Schema, field reflect.StructField) {
+	if tag := field.Tag.Get("format"); tag != "" {
+		prop.Format = tag
+	}
+}
+
 func setMaximum(prop *spec.Schema, field reflect.StructField) {
 	if tag := field.Tag.Get("maximum"); tag != "" {
 		prop.Maximum = stringReflectType(field.Type, tag)
@@ -43,6 +52,34 @@ func setMinimum(prop *spec.Schema, field reflect.StructField) {
 	}
 }
 
+func setMinLength(prop *spec.Schema, field reflect.StructField) {
+	if tag := field.Tag.Get("minLength"); tag != "" && isStringProperty(prop, field) {
+		if v, err := strconv.ParseInt(tag, 10, 64); err == nil {
+			prop.MinLength = &v
+		}
+	}
+}
+
+func setMaxLength(prop *spec.Schema, field reflect.StructField) {
+	if tag := field.Tag.Get("maxLength"); tag != "" && isStringProperty(prop, field) {
+		if v, err := strconv.ParseInt(tag, 10, 64); err == nil {
+			prop.MaxLength = &v
+		}
+	}
+}
+
+// isStringProperty returns whether the field is a string (or pointer to one) or its type is overridden to be a string.
+func isStringProperty(prop *spec.Schema, field reflect.StructField) bool {
+	if prop.Type.Contains("string") {
+		return true
+	}
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	return ft.Kind() == reflect.String
+}
+
 func setType(prop *spec.Schema, field reflect.StructField) {
 	if tag := field.Tag.Get("type"); tag != "" {
 		// Check if the first two characters of the type tag are
@@ -93,5 +130,86 @@ func setPropertyMetadata(prop *spec.Schema, field reflect.StructField) {
 	setMaximum(prop, field)
 	setUniqueItems(prop, field)
 	setType(prop, field)
+	setMinLength(prop, field)
+	setMaxLength(prop, field)
 	setReadOnly(prop, field)
+	setFormat(prop, field)
+	setXML(prop, field)
+	setDeprecated(prop, field)
+}
+
+// setDeprecated flags a property that is being phased out, e.g. `deprecated:"true"`, see ExtensionDeprecated.
+func setDeprecated(prop *spec.Schema, field reflect.StructField) {
+	if deprecated, err := strconv.ParseBool(field.Tag.Get("deprecated")); err == nil && deprecated {
+		prop.AddExtension(ExtensionDeprecated, true)
+	}
+}
+
+// setXML documents the name, namespace and attribute flag from the xml tag, e.g. `xml:"id,attr"`.
+// A tag like `xml:"items>item"` on a slice documents a wrapped array, see setXMLItems.
+func setXML(prop *spec.Schema, field reflect.StructField) {
+	tag := field.Tag.Get("xml")
+	if tag == "" || tag == "-" {
+		return
+	}
+	namespace, path, flags := parseXMLTag(tag)
+	if containsString(flags, "chardata") || containsString(flags, "innerxml") || containsString(flags, "comment") {
+		// not an element or attribute of its own
+		return
+	}
+	names := strings.Split(path, ">")
+	name := names[0]
+	attribute := containsString(flags, "attr")
+	if name == "" && attribute {
+		name = field.Name
+	}
+	if name != "" {
+		prop.WithXMLName(name)
+	}
+	if namespace != "" {
+		prop.WithXMLNamespace(namespace)
+	}
+	if attribute {
+		prop.AsXMLAttribute()
+	}
+	if len(names) > 1 && isSliceField(field) {
+		prop.AsWrappedXML()
+	}
+}
+
+// setXMLItems documents the name of the elements of a wrapped array, e.g. item for `xml:"items>item"`.
+func setXMLItems(prop *spec.Schema, field reflect.StructField) {
+	_, path, _ := parseXMLTag(field.Tag.Get("xml"))
+	names := strings.Split(path, ">")
+	if len(names) < 2 || prop.Items == nil || prop.Items.Schema == nil {
+		return
+	}
+	prop.Items.Schema.WithXMLName(names[len(names)-1])
+}
+
+// parseXMLTag returns the namespace, the name (path) and the flags of an xml tag, e.g. `xml:"ns name,attr"`.
+func parseXMLTag(tag string) (namespace, path string, flags []string) {
+	parts := strings.Split(tag, ",")
+	path, flags = parts[0], parts[1:]
+	if i := strings.LastIndex(path, " "); i != -1 {
+		namespace, path = path[:i], path[i+1:]
+	}
+	return namespace, path, flags
+}
+
+func isSliceField(field reflect.StructField) bool {
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	return ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array
+}
+
+func containsString(list []string, value string) bool {
+	for _, each := range list {
+		if each == value {
+			return true
+		}
+	}
+	return false
 }
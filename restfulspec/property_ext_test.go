@@ -1,10 +1,14 @@
 package restfulspec
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net"
 	"strings"
 	"testing"
+
+	"github.com/tangblue/goapi/spec"
 )
 
 func TestThatExtraTagsAreReadIntoModel(t *testing.T) {
@@ -21,6 +25,8 @@ func TestThatExtraTagsAreReadIntoModel(t *testing.T) {
 		Password  string
 		Optional  bool   `optional:"true"`
 		Created   string `readOnly:"true"`
+		Nick      string `minLength:"1" maxLength:"64"`
+		Count     int    `minLength:"1" maxLength:"64"`
 	}
 	d := definitionsFromStruct(Anything{})
 	props, _ := d["restfulspec.Anything"]
@@ -76,6 +82,17 @@ func TestThatExtraTagsAreReadIntoModel(t *testing.T) {
 	if got, want := p9.ReadOnly, true; got != want {
 		t.Errorf("got %v want %v", got, want)
 	}
+	p10, _ := props.Properties["Nick"]
+	if p10.MinLength == nil || *p10.MinLength != 1 {
+		t.Errorf("unexpected minLength %v", p10.MinLength)
+	}
+	if p10.MaxLength == nil || *p10.MaxLength != 64 {
+		t.Errorf("unexpected maxLength %v", p10.MaxLength)
+	}
+	p11, _ := props.Properties["Count"]
+	if p11.MinLength != nil || p11.MaxLength != nil {
+		t.Error("unexpected length constraints on integer property")
+	}
 	if got, want := strings.Contains(fmt.Sprintf("%v", props.Required), "Optional"), false; got != want {
 		t.Errorf("got %v want %v", got, want)
 	}
@@ -83,3 +100,99 @@ func TestThatExtraTagsAreReadIntoModel(t *testing.T) {
 		t.Errorf("got %v want %v", got, want)
 	}
 }
+
+type XMLUser struct {
+	XMLName xml.Name `xml:"urn:users user"`
+	ID      string   `json:"id" xml:"id,attr"`
+	Name    string   `json:"name" xml:"full-name"`
+	Tags    []string `json:"tags" xml:"tags>tag"`
+	Note    string   `json:"note" xml:",chardata"`
+	Age     int      `json:"age"`
+}
+
+func TestXMLTags(t *testing.T) {
+	db := definitionBuilder{Definitions: spec.Definitions{}, Config: Config{}}
+	db.addModelFrom(XMLUser{})
+	sc := db.Definitions["restfulspec.XMLUser"]
+
+	if got, want := *sc.XML, (spec.XMLObject{Name: "user", Namespace: "urn:users"}); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := *sc.Properties["id"].XML, (spec.XMLObject{Name: "id", Attribute: true}); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := *sc.Properties["name"].XML, (spec.XMLObject{Name: "full-name"}); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	tags := sc.Properties["tags"]
+	if got, want := *tags.XML, (spec.XMLObject{Name: "tags", Wrapped: true}); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := tags.Items.Schema.XML.Name, "tag"; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	for _, each := range []string{"note", "age"} {
+		if sc.Properties[each].XML != nil {
+			t.Errorf("%s: unexpected xml %v", each, sc.Properties[each].XML)
+		}
+	}
+	data, _ := json.Marshal(sc.Properties["id"])
+	if !strings.Contains(string(data), `"xml":{"name":"id","attribute":true}`) {
+		t.Errorf("unexpected json %s", data)
+	}
+}
+
+type Amount float64
+
+func TestFormatPrecedence(t *testing.T) {
+	type Payment struct {
+		Total    Amount   `format:"decimal"`
+		Fee      Amount   // format of the handler
+		Rate     float64  // built-in format
+		Discount *Amount  `format:"currency"`
+		Quantity int      `format:"int64"`
+		Currency string   `format:"iso-4217"`
+		Taxes    []Amount // format of the handler for the items
+	}
+	d := definitionsFromStructWithConfig(Payment{}, Config{
+		SchemaFormatHandler: func(typeName string) string {
+			if typeName == "restfulspec.Amount" {
+				return "money"
+			}
+			return ""
+		},
+	})
+	props := d["restfulspec.Payment"].Properties
+	for name, want := range map[string]string{
+		"Total":    "decimal",
+		"Fee":      "money",
+		"Rate":     "double",
+		"Discount": "currency",
+		"Quantity": "int64",
+		"Currency": "iso-4217",
+	} {
+		if got := props[name].Format; got != want {
+			t.Errorf("%s: got format %q want %q", name, got, want)
+		}
+	}
+	if got, want := props["Taxes"].Items.Schema.Format, "money"; got != want {
+		t.Errorf("Taxes items: got format %q want %q", got, want)
+	}
+}
+
+func TestDeprecatedProperty(t *testing.T) {
+	type Customer struct {
+		Name  string
+		Fax   string `deprecated:"true"`
+		Phone string `deprecated:"false"`
+	}
+	props := definitionsFromStruct(Customer{})["restfulspec.Customer"].Properties
+	if deprecated, _ := props["Fax"].Extensions.GetBool(ExtensionDeprecated); !deprecated {
+		t.Errorf("expected %s on Fax", ExtensionDeprecated)
+	}
+	for _, name := range []string{"Name", "Phone"} {
+		if _, ok := props[name].Extensions[ExtensionDeprecated]; ok {
+			t.Errorf("unexpected %s on %s", ExtensionDeprecated, name)
+		}
+	}
+}
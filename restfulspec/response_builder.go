@@ -59,5 +59,14 @@ func (b *responseBuilder) createResponse(e *restful.ResponseError, defBuilder *d
 			e.AddHeader(k, &v)
 		}
 	}
-	return e.Response
+	if len(e.ProducedTypes) > 0 {
+		// OpenAPI 2.0 has no content types per response
+		e.AddExtension(ExtensionProduces, e.ProducedTypes)
+	}
+	r = e.Response
+	if b.Config.ResponseEnvelope != nil && r.Schema != nil && e.Code >= 200 && e.Code <= 299 {
+		// the ResponseError may be shared by routes ; do not wrap its schema more than once
+		r.Schema = b.Config.ResponseEnvelope(r.Schema)
+	}
+	return r
 }
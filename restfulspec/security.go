@@ -0,0 +1,57 @@
+package restfulspec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tangblue/goapi/spec"
+)
+
+// ValidateSecurity returns an error that lists each security requirement, of the Swagger object
+// or of any of its operations, that refers to a scheme name not defined in its SecurityDefinitions.
+func ValidateSecurity(swagger *spec.Swagger) error {
+	problems := undefinedSchemes(swagger.SecurityDefinitions, "security", swagger.Security)
+	if swagger.Paths != nil {
+		paths := make([]string, 0, len(swagger.Paths.Paths))
+		for path := range swagger.Paths.Paths {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			item := swagger.Paths.Paths[path]
+			for _, each := range []struct {
+				method string
+				op     *spec.Operation
+			}{
+				{"GET", item.Get}, {"PUT", item.Put}, {"POST", item.Post}, {"DELETE", item.Delete},
+				{"OPTIONS", item.Options}, {"HEAD", item.Head}, {"PATCH", item.Patch},
+			} {
+				if each.op != nil {
+					problems = append(problems, undefinedSchemes(swagger.SecurityDefinitions, each.method+" "+path, each.op.Security)...)
+				}
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("undefined security schemes: %s", strings.Join(problems, "; "))
+}
+
+// undefinedSchemes returns a description for each name in the requirements that has no definition.
+func undefinedSchemes(definitions spec.SecurityDefinitions, where string, requirements []map[string][]string) (problems []string) {
+	for _, requirement := range requirements {
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, ok := definitions[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s refers to %q", where, name))
+			}
+		}
+	}
+	return problems
+}
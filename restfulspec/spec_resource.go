@@ -1,6 +1,10 @@
 package restfulspec
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
 	"github.com/tangblue/goapi/restful"
 	"github.com/tangblue/goapi/spec"
 )
@@ -31,23 +35,29 @@ func NewOpenAPIService(config Config) *restful.WebService {
 	}
 
 	swagger := BuildSwagger(config)
-	resource := specResource{swagger: swagger}
+	resource := specResource{swagger: swagger, indentJSON: config.IndentJSON}
 	ws.Route(ws.GET("/").Handler(resource.getSwagger))
 	return ws
 }
 
 // BuildSwagger returns a Swagger object for all services' API endpoints.
+// It panics if Config.ValidateSecurity is set and a security requirement refers to an undefined scheme.
 func BuildSwagger(config Config) *spec.Swagger {
 	// collect paths and model definitions to build Swagger object.
 	paths := &spec.Paths{Paths: map[string]spec.PathItem{}}
 	sb := &swaggerBuilder{}
 	sb.def.Definitions = spec.Definitions{}
+	sb.def.Config = config
+	sb.resp.Config = config
 
 	for _, each := range config.WebServices {
 		for path, item := range buildPaths(each, config, sb).Paths {
 			existingPathItem, ok := paths.Paths[path]
 			if ok {
 				for _, r := range each.Routes() {
+					if isHidden(r) || !documentsVersion(each, r, config) {
+						continue
+					}
 					_, patterns := sanitizePath(r.Path)
 					item = buildPathItem(each, r, existingPathItem, patterns, config, sb)
 				}
@@ -57,19 +67,49 @@ func BuildSwagger(config Config) *spec.Swagger {
 	}
 	swagger := &spec.Swagger{
 		SwaggerProps: spec.SwaggerProps{
-			Swagger:     "2.0",
-			Paths:       paths,
-			Definitions: sb.def.getDefinitions(),
-			Parameters:  sb.param.getRefParameters(&sb.def),
-			Responses:   sb.resp.getRefResponses(&sb.def),
+			Swagger:             "2.0",
+			Host:                config.Host,
+			BasePath:            config.BasePath,
+			Schemes:             validSchemes(config.Schemes),
+			Consumes:            contentTypes(config.Consumes),
+			Produces:            contentTypes(config.Produces),
+			Paths:               paths,
+			Definitions:         sb.def.getDefinitions(),
+			Parameters:          sb.param.getRefParameters(&sb.def),
+			Responses:           sb.resp.getRefResponses(&sb.def),
+			SecurityDefinitions: config.SecurityDefinitions,
+			Security:            config.Security,
 		},
 	}
 	if config.PostBuildSwaggerObjectHandler != nil {
 		config.PostBuildSwaggerObjectHandler(swagger)
 	}
+	if config.ValidateSecurity {
+		if err := ValidateSecurity(swagger); err != nil {
+			panic(err.Error())
+		}
+	}
 	return swagger
 }
 
+var supportedSchemes = []string{"http", "https", "ws", "wss"}
+
+// validSchemes returns the schemes if all are supported by the specification ; it panics otherwise.
+func validSchemes(schemes []string) []string {
+	for _, each := range schemes {
+		supported := false
+		for _, other := range supportedSchemes {
+			if each == other {
+				supported = true
+			}
+		}
+		if !supported {
+			panic(fmt.Sprintf("unsupported scheme %q, must be one of %v", each, supportedSchemes))
+		}
+	}
+	return schemes
+}
+
 func enableCORS(req *restful.Request, resp *restful.Response, next func(*restful.Request, *restful.Response)) {
 	if origin := req.HeaderParameter(restful.HEADER_Origin); origin != "" {
 		// prevent duplicate header
@@ -82,9 +122,21 @@ func enableCORS(req *restful.Request, resp *restful.Response, next func(*restful
 
 // specResource is a REST resource to serve the Open-API spec.
 type specResource struct {
-	swagger *spec.Swagger
+	swagger    *spec.Swagger
+	indentJSON bool
 }
 
 func (s specResource) getSwagger(req *restful.Request, resp *restful.Response) {
-	resp.WriteAsJson(s.swagger)
+	if !s.indentJSON {
+		resp.PrettyPrint(false)
+		resp.WriteAsJson(s.swagger)
+		return
+	}
+	output, err := json.MarshalIndent(s.swagger, "", "  ")
+	if err != nil {
+		resp.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+	resp.Header().Set(restful.HEADER_ContentType, restful.MIME_JSON)
+	resp.Write(output)
 }
@@ -1,9 +1,15 @@
 package restfulspec
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	restful "github.com/tangblue/goapi/restful"
+	"github.com/tangblue/goapi/spec"
 )
 
 func TestBuildSwagger(t *testing.T) {
@@ -11,11 +17,11 @@ func TestBuildSwagger(t *testing.T) {
 
 	ws1 := new(restful.WebService)
 	ws1.Path(path)
-	ws1.Route(ws1.GET("").To(dummy))
+	ws1.Route(ws1.GET("").Handler(dummy))
 
 	ws2 := new(restful.WebService)
 	ws2.Path(path)
-	ws2.Route(ws2.DELETE("").To(dummy))
+	ws2.Route(ws2.DELETE("").Handler(dummy))
 
 	c := Config{}
 	c.WebServices = []*restful.WebService{ws1, ws2}
@@ -26,3 +32,275 @@ func TestBuildSwagger(t *testing.T) {
 	}
 
 }
+
+func TestBuildSwaggerWithBasePath(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/api/users")
+	ws.Route(ws.GET("/{id}").Handler(dummy))
+	ws.Route(ws.GET("").Handler(dummy))
+
+	other := new(restful.WebService)
+	other.Path("/health")
+	other.Route(other.GET("").Handler(dummy))
+
+	s := BuildSwagger(Config{WebServices: []*restful.WebService{ws, other}, BasePath: "/api"})
+
+	if s.BasePath != "/api" {
+		t.Errorf("got basePath %q want %q", s.BasePath, "/api")
+	}
+	for _, path := range []string{"/users/{id}", "/users", "/health"} {
+		if _, ok := s.Paths.Paths[path]; !ok {
+			t.Errorf("expected path %s in %v", path, s.Paths.Paths)
+		}
+	}
+	if _, ok := s.Paths.Paths["/api/users/{id}"]; ok {
+		t.Error("unexpected path with base path prefix")
+	}
+}
+
+func TestBuildSwaggerWithHostAndSchemes(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/users")
+	ws.Route(ws.GET("").Handler(dummy))
+
+	s := BuildSwagger(Config{
+		WebServices: []*restful.WebService{ws},
+		Host:        "api.example.com:8080",
+		Schemes:     []string{"https", "wss"},
+		PostBuildSwaggerObjectHandler: func(s *spec.Swagger) {
+			s.Schemes = append(s.Schemes, "http")
+		}})
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, each := range []string{`"host":"api.example.com:8080"`, `"schemes":["https","wss","http"]`} {
+		if !strings.Contains(string(data), each) {
+			t.Errorf("expected %s in %s", each, data)
+		}
+	}
+}
+
+func TestBuildSwaggerWithUnsupportedScheme(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for unsupported scheme")
+		}
+	}()
+	BuildSwagger(Config{Schemes: []string{"ftp"}})
+}
+
+func TestBuildSwaggerWithSecurity(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/users")
+	ws.Route(ws.GET("").Handler(dummy))
+	ws.Route(ws.POST("").Handler(dummy).Security("api_key", []string{}))
+
+	s := BuildSwagger(Config{
+		WebServices: []*restful.WebService{ws},
+		SecurityDefinitions: spec.SecurityDefinitions{
+			"basic":   spec.BasicAuth(),
+			"api_key": spec.APIKeyAuth("X-API-Key", "header"),
+		},
+		Security: []map[string][]string{{"basic": {}}}})
+
+	if got, want := len(s.SecurityDefinitions), 2; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	item := s.Paths.Paths["/users"]
+	if _, ok := item.Get.Security[0]["basic"]; !ok {
+		t.Errorf("expected default security requirement, got %v", item.Get.Security)
+	}
+	if _, ok := item.Post.Security[0]["api_key"]; !ok || len(item.Post.Security) != 1 {
+		t.Errorf("expected route security requirement only, got %v", item.Post.Security)
+	}
+}
+
+func TestBuildSwaggerWithSecurityRequirements(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/users")
+	// Bearer OR Basic
+	ws.Route(ws.GET("").Handler(dummy).Security("Bearer", nil).Security("Basic", []string{}))
+	// Bearer AND TenantKey
+	ws.Route(ws.DELETE("/{id}").Handler(dummy).SecurityAll(
+		restful.SecurityPair{Name: "Bearer", Scopes: []string{"admin"}},
+		restful.SecurityPair{Name: "TenantKey"}))
+
+	paths := BuildSwagger(Config{WebServices: []*restful.WebService{ws}}).Paths.Paths
+	for _, each := range []struct {
+		op   *spec.Operation
+		want string
+	}{
+		{paths["/users"].Get, `[{"Bearer":[]},{"Basic":[]}]`},
+		{paths["/users/{id}"].Delete, `[{"Bearer":["admin"],"TenantKey":[]}]`},
+	} {
+		data, _ := json.Marshal(each.op.Security)
+		if got := string(data); got != each.want {
+			t.Errorf("got %s want %s", got, each.want)
+		}
+	}
+}
+
+func TestValidateSecurity(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/users")
+	ws.Route(ws.GET("").Handler(dummy).Security("Bearer", []string{}))
+	ws.Route(ws.DELETE("/{id}").Handler(dummy).Security("basic", []string{}))
+
+	config := Config{
+		WebServices:         []*restful.WebService{ws},
+		SecurityDefinitions: spec.SecurityDefinitions{"basic": spec.BasicAuth()},
+	}
+	err := ValidateSecurity(BuildSwagger(config))
+	if err == nil {
+		t.Fatal("expected error for undefined scheme")
+	}
+	if got, want := err.Error(), `undefined security schemes: GET /users refers to "Bearer"`; got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+
+	config.PostBuildSwaggerObjectHandler = func(s *spec.Swagger) {
+		s.SecurityDefinitions["Bearer"] = spec.APIKeyAuth("Authorization", "header")
+	}
+	config.ValidateSecurity = true
+	BuildSwagger(config)
+}
+
+func TestBuildSwaggerValidatesSecurity(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for undefined scheme")
+		}
+	}()
+	ws := new(restful.WebService)
+	ws.Path("/users")
+	ws.Route(ws.GET("").Handler(dummy).Security("Bearer", []string{}))
+	BuildSwagger(Config{WebServices: []*restful.WebService{ws}, ValidateSecurity: true})
+}
+
+func TestBuildSwaggerWithShortDefinitionNames(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/products")
+	ws.Route(ws.GET("").Handler(dummy).Return(200, "OK", Product{}))
+
+	s := BuildSwagger(Config{WebServices: []*restful.WebService{ws}, ShortDefinitionNames: true})
+
+	for _, each := range []string{"Product", "License", "spec.License"} {
+		if _, ok := s.Definitions[each]; !ok {
+			t.Errorf("missing definition %q", each)
+		}
+	}
+}
+
+func TestServeIndentedJSON(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Path("/tests")
+	ws.Route(ws.GET("").Handler(dummy))
+
+	for _, indent := range []bool{false, true} {
+		container := restful.NewContainer()
+		container.Add(NewOpenAPIService(Config{WebServices: []*restful.WebService{ws}, APIPath: "/apidocs.json", IndentJSON: indent}))
+		recorder := httptest.NewRecorder()
+		container.ServeHTTP(recorder, httptest.NewRequest("GET", "/apidocs.json", nil))
+
+		if got := recorder.Header().Get(restful.HEADER_ContentType); got != restful.MIME_JSON {
+			t.Errorf("indent %v: got content type %q", indent, got)
+		}
+		body := strings.TrimSpace(recorder.Body.String())
+		if got := strings.Contains(body, "\n"); got != indent {
+			t.Errorf("indent %v: got newlines %v in %q", indent, got, body)
+		}
+		var swagger spec.Swagger
+		if err := json.Unmarshal(recorder.Body.Bytes(), &swagger); err != nil {
+			t.Errorf("indent %v: %v", indent, err)
+		}
+	}
+}
+
+func TestBuildSwaggerSkipsStaticService(t *testing.T) {
+	ws := new(restful.WebService).Path("/tests")
+	ws.Route(ws.GET("/a").Handler(dummy))
+	ws.Route(ws.GET("/b").Handler(dummy).Metadata(KeyOpenAPIHidden, true))
+	static := restful.StaticService("/apidocs", fstest.MapFS{"index.html": {Data: []byte("ui")}})
+
+	paths := BuildSwagger(Config{WebServices: []*restful.WebService{ws, static}}).Paths.Paths
+	if _, ok := paths["/tests/a"]; !ok {
+		t.Error("expected path /tests/a")
+	}
+	for path := range paths {
+		if path != "/tests/a" {
+			t.Errorf("unexpected path %s", path)
+		}
+	}
+}
+
+func TestBuildSwaggerWithContainerDefaults(t *testing.T) {
+	wc := restful.NewContainer()
+	wc.DefaultProduces(restful.MIME_JSON)
+	ws := new(restful.WebService).Path("/tests")
+	ws.Route(ws.GET("/a").Handler(dummy))
+	ws.Route(ws.GET("/b").Produces(restful.MIME_XML).Handler(dummy))
+	if err := wc.Add(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := BuildSwagger(Config{WebServices: wc.RegisteredWebServices()}).Paths.Paths
+	if got := paths["/tests/a"].Get.Produces; len(got) != 1 || got[0] != restful.MIME_JSON {
+		t.Errorf("got produces %v want [%s]", got, restful.MIME_JSON)
+	}
+	if got := paths["/tests/b"].Get.Produces; len(got) != 1 || got[0] != restful.MIME_XML {
+		t.Errorf("got produces %v want [%s]", got, restful.MIME_XML)
+	}
+}
+
+func TestBuildSwaggerWebServiceMetadataTags(t *testing.T) {
+	ws := new(restful.WebService).Path("/tests").Metadata(KeyOpenAPITags, []string{"tests"})
+	ws.Route(ws.GET("/a").Handler(dummy))
+	ws.Route(ws.GET("/b").Handler(dummy).Metadata(KeyOpenAPITags, []string{"other"}))
+
+	paths := BuildSwagger(Config{WebServices: []*restful.WebService{ws}}).Paths.Paths
+	if got := paths["/tests/a"].Get.Tags; len(got) != 1 || got[0] != "tests" {
+		t.Errorf("got tags %v want [tests]", got)
+	}
+	if got := paths["/tests/b"].Get.Tags; len(got) != 1 || got[0] != "other" {
+		t.Errorf("got tags %v want [other]", got)
+	}
+}
+
+// dataEnvelope documents the payloads written using restful.Response.WriteEnveloped
+func dataEnvelope(schema *spec.Schema) *spec.Schema {
+	return new(spec.Schema).Typed("object", "").
+		SetProperty("data", *schema).
+		SetProperty("meta", *spec.MapProperty(nil)).
+		WithRequired("data")
+}
+
+func TestBuildSwaggerWithResponseEnvelope(t *testing.T) {
+	ws := new(restful.WebService).Path("/tests")
+	ws.Route(ws.GET("/a").Handler(dummy).
+		Return(http.StatusOK, "OK", Sample{}).
+		Return(http.StatusBadRequest, "Bad Request", restful.ServiceError{}))
+	ws.Route(ws.DELETE("/a").Handler(dummy).
+		Return(http.StatusNoContent, "No Content", nil))
+
+	swagger := BuildSwagger(Config{WebServices: []*restful.WebService{ws}, ResponseEnvelope: dataEnvelope})
+	responses := swagger.Paths.Paths["/tests/a"].Get.Responses.StatusCodeResponses
+	schema := responses[http.StatusOK].Schema
+	if schema == nil || !schema.Type.Contains("object") {
+		t.Fatalf("got schema %v want an envelope", schema)
+	}
+	data := schema.Properties["data"]
+	if got, want := data.Ref.String(), "#/definitions/restfulspec.Sample"; got != want {
+		t.Errorf("got data %q want %q", got, want)
+	}
+	if _, ok := schema.Properties["meta"]; !ok {
+		t.Error("missing meta property")
+	}
+	if got := responses[http.StatusBadRequest].Schema; got == nil || len(got.Properties) > 0 {
+		t.Errorf("got error schema %v want the ServiceError not wrapped", got)
+	}
+	if got := swagger.Paths.Paths["/tests/a"].Delete.Responses.StatusCodeResponses[http.StatusNoContent].Schema; got != nil {
+		t.Errorf("got schema %v of a response without payload", got)
+	}
+}